@@ -15,6 +15,9 @@
 package main
 
 import (
+	"errors"
+	"os"
+
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that `exec-entrypoint` and `run` can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -26,6 +29,12 @@ import (
 
 func main() {
 	if err := cli.Run(); err != nil {
+		// Commands may set the exit code by class of failure.
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			log.Error(err)
+			os.Exit(exitErr.ExitCode())
+		}
 		log.Fatal(err)
 	}
 }
@@ -4,6 +4,8 @@ go 1.16
 
 require (
 	github.com/blang/semver/v4 v4.0.0
+	github.com/docker/distribution v2.7.1+incompatible
+	github.com/evanphx/json-patch v4.11.0+incompatible
 	github.com/fatih/structtag v1.1.0
 	github.com/go-logr/logr v0.4.0
 	github.com/go-task/slim-sprig v0.0.0-20210107165309-348f09dbbbc0
@@ -13,6 +15,8 @@ require (
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.2.2
 	github.com/onsi/ginkgo v1.16.4
 	github.com/onsi/gomega v1.15.0
+	github.com/opencontainers/go-digest v1.0.0
+	github.com/opencontainers/image-spec v1.0.2-0.20190823105129-775207bd45b6
 	github.com/operator-framework/api v0.10.7
 	github.com/operator-framework/java-operator-plugins v0.1.0
 	github.com/operator-framework/operator-lib v0.6.0
@@ -27,9 +31,11 @@ require (
 	github.com/spf13/viper v1.8.1
 	github.com/stretchr/testify v1.7.0
 	github.com/thoas/go-funk v0.8.0
+	github.com/xeipuuv/gojsonschema v1.2.0
 	golang.org/x/mod v0.4.2
 	golang.org/x/tools v0.1.5
 	gomodules.xyz/jsonpatch/v3 v3.0.1
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 	helm.sh/helm/v3 v3.6.2
 	k8s.io/api v0.22.2
 	k8s.io/apiextensions-apiserver v0.22.2
@@ -37,9 +43,11 @@ require (
 	k8s.io/cli-runtime v0.21.0
 	k8s.io/client-go v0.22.2
 	k8s.io/kubectl v0.21.0
+	k8s.io/utils v0.0.0-20210819203725-bdf08cb9a70a
 	sigs.k8s.io/controller-runtime v0.10.0
 	sigs.k8s.io/controller-tools v0.7.0
 	sigs.k8s.io/kubebuilder/v3 v3.0.0-alpha.0.0.20211001202619-87eb9d55ecdc
+	sigs.k8s.io/kustomize/api v0.8.5
 	sigs.k8s.io/yaml v1.2.0
 )
 
@@ -53,7 +61,6 @@ replace (
 	github.com/docker/distribution => github.com/docker/distribution v0.0.0-20191216044856-a8371794149d
 	github.com/mattn/go-sqlite3 => github.com/mattn/go-sqlite3 v1.10.0
 	golang.org/x/text => golang.org/x/text v0.3.3 // Required to fix CVE-2020-14040
-
 )
 
 exclude github.com/spf13/viper v1.3.2 // Required to fix CVE-2018-1098
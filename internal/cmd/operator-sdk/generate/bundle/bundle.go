@@ -202,6 +202,7 @@ func (c bundleCmd) runManifests() (err error) {
 		Collector:            col,
 		Annotations:          metricsannotations.MakeBundleObjectAnnotations(c.layout),
 		ExtraServiceAccounts: c.extraServiceAccounts,
+		AllowEmptyInstall:    c.allowEmptyInstall,
 	}
 	if err := csvGen.Generate(opts...); err != nil {
 		return fmt.Errorf("error generating ClusterServiceVersion: %v", err)
@@ -214,7 +215,7 @@ func (c bundleCmd) runManifests() (err error) {
 		}
 	} else {
 		dir := filepath.Join(c.outputDir, bundle.ManifestsDir)
-		if err := genutil.WriteObjectsToFiles(dir, objs...); err != nil {
+		if err := genutil.WriteObjectsToFiles(dir, genutil.WriteOptions{}, objs...); err != nil {
 			return err
 		}
 	}
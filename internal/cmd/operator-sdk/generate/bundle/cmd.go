@@ -40,6 +40,8 @@ type bundleCmd struct {
 	quiet        bool
 	// ServiceAccount names to consider outside of the operator's service account.
 	extraServiceAccounts []string
+	// allowEmptyInstall allows a CSV with no install strategy deployments.
+	allowEmptyInstall bool
 
 	// Metadata options.
 	channels       string
@@ -134,6 +136,9 @@ func (c *bundleCmd) addFlagsTo(fs *pflag.FlagSet) {
 	fs.StringSliceVar(&c.extraServiceAccounts, "extra-service-accounts", nil,
 		"Names of service accounts, outside of the operator's Deployment account, "+
 			"that have bindings to {Cluster}Roles that should be added to the CSV")
+	fs.BoolVar(&c.allowEmptyInstall, "allow-empty-install", false, "Allow generating a CSV whose install strategy "+
+		"has no Deployments, which OLM installs as an operator that does nothing. Without this flag, generation fails "+
+		"if no Deployments are collected")
 	fs.BoolVar(&c.overwrite, "overwrite", true, "Overwrite the bundle's metadata and Dockerfile if they exist")
 	fs.BoolVarP(&c.quiet, "quiet", "q", false, "Run in quiet mode")
 	fs.BoolVar(&c.stdout, "stdout", false, "Write bundle manifest to stdout")
@@ -22,11 +22,15 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/blang/semver/v4"
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
 	"github.com/operator-framework/operator-sdk/internal/util/projutil"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
 	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	cfgv2 "sigs.k8s.io/kubebuilder/v3/pkg/config/v2"
 	"sigs.k8s.io/yaml"
@@ -46,6 +50,25 @@ func ValidateVersion(version string) error {
 	return nil
 }
 
+// ValidateCSVName returns an error if name is not a valid ClusterServiceVersion name of the form
+// <package>.v<version>, where package is a DNS-1123 label and version a strict semantic version.
+func ValidateCSVName(name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		return fmt.Errorf("CSV name %q is invalid: %s", name, strings.Join(errs, "; "))
+	}
+	i := strings.Index(name, ".v")
+	if i < 0 {
+		return fmt.Errorf("CSV name %q must be of the form <package>.v<version>", name)
+	}
+	if errs := validation.IsDNS1123Label(name[:i]); len(errs) != 0 {
+		return fmt.Errorf("CSV name %q package %q is invalid: %s", name, name[:i], strings.Join(errs, "; "))
+	}
+	if err := ValidateVersion(name[i+2:]); err != nil {
+		return fmt.Errorf("CSV name %q version is invalid: %v", name, err)
+	}
+	return nil
+}
+
 // IsPipeReader returns true if stdin is an open pipe, i.e. the caller can
 // accept input from stdin.
 func IsPipeReader() bool {
@@ -58,52 +81,132 @@ func IsPipeReader() bool {
 
 // WriteObjects writes each object in objs to w.
 func WriteObjects(w io.Writer, objs ...client.Object) error {
+	return WriteObjectsFormatted(w, yamlutil.Options{}, objs...)
+}
+
+// WriteObjectsFormatted writes each object in objs to w, formatted with format.
+func WriteObjectsFormatted(w io.Writer, format yamlutil.Options, objs ...client.Object) error {
 	for _, obj := range objs {
-		if err := writeObject(w, obj); err != nil {
+		if err := writeObject(w, obj, format); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// WriteObjectsToFiles creates dir then writes each object in objs to a file in dir.
-func WriteObjectsToFiles(dir string, objs ...client.Object) error {
-	if err := os.MkdirAll(dir, 0755); err != nil {
+// FileNaming is a scheme for naming the file each object is written to.
+type FileNaming string
+
+const (
+	// FileNamingGVK names files "<name>_<group>_<version>_<kind>.yaml", and CRD files "<group>_<plural>.yaml".
+	FileNamingGVK FileNaming = "gvk"
+	// FileNamingKindName names files "<kind>_<name>.yaml", with kind in snake case, ex. "role_binding_manager-rolebinding.yaml".
+	FileNamingKindName FileNaming = "kind-name"
+	// FileNamingName names files "<name>.yaml".
+	FileNamingName FileNaming = "name"
+)
+
+// FileNamings are all file naming schemes.
+var FileNamings = []FileNaming{FileNamingGVK, FileNamingKindName, FileNamingName}
+
+// WriteOptions configure how objects are written to files.
+type WriteOptions struct {
+	// DirMode and FileMode are the modes of the created directory and files. Zero modes keep the defaults.
+	DirMode, FileMode os.FileMode
+	// Format configures how each object's YAML is formatted.
+	Format yamlutil.Options
+	// FileNaming is the scheme file names are made with. An empty scheme is FileNamingGVK.
+	FileNaming FileNaming
+	// Verbatim maps objects to bytes written as their file instead of their marshaled, formatted YAML,
+	// ex. the unchanged source file of an object, to keep its comments.
+	Verbatim map[client.Object][]byte
+}
+
+// WriteObjectsToFiles creates dir then writes each object in objs to a file in dir as configured by opts.
+func WriteObjectsToFiles(dir string, opts WriteOptions, objs ...client.Object) error {
+	fileNames, err := MakeObjectFileNames(opts.FileNaming, objs...)
+	if err != nil {
 		return err
 	}
+	if err := fileutil.MkdirAll(dir, opts.DirMode); err != nil {
+		return err
+	}
+	for i, obj := range objs {
+		if b, isVerbatim := opts.Verbatim[obj]; isVerbatim {
+			if err := fileutil.WriteFile(filepath.Join(dir, fileNames[i]), b, opts.FileMode); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeObjectToFile(dir, obj, fileNames[i], opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// MakeObjectFileNames returns the name of the file each of objs is written to by WriteObjectsToFiles
+// with naming, in order.
+func MakeObjectFileNames(naming FileNaming, objs ...client.Object) ([]string, error) {
+	fileNames := make([]string, 0, len(objs))
 	seenFiles := make(map[string]struct{})
 	// Use the number of dupliates in file names so users can debug duplicate file behavior.
 	dupCount := 0
 	for _, obj := range objs {
 		var fileName string
-		switch t := obj.(type) {
-		case *apiextv1.CustomResourceDefinition:
-			if t.Spec.Group != "" && t.Spec.Names.Plural != "" {
-				fileName = makeCRDFileName(t.Spec.Group, t.Spec.Names.Plural)
-			} else {
-				fileName = makeObjectFileName(t)
-			}
-		case *apiextv1beta1.CustomResourceDefinition:
-			if t.Spec.Group != "" && t.Spec.Names.Plural != "" {
-				fileName = makeCRDFileName(t.Spec.Group, t.Spec.Names.Plural)
-			} else {
-				fileName = makeObjectFileName(t)
-			}
+		switch naming {
+		case "", FileNamingGVK:
+			fileName = makeGVKFileName(obj)
+		case FileNamingKindName:
+			fileName = fmt.Sprintf("%s_%s.yaml", toSnakeCase(obj.GetObjectKind().GroupVersionKind().Kind), obj.GetName())
+		case FileNamingName:
+			fileName = obj.GetName() + ".yaml"
 		default:
-			fileName = makeObjectFileName(t)
+			return nil, fmt.Errorf("unknown file naming scheme %q", naming)
 		}
 
 		if _, hasFile := seenFiles[fileName]; hasFile {
 			fileName = fmt.Sprintf("dup%d_%s", dupCount, fileName)
 			dupCount++
 		}
-		if err := writeObjectToFile(dir, obj, fileName); err != nil {
-			return err
-		}
+		fileNames = append(fileNames, fileName)
 		seenFiles[fileName] = struct{}{}
 	}
-	return nil
+	return fileNames, nil
+}
+
+// makeGVKFileName returns obj's file name for FileNamingGVK.
+func makeGVKFileName(obj client.Object) string {
+	switch t := obj.(type) {
+	case *apiextv1.CustomResourceDefinition:
+		if t.Spec.Group != "" && t.Spec.Names.Plural != "" {
+			return makeCRDFileName(t.Spec.Group, t.Spec.Names.Plural)
+		}
+	case *apiextv1beta1.CustomResourceDefinition:
+		if t.Spec.Group != "" && t.Spec.Names.Plural != "" {
+			return makeCRDFileName(t.Spec.Group, t.Spec.Names.Plural)
+		}
+	}
+	return makeObjectFileName(obj)
+}
+
+// toSnakeCase converts a CamelCase kind to snake case, ex. "ClusterRoleBinding" to "cluster_role_binding".
+// A run of capitals is one word, ex. "CSVName" is "csv_name".
+func toSnakeCase(kind string) string {
+	var sb strings.Builder
+	runes := []rune(kind)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A capital starts a word after a lowercase letter or digit, or ends a run of capitals
+			// if followed by a lowercase letter.
+			if i != 0 && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
 }
 
 func makeCRDFileName(group, resource string) string {
@@ -118,22 +221,25 @@ func makeObjectFileName(obj client.Object) string {
 	return fmt.Sprintf("%s_%s_%s_%s.yaml", obj.GetName(), gvk.Group, gvk.Version, strings.ToLower(gvk.Kind))
 }
 
-// writeObjectToFile marshals crd to bytes and writes them to dir in file.
-func writeObjectToFile(dir string, obj interface{}, fileName string) error {
-	f, err := os.Create(filepath.Join(dir, fileName))
+// writeObjectToFile marshals crd to bytes and writes them to dir in file as configured by opts.
+func writeObjectToFile(dir string, obj interface{}, fileName string, opts WriteOptions) error {
+	f, err := fileutil.Create(filepath.Join(dir, fileName), opts.FileMode)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	return writeObject(f, obj)
+	return writeObject(f, obj, opts.Format)
 }
 
-// writeObject marshals crd to bytes and writes them to w.
-func writeObject(w io.Writer, obj interface{}) error {
+// writeObject marshals crd to bytes, formats them with format, and writes them to w.
+func writeObject(w io.Writer, obj interface{}, format yamlutil.Options) error {
 	b, err := yaml.Marshal(obj)
 	if err != nil {
 		return err
 	}
+	if b, err = yamlutil.Format(b, format); err != nil {
+		return err
+	}
 	_, err = w.Write(b)
 	return err
 }
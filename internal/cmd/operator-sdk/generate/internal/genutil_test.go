@@ -0,0 +1,166 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ValidateCSVName", func() {
+	It("accepts names of the form <package>.v<version>", func() {
+		Expect(ValidateCSVName("memcached-operator.v0.1.0")).To(Succeed())
+		Expect(ValidateCSVName("memcached-operator.v0.1.0-rc.1")).To(Succeed())
+	})
+	It("rejects names without a package and version", func() {
+		Expect(ValidateCSVName("memcached-operator")).To(MatchError(ContainSubstring("must be of the form")))
+		Expect(ValidateCSVName("memcached-operator.v01.0")).To(MatchError(ContainSubstring("version is invalid")))
+		Expect(ValidateCSVName("Memcached.v0.1.0")).To(MatchError(ContainSubstring("is invalid")))
+	})
+})
+
+var _ = DescribeTable("toSnakeCase",
+	func(kind, expected string) {
+		Expect(toSnakeCase(kind)).To(Equal(expected))
+	},
+	Entry("one word", "Role", "role"),
+	Entry("several words", "ClusterRoleBinding", "cluster_role_binding"),
+	Entry("a leading acronym", "CSVName", "csv_name"),
+	Entry("a trailing acronym", "ServiceCA", "service_ca"),
+	Entry("an inner acronym", "ClusterCSVDescription", "cluster_csv_description"),
+	Entry("a digit before a word", "V1Beta1Config", "v1_beta1_config"),
+)
+
+var _ = Describe("WriteObjectsToFiles", func() {
+	var (
+		tmp  string
+		objs []client.Object
+	)
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "genutil-")
+		Expect(err).NotTo(HaveOccurred())
+		objs = []client.Object{
+			&apiextv1.CustomResourceDefinition{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition"},
+				ObjectMeta: metav1.ObjectMeta{Name: "memcacheds.cache.example.com"},
+				Spec: apiextv1.CustomResourceDefinitionSpec{
+					Group: "cache.example.com",
+					Names: apiextv1.CustomResourceDefinitionNames{Plural: "memcacheds"},
+				},
+			},
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: "manager-role"},
+			},
+			&rbacv1.ClusterRole{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+				ObjectMeta: metav1.ObjectMeta{Name: "manager-role"},
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: "manager-rolebinding"},
+			},
+			&corev1.Service{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+				ObjectMeta: metav1.ObjectMeta{Name: "metrics-service"},
+			},
+		}
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	fileNames := func(naming FileNaming) []string {
+		Expect(WriteObjectsToFiles(tmp, WriteOptions{FileNaming: naming}, objs...)).To(Succeed())
+		infos, err := ioutil.ReadDir(tmp)
+		Expect(err).NotTo(HaveOccurred())
+		names := make([]string, len(infos))
+		for i, info := range infos {
+			names[i] = info.Name()
+		}
+		return names
+	}
+
+	It("sets directory and file modes regardless of umask or existing files", func() {
+		dir := filepath.Join(tmp, "0.0.1")
+		role := &rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		}
+		Expect(WriteObjectsToFiles(dir, WriteOptions{}, role)).To(Succeed())
+		Expect(WriteObjectsToFiles(dir, WriteOptions{DirMode: 0750, FileMode: 0640}, role)).To(Succeed())
+
+		info, err := os.Stat(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)))
+		info, err = os.Stat(filepath.Join(dir, "foo_rbac.authorization.k8s.io_v1_role.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0640)))
+	})
+
+	It("names files by name, group, version, and kind by default", func() {
+		Expect(fileNames("")).To(ConsistOf(
+			"cache.example.com_memcacheds.yaml",
+			"manager-role_rbac.authorization.k8s.io_v1_role.yaml",
+			"manager-role_rbac.authorization.k8s.io_v1_clusterrole.yaml",
+			"manager-rolebinding_rbac.authorization.k8s.io_v1_rolebinding.yaml",
+			"metrics-service_v1_service.yaml",
+		))
+	})
+	It("names files by kind and name", func() {
+		Expect(fileNames(FileNamingKindName)).To(ConsistOf(
+			"custom_resource_definition_memcacheds.cache.example.com.yaml",
+			"role_manager-role.yaml",
+			"cluster_role_manager-role.yaml",
+			"role_binding_manager-rolebinding.yaml",
+			"service_metrics-service.yaml",
+		))
+	})
+	It("names files by name, prefixing duplicate names", func() {
+		Expect(fileNames(FileNamingName)).To(ConsistOf(
+			"memcacheds.cache.example.com.yaml",
+			"manager-role.yaml",
+			"dup0_manager-role.yaml",
+			"manager-rolebinding.yaml",
+			"metrics-service.yaml",
+		))
+	})
+	It("returns an error for an unknown scheme", func() {
+		err := WriteObjectsToFiles(tmp, WriteOptions{FileNaming: "kind"}, objs...)
+		Expect(err).To(MatchError(`unknown file naming scheme "kind"`))
+	})
+	It("writes verbatim bytes instead of marshaling their objects", func() {
+		src := []byte("# Copyright 2021 Example Authors\nkind: Service\n")
+		opts := WriteOptions{Verbatim: map[client.Object][]byte{objs[4]: src}}
+		Expect(WriteObjectsToFiles(tmp, opts, objs...)).To(Succeed())
+		b, err := ioutil.ReadFile(filepath.Join(tmp, "metrics-service_v1_service.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).To(Equal(src))
+		b, err = ioutil.ReadFile(filepath.Join(tmp, "manager-role_rbac.authorization.k8s.io_v1_role.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(HavePrefix("apiVersion: rbac.authorization.k8s.io/v1\n"))
+	})
+})
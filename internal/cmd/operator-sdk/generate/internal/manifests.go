@@ -15,14 +15,33 @@
 package genutil
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
 	"github.com/operator-framework/operator-sdk/internal/generate/collector"
 )
 
 // GetManifestObjects returns all objects to be written to a manifests directory from collector.Manifests.
 func GetManifestObjects(c *collector.Manifests, extraSAs []string) (objs []client.Object) {
+	return GetManifestObjectsWithKinds(c, extraSAs, nil)
+}
+
+// GetManifestObjectsWithKinds is like GetManifestObjects, but also returns objects of each kind
+// in extraKinds, ex. HorizontalPodAutoscaler, which are skipped by default since OLM does not support
+// installing them from bundles.
+func GetManifestObjectsWithKinds(c *collector.Manifests, extraSAs, extraKinds []string) (objs []client.Object) {
+	included := make(map[string]struct{}, len(extraKinds))
+	for _, kind := range extraKinds {
+		included[kind] = struct{}{}
+	}
+
 	// All CRDs passed in should be written.
 	for i := range c.V1CustomResourceDefinitions {
 		objs = append(objs, &c.V1CustomResourceDefinitions[i])
@@ -39,8 +58,14 @@ func GetManifestObjects(c *collector.Manifests, extraSAs []string) (objs []clien
 	// Add all other supported kinds
 	for i := range c.Others {
 		obj := &c.Others[i]
-		if supported, _ := bundle.IsSupported(obj.GroupVersionKind().Kind); supported {
+		kind := obj.GroupVersionKind().Kind
+		if supported, _ := bundle.IsSupported(kind); supported {
 			objs = append(objs, obj)
+		} else if _, isIncluded := included[kind]; isIncluded {
+			log.Warnf("Including %s %q: kind is not supported in bundles, so OLM may fail to install it", kind, obj.GetName())
+			objs = append(objs, obj)
+		} else {
+			log.Debugf("Skipping %s %q: kind is not supported in bundles", obj.GroupVersionKind().Kind, obj.GetName())
 		}
 	}
 
@@ -64,3 +89,105 @@ func removeNamespace(objs []client.Object) {
 		obj.SetNamespace("")
 	}
 }
+
+// SetLabels adds labels to each object in objs, overriding existing labels with the same key.
+func SetLabels(objs []client.Object, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	for _, obj := range objs {
+		objLabels := obj.GetLabels()
+		if objLabels == nil {
+			objLabels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			objLabels[k] = v
+		}
+		obj.SetLabels(objLabels)
+	}
+}
+
+// SplitSecrets splits objs into Secrets and all other objects. Secrets may contain credentials,
+// so callers should only write them if explicitly requested.
+func SplitSecrets(objs []client.Object) (secrets, others []client.Object) {
+	for _, obj := range objs {
+		if gvk := obj.GetObjectKind().GroupVersionKind(); gvk.Group == "" && gvk.Kind == bundle.SecretKind {
+			secrets = append(secrets, obj)
+		} else {
+			others = append(others, obj)
+		}
+	}
+	return secrets, others
+}
+
+// TransformObjects applies each transform, in order, to each object in objs, such that non-CSV objects
+// written alongside a CSV can be mutated like the CSV is by gencsv.WithObjectTransform.
+func TransformObjects(objs []client.Object, transforms ...gencsv.ObjectTransform) error {
+	for _, obj := range objs {
+		for _, transform := range transforms {
+			if err := transform(obj); err != nil {
+				return fmt.Errorf("error transforming %s %q: %v", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// SplitManagerDeployments leaves only the Deployments in c that make up the CSV's install strategy,
+// and returns all other Deployments so they can be written as extra objects. If name is set, only the
+// Deployment named name is kept. Otherwise if selector is set, all Deployments matching selector are kept.
+// If neither is set, c must contain at most one Deployment, since the operator's cannot be determined.
+func SplitManagerDeployments(c *collector.Manifests, name string, selector labels.Selector) (others []appsv1.Deployment, err error) {
+	if name == "" && selector == nil {
+		if len(c.Deployments) > 1 {
+			return nil, fmt.Errorf("found %d Deployments %s, so the operator's Deployment is ambiguous",
+				len(c.Deployments), strings.Join(deploymentNames(c.Deployments), ", "))
+		}
+		return nil, nil
+	}
+
+	var managers []appsv1.Deployment
+	for _, dep := range c.Deployments {
+		if (name != "" && dep.GetName() == name) || (name == "" && selector.Matches(labels.Set(dep.GetLabels()))) {
+			managers = append(managers, dep)
+		} else {
+			others = append(others, dep)
+		}
+	}
+	switch {
+	case name != "" && len(managers) == 0:
+		return nil, fmt.Errorf("no Deployment named %q found", name)
+	case name != "" && len(managers) > 1:
+		return nil, fmt.Errorf("found %d Deployments named %q", len(managers), name)
+	case name == "" && len(managers) == 0:
+		return nil, fmt.Errorf("no Deployment matches selector %q", selector)
+	}
+	c.Deployments = managers
+	return others, nil
+}
+
+// SplitIncludedDeployments splits deps into those to write as extra objects and those to skip. Deployments
+// are not supported in bundles, so like other such kinds they are only included if extraKinds includes Deployment.
+func SplitIncludedDeployments(deps []appsv1.Deployment, extraKinds []string) (included, skipped []appsv1.Deployment) {
+	const kind = "Deployment"
+	if supported, _ := bundle.IsSupported(kind); supported {
+		return deps, nil
+	}
+	for _, extraKind := range extraKinds {
+		if extraKind == kind {
+			for _, dep := range deps {
+				log.Warnf("Including %s %q: kind is not supported in bundles, so OLM may fail to install it", kind, dep.GetName())
+			}
+			return deps, nil
+		}
+	}
+	return nil, deps
+}
+
+// deploymentNames returns the names of deps.
+func deploymentNames(deps []appsv1.Deployment) (names []string) {
+	for _, dep := range deps {
+		names = append(names, fmt.Sprintf("%q", dep.GetName()))
+	}
+	return names
+}
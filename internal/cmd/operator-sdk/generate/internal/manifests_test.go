@@ -15,14 +15,22 @@
 package genutil
 
 import (
+	"errors"
+	"strings"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
 	"github.com/operator-framework/operator-sdk/internal/generate/collector"
 )
 
@@ -53,4 +61,167 @@ var _ = Describe("GetManifestObjects", func() {
 			Expect(obj.GetNamespace()).To(BeEmpty())
 		}
 	})
+	Context("with PodDisruptionBudgets and HorizontalPodAutoscalers", func() {
+		const manifests = `apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: controller-manager
+  namespace: system
+spec:
+  minAvailable: 1
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: controller-manager
+  namespace: system
+spec:
+  maxReplicas: 3
+`
+		var m *collector.Manifests
+		BeforeEach(func() {
+			m = &collector.Manifests{}
+			Expect(m.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+		})
+		kinds := func(objs []client.Object) (kinds []string) {
+			for _, obj := range objs {
+				kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+			}
+			return kinds
+		}
+
+		It("returns PodDisruptionBudgets, which OLM supports", func() {
+			objs := GetManifestObjects(m, nil)
+			Expect(kinds(objs)).To(Equal([]string{"PodDisruptionBudget"}))
+			Expect(objs[0].GetNamespace()).To(BeEmpty())
+		})
+		It("returns HorizontalPodAutoscalers only if their kind is included", func() {
+			objs := GetManifestObjectsWithKinds(m, nil, []string{"HorizontalPodAutoscaler"})
+			Expect(kinds(objs)).To(Equal([]string{"PodDisruptionBudget", "HorizontalPodAutoscaler"}))
+		})
+	})
+})
+
+var _ = Describe("SplitSecrets", func() {
+	It("splits Secrets from ConfigMaps and other objects", func() {
+		newOther := func(kind, name string) unstructured.Unstructured {
+			u := unstructured.Unstructured{}
+			u.SetAPIVersion("v1")
+			u.SetKind(kind)
+			u.SetName(name)
+			return u
+		}
+		m := collector.Manifests{
+			Roles:  []rbacv1.Role{{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}},
+			Others: []unstructured.Unstructured{newOther("ConfigMap", "config"), newOther("Secret", "creds")},
+		}
+		objs := GetManifestObjects(&m, nil)
+		Expect(objs).To(HaveLen(3))
+
+		secrets, others := SplitSecrets(objs)
+		Expect(secrets).To(HaveLen(1))
+		Expect(secrets[0].GetName()).To(Equal("creds"))
+		Expect(others).To(HaveLen(2))
+		Expect(others[0].GetName()).To(Equal("config"))
+	})
 })
+
+var _ = Describe("SplitManagerDeployments", func() {
+	var c *collector.Manifests
+	BeforeEach(func() {
+		c = &collector.Manifests{Deployments: []appsv1.Deployment{
+			newManagerDeployment("controller-manager", map[string]string{"control-plane": "controller-manager"}),
+			newManagerDeployment("sidecar", nil),
+		}}
+	})
+
+	It("keeps a single Deployment without a name or selector", func() {
+		c.Deployments = c.Deployments[1:]
+		others, err := SplitManagerDeployments(c, "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(others).To(BeEmpty())
+		Expect(c.Deployments).To(HaveLen(1))
+	})
+	It("fails for multiple Deployments without a name or selector", func() {
+		_, err := SplitManagerDeployments(c, "", nil)
+		Expect(err).To(MatchError(`found 2 Deployments "controller-manager", "sidecar", ` +
+			`so the operator's Deployment is ambiguous`))
+	})
+	It("keeps the Deployment with name", func() {
+		others, err := SplitManagerDeployments(c, "sidecar", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Deployments).To(Equal([]appsv1.Deployment{newManagerDeployment("sidecar", nil)}))
+		Expect(others).To(HaveLen(1))
+		Expect(others[0].GetName()).To(Equal("controller-manager"))
+
+		_, err = SplitManagerDeployments(c, "potato", nil)
+		Expect(err).To(MatchError(`no Deployment named "potato" found`))
+	})
+	It("keeps Deployments matching selector", func() {
+		selector := labels.SelectorFromSet(labels.Set{"control-plane": "controller-manager"})
+		others, err := SplitManagerDeployments(c, "", selector)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Deployments).To(HaveLen(1))
+		Expect(c.Deployments[0].GetName()).To(Equal("controller-manager"))
+		Expect(others).To(Equal([]appsv1.Deployment{newManagerDeployment("sidecar", nil)}))
+
+		c.Deployments = others
+		_, err = SplitManagerDeployments(c, "", selector)
+		Expect(err).To(MatchError(`no Deployment matches selector "control-plane=controller-manager"`))
+	})
+})
+
+var _ = Describe("SplitIncludedDeployments", func() {
+	It("includes Deployments only if their kind is included", func() {
+		deps := []appsv1.Deployment{newManagerDeployment("sidecar", nil)}
+		included, skipped := SplitIncludedDeployments(deps, nil)
+		Expect(included).To(BeEmpty())
+		Expect(skipped).To(Equal(deps))
+
+		included, skipped = SplitIncludedDeployments(deps, []string{"HorizontalPodAutoscaler", "Deployment"})
+		Expect(included).To(Equal(deps))
+		Expect(skipped).To(BeEmpty())
+	})
+})
+
+var _ = Describe("TransformObjects", func() {
+	It("applies transforms in order to each object", func() {
+		crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+		role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}
+		appendName := func(suffix string) gencsv.ObjectTransform {
+			return func(obj client.Object) error {
+				obj.SetName(obj.GetName() + suffix)
+				return nil
+			}
+		}
+		Expect(TransformObjects([]client.Object{crd, role}, appendName("-a"), appendName("-b"))).To(Succeed())
+		Expect(crd.GetName()).To(Equal("foo-a-b"))
+		Expect(role.GetName()).To(Equal("bar-a-b"))
+	})
+	It("returns the first error", func() {
+		role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}
+		role.SetGroupVersionKind(rbacv1.SchemeGroupVersion.WithKind("Role"))
+		fail := func(client.Object) error { return errors.New("no digest") }
+		err := TransformObjects([]client.Object{role}, fail)
+		Expect(err).To(MatchError(`error transforming Role "bar": no digest`))
+	})
+})
+
+var _ = Describe("SetLabels", func() {
+	It("adds labels to CRDs and extra objects, overriding existing keys", func() {
+		crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+		role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{
+			Name:   "bar",
+			Labels: map[string]string{"app.kubernetes.io/part-of": "old", "keep": "me"},
+		}}
+		SetLabels([]client.Object{crd, role}, map[string]string{"app.kubernetes.io/part-of": "memcached", "build": "5"})
+		Expect(crd.GetLabels()).To(Equal(map[string]string{"app.kubernetes.io/part-of": "memcached", "build": "5"}))
+		Expect(role.GetLabels()).To(Equal(map[string]string{"app.kubernetes.io/part-of": "memcached", "build": "5", "keep": "me"}))
+	})
+})
+
+func newManagerDeployment(name string, labels map[string]string) (d appsv1.Deployment) {
+	d.SetName(name)
+	d.SetLabels(labels)
+	return d
+}
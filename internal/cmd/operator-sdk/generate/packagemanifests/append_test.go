@@ -0,0 +1,150 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+)
+
+var _ = Describe("Adding a version to existing package manifests", func() {
+	const manifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: memcached-operator-manager-config
+`
+
+	var tmp, outputDir string
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-append-")
+		Expect(err).NotTo(HaveOccurred())
+		outputDir = filepath.Join(tmp, "packagemanifests")
+		deployDir := filepath.Join(tmp, "deploy")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "manifests.yaml"), []byte(manifests), 0644)).To(Succeed())
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	generate := func(version, fromVersion string) {
+		c := packagemanifestsCmd{
+			packageName:   "memcached-operator",
+			version:       version,
+			fromVersion:   fromVersion,
+			channelName:   "alpha",
+			inputDir:      outputDir,
+			outputDir:     outputDir,
+			deployDir:     filepath.Join(tmp, "deploy"),
+			updateObjects: true,
+			quiet:         true,
+			generator:     genpkg.NewGenerator(),
+		}
+		Expect(c.run()).To(Succeed())
+	}
+
+	It("leaves the package manifest unchanged if a version cannot be generated", func() {
+		generate("0.1.0", "")
+		pkgPath := filepath.Join(outputDir, "memcached-operator.package.yaml")
+		pkg, err := ioutil.ReadFile(pkgPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Without the operator's Deployment, the install strategy is empty.
+		emptyDir := filepath.Join(tmp, "empty")
+		Expect(os.Mkdir(emptyDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(emptyDir, "manifests.yaml"),
+			[]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"), 0644)).To(Succeed())
+		c := packagemanifestsCmd{
+			packageName: "memcached-operator",
+			version:     "0.2.0",
+			fromVersion: "0.1.0",
+			channelName: "alpha",
+			inputDir:    outputDir,
+			outputDir:   outputDir,
+			deployDir:   emptyDir,
+			quiet:       true,
+			generator:   genpkg.NewGenerator(),
+		}
+		Expect(c.run()).To(MatchError(ContainSubstring("install strategy has no deployments")))
+		Expect(filepath.Join(outputDir, "0.2.0")).NotTo(BeADirectory())
+		Expect(ioutil.ReadFile(pkgPath)).To(Equal(pkg))
+	})
+
+	It("only writes the new version directory and the package manifest", func() {
+		generate("0.1.0", "")
+		generate("0.2.0", "0.1.0")
+		generate("0.3.0", "0.2.0")
+
+		// Old files are backdated so a rewrite with the same content is detected by its modtime.
+		old := make(map[string][]byte)
+		past := time.Now().Add(-time.Hour).Truncate(time.Second)
+		for _, version := range []string{"0.1.0", "0.2.0", "0.3.0"} {
+			files, err := filepath.Glob(filepath.Join(outputDir, version, "*"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(2))
+			for _, path := range files {
+				b, err := ioutil.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				old[path] = b
+				Expect(os.Chtimes(path, past, past)).To(Succeed())
+			}
+		}
+
+		generate("0.4.0", "0.3.0")
+
+		for path, b := range old {
+			info, err := os.Stat(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ModTime()).To(Equal(past), "%s was rewritten", path)
+			Expect(ioutil.ReadFile(path)).To(Equal(b), "%s was changed", path)
+		}
+		entries, err := ioutil.ReadDir(outputDir)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		Expect(names).To(Equal([]string{"0.1.0", "0.2.0", "0.3.0", "0.4.0", "memcached-operator.package.yaml"}))
+		pkg, err := genpkg.PackageManifest{BasePath: filepath.Join(outputDir, "memcached-operator.package.yaml")}.GetBase()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pkg.Channels).To(HaveLen(1))
+		Expect(pkg.Channels[0].CurrentCSVName).To(Equal("memcached-operator.v0.4.0"))
+	})
+})
@@ -0,0 +1,264 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+)
+
+// batchFlags only apply to a batch, so are neither shared with nor set by its operators. --verbose sets
+// the level of the logger every operator shares, so is set once for the batch.
+var batchFlags = []string{"batch", "batch-parallelism", "verbose"}
+
+// batchConfig is the format of a --batch file.
+type batchConfig struct {
+	// Operators are mappings of flag names to values, one per operator to generate.
+	Operators []map[string]interface{} `json:"operators"`
+}
+
+// batchOperator is an operator listed in a --batch file, and the command generating it.
+type batchOperator struct {
+	// label names the operator by its --package, or its position in the file if it sets none.
+	label string
+	cmd   *packagemanifestsCmd
+	fs    *pflag.FlagSet
+	// err is the error generating the operator, set once it is generated.
+	err error
+}
+
+// runBatch generates each operator in --batch as a separate run would, at most --batch-parallelism at once,
+// then prints whether each succeeded and returns an error listing those that failed. Operators with the
+// same output directory are generated one at a time, since they may share its package manifest.
+func (c packagemanifestsCmd) runBatch(cmd *cobra.Command) error {
+	if c.batchParallelism < 1 {
+		return withExitCode(exitUsage, errors.New("invalid command options: --batch-parallelism must be at least 1"))
+	}
+	if c.stdout {
+		return withExitCode(exitUsage, errors.New("invalid command options: --stdout cannot be set with --batch"))
+	}
+	if genutil.IsPipeReader() {
+		// Stdin can only be read once, so all but one operator would collect nothing from it.
+		return withExitCode(exitUsage, errors.New("invalid command options: manifests cannot be piped to stdin "+
+			"with --batch; set --deploy-dir for each operator instead"))
+	}
+	ops, err := readBatchOperators(c.batchFile, cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("invalid command options: %v", err))
+	}
+	if c.batchParallelism > 1 {
+		for _, op := range ops {
+			// Warnings are captured from the shared logger, so would be attributed to every operator.
+			if op.cmd.strict {
+				return withExitCode(exitUsage, fmt.Errorf("invalid command options: --strict cannot be set for "+
+					"operator %s with --batch-parallelism greater than 1", op.label))
+			}
+			// Validation results are printed as a table to stdout, so tables of operators would interleave.
+			if op.cmd.validateOnly {
+				return withExitCode(exitUsage, fmt.Errorf("invalid command options: --validate cannot be set for "+
+					"operator %s with --batch-parallelism greater than 1", op.label))
+			}
+		}
+	}
+
+	if level, ok := logLevel(c.verbosity); ok {
+		log.SetLevel(level)
+	}
+
+	locks := make(map[string]*sync.Mutex, len(ops))
+	for _, op := range ops {
+		if _, ok := locks[op.outputDirKey()]; !ok {
+			locks[op.outputDirKey()] = &sync.Mutex{}
+		}
+	}
+	sem := make(chan struct{}, c.batchParallelism)
+	wg := sync.WaitGroup{}
+	for _, op := range ops {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(op *batchOperator) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			lock := locks[op.outputDirKey()]
+			lock.Lock()
+			defer lock.Unlock()
+			// Failed runs silence their command's usage, so each operator has its own.
+			op.err = op.cmd.execute(&cobra.Command{}, op.fs)
+		}(op)
+	}
+	wg.Wait()
+
+	if !c.quiet {
+		if err := printBatchSummary(os.Stdout, ops); err != nil {
+			return runError(cmd, "Error printing --batch summary", withExitCode(exitIO, err))
+		}
+	}
+	if err := batchError(ops); err != nil {
+		return runError(cmd, "Error generating --batch operators", err)
+	}
+	return nil
+}
+
+// readBatchOperators returns a command for each operator in the --batch file at path, with the flag values
+// in the file, then those of its --config, then those set in shared, so operators override shared flags.
+func readBatchOperators(path string, shared *pflag.FlagSet) ([]*batchOperator, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading --batch: %v", err)
+	}
+	cfg := batchConfig{}
+	if err := yaml.UnmarshalStrict(b, &cfg, useNumber); err != nil {
+		return nil, fmt.Errorf("error parsing --batch %s: %v", path, err)
+	}
+	if len(cfg.Operators) == 0 {
+		return nil, fmt.Errorf("--batch %s lists no operators", path)
+	}
+
+	ops := make([]*batchOperator, 0, len(cfg.Operators))
+	for i, values := range cfg.Operators {
+		source := fmt.Sprintf("--batch %s operators[%d]", path, i)
+		op := &batchOperator{
+			label: fmt.Sprintf("operators[%d]", i),
+			cmd:   &packagemanifestsCmd{},
+			fs:    pflag.NewFlagSet(source, pflag.ContinueOnError),
+		}
+		op.cmd.addFlagsTo(op.fs)
+		if err := applyFlagValues(op.fs, values, source); err != nil {
+			return nil, err
+		}
+		if err := applyConfig(op.fs, op.cmd.configFile); err != nil {
+			return nil, fmt.Errorf("%s: %v", source, err)
+		}
+		for _, name := range append(batchFlags, "stdout") {
+			if op.fs.Changed(name) {
+				return nil, fmt.Errorf("%s cannot set --%s", source, name)
+			}
+		}
+		if err := setSharedFlags(op.fs, shared); err != nil {
+			return nil, err
+		}
+		if op.cmd.packageName != "" {
+			op.label = op.cmd.packageName
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// setSharedFlags sets each flag in fs that is set in shared, except --config and batchFlags, to its value
+// in shared, unless that flag was already set.
+func setSharedFlags(fs, shared *pflag.FlagSet) (err error) {
+	shared.Visit(func(f *pflag.Flag) {
+		if err != nil || f.Name == "config" || isBatchFlag(f.Name) || fs.Lookup(f.Name) == nil || fs.Changed(f.Name) {
+			return
+		}
+		values := []string{f.Value.String()}
+		if sv, isSlice := f.Value.(pflag.SliceValue); isSlice {
+			values = sv.GetSlice()
+		} else if f.Value.Type() == "stringToString" {
+			// key=value flags print their pairs in brackets, which setting them does not accept.
+			values = nil
+			if pairs := strings.TrimSuffix(strings.TrimPrefix(f.Value.String(), "["), "]"); pairs != "" {
+				values = append(values, pairs)
+			}
+		}
+		for _, value := range values {
+			if err = fs.Set(f.Name, value); err != nil {
+				err = fmt.Errorf("error setting --%s for every --batch operator: %v", f.Name, err)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// isBatchFlag returns true if name is one of batchFlags.
+func isBatchFlag(name string) bool {
+	for _, batchFlag := range batchFlags {
+		if name == batchFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// outputDirKey returns the absolute output directory of op, which is the default one if unset.
+func (op batchOperator) outputDirKey() string {
+	dir := op.cmd.outputDir
+	if dir == "" {
+		dir = defaultRootDir
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return filepath.Clean(dir)
+}
+
+// version returns the version or versions op generates, once it is generated.
+func (op batchOperator) version() string {
+	if len(op.cmd.versions) != 0 {
+		return listOrNone(op.cmd.versions)
+	}
+	return listOrNone([]string{op.cmd.version})
+}
+
+// printBatchSummary prints a table of each operator in ops, the version it generates, and whether it succeeded.
+func printBatchSummary(w io.Writer, ops []*batchOperator) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 4, ' ', 0)
+	fmt.Fprintln(tw, "OPERATOR\tVERSION\tRESULT")
+	for _, op := range ops {
+		result := "succeeded"
+		if op.err != nil {
+			result = "failed"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", op.label, op.version(), result)
+	}
+	return tw.Flush()
+}
+
+// batchError returns an error listing the error of each operator in ops that failed, with the exit code of
+// the first one, or nil if all succeeded.
+func batchError(ops []*batchOperator) error {
+	var failed []string
+	code := exitInternal
+	for _, op := range ops {
+		if op.err == nil {
+			continue
+		}
+		if len(failed) == 0 {
+			code = exitCode(op.err)
+		}
+		failed = append(failed, fmt.Sprintf("%s: %v", op.label, op.err))
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return withExitCode(code, fmt.Errorf("%d of %d operator(s) failed:\n  - %s",
+		len(failed), len(ops), strings.Join(failed, "\n  - ")))
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generating a batch of operators", func() {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/operator:v0.0.1
+        name: manager
+`
+
+	var tmp, batchPath string
+	writeBatch := func(batch string) {
+		Expect(ioutil.WriteFile(batchPath, []byte(batch), 0644)).To(Succeed())
+	}
+	execute := func(args ...string) error {
+		cmd := NewCmd()
+		cmd.SetArgs(append([]string{"--batch", batchPath, "--crds-dir", filepath.Join(tmp, "crds"),
+			"--quiet"}, args...))
+		cmd.SetOut(GinkgoWriter)
+		cmd.SetErr(GinkgoWriter)
+		return cmd.Execute()
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-batch-")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Mkdir(filepath.Join(tmp, "deploy"), 0755)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(tmp, "crds"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(tmp, "deploy", "manager.yaml"), []byte(deployment), 0644)).To(Succeed())
+		batchPath = filepath.Join(tmp, "batch.yaml")
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("generates every operator in parallel, with shared flags, and fails listing those that failed", func() {
+		writeBatch(fmt.Sprintf(`operators:
+- package: memcached-operator
+  version: 0.1.0
+  deploy-dir: %[1]s/deploy
+  output-dir: %[1]s/memcached-operator
+- package: nginx-operator
+  version: 0.2.0
+  channel: beta
+  deploy-dir: %[1]s/deploy
+  output-dir: %[1]s/nginx-operator
+- package: broken-operator
+  version: 0.1.0
+  deploy-dir: %[1]s/missing
+  output-dir: %[1]s/broken-operator
+`, tmp))
+		err := execute("--channel", "stable", "--batch-parallelism", "2")
+		Expect(err).To(MatchError(ContainSubstring("1 of 3 operator(s) failed:\n  - broken-operator: ")))
+		Expect(exitCode(err)).To(Equal(exitInputNotFound))
+
+		for pkg, channel := range map[string]string{"memcached-operator": "stable", "nginx-operator": "beta"} {
+			b, err := ioutil.ReadFile(filepath.Join(tmp, pkg, pkg+".package.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(ContainSubstring("defaultChannel: " + channel))
+		}
+		Expect(filepath.Join(tmp, "nginx-operator", "0.2.0", "nginx-operator.clusterserviceversion.yaml")).To(BeAnExistingFile())
+		Expect(filepath.Join(tmp, "broken-operator")).NotTo(BeADirectory())
+	})
+	It("generates operators sharing an output directory one at a time", func() {
+		writeBatch(fmt.Sprintf(`operators:
+- package: memcached-operator
+  version: 0.1.0
+  deploy-dir: %[1]s/deploy
+  output-dir: %[1]s/packagemanifests
+- package: memcached-operator
+  version: 0.2.0
+  from-version: 0.1.0
+  deploy-dir: %[1]s/deploy
+  input-dir: %[1]s/packagemanifests
+  output-dir: %[1]s/packagemanifests
+`, tmp))
+		Expect(execute("--batch-parallelism", "2")).To(Succeed())
+		Expect(filepath.Join(tmp, "packagemanifests", "0.1.0")).To(BeADirectory())
+		Expect(filepath.Join(tmp, "packagemanifests", "0.2.0")).To(BeADirectory())
+	})
+	It("fails with the usage code for an invalid batch", func() {
+		for batch, msg := range map[string]string{
+			"operators: []\n":                              "lists no operators",
+			"operator:\n- version: 0.1.0\n":                "error parsing --batch",
+			"operators:\n- no-such-flag: true\n":           `operators[0] sets unknown flag "no-such-flag"`,
+			"operators:\n- stdout: true\n":                 "operators[0] cannot set --stdout",
+			"operators:\n- batch-parallelism: 2\n":         "operators[0] cannot set --batch-parallelism",
+			"operators:\n- package: a\n  strict: true\n":   "--strict cannot be set for operator a",
+			"operators:\n- package: a\n  validate: true\n": "--validate cannot be set for operator a",
+			"operators:\n- verbose: 2\n":                   "operators[0] cannot set --verbose",
+		} {
+			writeBatch(batch)
+			err := execute("--batch-parallelism", "2")
+			Expect(err).To(MatchError(ContainSubstring(msg)), batch)
+			Expect(exitCode(err)).To(Equal(exitUsage))
+		}
+		Expect(execute("--batch-parallelism", "0")).To(MatchError(ContainSubstring("must be at least 1")))
+	})
+	It("prints whether each operator succeeded", func() {
+		ops := []*batchOperator{
+			{label: "memcached-operator", cmd: &packagemanifestsCmd{version: "0.1.0"}},
+			{label: "operators[1]", cmd: &packagemanifestsCmd{versions: []string{"0.1.0", "0.2.0"}}, err: errors.New("oops")},
+		}
+		buf := &bytes.Buffer{}
+		Expect(printBatchSummary(buf, ops)).To(Succeed())
+		Expect(buf.String()).To(Equal(`OPERATOR              VERSION        RESULT
+memcached-operator    0.1.0          succeeded
+operators[1]          0.1.0,0.2.0    failed
+`))
+	})
+})
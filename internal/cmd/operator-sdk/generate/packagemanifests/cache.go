@@ -0,0 +1,65 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+// manifestsCacheDir is the directory under the user's cache directory that manifests decoded from input
+// directories are cached in, in a file per working directory.
+const manifestsCacheDir = "operator-sdk/manifests"
+
+// openManifestsCache opens the cache of manifests decoded from input directories, or returns nil
+// unless --cache is set.
+func (c packagemanifestsCmd) openManifestsCache() *collector.FileCache {
+	if !c.cache {
+		return nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Debugf("Not caching manifests: %v", err)
+		return nil
+	}
+	// Caches are per user, since a cache another user could write could inject objects into the package.
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		log.Debugf("Not caching manifests: %v", err)
+		return nil
+	}
+	h := sha256.Sum256([]byte(wd))
+	path := filepath.Join(cacheDir, filepath.FromSlash(manifestsCacheDir), hex.EncodeToString(h[:8])+".json")
+	// Files are decoded the same way regardless of flags, since flags like --selector and --keep-status
+	// apply to collected objects after they are decoded, so one cache serves every run in wd.
+	return collector.OpenFileCache(path)
+}
+
+// saveManifestsCache saves cache if it is not nil. Failing to save only makes the next run slower,
+// so errors are logged instead of returned.
+func saveManifestsCache(cache *collector.FileCache) {
+	if cache == nil {
+		return
+	}
+	if err := cache.Save(); err != nil {
+		log.Debugf("Error saving manifests cache: %v", err)
+	}
+}
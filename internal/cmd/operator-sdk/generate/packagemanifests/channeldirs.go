@@ -0,0 +1,73 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+)
+
+// versionsDir returns the directory version directories are written to: c.outputDir, or the channel's
+// directory in c.outputDir if --channel-dirs is set.
+func (c packagemanifestsCmd) versionsDir() string {
+	if c.channelDirs {
+		return filepath.Join(c.outputDir, c.channelName)
+	}
+	return c.outputDir
+}
+
+// versionDir returns the directory the generated version is written to.
+func (c packagemanifestsCmd) versionDir() string {
+	return filepath.Join(c.versionsDir(), c.version)
+}
+
+// validateChannelDirs validates that --channel-dirs is set with one channel, and that c.outputDir does not
+// already use the other layout, since versions written in one layout would be orphaned from those in the other.
+func (c packagemanifestsCmd) validateChannelDirs() error {
+	if c.channelDirs {
+		if c.stdout || c.singleFile != "" {
+			return errors.New("--channel-dirs cannot be set with --stdout or --single-file, " +
+				"since no package directory is written")
+		}
+		if c.channelConfig != "" {
+			return errors.New("--channel-dirs cannot be set with --channel-config, " +
+				"since each version is written to the directory of one channel")
+		}
+		if c.channelName == "" {
+			return errors.New("--channel must be set if --channel-dirs is set")
+		}
+	}
+	if c.stdout || c.singleFile != "" {
+		return nil
+	}
+
+	versions, channels, err := validation.ReadPackageLayout(c.outputDir)
+	if err != nil {
+		return err
+	}
+	if c.channelDirs && len(versions) != 0 {
+		return fmt.Errorf("--channel-dirs is set, but %s has version directories not in a channel directory: %s; "+
+			"move them to their channel's directory first", c.outputDir, strings.Join(versions, ", "))
+	}
+	if !c.channelDirs && len(channels) != 0 {
+		return fmt.Errorf("%s has channel directories %s; set --channel-dirs to write versions to them",
+			c.outputDir, strings.Join(channels, ", "))
+	}
+	return nil
+}
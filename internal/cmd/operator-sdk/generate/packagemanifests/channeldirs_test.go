@@ -0,0 +1,75 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Writing versions to channel directories", func() {
+	var (
+		c   packagemanifestsCmd
+		tmp string
+	)
+	mkdir := func(path string) {
+		Expect(os.MkdirAll(filepath.Join(tmp, path), 0755)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-channel-dirs-")
+		Expect(err).NotTo(HaveOccurred())
+		c = packagemanifestsCmd{outputDir: tmp, version: "0.0.2", channelName: "stable", channelDirs: true}
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("nests the version directory in the channel's directory", func() {
+		Expect(c.versionDir()).To(Equal(filepath.Join(tmp, "stable", "0.0.2")))
+		c.channelDirs = false
+		Expect(c.versionDir()).To(Equal(filepath.Join(tmp, "0.0.2")))
+	})
+	It("succeeds for a new or channel-scoped package", func() {
+		Expect(c.validateChannelDirs()).To(Succeed())
+		mkdir("stable/0.0.1")
+		mkdir("metadata")
+		Expect(c.validateChannelDirs()).To(Succeed())
+	})
+	It("requires a single channel", func() {
+		c.channelName = ""
+		Expect(c.validateChannelDirs()).To(MatchError("--channel must be set if --channel-dirs is set"))
+		c.channelName, c.channelConfig = "stable", "channels.yaml"
+		Expect(c.validateChannelDirs()).To(MatchError(HavePrefix("--channel-dirs cannot be set with --channel-config")))
+	})
+	It("fails if versions of the flat layout would be orphaned", func() {
+		mkdir("0.0.1")
+		mkdir("0.1.0")
+		Expect(c.validateChannelDirs()).To(MatchError(ContainSubstring(
+			"has version directories not in a channel directory: 0.0.1, 0.1.0")))
+	})
+	It("fails if versions of the channel layout would be orphaned", func() {
+		mkdir("alpha/0.0.1")
+		mkdir("stable/0.0.1")
+		c.channelDirs = false
+		Expect(c.validateChannelDirs()).To(MatchError(tmp + " has channel directories alpha, stable; " +
+			"set --channel-dirs to write versions to them"))
+	})
+})
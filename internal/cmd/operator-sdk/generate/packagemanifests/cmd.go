@@ -15,14 +15,19 @@
 package packagemanifests
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
 	"github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
 )
 
 //nolint:maligned
@@ -30,22 +35,191 @@ type packagemanifestsCmd struct {
 	// Common options.
 	version       string
 	fromVersion   string
+	versions      []string
+	versionInputs map[string]string
 	inputDir      string
 	outputDir     string
 	kustomizeDir  string
 	deployDir     string
 	crdsDir       string
+	inputGit      string
+	inputURLs     []string
+	fromDir       string
 	updateObjects bool
 	stdout        bool
 	quiet         bool
+	verbosity     int
+	checkGraph    bool
+	ociOut        string
+	// sbomFile is a file to write a CycloneDX SBOM of the generated version's images and files to.
+	sbomFile      string
+	genDockerfile bool
+	dockerfileDir string
+	dirMode       string
+	fileMode      string
+	yamlIndent    int
+	yamlSortKeys  bool
+	// pruneEmpty omits null values and empty collections from written manifests; keepEmptyFields
+	// disables it.
+	pruneEmpty      bool
+	keepEmptyFields bool
+	// skipIfUnchanged skips generation if the hash of inputs and flagValues matches a prior run's.
+	skipIfUnchanged bool
+	flagValues      []string
+	// listKinds prints what generation does with each collected object instead of generating.
+	listKinds bool
+	// listVersions prints the versions in outputDir instead of generating.
+	listVersions bool
+	// listFormat is the format listVersions prints in, one of listFormatText or listFormatJSON.
+	listFormat string
+	// selfCheck generates the package twice in a temporary directory to check that regeneration is a no-op.
+	selfCheck bool
+	// validateOnly prints the problems generation would find instead of generating.
+	validateOnly bool
+	// singleFile is a file to write the whole package to as one multi-document YAML stream.
+	singleFile string
+	// csvOnly writes only the CSV, skipping the package manifest and all other objects.
+	csvOnly bool
+	// strict promotes warnings logged during generation to errors, reported together when generation ends.
+	strict bool
+	// includeSecrets writes collected Secrets to the package, which are skipped by default.
+	includeSecrets bool
+	// includeKinds are kinds OLM does not support in bundles to write to the package anyway.
+	includeKinds []string
+	// includeSCC writes collected OpenShift SecurityContextConstraints to the package.
+	includeSCC bool
+	// grantSCCUsers grants ServiceAccount users of collected SCCs their use in the CSV.
+	grantSCCUsers bool
+	// schemaFile is a JSON schema each written manifest must match.
+	schemaFile string
+	// keepStatus keeps collected objects' status, which is removed by default.
+	keepStatus bool
+	// keepRuntimeMetadata keeps collected objects' managedFields and other metadata set by the API server
+	// or kubectl, which are removed by default.
+	keepRuntimeMetadata bool
+	// upgradeAPIVersions sets deprecated API versions of collected objects to their stable equivalents.
+	upgradeAPIVersions bool
+	// normalizeCRDSchemas canonicalizes CRD validation schemas so equivalent schemas are written the same.
+	normalizeCRDSchemas bool
+	// render collects manifests rendered from kustomizeDir with the kustomize API.
+	render bool
+	// helmChart is a Helm chart directory whose manifests, rendered with helmValues files, are collected.
+	helmChart  string
+	helmValues []string
+	// ignoreMissingDirs treats a nonexistent deployDir or crdsDir as empty, ex. for CRD-less operators.
+	ignoreMissingDirs bool
+	// inputPrecedence is the input, stdin or dir, whose objects override the other's.
+	inputPrecedence string
+	// inputFormat is the format of manifests read from stdin.
+	inputFormat string
+	// writeBase writes a base extracted from the generated CSV to the kustomize bases directory.
+	writeBase     bool
+	overwriteBase bool
+	// fileNaming is the scheme non-CSV objects' file names are made with.
+	fileNaming string
+	// selector keeps only collected objects with matching labels, and CRDs if selectorIncludeCRDs is set.
+	selector            string
+	selectorIncludeCRDs bool
+	// cache reads unchanged input files' manifests from a cache instead of decoding them.
+	cache bool
+
+	// ClusterServiceVersion options.
+	reconcileDescriptors bool
+	olmProperties        []string
+	maxOpenShiftVersion  string
+	labels               map[string]string
+	metricsAnnotations   map[string]string
+	archs                []string
+	oses                 []string
+	injectWatchNamespace bool
+	crdsExternal         bool
+	requireResources     bool
+	requireSAs           bool
+	cleanupEnabled       bool
+	// cleanup is set to &cleanupEnabled only if --cleanup-enabled is set, so a base's value is kept otherwise.
+	cleanup      *bool
+	maxLineWidth int
+	// requireQualifiedImages fails generation if any written image has no registry host.
+	requireQualifiedImages bool
+	// gitAnnotations adds annotations derived from the git repository in the working directory to the CSV,
+	// which csvAnnotations override.
+	gitAnnotations bool
+	csvAnnotations map[string]string
+	// kustomizeDirSet is true if --kustomize-dir was set, so a missing directory is likely a mistake.
+	kustomizeDirSet bool
+	// managerDeployment and managerDeploymentSelector select the Deployments in the install strategy.
+	managerDeployment         string
+	managerDeploymentSelector string
+	// registryFormat gates optional CSV fields that older operator-registry releases do not understand.
+	registryFormat string
+	// pinDigests pins deployment and related images to digests, querying registries unless pinDigestsOffline is set.
+	pinDigests        bool
+	pinDigestsOffline bool
+	// allowEmptyInstall allows a CSV with no install strategy deployments.
+	allowEmptyInstall bool
+	// csvVersion overrides the CSV's spec.version, while version names the CSV and its directory.
+	csvVersion string
+	// images maps container names to the images set in collected Deployments.
+	images map[string]string
+	// nodeSelector is added to the pod node selector of collected Deployments.
+	nodeSelector map[string]string
+	// imagePullSecrets are names of Secrets added to the image pull secrets of collected Deployments.
+	imagePullSecrets []string
+	// deprecateVersions are prior versions whose CSVs are annotated as deprecated.
+	deprecateVersions []string
+	// compareWith is the directory or git input of the published package manifests to check the
+	// generated version's compatibility with.
+	compareWith string
+	// allowBreaking permits breaking changes found by the compareWith check, which are then logged.
+	allowBreaking bool
+	// preserveComments writes the source files of objects the generator did not modify verbatim.
+	preserveComments bool
+	// replacesCSVName is the name of the CSV being upgraded from, which takes precedence over fromVersion.
+	replacesCSVName string
+	// versionFile is a file to read version from if version is not set.
+	versionFile string
+	// csvPatches are patch files applied, in order, to the generated CSV before it is written.
+	csvPatches []string
+	// csvPatchType is the type of csvPatches that are objects, "strategic" or "merge".
+	csvPatchType string
+	// dependenciesFile declares APIs and packages of other operators the operator requires.
+	dependenciesFile string
+	// baseOverlay is a directory of CSV fragments merged onto the base before generation.
+	baseOverlay string
+	// noWatchNamespaceEnv strips WATCH_NAMESPACE referencing the CSV's target namespaces from deployments.
+	noWatchNamespaceEnv bool
+	// maxCSVSize and maxObjectSize are the maximum sizes in bytes of the serialized CSV and other objects.
+	maxCSVSize    int
+	maxObjectSize int
+	// requiredAnnotations are annotation keys the generated CSV must set.
+	requiredAnnotations []string
 
 	// Package manifest options.
 	channelName      string
 	isDefaultChannel bool
+	packageTemplate  string
+	// channelConfig is a file declaring all channels, their heads, and the default channel.
+	channelConfig string
+	// channelDirs nests version directories in a directory named after channelName.
+	channelDirs bool
+	// checkPackageDir requires outputDir to be named after the package.
+	checkPackageDir bool
+	// preset is the name of a set of flag values applied before generation.
+	preset string
+	// configFile is a YAML file of flag values applied before generation.
+	configFile string
+	// batchFile is a YAML file listing operators, each generated as if by a command with its flag values.
+	batchFile string
+	// batchParallelism is the maximum number of batchFile operators generated at once.
+	batchParallelism int
 
 	// These are set if a PROJECT config is not present.
 	layout      string
 	packageName string
+	// renamePackage replaces packageName in all outputs, and basePackageName is packageName before
+	// renaming, which the CSV base is still named after.
+	renamePackage   string
+	basePackageName string
 	// Backend generator
 	generator packagemanifest.Generator
 }
@@ -65,32 +239,162 @@ func NewCmd() *cobra.Command {
 		Example: examples,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 0 {
-				return fmt.Errorf("command %s doesn't accept any arguments", cmd.CommandPath())
-			}
-
-			if err := c.setDefaults(); err != nil {
-				return err
+				return withExitCode(exitUsage, fmt.Errorf("command %s doesn't accept any arguments", cmd.CommandPath()))
 			}
 
-			if err := c.validate(); err != nil {
-				return fmt.Errorf("invalid command options: %v", err)
+			// Config file values are set first, so a preset in the file is applied and cannot override them.
+			if err := applyConfig(cmd.Flags(), c.configFile); err != nil {
+				return usageError(fmt.Errorf("invalid command options: %w", err))
 			}
-			if err := c.run(); err != nil {
-				log.Fatalf("Error generating package manifests: %v", err)
+			if c.batchFile != "" {
+				return c.runBatch(cmd)
 			}
-
-			return nil
+			return c.execute(cmd, cmd.Flags())
 		},
 	}
 
 	c.addFlagsTo(cmd.Flags())
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
 
 	return cmd
 }
 
+// execute applies --preset to fs, whose flags set c, then generates or checks package manifests
+// as c's flags request. Errors of a failed run silence cmd's usage.
+func (c *packagemanifestsCmd) execute(cmd *cobra.Command, fs *pflag.FlagSet) error {
+	if err := applyPreset(fs, c.preset); err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("invalid command options: %v", err))
+	}
+	if fs.Changed("cleanup-enabled") {
+		c.cleanup = &c.cleanupEnabled
+	}
+	c.kustomizeDirSet = fs.Changed("kustomize-dir")
+	if fs.Changed("keep-empty-fields") {
+		if fs.Changed("prune-empty") {
+			return withExitCode(exitUsage,
+				errors.New("invalid command options: --prune-empty and --keep-empty-fields cannot both be set"))
+		}
+		c.pruneEmpty = !c.keepEmptyFields
+	}
+	c.setFlagValues(fs)
+
+	if level, ok := logLevel(c.verbosity); ok {
+		log.SetLevel(level)
+	}
+
+	if err := c.setDefaults(); err != nil {
+		return usageError(err)
+	}
+
+	if err := c.validate(); err != nil {
+		return usageError(fmt.Errorf("invalid command options: %w", err))
+	}
+	if c.checkGraph {
+		if err := c.runCheckGraph(); err != nil {
+			return runError(cmd, "Error checking package manifests", err)
+		}
+		return nil
+	}
+	if c.listVersions {
+		if err := c.runListVersions(); err != nil {
+			return runError(cmd, "Error listing package versions", err)
+		}
+		return nil
+	}
+	if c.listKinds {
+		if err := c.runListKinds(); err != nil {
+			return runError(cmd, "Error listing collected kinds", err)
+		}
+		return nil
+	}
+	if c.validateOnly {
+		if err := c.runValidate(); err != nil {
+			return runError(cmd, "Error validating package manifests", err)
+		}
+		return nil
+	}
+	if c.selfCheck {
+		if err := c.strictly(packagemanifestsCmd.runSelfCheck)(); err != nil {
+			return runError(cmd, "Error checking package manifests", err)
+		}
+		return nil
+	}
+	if c.singleFile != "" {
+		if err := c.strictly(packagemanifestsCmd.runSingleFile)(); err != nil {
+			return runError(cmd, "Error generating package manifests", err)
+		}
+		return nil
+	}
+	if len(c.versions) != 0 {
+		if err := c.strictly(packagemanifestsCmd.runVersions)(); err != nil {
+			return runError(cmd, "Error generating package manifests", err)
+		}
+		return nil
+	}
+	if err := c.strictly(packagemanifestsCmd.run)(); err != nil {
+		return runError(cmd, "Error generating package manifests", err)
+	}
+
+	return nil
+}
+
 func (c *packagemanifestsCmd) addFlagsTo(fs *pflag.FlagSet) {
 	fs.StringVarP(&c.version, "version", "v", "", "Semantic version of the packaged operator")
+	fs.StringVar(&c.versionFile, "version-file", "", "File containing the semantic version of the packaged operator, "+
+		"ex. a VERSION file maintained by release tooling, to use instead of --version")
 	fs.StringVar(&c.fromVersion, "from-version", "", "Semantic version of the operator being upgraded from")
+	fs.StringArrayVar(&c.deprecateVersions, "deprecate-version", nil, "Prior version, ex. 0.1.0, to mark as "+
+		"deprecated by setting the olm.deprecated annotation of its CSV in --output-dir to a deprecation message "+
+		"naming --version as the version to upgrade to. The version directory must exist. Repeat to deprecate "+
+		"more than one version. Cannot be set with --csv-only")
+	fs.StringVar(&c.compareWith, "compare-with", "", "Directory, or git reference of the form repo@ref[:path], "+
+		"of the published package manifests to compare the generated version with. Removed CRDs, CRD versions "+
+		"no longer served, and narrowed CRD schemas are breaking changes, and permission changes are warned about. "+
+		"The check runs before anything is written, so this may be --output-dir. Manifests cannot be piped to stdin, "+
+		"since the version is generated twice")
+	fs.BoolVar(&c.allowBreaking, "allow-breaking", false, "Warn about breaking changes found by --compare-with "+
+		"instead of failing")
+	fs.BoolVar(&c.preserveComments, "preserve-comments", false, "Copy the source file of each object in "+
+		"--deploy-dir or --crds-dir that is written unchanged, ex. a CRD, instead of re-serializing it, which keeps "+
+		"its comments, ex. a license header, and formatting. Objects the generator modifies are re-serialized")
+	fs.StringVar(&c.replacesCSVName, "replaces-csv-name", "", "Name of the CSV being upgraded from, ex. "+
+		"memcached-operator.v0.1.0, to set as the CSV's spec.replaces instead of the name derived from --package "+
+		"and --from-version, ex. if the operator was renamed since that CSV was published")
+	fs.StringVar(&c.csvVersion, "csv-version", "", "Semantic version to set as the CSV's spec.version "+
+		"instead of --version, ex. 0.3.0+build.5 for a prerelease build. --version still names the CSV "+
+		"and its version directory")
+	fs.StringToStringVar(&c.images, "image", nil, "Image of the form container=image to set for every container, "+
+		"including init containers, of that name in collected Deployments before the CSV is generated, ex. "+
+		"manager=quay.io/example/memcached-operator:v0.0.1. This flag can be repeated, and each container must be "+
+		"found in a Deployment")
+	fs.StringToStringVar(&c.nodeSelector, "node-selector", nil, "Node label of the form key=value to add to the "+
+		"pod node selector of every collected Deployment before the CSV is generated, overriding a value of the "+
+		"same key, ex. kubernetes.io/os=linux, to constrain where the operator runs. This flag can be repeated. "+
+		"Affinity and tolerations of Deployments are kept as they are")
+	fs.StringArrayVar(&c.imagePullSecrets, "image-pull-secret", nil, "Name of a Secret to add to the image pull "+
+		"secrets of every collected Deployment's pods before the CSV is generated, ex. to pull the operator image from "+
+		"a private registry. The Secret must exist in the namespace the operator is installed in. This flag can be "+
+		"repeated, and Secrets a Deployment already references are not added again")
+	fs.StringArrayVar(&c.csvPatches, "csv-patch", nil, "Path to a YAML or JSON patch to apply to the generated "+
+		"CSV before it is written, for fields no flag sets. An object is a strategic merge patch, and a list is a "+
+		"JSON patch. This flag can be repeated, and patches are applied in order")
+	fs.StringVar(&c.csvPatchType, "csv-patch-type", defaultCSVPatchType, "Type of --csv-patch patches that are "+
+		"objects: \"strategic\", a strategic merge patch, or \"merge\", a JSON merge patch (RFC 7386), which "+
+		"removes fields set to null")
+	fs.StringVar(&c.dependenciesFile, "dependencies", "", "Path to a file, in the format of a bundle's "+
+		"metadata/dependencies.yaml, declaring olm.gvk and olm.package dependencies on other operators. Required "+
+		"APIs are added to spec.customresourcedefinitions.required, and all dependencies to the CSV's "+
+		"olm.properties annotation as olm.gvk.required and olm.package.required properties")
+	fs.StringVar(&c.baseOverlay, "base-overlay", "", "Directory of partial YAML or JSON CSV fragments, "+
+		"ex. metadata.yaml and spec-descriptors.yaml, deep-merged onto the CSV base in file name order "+
+		"before generation. Objects are merged by key, and any other value replaces the base's")
+	fs.StringSliceVar(&c.versions, "versions", nil, "Comma-separated semantic versions to generate in one run, "+
+		"ex. 0.1.0,0.2.0. Versions are generated from lowest to highest, each replacing the previous one, "+
+		"from manifests in each version's --version-input directory")
+	fs.StringToStringVar(&c.versionInputs, "version-input", nil, "Directory of the form VERSION=DIR containing "+
+		"all manifests, including CRDs, of a version in --versions. This flag can be repeated")
 	fs.StringVar(&c.inputDir, "input-dir", defaultRootDir, "Directory to read existing package manifests from. "+
 		"This directory is the parent of individual versioned package directories, and different from --deploy-dir")
 	fs.StringVar(&c.outputDir, "output-dir", "", "Directory in which to write package manifests")
@@ -100,15 +404,255 @@ func (c *packagemanifestsCmd) addFlagsTo(fs *pflag.FlagSet) {
 		"If --crds-dir is not set, CRDs are ready from this directory")
 	fs.StringVar(&c.crdsDir, "crds-dir", "", "Directory to read cluster-ready CustomResoureDefinition manifests from. "+
 		"This option can only be used if --deploy-dir is set")
+	fs.StringVar(&c.fromDir, "from-dir", "", "Directory laid out like a kubebuilder project's config directory "+
+		"to read cluster-ready operator manifests from, ex. config. CRDs are read from crd/bases, and all other "+
+		"manifests from rbac, manager, and if present webhook and samples. Cannot be used with --deploy-dir")
+	fs.StringVar(&c.inputGit, "input-git", "", "Git reference to read cluster-ready operator manifests from, "+
+		"of the form repo@ref[:path], ex. https://github.com/org/repo.git@v0.1.0:deploy. "+
+		"The ref is shallow-fetched each run, unless it is a full commit SHA, which is cached in the user's cache "+
+		"directory")
+	fs.StringArrayVar(&c.inputURLs, "input-url", nil, "HTTP(S) URL of a multi-document YAML file "+
+		"of cluster-ready operator manifests, ex. a release asset. This flag can be repeated")
+	fs.StringVar(&c.inputPrecedence, "input-precedence", inputPrecedenceDir, "Input whose objects take "+
+		"precedence if manifests are read from both stdin and --deploy-dir, --from-dir, --input-git, or --input-url, "+
+		"one of: stdin, dir. Objects in the other input with the same kind and name are overridden, and a warning "+
+		"lists them. Objects read from --input-git, then each --input-url in order, likewise override objects read "+
+		"from earlier non-stdin inputs")
+	fs.StringVar(&c.inputFormat, "input-format", string(collector.InputFormatAuto), "Format of manifests read "+
+		"from stdin, one of: yaml, json, auto. JSON is a stream of objects or arrays of objects. auto reads JSON if "+
+		"the first non-whitespace byte starts a JSON object or array, and YAML otherwise")
 	fs.StringVar(&c.channelName, "channel", "", "Channel name for the generated package")
 	fs.BoolVar(&c.isDefaultChannel, "default-channel", false, "Use the channel passed to --channel "+
 		"as the package manifest file's default channel")
+	fs.StringVar(&c.channelConfig, "channel-config", "", "Path to a file declaring all of the package's channels, "+
+		"each with the version of its head CSV, and the default channel, ex. 'defaultChannel: stable' and "+
+		"'channels: [{name: stable, head: 0.1.0}]'. The package manifest's channels are replaced by those declared, "+
+		"and each head must be a version directory in --output-dir or a version being generated. "+
+		"This flag cannot be set with --channel or --default-channel")
+	fs.BoolVar(&c.channelDirs, "channel-dirs", false, "Write the version directory to a directory named "+
+		"after --channel in --output-dir, ex. <output-dir>/stable/0.0.1, as some registries expect. The package "+
+		"manifest is still written to --output-dir. Fails if --output-dir already has version directories in "+
+		"the other layout. Requires --channel")
+	fs.BoolVar(&c.checkPackageDir, "check-package-dir", false, "Fail if the base name of --output-dir is not "+
+		"the package name, as catalogs with a directory per package require")
+	fs.StringVar(&c.preset, "preset", "", fmt.Sprintf("Named set of flag values to apply, one of: %s. "+
+		"Flags set on the command line override the preset's values. %q sets --check-package-dir and "+
+		"--require-annotation=%s", strings.Join(presetNames(), ", "), presetCommunityOperators,
+		presets[presetCommunityOperators]["require-annotation"]))
+	fs.StringVar(&c.configFile, "config", "", "Path to a YAML file mapping flag names to values, ex. "+
+		"\"channel: stable\", to set flags not set on the command line, which keeps long invocations the same "+
+		"across CI jobs. Lists set repeatable flags, and mappings set key=value flags. Values in the file take "+
+		"precedence over --preset's. Unknown flag names are an error")
+	fs.StringVar(&c.batchFile, "batch", "", "Path to a YAML file listing operators under \"operators\", each a "+
+		"mapping of flag names to values like --config's, ex. its package, version, channel, and input dirs, to "+
+		"generate all of them as separate runs would. Other flags set apply to every operator that does not set "+
+		"them. A summary of which operators succeeded is printed, and the command fails if any operator failed")
+	fs.IntVar(&c.batchParallelism, "batch-parallelism", 1, "Maximum number of --batch operators to generate at "+
+		"once. --strict and --validate cannot be set if greater than 1")
+	fs.StringVar(&c.packageTemplate, "package-template", "", "Path to a Go template to render the package manifest "+
+		"file with, given .PackageName, .Channels (each with .Name and .CurrentCSVName), and .DefaultChannel. "+
+		"The rendered file must be a valid package manifest")
+	fs.BoolVar(&c.render, "render", false, "Render the kustomization in --kustomize-dir in-process, like "+
+		"'kustomize build <kustomize-dir>', and collect the rendered manifests instead of reading --deploy-dir "+
+		"and --crds-dir. This avoids piping from a kustomize binary whose version may differ from the SDK's. "+
+		"Kustomizations are rendered like kustomize v4 renders them")
+	fs.StringVar(&c.helmChart, "helm-chart", "", "Helm chart directory to render in-process, like 'helm template "+
+		"<helm-chart>', and collect the chart's CRDs and rendered manifests instead of reading --deploy-dir and "+
+		"--crds-dir, ex. to package an operator distributed as a Helm chart. Dependencies must be in the chart's "+
+		"charts/ directory, and hooks are not collected")
+	fs.StringArrayVar(&c.helmValues, "helm-values", nil, "Values file to render --helm-chart with. This flag "+
+		"can be repeated, and values in later files take precedence")
+	fs.BoolVar(&c.ignoreMissingDirs, "ignore-missing-dirs", false, "Treat a nonexistent --deploy-dir or "+
+		"--crds-dir as empty, and do not require either to be set, ex. when looping over operators without CRDs. "+
+		"Generation still fails if no input yields manifests")
+	fs.BoolVar(&c.writeBase, "write-base", false, "After generating, write a base containing only the generated "+
+		"CSV's metadata, ex. displayName, description, and icon, to <kustomize-dir>/bases/<package>.clusterserviceversion.yaml "+
+		"so later runs reuse it. An existing base is not overwritten unless --overwrite-base is set")
+	fs.BoolVar(&c.overwriteBase, "overwrite-base", false, "Overwrite an existing base when --write-base is set")
+	fs.StringVarP(&c.selector, "selector", "l", "", "Label selector, ex. operator=memcached, that collected "+
+		"objects must match to be added to the CSV or written to the package, like 'kubectl get -l'. "+
+		"The CSV base and webhooks are always kept")
+	fs.BoolVar(&c.selectorIncludeCRDs, "selector-include-crds", false, "Keep CustomResourceDefinitions "+
+		"that do not match --selector")
+	fs.BoolVar(&c.cache, "cache", false, "Cache the manifests decoded from files in --deploy-dir, --crds-dir, "+
+		"--from-dir, and --input-git in the user's cache directory, and read the manifests of files whose "+
+		"contents are unchanged since a previous run from the cache instead of decoding them")
 	fs.BoolVar(&c.updateObjects, "update-objects", true, "Update non-CSV objects in this package, "+
 		"ex. CustomResoureDefinitions, Roles")
+	fs.BoolVar(&c.keepStatus, "keep-status", false, "Keep the status of collected objects, ex. of manifests "+
+		"exported from a live cluster. Status is removed by default, since OLM does not use runtime status")
+	fs.BoolVar(&c.keepRuntimeMetadata, "keep-runtime-metadata", false, "Keep the managedFields, resourceVersion, "+
+		"uid, generation, creationTimestamp, and kubectl.kubernetes.io/last-applied-configuration annotation of "+
+		"collected objects, ex. of manifests exported from a live cluster. They are removed by default, since "+
+		"they are set by the API server or kubectl and are not part of the operator's manifests")
+	fs.BoolVar(&c.upgradeAPIVersions, "upgrade-api-versions", false, "Set the apiVersion of collected objects of "+
+		"a deprecated API version to its stable equivalent, ex. rbac.authorization.k8s.io/v1beta1 to "+
+		"rbac.authorization.k8s.io/v1 and extensions/v1beta1 Deployments to apps/v1, warning about each, so the package "+
+		"installs on current clusters. Only kinds whose stable schema accepts the object unchanged are converted")
+	fs.BoolVar(&c.normalizeCRDSchemas, "normalize-crd-schemas", false, "Canonicalize the OpenAPI v3 "+
+		"validation schemas of written CRDs, sorting required properties and re-encoding default, example, and "+
+		"enum values with sorted keys and without trailing fractional zeros, so schemas generated by different "+
+		"controller-gen versions do not differ. Schema semantics are unchanged")
+	fs.StringSliceVar(&c.includeKinds, "include-kinds", nil, "Comma-separated kinds of collected objects "+
+		"to write to the package even though OLM does not support them in bundles, ex. HorizontalPodAutoscaler. "+
+		"Objects of supported kinds, ex. PodDisruptionBudget, are always written")
+	fs.StringVar(&c.schemaFile, "schema", "", "Path to a JSON schema each written manifest, including the CSV, "+
+		"must match, ex. to require organization-specific annotations. Generation fails listing the file and JSON "+
+		"pointer of each violation. Use the schema's if/then keywords to constrain only some kinds")
+	fs.BoolVar(&c.includeSCC, "include-scc", false, "Write collected OpenShift SecurityContextConstraints to "+
+		"the package. A ClusterRole granting use of an SCC is added to the CSV like any other ClusterRole bound "+
+		"to its ServiceAccounts")
+	fs.BoolVar(&c.grantSCCUsers, "grant-scc-users", false, "Also grant the CSV's ServiceAccounts listed as users "+
+		"of a collected SCC the use of it in clusterPermissions, since the namespace in SCC users is not known "+
+		"until OLM installs the operator. Requires --include-scc")
+	fs.BoolVar(&c.includeSecrets, "include-secrets", false, "Write collected Secrets to the package alongside "+
+		"ConfigMaps and other objects. Secrets are skipped by default so credentials are not shipped by accident")
+	fs.StringVar(&c.registryFormat, "registry-format", defaultRegistryFormat, "Package manifests format version "+
+		"to generate for, one of: v1, v2. Format v1 targets catalogs built by older operator-registry releases, "+
+		"so the CSV's spec.cleanup and properties annotation, which v2 adds, are not written")
+	fs.StringVar(&c.managerDeployment, "manager-deployment", "", "Name of the operator's Deployment, which is "+
+		"the only Deployment in the CSV's install strategy. Other Deployments are written as extra objects "+
+		"only if --include-kinds includes Deployment. "+
+		"Either this flag or --manager-deployment-selector must be set if multiple Deployments are collected")
+	fs.StringVar(&c.managerDeploymentSelector, "manager-deployment-selector", "", "Label selector of the "+
+		"Deployments in the CSV's install strategy, ex. control-plane=controller-manager. "+
+		"Other Deployments are written as extra objects only if --include-kinds includes Deployment")
+	fs.BoolVar(&c.pinDigests, "pin-digests", false, "Pin the images of the CSV's install strategy Deployments, "+
+		"extra Deployments, and spec.relatedImages to immutable digests, ex. quay.io/example/operator@sha256:..., "+
+		"by querying their registries with credentials from the docker config file, each for at most a minute. "+
+		"Deployment images are also added to spec.relatedImages, as required for disconnected installs")
+	fs.BoolVar(&c.pinDigestsOffline, "pin-digests-offline", false, "With --pin-digests, do not query registries: "+
+		"fail if any image is not already pinned to a digest")
+	fs.BoolVar(&c.reconcileDescriptors, "reconcile-descriptors", false, "Reconcile owned CRD spec and status "+
+		"descriptors in the base CSV with CRD schemas: descriptors for removed fields are pruned, "+
+		"empty descriptions are filled from schema descriptions, and new top-level fields are added")
+	fs.StringArrayVar(&c.olmProperties, "olm-property", nil, "OLM property of the form type=value "+
+		"to add to the CSV's \"olm.properties\" annotation. JSON object and array values are preserved. "+
+		"This flag can be repeated")
+	fs.StringVar(&c.maxOpenShiftVersion, "max-openshift-version", "", "Maximum OpenShift version, of the form X.Y, "+
+		"the operator can be installed on. Adds an \"olm.maxOpenShiftVersion\" property to the CSV")
+	fs.StringToStringVar(&c.labels, "label", nil, "Label of the form key=value to add to the CSV and all other "+
+		"generated objects, overriding existing labels with the same key. This flag can be repeated")
+	fs.StringToStringVar(&c.metricsAnnotations, "metrics-annotation", nil, "Annotation of the form key=value "+
+		"to add to the CSV alongside the SDK's builder and project layout annotations, ex. a downstream build "+
+		"identifier. Values take precedence over the SDK's and the base CSV's annotations. This flag can be repeated")
+	fs.BoolVar(&c.gitAnnotations, "git-annotations", false, "Add the CSV annotations repository, the git "+
+		"repository in the working directory's origin URL, vcs-ref, its HEAD commit, and containerImage, the image "+
+		"of the operator's \"manager\" container. These are skipped with a warning if they cannot be read. "+
+		"Values take precedence over the base CSV's annotations")
+	fs.StringToStringVar(&c.csvAnnotations, "csv-annotation", nil, "Annotation of the form key=value to add to "+
+		"the CSV. Values take precedence over all other annotations, ex. those of --git-annotations. "+
+		"This flag can be repeated")
+	fs.StringSliceVar(&c.archs, "arch", nil, "Comma-separated architectures the operator supports, ex. amd64,arm64. "+
+		"Sets an \"operatorframework.io/arch.<arch>\" label on the CSV for each")
+	fs.StringSliceVar(&c.oses, "os", nil, "Comma-separated operating systems the operator supports, ex. linux. "+
+		"Sets an \"operatorframework.io/os.<os>\" label on the CSV for each")
+	fs.BoolVar(&c.injectWatchNamespace, "inject-watch-namespace", false, "Add a WATCH_NAMESPACE environment "+
+		"variable referencing the CSV's target namespaces to each deployment's manager container if not present")
+	fs.BoolVar(&c.noWatchNamespaceEnv, "no-watch-namespace-env", false, "Remove WATCH_NAMESPACE environment "+
+		"variables referencing the CSV's target namespaces from all deployments, ex. for operators that only "+
+		"support AllNamespaces, for which the variable is empty")
+	fs.BoolVar(&c.crdsExternal, "crds-external", false, "Mark collected CustomResourceDefinitions as required "+
+		"instead of owned in the CSV, and do not write them to the package, for CRDs shipped separately")
+	fs.BoolVar(&c.cleanupEnabled, "cleanup-enabled", false, "Set the CSV's spec.cleanup.enabled, which OLM uses to "+
+		"delete custom resources when the CSV is deleted. If not set, the base CSV's value is kept")
+	fs.BoolVar(&c.allowEmptyInstall, "allow-empty-install", false, "Allow generating a CSV whose install strategy "+
+		"has no Deployments, which OLM installs as an operator that does nothing. Without this flag, generation fails "+
+		"if no Deployments are collected")
+	fs.BoolVar(&c.requireResources, "require-resources", false, "Fail if any container of a deployment "+
+		"added to the CSV does not request CPU and memory")
+	fs.BoolVar(&c.requireSAs, "require-service-accounts", false, "Fail if any deployment added to the CSV "+
+		"runs as a ServiceAccount, other than default, that was not collected, which OLM would not create. "+
+		"Without this flag, a warning is logged")
+	fs.BoolVar(&c.requireQualifiedImages, "require-qualified-images", false, "Fail if any container image of a "+
+		"collected Deployment, or related image of the CSV, has no registry host, ex. busybox or controller:latest, "+
+		"which disconnected installs cannot resolve")
+	fs.StringSliceVar(&c.requiredAnnotations, "require-annotation", nil, "Comma-separated annotation keys "+
+		"the generated CSV must set to a non-empty value, ex. from its base or --csv-patch. Generation fails if any is missing")
+	fs.IntVar(&c.maxCSVSize, "max-csv-size", k8sutil.DefaultMaxObjectSize, "Fail if the generated CSV, "+
+		"serialized as JSON as an API server stores it, is larger than this many bytes. Defaults to etcd's default "+
+		"maximum request size, above which the CSV cannot be installed. Zero disables the check")
+	fs.IntVar(&c.maxObjectSize, "max-object-size", k8sutil.DefaultMaxObjectSize, "Fail if any other generated "+
+		"object, ex. a CRD with a large schema, serialized as JSON is larger than this many bytes. Defaults to "+
+		"etcd's default maximum request size. Zero disables the check")
+	fs.IntVar(&c.maxLineWidth, "csv-max-line-width", 0, "Wrap string values in the CSV, ex. descriptions, at spaces "+
+		"so lines end at or before this width, for readable diffs. Values are unchanged when read. Setting this "+
+		"re-encodes the CSV, which also indents lists under their keys. Zero disables wrapping")
 	fs.BoolVarP(&c.quiet, "quiet", "q", false, "Run in quiet mode")
+	// This shadows the global boolean --verbose so it can be repeated. The -v shorthand is taken by --version.
+	fs.CountVar(&c.verbosity, "verbose", "Log which CSV field or file each collected object was applied to, "+
+		"and base and channel decisions. Repeat (--verbose --verbose) or set --verbose=2 to also log per-file parse details. "+
+		"Unlike most commands' --verbose, it has no -v shorthand, since -v is the shorthand of --version")
 	fs.BoolVar(&c.stdout, "stdout", false, "Write package to stdout")
+	fs.BoolVar(&c.csvOnly, "csv-only", false, "Write only the CSV, to stdout if --stdout is set or to its version "+
+		"directory in --output-dir otherwise, without writing the package manifest or any other object. Flags "+
+		"configuring only those files, ex. --channel, are ignored")
+	fs.BoolVar(&c.strict, "strict", false, "Fail if any warning is logged during generation, ex. of a missing "+
+		"CRD or a deprecated CRD version, after reporting every warning and error found as one list, to lint and "+
+		"build a package in one run. Generation is checked in a temporary directory first, so no files are written "+
+		"if any problem is found. Manifests cannot be piped to stdin, since they are read twice")
+	fs.BoolVar(&c.checkGraph, "check-graph", false, "Instead of generating a package, check that the replaces and "+
+		"skips fields of all CSVs in --output-dir form a consistent graph, that no two version directories contain CSVs "+
+		"with the same name, and that each CSV's version matches its directory, and exit non-zero if they do not. "+
+		"Version directories may be in channel directories, as written with --channel-dirs")
+	fs.BoolVar(&c.listKinds, "list-kinds", false, "Instead of generating a package, collect manifests and print "+
+		"each collected object's apiVersion, kind, and name, and whether it is folded into the CSV, written as an "+
+		"extra object, or ignored, and why. --version is not required")
+	fs.BoolVar(&c.listVersions, "list-versions", false, "Instead of generating a package, print each version "+
+		"in --output-dir with its CSV name, replaces and skips fields, the channels whose replaces graph includes it, "+
+		"and the channels it is the head of. --version is not required")
+	fs.StringVar(&c.listFormat, "list-format", listFormatText, "Format of the --list-versions listing, one of: "+
+		"text, json")
+	fs.BoolVar(&c.selfCheck, "self-check", false, "Instead of writing a package to --output-dir, generate it "+
+		"in a temporary directory, regenerate it from the same inputs, and exit non-zero listing every file and field "+
+		"that changed, ex. timestamps or ordering that differ between runs. Nothing is written outside of the "+
+		"temporary directory")
+	fs.BoolVar(&c.validateOnly, "validate", false, "Instead of writing a package to --output-dir, generate it "+
+		"in a temporary copy of --output-dir and print every problem found, ex. unqualified images, custom resources "+
+		"without a collected CRD, an empty install strategy, or an inconsistent replaces graph, with its severity and "+
+		"object. Exit non-zero if any problem is an error, or with --strict, if any problem is found")
+	fs.StringVar(&c.singleFile, "single-file", "", "File to write the package manifest, CSV, and all other "+
+		"generated objects to as one multi-document YAML stream instead of a package directory, ex. for GitOps "+
+		"tools. Documents are ordered by file: the package manifest, the CSV, then all other files by name")
+	fs.StringVar(&c.ociOut, "oci-out", "", "Directory in which to also write the generated version's manifests "+
+		"as an operator bundle image in OCI image layout format, ex. for pushing with a registry client. The image's "+
+		"channel labels are those of the version in the written package manifest")
+
+	fs.StringVar(&c.sbomFile, "sbom", "", "File to write a CycloneDX JSON software bill of materials to, listing "+
+		"the images of the CSV's install strategy and extra Deployments and its related images, and each file "+
+		"generated for the version with its SHA-256 hash")
+
+	fs.BoolVar(&c.genDockerfile, "gen-dockerfile", false, "Also write a bundle.Dockerfile and bundle metadata "+
+		"for the generated version to <dockerfile-dir>/<package>/<version>, for building the version as a bundle "+
+		"image with the current directory as the build context")
+	fs.StringVar(&c.dockerfileDir, "dockerfile-dir", defaultDockerfileDir, "Directory in which --gen-dockerfile "+
+		"writes a directory per package and version, which must not be in --output-dir")
+
+	fs.StringVar(&c.dirMode, "dir-mode", "", "Octal permission mode of written directories, ex. 0750. "+
+		"Defaults to 0755 subject to umask")
+	fs.StringVar(&c.fileMode, "file-mode", "", "Octal permission mode of written files, ex. 0640. "+
+		"Defaults to 0666 subject to umask")
+	fs.StringVar(&c.fileNaming, "file-naming", string(genutil.FileNamingGVK), "Scheme non-CSV objects' file "+
+		"names are made with, one of: gvk (<name>_<group>_<version>_<kind>.yaml, or <group>_<plural>.yaml for CRDs), "+
+		"kind-name (<kind>_<name>.yaml, ex. role_binding_manager-rolebinding.yaml), or name (<name>.yaml). "+
+		"Files that would have the same name are prefixed with dup<N>_")
+	fs.IntVar(&c.yamlIndent, "yaml-indent", 0, "Number of spaces, from 2 to 9, to indent nested YAML by. Setting "+
+		"this or --yaml-sort-keys re-encodes written manifests in a canonical style, which also indents lists under "+
+		"their keys and does not wrap strings")
+	fs.BoolVar(&c.yamlSortKeys, "yaml-sort-keys", false, "Sort mapping keys in written manifests")
+	fs.BoolVar(&c.pruneEmpty, "prune-empty", true, "Omit null values and empty maps and lists from written "+
+		"manifests' fields known to mean the same when empty as when unset, ex. metadata.labels: {} or a CSV's "+
+		"spec.relatedImages: [], keeping those of other fields, ex. a PodDisruptionBudget's selector: {}")
+	fs.BoolVar(&c.keepEmptyFields, "keep-empty-fields", false, "Keep null values and empty maps and lists in "+
+		"written manifests. Equivalent to --prune-empty=false")
+	fs.BoolVar(&c.skipIfUnchanged, "skip-if-unchanged", false, "Skip generation, and exit successfully, if the "+
+		"collected manifests, base package manifest, flag values, and SDK version are unchanged since the last run "+
+		"with this flag. A hash of these inputs is written to a hidden file in --output-dir")
 
 	fs.StringVar(&c.packageName, "package", "", "Package name")
+	fs.StringVar(&c.renamePackage, "rename-package", "", "Package name to publish the operator under instead of "+
+		"--package, ex. to rebrand it for a partner. The CSV, package manifest, and bundle metadata are named "+
+		"after this name, while the CSV base is still read from the path named after --package")
 }
 
 func (c packagemanifestsCmd) println(a ...interface{}) {
@@ -116,3 +660,15 @@ func (c packagemanifestsCmd) println(a ...interface{}) {
 		fmt.Println(a...)
 	}
 }
+
+// logLevel returns the log level for a --verbose count, and false if the level should not change.
+func logLevel(verbosity int) (log.Level, bool) {
+	switch {
+	case verbosity <= 0:
+		return log.InfoLevel, false
+	case verbosity == 1:
+		return log.DebugLevel, true
+	default:
+		return log.TraceLevel, true
+	}
+}
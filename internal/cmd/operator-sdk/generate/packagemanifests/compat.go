@@ -0,0 +1,453 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	apimanifests "github.com/operator-framework/api/pkg/manifests"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+)
+
+// validateCompareWith validates that --compare-with is a directory or git input, and is set with options
+// writing a version directory to compare.
+func (c packagemanifestsCmd) validateCompareWith() error {
+	if c.compareWith == "" {
+		if c.allowBreaking {
+			return errors.New("--allow-breaking can only be set if --compare-with is set")
+		}
+		return nil
+	}
+	if c.stdout || c.singleFile != "" || c.csvOnly {
+		return errors.New("--compare-with cannot be set with --stdout, --single-file, or --csv-only, " +
+			"since no version directory is written to compare")
+	}
+	if genutil.IsPipeReader() {
+		// The version is generated once to compare and again to write it, so inputs are read twice.
+		return errors.New("--compare-with cannot be set if reading from stdin, which can only be read once")
+	}
+	if !isDir(c.compareWith) {
+		if _, err := parseGitInput(c.compareWith); err != nil {
+			return fmt.Errorf("--compare-with %s must be an existing directory or a git reference of the form "+
+				"repo@ref[:path]", c.compareWith)
+		}
+	}
+	return nil
+}
+
+// checkCompatibility compares the version c would generate with the published version in --compare-with,
+// the head of c.channelName, or of the default channel if it is not set. The published version is read
+// and the version generated into a temporary directory before anything is written, so --compare-with
+// may be --output-dir. Permission changes are logged as warnings. Breaking changes are returned as an
+// error, or logged as warnings if --allow-breaking is set.
+func (c packagemanifestsCmd) checkCompatibility() error {
+	dir := c.compareWith
+	if !isDir(dir) {
+		in, err := parseGitInput(c.compareWith)
+		if err != nil {
+			return err
+		}
+		var cleanup func()
+		if dir, cleanup, err = in.fetch(); err != nil {
+			return err
+		}
+		defer cleanup()
+	}
+	published, err := c.publishedBundle(dir)
+	if err != nil {
+		return err
+	}
+	generated, err := c.generateBundle()
+	if err != nil {
+		return err
+	}
+
+	from := published.CSV.GetName()
+	for _, change := range permissionChanges(published.CSV, generated.CSV) {
+		log.Warnf("Permissions changed from %s: %s", from, change)
+	}
+	breaking := breakingChanges(published, generated)
+	if len(breaking) == 0 {
+		c.println("Version", c.version, "is a compatible upgrade from", from)
+		return nil
+	}
+	if c.allowBreaking {
+		for _, change := range breaking {
+			log.Warnf("Breaking change from %s, allowed by --allow-breaking: %s", from, change)
+		}
+		return nil
+	}
+	return withExitCode(exitValidation, fmt.Errorf("version %s has %d breaking change(s) from %s; "+
+		"set --allow-breaking to allow them:\n  - %s", c.version, len(breaking), from, strings.Join(breaking, "\n  - ")))
+}
+
+// generateBundle generates the version into a temporary directory and returns it as a bundle.
+func (c packagemanifestsCmd) generateBundle() (*apimanifests.Bundle, error) {
+	tmp, err := ioutil.TempDir("", "packagemanifests-compare-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmp); err != nil {
+			log.Warnf("Error removing directory %s: %v", tmp, err)
+		}
+	}()
+
+	// Only the version is compared, so nothing is written outside of tmp.
+	gen := c
+	gen.outputDir = tmp
+	gen.compareWith = ""
+	gen.quiet = true
+	gen.skipIfUnchanged = false
+	gen.writeBase = false
+	gen.ociOut = ""
+	gen.sbomFile = ""
+	gen.genDockerfile = false
+	gen.deprecateVersions = nil
+	if err := gen.run(); err != nil {
+		return nil, err
+	}
+	generated, err := apimanifests.GetBundleFromDir(gen.versionDir())
+	if err != nil {
+		return nil, fmt.Errorf("error reading generated version: %w", err)
+	}
+	return generated, nil
+}
+
+// publishedBundle returns the bundle of the head of c.channelName, or of the default channel if it is
+// not set, in the package manifests in dir.
+func (c packagemanifestsCmd) publishedBundle(dir string) (*apimanifests.Bundle, error) {
+	pkg, bundles, err := apimanifests.GetManifestsDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading package manifests from %s: %w", dir, err)
+	}
+	if pkg == nil || pkg.IsEmpty() {
+		return nil, withExitCode(exitInputNotFound, fmt.Errorf("no package manifest found in %s", dir))
+	}
+	channelName := c.channelName
+	if channelName == "" {
+		channelName = pkg.DefaultChannelName
+	}
+	head := ""
+	for _, channel := range pkg.Channels {
+		if channel.Name == channelName {
+			head = channel.CurrentCSVName
+		}
+	}
+	if head == "" {
+		return nil, withExitCode(exitInputNotFound, fmt.Errorf("package %s in %s has no channel %q",
+			pkg.PackageName, dir, channelName))
+	}
+	for _, b := range bundles {
+		if b.CSV.GetName() == head {
+			return b, nil
+		}
+	}
+	return nil, withExitCode(exitInputNotFound, fmt.Errorf("head %s of channel %q was not found in %s",
+		head, channelName, dir))
+}
+
+// crdVersion is a version of a CRD of either API version.
+type crdVersion struct {
+	served bool
+	// schema is the version's OpenAPI v3 validation schema as unmarshaled JSON, or nil if it has none.
+	schema map[string]interface{}
+}
+
+// breakingChanges returns a description of each change in generated that breaks clients or stored
+// objects of published: CRDs removed, unless now required, CRD versions no longer served, and
+// schemas of served versions narrowed. Changes are sorted by CRD and version.
+func breakingChanges(published, generated *apimanifests.Bundle) (changes []string) {
+	oldCRDs, newCRDs := bundleCRDVersions(published), bundleCRDVersions(generated)
+	required := make(map[string]struct{})
+	for _, desc := range generated.CSV.Spec.CustomResourceDefinitions.Required {
+		required[desc.Name] = struct{}{}
+	}
+
+	for _, name := range sortedCRDNames(oldCRDs) {
+		newVersions, exists := newCRDs[name]
+		if !exists {
+			// CRDs moved to another package, ex. with --crds-external, are still served.
+			if _, isRequired := required[name]; !isRequired {
+				changes = append(changes, fmt.Sprintf("CRD %s was removed", name))
+			}
+			continue
+		}
+		oldVersions := oldCRDs[name]
+		for _, version := range sortedVersionNames(oldVersions) {
+			oldVersion := oldVersions[version]
+			if !oldVersion.served {
+				continue
+			}
+			newVersion, exists := newVersions[version]
+			if !exists || !newVersion.served {
+				changes = append(changes, fmt.Sprintf("CRD %s version %s is no longer served", name, version))
+				continue
+			}
+			for _, narrowed := range narrowedSchema("", oldVersion.schema, newVersion.schema) {
+				changes = append(changes, fmt.Sprintf("CRD %s version %s schema %s", name, version, narrowed))
+			}
+		}
+	}
+	return changes
+}
+
+// bundleCRDVersions returns the versions of each CRD in b keyed by CRD name and version name.
+func bundleCRDVersions(b *apimanifests.Bundle) map[string]map[string]crdVersion {
+	crds := make(map[string]map[string]crdVersion)
+	for _, crd := range b.V1CRDs {
+		versions := make(map[string]crdVersion, len(crd.Spec.Versions))
+		for _, v := range crd.Spec.Versions {
+			var schema interface{}
+			if v.Schema != nil {
+				schema = v.Schema.OpenAPIV3Schema
+			}
+			versions[v.Name] = crdVersion{served: v.Served, schema: schemaMap(schema)}
+		}
+		crds[crd.GetName()] = versions
+	}
+	for _, crd := range b.V1beta1CRDs {
+		// A v1beta1 CRD's top-level schema applies to all versions without their own.
+		var topSchema interface{}
+		if crd.Spec.Validation != nil {
+			topSchema = crd.Spec.Validation.OpenAPIV3Schema
+		}
+		versions := make(map[string]crdVersion, len(crd.Spec.Versions)+1)
+		for _, v := range crd.Spec.Versions {
+			schema := topSchema
+			if v.Schema != nil {
+				schema = v.Schema.OpenAPIV3Schema
+			}
+			versions[v.Name] = crdVersion{served: v.Served, schema: schemaMap(schema)}
+		}
+		if len(crd.Spec.Versions) == 0 && crd.Spec.Version != "" {
+			versions[crd.Spec.Version] = crdVersion{served: true, schema: schemaMap(topSchema)}
+		}
+		crds[crd.GetName()] = versions
+	}
+	return crds
+}
+
+// schemaMap returns schema, a *JSONSchemaProps of either CRD API version, as unmarshaled JSON.
+func schemaMap(schema interface{}) map[string]interface{} {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// narrowedSchema returns a description of each way the schema new at path accepts fewer objects than
+// old: a schema added where there was none, changed types, removed properties, newly required
+// properties, and added or narrowed enums.
+func narrowedSchema(path string, old, new map[string]interface{}) (narrowed []string) {
+	at := func(format string, args ...interface{}) {
+		loc := path
+		if loc == "" {
+			loc = "<root>"
+		}
+		narrowed = append(narrowed, loc+": "+fmt.Sprintf(format, args...))
+	}
+	if new == nil {
+		return nil
+	}
+	if old == nil {
+		at("validation was added")
+		return narrowed
+	}
+
+	oldType, _ := old["type"].(string)
+	if newType, _ := new["type"].(string); newType != "" && newType != oldType {
+		at("type changed from %q to %q", oldType, newType)
+	}
+
+	oldProps, _ := old["properties"].(map[string]interface{})
+	newProps, _ := new["properties"].(map[string]interface{})
+	preservesUnknown, _ := new["x-kubernetes-preserve-unknown-fields"].(bool)
+	_, hasAdditional := new["additionalProperties"]
+	for _, name := range sortedPropertyNames(oldProps) {
+		oldProp, _ := oldProps[name].(map[string]interface{})
+		newProp, exists := newProps[name].(map[string]interface{})
+		if !exists {
+			if !preservesUnknown && !hasAdditional {
+				at("property %s was removed", name)
+			}
+			continue
+		}
+		narrowed = append(narrowed, narrowedSchema(joinSchemaPath(path, name), oldProp, newProp)...)
+	}
+
+	oldRequired := make(map[string]struct{})
+	if list, ok := old["required"].([]interface{}); ok {
+		for _, name := range list {
+			oldRequired[fmt.Sprint(name)] = struct{}{}
+		}
+	}
+	if list, ok := new["required"].([]interface{}); ok {
+		for _, name := range list {
+			if _, wasRequired := oldRequired[fmt.Sprint(name)]; !wasRequired {
+				at("property %v is newly required", name)
+			}
+		}
+	}
+
+	if newEnum, ok := new["enum"].([]interface{}); ok {
+		oldEnum, hadEnum := old["enum"].([]interface{})
+		if !hadEnum {
+			at("enum was added")
+		} else {
+			values := make(map[string]struct{}, len(newEnum))
+			for _, v := range newEnum {
+				b, _ := json.Marshal(v)
+				values[string(b)] = struct{}{}
+			}
+			for _, v := range oldEnum {
+				b, _ := json.Marshal(v)
+				if _, kept := values[string(b)]; !kept {
+					at("enum value %s was removed", b)
+				}
+			}
+		}
+	}
+
+	oldItems, _ := old["items"].(map[string]interface{})
+	newItems, _ := new["items"].(map[string]interface{})
+	if oldItems != nil && newItems != nil {
+		narrowed = append(narrowed, narrowedSchema(path+"[*]", oldItems, newItems)...)
+	}
+	return narrowed
+}
+
+// joinSchemaPath returns the path of property name of the schema at path.
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// permissionChanges returns a description of each permission rule added to or removed from a
+// ServiceAccount of published's install strategy in generated's install strategy.
+func permissionChanges(published, generated *operatorsv1alpha1.ClusterServiceVersion) (changes []string) {
+	oldStrategy := published.Spec.InstallStrategy.StrategySpec
+	newStrategy := generated.Spec.InstallStrategy.StrategySpec
+	changes = append(changes, diffPermissions("permission", oldStrategy.Permissions, newStrategy.Permissions)...)
+	changes = append(changes, diffPermissions("cluster permission", oldStrategy.ClusterPermissions,
+		newStrategy.ClusterPermissions)...)
+	return changes
+}
+
+// diffPermissions returns a description, prefixed by kind, of each rule added or removed in new compared to old.
+func diffPermissions(kind string, old, new []operatorsv1alpha1.StrategyDeploymentPermissions) (changes []string) {
+	oldRules, newRules := permissionRules(old), permissionRules(new)
+	accounts := make(map[string]struct{})
+	for sa := range oldRules {
+		accounts[sa] = struct{}{}
+	}
+	for sa := range newRules {
+		accounts[sa] = struct{}{}
+	}
+	for _, sa := range sortedKeys(accounts) {
+		for _, rule := range sortedKeys(newRules[sa]) {
+			if _, existed := oldRules[sa][rule]; !existed {
+				changes = append(changes, fmt.Sprintf("ServiceAccount %s %s added: %s", sa, kind, rule))
+			}
+		}
+		for _, rule := range sortedKeys(oldRules[sa]) {
+			if _, exists := newRules[sa][rule]; !exists {
+				changes = append(changes, fmt.Sprintf("ServiceAccount %s %s removed: %s", sa, kind, rule))
+			}
+		}
+	}
+	return changes
+}
+
+// permissionRules returns the description of each rule in perms keyed by ServiceAccount name.
+func permissionRules(perms []operatorsv1alpha1.StrategyDeploymentPermissions) map[string]map[string]struct{} {
+	rules := make(map[string]map[string]struct{})
+	for _, perm := range perms {
+		if rules[perm.ServiceAccountName] == nil {
+			rules[perm.ServiceAccountName] = make(map[string]struct{})
+		}
+		for _, rule := range perm.Rules {
+			rules[perm.ServiceAccountName][describeRule(rule)] = struct{}{}
+		}
+	}
+	return rules
+}
+
+// describeRule returns rule's set fields, ex. "apiGroups=[apps] resources=[deployments] verbs=[get list]".
+func describeRule(rule rbacv1.PolicyRule) string {
+	var fields []string
+	for _, field := range []struct {
+		name   string
+		values []string
+	}{
+		{"apiGroups", rule.APIGroups},
+		{"resources", rule.Resources},
+		{"resourceNames", rule.ResourceNames},
+		{"nonResourceURLs", rule.NonResourceURLs},
+		{"verbs", rule.Verbs},
+	} {
+		if len(field.values) != 0 {
+			fields = append(fields, fmt.Sprintf("%s=[%s]", field.name, strings.Join(field.values, " ")))
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// sortedCRDNames returns the CRD names of crds sorted.
+func sortedCRDNames(crds map[string]map[string]crdVersion) []string {
+	names := make([]string, 0, len(crds))
+	for name := range crds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedVersionNames returns the version names of versions sorted.
+func sortedVersionNames(versions map[string]crdVersion) []string {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedPropertyNames returns the property names of props sorted.
+func sortedPropertyNames(props map[string]interface{}) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
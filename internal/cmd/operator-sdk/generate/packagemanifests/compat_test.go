@@ -0,0 +1,253 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+)
+
+var _ = Describe("Checking compatibility with published package manifests", func() {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+`
+	const crd = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    listKind: MemcachedList
+    plural: memcacheds
+    singular: memcached
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: false
+    schema:
+      openAPIV3Schema:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+  - name: v1beta1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+              image:
+                type: string
+`
+
+	var tmp, deployDir, publishedDir, outputDir string
+	newCmd := func(version, outputDir string) packagemanifestsCmd {
+		return packagemanifestsCmd{
+			packageName:   "memcached-operator",
+			version:       version,
+			channelName:   "alpha",
+			inputDir:      outputDir,
+			outputDir:     outputDir,
+			deployDir:     deployDir,
+			crdsDir:       deployDir,
+			updateObjects: true,
+			quiet:         true,
+			generator:     genpkg.NewGenerator(),
+		}
+	}
+	writeManifests := func(docs ...string) {
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "manifests.yaml"),
+			[]byte(strings.Join(docs, "---\n")), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-compat-")
+		Expect(err).NotTo(HaveOccurred())
+		deployDir = filepath.Join(tmp, "deploy")
+		publishedDir = filepath.Join(tmp, "published")
+		outputDir = filepath.Join(tmp, "packagemanifests")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		writeManifests(deployment, crd)
+		Expect(newCmd("0.1.0", publishedDir).run()).To(Succeed())
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("succeeds if nothing changed", func() {
+		c := newCmd("0.2.0", outputDir)
+		c.compareWith = publishedDir
+		Expect(c.validateCompareWith()).To(Succeed())
+		Expect(c.run()).To(Succeed())
+	})
+	It("fails with breaking changes, naming each", func() {
+		writeManifests(deployment, strings.NewReplacer(
+			"  - name: v1alpha1\n    served: true", "  - name: v1alpha1\n    served: false",
+			"              image:\n                type: string\n", "",
+		).Replace(crd))
+		c := newCmd("0.2.0", outputDir)
+		c.compareWith = publishedDir
+		err := c.run()
+		Expect(err).To(MatchError(
+			"version 0.2.0 has 2 breaking change(s) from memcached-operator.v0.1.0; " +
+				"set --allow-breaking to allow them:\n" +
+				"  - CRD memcacheds.cache.example.com version v1alpha1 is no longer served\n" +
+				"  - CRD memcacheds.cache.example.com version v1beta1 schema spec: property image was removed"))
+		Expect(exitCode(err)).To(Equal(exitValidation))
+		Expect(outputDir).NotTo(BeADirectory())
+
+		c.allowBreaking = true
+		Expect(c.run()).To(Succeed())
+	})
+	It("compares with the published head if --compare-with is --output-dir", func() {
+		writeManifests(deployment, strings.Replace(crd, "  - name: v1alpha1\n    served: true",
+			"  - name: v1alpha1\n    served: false", 1))
+		c := newCmd("0.2.0", publishedDir)
+		c.compareWith = publishedDir
+		err := c.run()
+		Expect(err).To(MatchError(ContainSubstring("version 0.2.0 has 1 breaking change(s) from memcached-operator.v0.1.0")))
+		Expect(filepath.Join(publishedDir, "0.2.0")).NotTo(BeADirectory())
+		b, err := ioutil.ReadFile(filepath.Join(publishedDir, "memcached-operator.package.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring("currentCSV: memcached-operator.v0.1.0"))
+	})
+	It("fails if the channel does not exist in the published package", func() {
+		c := newCmd("0.2.0", outputDir)
+		c.compareWith = publishedDir
+		c.channelName = "stable"
+		err := c.run()
+		Expect(err).To(MatchError(ContainSubstring(`has no channel "stable"`)))
+		Expect(exitCode(err)).To(Equal(exitInputNotFound))
+	})
+	It("fails if set with --stdout or without a directory or git reference", func() {
+		c := newCmd("0.2.0", outputDir)
+		c.compareWith = publishedDir
+		c.stdout = true
+		Expect(c.validateCompareWith()).To(MatchError(ContainSubstring("--compare-with cannot be set with --stdout")))
+
+		c = newCmd("0.2.0", outputDir)
+		c.compareWith = filepath.Join(tmp, "missing")
+		Expect(c.validateCompareWith()).To(MatchError(ContainSubstring("must be an existing directory or a git reference")))
+
+		c = newCmd("0.2.0", outputDir)
+		c.allowBreaking = true
+		Expect(c.validateCompareWith()).To(MatchError("--allow-breaking can only be set if --compare-with is set"))
+	})
+	It("fails if manifests are piped to stdin", func() {
+		r, w, err := os.Pipe()
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Close()
+		origStdin := os.Stdin
+		defer func() { os.Stdin = origStdin }()
+		os.Stdin = r
+
+		c := newCmd("0.2.0", outputDir)
+		c.compareWith = publishedDir
+		Expect(c.validateCompareWith()).To(MatchError("--compare-with cannot be set if reading from stdin, " +
+			"which can only be read once"))
+	})
+})
+
+var _ = Describe("narrowedSchema", func() {
+	object := func(fields map[string]interface{}) map[string]interface{} {
+		schema := map[string]interface{}{"type": "object"}
+		for k, v := range fields {
+			schema[k] = v
+		}
+		return schema
+	}
+	old := object(map[string]interface{}{
+		"properties": map[string]interface{}{
+			"size":  map[string]interface{}{"type": "integer"},
+			"mode":  map[string]interface{}{"type": "string", "enum": []interface{}{"fast", "safe"}},
+			"hosts": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	})
+
+	It("returns nothing for identical or widened schemas", func() {
+		Expect(narrowedSchema("", old, old)).To(BeEmpty())
+		Expect(narrowedSchema("", old, object(map[string]interface{}{
+			"x-kubernetes-preserve-unknown-fields": true,
+		}))).To(BeEmpty())
+		Expect(narrowedSchema("", old, nil)).To(BeEmpty())
+	})
+	It("returns each narrowing with its path", func() {
+		Expect(narrowedSchema("", old, object(map[string]interface{}{
+			"required": []interface{}{"size"},
+			"properties": map[string]interface{}{
+				"size":  map[string]interface{}{"type": "string"},
+				"mode":  map[string]interface{}{"type": "string", "enum": []interface{}{"safe"}},
+				"hosts": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+			},
+		}))).To(Equal([]string{
+			`hosts[*]: type changed from "string" to "integer"`,
+			`mode: enum value "fast" was removed`,
+			`size: type changed from "integer" to "string"`,
+			`<root>: property size is newly required`,
+		}))
+		Expect(narrowedSchema("", nil, old)).To(Equal([]string{"<root>: validation was added"}))
+	})
+})
+
+var _ = Describe("permissionChanges", func() {
+	csv := func(rules ...rbacv1.PolicyRule) *operatorsv1alpha1.ClusterServiceVersion {
+		csv := &operatorsv1alpha1.ClusterServiceVersion{}
+		csv.Spec.InstallStrategy.StrategySpec.ClusterPermissions = []operatorsv1alpha1.StrategyDeploymentPermissions{
+			{ServiceAccountName: "controller-manager", Rules: rules},
+		}
+		return csv
+	}
+	get := rbacv1.PolicyRule{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get"}}
+	list := rbacv1.PolicyRule{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"list"}}
+
+	It("returns added and removed rules by ServiceAccount", func() {
+		Expect(permissionChanges(csv(get), csv(get))).To(BeEmpty())
+		Expect(permissionChanges(csv(get), csv(list))).To(Equal([]string{
+			"ServiceAccount controller-manager cluster permission added: apiGroups=[apps] resources=[deployments] verbs=[list]",
+			"ServiceAccount controller-manager cluster permission removed: apiGroups=[apps] resources=[deployments] verbs=[get]",
+		}))
+	})
+})
@@ -0,0 +1,119 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// applyConfig sets each flag in fs from the config file at path, a YAML mapping of flag names to values,
+// unless that flag was already set. A list sets a repeatable flag once per element, and a mapping sets a
+// key=value flag, ex. --metrics-annotation, to all of its pairs.
+func applyConfig(fs *pflag.FlagSet, path string) error {
+	if path == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading --config: %w", err)
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &values, useNumber); err != nil {
+		return fmt.Errorf("error parsing --config %s: %v", path, err)
+	}
+
+	if _, ok := values["config"]; ok {
+		return fmt.Errorf("--config %s cannot set --config", path)
+	}
+	return applyFlagValues(fs, values, "--config "+path)
+}
+
+// applyFlagValues sets each flag in fs to its value in values, unless that flag was already set.
+// source names where values were read from in errors.
+func applyFlagValues(fs *pflag.FlagSet, values map[string]interface{}, source string) error {
+	flagNames := make([]string, 0, len(values))
+	for flagName := range values {
+		if fs.Lookup(flagName) == nil {
+			return fmt.Errorf("%s sets unknown flag %q", source, flagName)
+		}
+		flagNames = append(flagNames, flagName)
+	}
+	sort.Strings(flagNames)
+	for _, flagName := range flagNames {
+		if fs.Changed(flagName) {
+			continue
+		}
+		flagValues, err := configFlagValues(values[flagName])
+		if err != nil {
+			return fmt.Errorf("error setting --%s from %s: %v", flagName, source, err)
+		}
+		for _, value := range flagValues {
+			if err := fs.Set(flagName, value); err != nil {
+				return fmt.Errorf("error setting --%s from %s: %v", flagName, source, err)
+			}
+		}
+	}
+	return nil
+}
+
+// useNumber decodes numbers in config files as written, so large ones are not set in exponent form.
+func useNumber(dec *json.Decoder) *json.Decoder {
+	dec.UseNumber()
+	return dec
+}
+
+// configFlagValues returns the values to set a flag to, in order, for value from a config file.
+func configFlagValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("value must not be null")
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, elem := range v {
+			if !isConfigScalar(elem) {
+				return nil, fmt.Errorf("list elements must be strings, numbers, or booleans")
+			}
+			values = append(values, fmt.Sprint(elem))
+		}
+		return values, nil
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(v))
+		for key, elem := range v {
+			if !isConfigScalar(elem) {
+				return nil, fmt.Errorf("mapping values must be strings, numbers, or booleans")
+			}
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, elem))
+		}
+		sort.Strings(pairs)
+		return []string{strings.Join(pairs, ",")}, nil
+	}
+	return []string{fmt.Sprint(value)}, nil
+}
+
+// isConfigScalar returns true if v, decoded from a config file, is a string, number, or boolean.
+func isConfigScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, json.Number, bool:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+)
+
+var _ = Describe("Applying a config file", func() {
+	var (
+		c    *packagemanifestsCmd
+		fs   *pflag.FlagSet
+		dir  string
+		path string
+	)
+	BeforeEach(func() {
+		c = &packagemanifestsCmd{}
+		fs = pflag.NewFlagSet("packagemanifests", pflag.ContinueOnError)
+		c.addFlagsTo(fs)
+		var err error
+		dir, err = ioutil.TempDir("", "packagemanifests-config-")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "packagemanifests.yaml")
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeConfig := func(content string) {
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	It("does nothing if no config file is set", func() {
+		Expect(applyConfig(fs, "")).To(Succeed())
+		Expect(c.channelName).To(BeEmpty())
+	})
+	It("sets flags of every type from the config file", func() {
+		writeConfig(`channel: stable
+default-channel: true
+max-csv-size: 2097152
+require-annotation: [capabilities, categories]
+csv-patch:
+- a.yaml
+- b.yaml
+label:
+  app: memcached
+  tier: cache
+`)
+		Expect(applyConfig(fs, path)).To(Succeed())
+		Expect(c.channelName).To(Equal("stable"))
+		Expect(c.isDefaultChannel).To(BeTrue())
+		Expect(c.maxCSVSize).To(Equal(2097152))
+		Expect(c.requiredAnnotations).To(Equal([]string{"capabilities", "categories"}))
+		Expect(c.csvPatches).To(Equal([]string{"a.yaml", "b.yaml"}))
+		Expect(c.labels).To(Equal(map[string]string{"app": "memcached", "tier": "cache"}))
+	})
+	It("keeps flags set on the command line", func() {
+		writeConfig("channel: stable\nversion: 0.0.1\n")
+		Expect(fs.Parse([]string{"--channel", "alpha"})).To(Succeed())
+		Expect(applyConfig(fs, path)).To(Succeed())
+		Expect(c.channelName).To(Equal("alpha"))
+		Expect(c.version).To(Equal("0.0.1"))
+	})
+	It("takes precedence over a preset it sets", func() {
+		writeConfig("preset: community-operators\ncheck-package-dir: false\n")
+		Expect(applyConfig(fs, path)).To(Succeed())
+		Expect(applyPreset(fs, c.preset)).To(Succeed())
+		Expect(c.checkPackageDir).To(BeFalse())
+		Expect(c.requiredAnnotations).NotTo(BeEmpty())
+	})
+	It("fails for an unknown flag", func() {
+		writeConfig("chanel: stable\n")
+		Expect(applyConfig(fs, path)).To(MatchError(`--config ` + path + ` sets unknown flag "chanel"`))
+	})
+	It("fails for an invalid value", func() {
+		writeConfig("max-csv-size: big\n")
+		Expect(applyConfig(fs, path)).To(MatchError(ContainSubstring("error setting --max-csv-size from --config")))
+	})
+	It("fails for a nested list", func() {
+		writeConfig("csv-patch: [[a.yaml]]\n")
+		Expect(applyConfig(fs, path)).To(MatchError(ContainSubstring("list elements must be strings, numbers, or booleans")))
+	})
+})
@@ -0,0 +1,113 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
+)
+
+// deprecatedAnnotation is the CSV annotation marking its version as deprecated. Its value is the
+// deprecation message the registry surfaces for the version.
+const deprecatedAnnotation = "olm.deprecated"
+
+// validateDeprecateVersions validates that each version to deprecate is a prior version in c.outputDir
+// with a CSV.
+func (c packagemanifestsCmd) validateDeprecateVersions() error {
+	if len(c.deprecateVersions) == 0 {
+		return nil
+	}
+	if c.stdout || c.singleFile != "" {
+		return errors.New("--deprecate-version cannot be set with --stdout or --single-file, " +
+			"since no package directory is written")
+	}
+	if c.csvOnly {
+		return errors.New("--deprecate-version cannot be set with --csv-only, since it writes files other than the CSV")
+	}
+	for _, version := range c.deprecateVersions {
+		if err := genutil.ValidateVersion(version); err != nil {
+			return fmt.Errorf("invalid --deprecate-version: %v", err)
+		}
+		if version == c.version {
+			return fmt.Errorf("--deprecate-version %s cannot be the generated version", version)
+		}
+		dir := filepath.Join(c.versionsDir(), version)
+		if !isDir(dir) {
+			return withExitCode(exitInputNotFound,
+				fmt.Errorf("--deprecate-version %s: version directory %s does not exist", version, dir))
+		}
+		csvs, err := validation.ReadDirCSVs(dir)
+		if err != nil {
+			return err
+		}
+		if len(csvs) == 0 {
+			return fmt.Errorf("--deprecate-version %s: no ClusterServiceVersion found in %s", version, dir)
+		}
+	}
+	return nil
+}
+
+// writeDeprecations sets the olm.deprecated annotation of the CSVs of c.deprecateVersions to a message
+// naming the generated version to upgrade to. CSVs already deprecated with the same message are not rewritten.
+func (c packagemanifestsCmd) writeDeprecations() error {
+	_, fileMode, err := c.fileModes()
+	if err != nil {
+		return err
+	}
+	for _, version := range c.deprecateVersions {
+		dir := filepath.Join(c.versionsDir(), version)
+		csvs, err := validation.ReadDirCSVs(dir)
+		if err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("version %s of package %s is deprecated, upgrade to version %s", version, c.packageName, c.version)
+		fileNames := make([]string, 0, len(csvs))
+		for fileName := range csvs {
+			fileNames = append(fileNames, fileName)
+		}
+		sort.Strings(fileNames)
+		for _, fileName := range fileNames {
+			csv := csvs[fileName]
+			if csv.GetAnnotations()[deprecatedAnnotation] == msg {
+				continue
+			}
+			// Only the annotation is changed, so the CSV keeps the format it was written in.
+			path := filepath.Join(dir, fileName)
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return withExitCode(exitIO, fmt.Errorf("error reading CSV %s: %w", path, err))
+			}
+			if b, err = yamlutil.SetAnnotation(b, deprecatedAnnotation, msg); err != nil {
+				return fmt.Errorf("error deprecating CSV %s: %v", path, err)
+			}
+			if err := fileutil.WriteFile(path, b, fileMode); err != nil {
+				return withExitCode(exitIO, fmt.Errorf("error writing deprecated CSV %s: %w", path, err))
+			}
+			log.Debugf("Deprecated ClusterServiceVersion %s in %s", csv.GetName(), path)
+		}
+		c.println("Deprecated version", version)
+	}
+	return nil
+}
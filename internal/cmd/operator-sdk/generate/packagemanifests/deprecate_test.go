@@ -0,0 +1,135 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+)
+
+var _ = Describe("Deprecating a prior version", func() {
+	const manifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+`
+
+	var tmp, outputDir, csvPath string
+	newCmd := func(version string, deprecateVersions ...string) packagemanifestsCmd {
+		return packagemanifestsCmd{
+			packageName:       "memcached-operator",
+			version:           version,
+			channelName:       "alpha",
+			inputDir:          outputDir,
+			outputDir:         outputDir,
+			deployDir:         filepath.Join(tmp, "deploy"),
+			updateObjects:     true,
+			quiet:             true,
+			deprecateVersions: deprecateVersions,
+			generator:         genpkg.NewGenerator(),
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-deprecate-")
+		Expect(err).NotTo(HaveOccurred())
+		outputDir = filepath.Join(tmp, "packagemanifests")
+		csvPath = filepath.Join(outputDir, "0.1.0", "memcached-operator.clusterserviceversion.yaml")
+		deployDir := filepath.Join(tmp, "deploy")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "manifests.yaml"), []byte(manifests), 0644)).To(Succeed())
+		Expect(newCmd("0.1.0").run()).To(Succeed())
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("only adds the olm.deprecated annotation to the prior version's CSV", func() {
+		before, err := ioutil.ReadFile(csvPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		c := newCmd("0.2.0", "0.1.0")
+		Expect(c.validateDeprecateVersions()).To(Succeed())
+		Expect(c.run()).To(Succeed())
+
+		after, err := ioutil.ReadFile(csvPath)
+		Expect(err).NotTo(HaveOccurred())
+		csvs, err := validation.ReadDirCSVs(filepath.Dir(csvPath))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(csvs["memcached-operator.clusterserviceversion.yaml"].GetAnnotations()).To(HaveKeyWithValue(
+			"olm.deprecated", "version 0.1.0 of package memcached-operator is deprecated, upgrade to version 0.2.0"))
+		annotation := regexp.MustCompile(`(?m)^    olm\.deprecated: .*\n`)
+		Expect(annotation.ReplaceAllString(string(after), "")).To(Equal(string(before)))
+	})
+	It("does not rewrite a CSV already deprecated with the same message", func() {
+		Expect(newCmd("0.2.0", "0.1.0").run()).To(Succeed())
+		past := time.Now().Add(-time.Hour).Truncate(time.Second)
+		Expect(os.Chtimes(csvPath, past, past)).To(Succeed())
+
+		Expect(newCmd("0.2.0", "0.1.0").run()).To(Succeed())
+		info, err := os.Stat(csvPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ModTime()).To(Equal(past))
+	})
+	It("fails if the version does not exist", func() {
+		Expect(newCmd("0.2.0", "0.0.9").validateDeprecateVersions()).To(
+			MatchError(ContainSubstring("--deprecate-version 0.0.9: version directory")))
+	})
+	It("fails with --csv-only, which writes no other files", func() {
+		c := newCmd("0.2.0", "0.1.0")
+		c.csvOnly = true
+		Expect(c.validateDeprecateVersions()).To(MatchError(ContainSubstring("cannot be set with --csv-only")))
+	})
+	It("keeps the format of the prior version's CSV", func() {
+		c := newCmd("0.1.0")
+		c.yamlIndent = 4
+		Expect(c.run()).To(Succeed())
+		before, err := ioutil.ReadFile(csvPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(newCmd("0.2.0", "0.1.0").run()).To(Succeed())
+		after, err := ioutil.ReadFile(csvPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(after)).To(ContainSubstring("\n        olm.deprecated: version 0.1.0"))
+		annotation := regexp.MustCompile(`(?m)^        olm\.deprecated: .*\n`)
+		Expect(annotation.ReplaceAllString(string(after), "")).To(Equal(string(before)))
+	})
+	It("fails if the version is the generated version", func() {
+		Expect(newCmd("0.1.0", "0.1.0").validateDeprecateVersions()).To(
+			MatchError("--deprecate-version 0.1.0 cannot be the generated version"))
+	})
+})
@@ -0,0 +1,140 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/distribution/reference"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
+	"github.com/operator-framework/operator-sdk/internal/registry"
+)
+
+// imageResolver returns image pinned to its digest.
+type imageResolver func(image string) (string, error)
+
+// resolveDigestTimeout bounds each registry query of --pin-digests, so an unreachable registry
+// fails generation instead of hanging it.
+const resolveDigestTimeout = registry.ResolveTimeout
+
+// newImageResolver returns a resolver that queries registries for digests, each for at most
+// resolveDigestTimeout, or if offline, one that fails on images not already pinned to a digest.
+func newImageResolver(ctx context.Context, offline bool) imageResolver {
+	resolve := func(image string) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, resolveDigestTimeout)
+		defer cancel()
+		return registry.ResolveImageDigest(ctx, image)
+	}
+	if offline {
+		resolve = pinnedImage
+	}
+	return cacheResolver(resolve)
+}
+
+// cacheResolver returns a resolver that calls resolve once per image.
+func cacheResolver(resolve imageResolver) imageResolver {
+	pinned := make(map[string]string)
+	return func(image string) (string, error) {
+		if p, resolved := pinned[image]; resolved {
+			return p, nil
+		}
+		p, err := resolve(image)
+		if err != nil {
+			return "", err
+		}
+		if p != image {
+			log.Debugf("Pinned image %s to %s", image, p)
+		}
+		pinned[image] = p
+		return p, nil
+	}
+}
+
+// pinnedImage returns image in normalized form if pinned to a digest, otherwise an error.
+func pinnedImage(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("error parsing image %s: %v", image, err)
+	}
+	if _, isCanonical := named.(reference.Canonical); !isCanonical {
+		return "", fmt.Errorf("image %s is not pinned to a digest, and registries are not queried with --pin-digests-offline", image)
+	}
+	return named.String(), nil
+}
+
+// pinDigests returns a transform that pins images of a CSV's install strategy deployments and
+// related images to digests with resolve, then adds each deployment image to the related images.
+// Images of a Deployment, ex. one written alongside the CSV, are pinned too.
+func pinDigests(resolve imageResolver) gencsv.ObjectTransform {
+	return func(obj client.Object) error {
+		if dep, isDeployment := obj.(*appsv1.Deployment); isDeployment {
+			return pinPodImages(&dep.Spec.Template.Spec, resolve)
+		}
+		csv, isCSV := obj.(*operatorsv1alpha1.ClusterServiceVersion)
+		if !isCSV {
+			return nil
+		}
+
+		var relatedImages []operatorsv1alpha1.RelatedImage
+		seen := make(map[string]struct{})
+		for _, relatedImage := range csv.Spec.RelatedImages {
+			image, err := resolve(relatedImage.Image)
+			if err != nil {
+				return err
+			}
+			if _, hasImage := seen[image]; !hasImage {
+				relatedImages = append(relatedImages, operatorsv1alpha1.RelatedImage{Name: relatedImage.Name, Image: image})
+				seen[image] = struct{}{}
+			}
+		}
+
+		for i := range csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+			podSpec := &csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[i].Spec.Template.Spec
+			if err := pinPodImages(podSpec, resolve); err != nil {
+				return err
+			}
+			for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+				for _, container := range containers {
+					if _, hasImage := seen[container.Image]; !hasImage {
+						relatedImages = append(relatedImages, operatorsv1alpha1.RelatedImage{Name: container.Name, Image: container.Image})
+						seen[container.Image] = struct{}{}
+					}
+				}
+			}
+		}
+		csv.Spec.RelatedImages = relatedImages
+
+		return nil
+	}
+}
+
+// pinPodImages pins the images of all containers in podSpec to digests with resolve.
+func pinPodImages(podSpec *corev1.PodSpec, resolve imageResolver) (err error) {
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			if containers[i].Image, err = resolve(containers[i].Image); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
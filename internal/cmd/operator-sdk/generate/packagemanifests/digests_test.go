@@ -0,0 +1,115 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+)
+
+var _ = Describe("Pinning image digests", func() {
+	const (
+		digest    = "sha256:2f1d7c7f3b09bb1ac2b0f8e3a6f2ba4f6ea3a1d4d3b0d6fbbd8d0c7f2e3b4a5c"
+		operator  = "quay.io/example/operator"
+		proxy     = "gcr.io/kubebuilder/kube-rbac-proxy"
+		operandDB = "quay.io/example/db"
+	)
+
+	Describe("pinDigests", func() {
+		var (
+			csv      *operatorsv1alpha1.ClusterServiceVersion
+			resolved []string
+			resolve  imageResolver
+		)
+
+		BeforeEach(func() {
+			resolved = nil
+			resolve = func(image string) (string, error) {
+				resolved = append(resolved, image)
+				if image == "quay.io/example/missing:v1" {
+					return "", fmt.Errorf("error resolving image %s: not found", image)
+				}
+				return pinnedImage(image[:len(image)-len(":v1")] + "@" + digest)
+			}
+			csv = &operatorsv1alpha1.ClusterServiceVersion{}
+			csv.Spec.RelatedImages = []operatorsv1alpha1.RelatedImage{{Name: "db", Image: operandDB + ":v1"}}
+			csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = []operatorsv1alpha1.StrategyDeploymentSpec{{Name: "manager"}}
+			podSpec := &csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[0].Spec.Template.Spec
+			podSpec.InitContainers = []corev1.Container{{Name: "init", Image: operator + ":v1"}}
+			podSpec.Containers = []corev1.Container{
+				{Name: "manager", Image: operator + ":v1"},
+				{Name: "kube-rbac-proxy", Image: proxy + ":v1"},
+			}
+		})
+
+		It("pins deployment and related images, and adds deployment images to related images", func() {
+			Expect(pinDigests(resolve)(csv)).To(Succeed())
+			podSpec := csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[0].Spec.Template.Spec
+			Expect(podSpec.InitContainers[0].Image).To(Equal(operator + "@" + digest))
+			Expect(podSpec.Containers[0].Image).To(Equal(operator + "@" + digest))
+			Expect(podSpec.Containers[1].Image).To(Equal(proxy + "@" + digest))
+			Expect(csv.Spec.RelatedImages).To(Equal([]operatorsv1alpha1.RelatedImage{
+				{Name: "db", Image: operandDB + "@" + digest},
+				{Name: "init", Image: operator + "@" + digest},
+				{Name: "kube-rbac-proxy", Image: proxy + "@" + digest},
+			}))
+		})
+		It("resolves each image once when cached", func() {
+			Expect(pinDigests(cacheResolver(resolve))(csv)).To(Succeed())
+			Expect(resolved).To(Equal([]string{operandDB + ":v1", operator + ":v1", proxy + ":v1"}))
+		})
+		It("pins Deployment images, and leaves other objects unchanged", func() {
+			dep := &appsv1.Deployment{}
+			dep.Spec.Template.Spec.Containers = []corev1.Container{{Name: "operand", Image: operandDB + ":v1"}}
+			cm := &corev1.ConfigMap{Data: map[string]string{"image": operator + ":v1"}}
+			Expect(genutil.TransformObjects([]client.Object{dep, cm}, pinDigests(resolve))).To(Succeed())
+			Expect(dep.Spec.Template.Spec.Containers[0].Image).To(Equal(operandDB + "@" + digest))
+			Expect(cm.Data).To(HaveKeyWithValue("image", operator+":v1"))
+		})
+		It("fails if an image cannot be resolved", func() {
+			csv.Spec.RelatedImages[0].Image = "quay.io/example/missing:v1"
+			Expect(pinDigests(resolve)(csv)).To(MatchError(ContainSubstring("error resolving image quay.io/example/missing:v1")))
+		})
+	})
+
+	Describe("pinnedImage", func() {
+		It("normalizes images pinned to a digest", func() {
+			Expect(pinnedImage("example/operator@" + digest)).To(Equal("docker.io/example/operator@" + digest))
+			Expect(pinnedImage(operator + ":v1@" + digest)).To(Equal(operator + ":v1@" + digest))
+		})
+		It("fails on images not pinned to a digest", func() {
+			_, err := pinnedImage(operator + ":v1")
+			Expect(err).To(MatchError(ContainSubstring("image %s:v1 is not pinned to a digest", operator)))
+		})
+	})
+	Describe("newImageResolver", func() {
+		It("fails if the registry does not respond in time", func() {
+			ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+			defer cancel()
+			_, err := newImageResolver(ctx, false)(operator + ":v1")
+			Expect(err).To(MatchError(fmt.Sprintf("error resolving image %s:v1: timed out querying its registry", operator)))
+		})
+	})
+})
@@ -0,0 +1,131 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
+)
+
+// defaultDockerfileDir is the default directory in which --gen-dockerfile writes bundle Dockerfiles.
+const defaultDockerfileDir = "bundles"
+
+// validateDockerfileDir validates that --dockerfile-dir is not in --output-dir, since its directories
+// would be read as versions of the package.
+func (c packagemanifestsCmd) validateDockerfileDir() error {
+	if !c.genDockerfile {
+		return nil
+	}
+	if c.dockerfileDir == "" {
+		return errors.New("--dockerfile-dir must be set with --gen-dockerfile")
+	}
+	if c.outputDir == "" {
+		return nil
+	}
+	inOutputDir, err := isSubdir(c.outputDir, c.dockerfileDir)
+	if err != nil {
+		return err
+	}
+	if inOutputDir {
+		return fmt.Errorf("--dockerfile-dir %s cannot be in --output-dir %s, since its directories would be "+
+			"read as package versions", c.dockerfileDir, c.outputDir)
+	}
+	return nil
+}
+
+// isSubdir returns true if path is dir or is in dir.
+func isSubdir(dir, path string) (bool, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false, nil
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// bundleDockerfileDir returns the directory to which --gen-dockerfile writes the generated version's
+// bundle Dockerfile and metadata.
+func (c packagemanifestsCmd) bundleDockerfileDir() string {
+	return filepath.Join(c.dockerfileDir, c.packageName, c.version)
+}
+
+// writeBundleDockerfile writes a bundle Dockerfile and bundle annotations for the generated version
+// to c.bundleDockerfileDir(), so the Dockerfile and metadata of each version are kept. The Dockerfile's
+// build context is the current directory, and it copies the version directory to the image's manifests
+// directory and the written metadata directory to its metadata directory.
+func (c packagemanifestsCmd) writeBundleDockerfile() error {
+	dirMode, fileMode, err := c.fileModes()
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	dir := c.bundleDockerfileDir()
+	for _, path := range []string{c.versionDir(), dir} {
+		inWD, err := isSubdir(wd, path)
+		if err != nil {
+			return err
+		}
+		if !inWD {
+			return fmt.Errorf("%s is not in the current directory, so a bundle Dockerfile cannot copy it", path)
+		}
+	}
+	channels, defaultChannel, err := c.bundleChannels()
+	if err != nil {
+		return err
+	}
+	annotationsFile, err := c.bundleAnnotations(channels, defaultChannel)
+	if err != nil {
+		return err
+	}
+	metadataDir := filepath.Join(dir, bundle.MetadataDir)
+	if err := fileutil.MkdirAll(metadataDir, dirMode); err != nil {
+		return err
+	}
+	if err := fileutil.WriteFile(filepath.Join(metadataDir, bundle.AnnotationsFile), annotationsFile, fileMode); err != nil {
+		return err
+	}
+
+	// The build context is wd, from which paths are made relative.
+	versionDir, err := filepath.Abs(c.versionDir())
+	if err != nil {
+		return err
+	}
+	if metadataDir, err = filepath.Abs(metadataDir); err != nil {
+		return err
+	}
+	dockerfile, err := bundle.GenerateDockerfile(bundle.RegistryV1Type, bundle.ManifestsDir, bundle.MetadataDir,
+		versionDir, metadataDir, wd, c.packageName, channels, defaultChannel)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(filepath.Join(dir, bundle.DockerFile), dockerfile, fileMode)
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Writing a bundle Dockerfile", func() {
+	var tmp, wd string
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-dockerfile-")
+		Expect(err).NotTo(HaveOccurred())
+		wd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(tmp)).To(Succeed())
+	})
+	AfterEach(func() {
+		Expect(os.Chdir(wd)).To(Succeed())
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("writes a Dockerfile copying the version directory and bundle metadata per version", func() {
+		c := packagemanifestsCmd{
+			outputDir:        "packagemanifests",
+			dockerfileDir:    defaultDockerfileDir,
+			genDockerfile:    true,
+			packageName:      "memcached-operator",
+			channelName:      "stable",
+			isDefaultChannel: true,
+		}
+		Expect(c.validateDockerfileDir()).To(Succeed())
+		for _, version := range []string{"0.0.1", "0.0.2"} {
+			Expect(os.MkdirAll(filepath.Join("packagemanifests", version), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join("packagemanifests", version, "memcached-operator.clusterserviceversion.yaml"),
+				[]byte("kind: ClusterServiceVersion\nmetadata:\n  name: memcached-operator.v"+version+"\n"), 0644)).To(Succeed())
+		}
+		Expect(ioutil.WriteFile(filepath.Join("packagemanifests", "memcached-operator.package.yaml"), []byte("packageName: memcached-operator\n"+
+			"defaultChannel: stable\nchannels:\n- name: stable\n  currentCSV: memcached-operator.v0.0.1\n"+
+			"- name: beta\n  currentCSV: memcached-operator.v0.0.2\n"), 0644)).To(Succeed())
+		for _, version := range []string{"0.0.1", "0.0.2"} {
+			c.version = version
+			Expect(c.writeBundleDockerfile()).To(Succeed())
+		}
+
+		dockerfile, err := ioutil.ReadFile(filepath.Join("bundles", "memcached-operator", "0.0.1", "bundle.Dockerfile"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dockerfile)).To(Equal(`FROM scratch
+
+LABEL operators.operatorframework.io.bundle.mediatype.v1=registry+v1
+LABEL operators.operatorframework.io.bundle.manifests.v1=manifests/
+LABEL operators.operatorframework.io.bundle.metadata.v1=metadata/
+LABEL operators.operatorframework.io.bundle.package.v1=memcached-operator
+LABEL operators.operatorframework.io.bundle.channels.v1=stable
+LABEL operators.operatorframework.io.bundle.channel.default.v1=stable
+
+COPY packagemanifests/0.0.1 /manifests/
+COPY bundles/memcached-operator/0.0.1/metadata /metadata/
+`))
+		Expect(filepath.Join("bundles", "memcached-operator", "0.0.2", "bundle.Dockerfile")).To(BeAnExistingFile())
+
+		annotations, err := ioutil.ReadFile(filepath.Join("bundles", "memcached-operator", "0.0.1", "metadata", "annotations.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(annotations)).To(ContainSubstring("operators.operatorframework.io.bundle.package.v1: memcached-operator"))
+		Expect(string(annotations)).To(ContainSubstring("operators.operatorframework.io.bundle.channel.default.v1: stable"))
+		Expect(filepath.Join("packagemanifests", "metadata")).NotTo(BeADirectory())
+	})
+	It("fails if --dockerfile-dir is in --output-dir", func() {
+		c := packagemanifestsCmd{outputDir: "packagemanifests", dockerfileDir: "packagemanifests/bundles", genDockerfile: true}
+		Expect(c.validateDockerfileDir()).To(MatchError(ContainSubstring("cannot be in --output-dir")))
+		c.dockerfileDir = "packagemanifests"
+		Expect(c.validateDockerfileDir()).To(MatchError(ContainSubstring("cannot be in --output-dir")))
+	})
+	It("fails if the version directory is not in the build context", func() {
+		c := packagemanifestsCmd{outputDir: "..", dockerfileDir: "bundles", version: "0.0.1", packageName: "memcached-operator"}
+		Expect(c.writeBundleDockerfile()).To(MatchError(ContainSubstring("is not in the current directory")))
+	})
+})
@@ -0,0 +1,105 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
+)
+
+// Exit codes of the command by class of failure, so scripts can branch on them.
+const (
+	// exitInternal is the exit code of unexpected errors.
+	exitInternal = 1
+	// exitUsage is the exit code of invalid arguments and flags.
+	exitUsage = 2
+	// exitInputNotFound is the exit code of inputs that do not exist.
+	exitInputNotFound = 3
+	// exitValidation is the exit code of inputs or generated manifests failing a check.
+	exitValidation = 4
+	// exitIO is the exit code of errors reading or writing files.
+	exitIO = 5
+)
+
+// exitError is an error with the exit code of its class of failure.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitError) Unwrap() error {
+	return e.err
+}
+
+// ExitCode returns the code the process exits with. The operator-sdk binary exits with the code
+// of any error implementing this method.
+func (e exitError) ExitCode() int {
+	return e.code
+}
+
+// withExitCode returns err with exit code, or nil if err is nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code of err: the code it was set with by withExitCode, exitValidation for
+// failed ClusterServiceVersion checks, exitInputNotFound for files that do not exist, exitIO for other
+// file errors, and exitInternal otherwise.
+func exitCode(err error) int {
+	var exitErr exitError
+	var checkErr gencsv.CheckError
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &checkErr):
+		return exitValidation
+	case errors.Is(err, fs.ErrNotExist):
+		return exitInputNotFound
+	case errors.As(err, &pathErr):
+		return exitIO
+	}
+	return exitInternal
+}
+
+// usageError returns err with its exit code, or exitUsage if it has none, since errors setting defaults or
+// validating options are usage errors unless caused by an input, ex. a file that does not exist.
+func usageError(err error) error {
+	code := exitCode(err)
+	if code == exitInternal {
+		code = exitUsage
+	}
+	return withExitCode(code, err)
+}
+
+// runError returns err, which failed a run of cmd, prefixed with msg and with its exit code.
+// Usage is not printed, since cmd was used correctly, and the error is printed by the caller.
+func runError(cmd *cobra.Command, msg string, err error) error {
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return withExitCode(exitCode(err), fmt.Errorf("%s: %w", msg, err))
+}
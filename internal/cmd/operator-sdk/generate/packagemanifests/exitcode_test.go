@@ -0,0 +1,82 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+)
+
+var _ = Describe("Exit codes", func() {
+	Describe("exitCode", func() {
+		It("returns the code an error was set with, even if wrapped", func() {
+			err := fmt.Errorf("error generating version 0.0.1: %w", withExitCode(exitValidation, errors.New("bad")))
+			Expect(exitCode(err)).To(Equal(exitValidation))
+		})
+		It("classifies file errors", func() {
+			_, err := os.Open("does-not-exist")
+			Expect(exitCode(fmt.Errorf("error collecting manifests: %w", err))).To(Equal(exitInputNotFound))
+			err = &os.PathError{Op: "write", Path: "file", Err: errors.New("no space left on device")}
+			Expect(exitCode(err)).To(Equal(exitIO))
+		})
+		It("returns the internal error code otherwise", func() {
+			Expect(exitCode(errors.New("unexpected"))).To(Equal(exitInternal))
+		})
+	})
+
+	Describe("usageError", func() {
+		It("keeps the code of input errors, and returns the usage code otherwise", func() {
+			_, openErr := os.Open("does-not-exist")
+			Expect(exitCode(usageError(fmt.Errorf("error reading version file: %w", openErr)))).To(Equal(exitInputNotFound))
+			Expect(exitCode(usageError(errors.New("--version and --version-file cannot both be set")))).To(Equal(exitUsage))
+		})
+	})
+
+	Describe("runError", func() {
+		It("prefixes the error and silences usage", func() {
+			cmd := &cobra.Command{}
+			_, openErr := os.Open("does-not-exist")
+			err := runError(cmd, "Error generating package manifests", openErr)
+			Expect(err.Error()).To(HavePrefix("Error generating package manifests: open does-not-exist"))
+			Expect(err.(exitError).ExitCode()).To(Equal(exitInputNotFound))
+			Expect(cmd.SilenceUsage).To(BeTrue())
+			Expect(cmd.SilenceErrors).To(BeTrue())
+		})
+	})
+
+	It("exits with the usage code for invalid flags", func() {
+		cmd := NewCmd()
+		cmd.SetArgs([]string{"--no-such-flag"})
+		cmd.SetOut(GinkgoWriter)
+		cmd.SetErr(GinkgoWriter)
+		err := cmd.Execute()
+		Expect(err).To(HaveOccurred())
+		Expect(exitCode(err)).To(Equal(exitUsage))
+	})
+	It("exits with the input not found code for a --version-file that does not exist", func() {
+		cmd := NewCmd()
+		cmd.SetArgs([]string{"--package", "memcached-operator", "--version-file", "does-not-exist"})
+		cmd.SetOut(GinkgoWriter)
+		cmd.SetErr(GinkgoWriter)
+		err := cmd.Execute()
+		Expect(err).To(MatchError(ContainSubstring("error reading version file")))
+		Expect(exitCode(err)).To(Equal(exitInputNotFound))
+	})
+})
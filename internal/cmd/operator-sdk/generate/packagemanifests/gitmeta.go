@@ -0,0 +1,152 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// repositoryAnnotation is the CSV annotation linking to the operator's source repository.
+	repositoryAnnotation = "repository"
+	// vcsRefAnnotation is the CSV annotation naming the commit the operator was built from.
+	vcsRefAnnotation = "vcs-ref"
+	// containerImageAnnotation is the CSV annotation naming the operator's image.
+	containerImageAnnotation = "containerImage"
+	// managerContainerName is the name of the operator's container in SDK-scaffolded Deployments.
+	managerContainerName = "manager"
+)
+
+// gitAnnotations returns CSV annotations describing the git repository containing dir: its origin URL
+// as repositoryAnnotation, and its HEAD commit as vcsRefAnnotation. Metadata that cannot be read,
+// ex. because dir is not in a git repository, is skipped with a warning.
+func gitAnnotations(dir string) map[string]string {
+	commit, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		if absDir, absErr := filepath.Abs(dir); absErr == nil {
+			dir = absDir
+		}
+		log.Warnf("Skipping --git-annotations: cannot read the HEAD commit of %s: %v", dir, err)
+		return nil
+	}
+	annotations := map[string]string{vcsRefAnnotation: commit}
+	if status, err := runGit(dir, "status", "--porcelain", "--untracked-files=no"); err == nil && status != "" {
+		log.Warnf("Git repository has uncommitted changes, so %s %s may not match the generated manifests",
+			vcsRefAnnotation, commit)
+	}
+
+	origin, err := runGit(dir, "config", "--get", "remote.origin.url")
+	if err != nil || origin == "" {
+		log.Warnf("Skipping the %s annotation: git repository has no origin remote", repositoryAnnotation)
+		return annotations
+	}
+	repository, err := repositoryURL(origin)
+	if err != nil {
+		log.Warnf("Skipping the %s annotation: %v", repositoryAnnotation, err)
+		return annotations
+	}
+	annotations[repositoryAnnotation] = repository
+	return annotations
+}
+
+// headCommit returns the HEAD commit of the git repository containing dir, or an empty string
+// if it cannot be read.
+func headCommit(dir string) string {
+	commit, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return commit
+}
+
+// setContainerImageAnnotation sets a CSV's containerImageAnnotation to the image of the operator's container,
+// the container named "manager" or the only container of its first install strategy deployment, so the
+// annotation matches the installed image, ex. once pinned to a digest. The annotation is skipped with
+// a warning if the CSV has no such container.
+func setContainerImageAnnotation(obj client.Object) error {
+	csv, isCSV := obj.(*operatorsv1alpha1.ClusterServiceVersion)
+	if !isCSV {
+		return nil
+	}
+	if deps := csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs; len(deps) != 0 {
+		containers := deps[0].Spec.Template.Spec.Containers
+		for _, container := range containers {
+			if container.Name == managerContainerName || len(containers) == 1 {
+				annotations := csv.GetAnnotations()
+				if annotations == nil {
+					annotations = make(map[string]string)
+				}
+				annotations[containerImageAnnotation] = container.Image
+				csv.SetAnnotations(annotations)
+				return nil
+			}
+		}
+	}
+	log.Warnf("Skipping the %s annotation: CSV has no operator container named %q",
+		containerImageAnnotation, managerContainerName)
+	return nil
+}
+
+// runGit runs git with args in dir, returning its trimmed output.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) != 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), bytes.TrimSpace(exitErr.Stderr))
+		}
+		return "", fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// scpLikeRe matches an scp-like git URL, ex. git@github.com:example/memcached-operator.git.
+var scpLikeRe = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// repositoryURL returns the browsable HTTPS URL of the repository with git remote URL origin, without
+// credentials or a ".git" suffix, ex. git@github.com:example/memcached-operator.git is
+// https://github.com/example/memcached-operator.
+func repositoryURL(origin string) (string, error) {
+	var host, path string
+	if m := scpLikeRe.FindStringSubmatch(origin); m != nil && !strings.Contains(origin, "://") {
+		host, path = m[1], m[2]
+	} else {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" {
+			// The URL may contain credentials, so is not included.
+			return "", errors.New("git origin is not a URL with a host")
+		}
+		host, path = u.Host, u.Path
+		if u.Scheme == "ssh" || u.Scheme == "git" {
+			// SSH ports are not the HTTPS port.
+			host = u.Hostname()
+		}
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	return "https://" + host + "/" + path, nil
+}
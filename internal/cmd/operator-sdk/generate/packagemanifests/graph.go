@@ -0,0 +1,63 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"fmt"
+	"strings"
+
+	apimanifests "github.com/operator-framework/api/pkg/manifests"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+)
+
+// runCheckGraph checks the version directories and replaces graph of the package in c.outputDir,
+// and returns an error describing every inconsistency found. Version directory problems are
+// reported before the graph is checked, since a duplicate CSV name makes the graph ambiguous.
+func (c packagemanifestsCmd) runCheckGraph() error {
+	problems, err := validation.CheckVersionDirs(c.outputDir)
+	if err != nil {
+		return err
+	}
+	if len(problems) != 0 {
+		return withExitCode(exitValidation, fmt.Errorf("version directories in %s have %d problem(s):\n  - %s",
+			c.outputDir, len(problems), strings.Join(problems, "\n  - ")))
+	}
+
+	pkg, csvs, err := readPackage(c.outputDir)
+	if err != nil {
+		return err
+	}
+	if problems := validation.CheckGraph(pkg, csvs); len(problems) != 0 {
+		return withExitCode(exitValidation, fmt.Errorf("replaces graph of package %s has %d problem(s):\n  - %s",
+			pkg.PackageName, len(problems), strings.Join(problems, "\n  - ")))
+	}
+
+	c.println("Replaces graph of package", pkg.PackageName, "is consistent")
+	return nil
+}
+
+// readPackage returns the package manifest and all CSVs of the package in dir.
+func readPackage(dir string) (*apimanifests.PackageManifest, []*operatorsv1alpha1.ClusterServiceVersion, error) {
+	pkg, csvs, err := validation.ReadPackage(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pkg == nil {
+		return nil, nil, withExitCode(exitInputNotFound, fmt.Errorf("no package manifest found in %s", dir))
+	}
+	return pkg, csvs, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checking the replaces graph", func() {
+	Describe("runCheckGraph", func() {
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "check-graph-")
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+		writeFile := func(path, content string) {
+			path = filepath.Join(dir, path)
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		}
+		writeNamedCSV := func(dirName, name, version, replaces string) {
+			writeFile(filepath.Join(dirName, "op.clusterserviceversion.yaml"), `apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  name: `+name+`
+spec:
+  version: `+version+`
+  replaces: `+replaces+`
+`)
+		}
+		writeCSV := func(version, replaces string) {
+			writeNamedCSV(version, "op.v"+version, version, replaces)
+		}
+
+		It("fails with a report of all problems", func() {
+			writeFile("op.package.yaml", "packageName: op\ndefaultChannel: alpha\nchannels:\n- name: alpha\n  currentCSV: op.v0.0.2\n")
+			writeCSV("0.0.1", "op.v0.0.0")
+			writeCSV("0.0.2", "op.v0.0.1")
+			writeCSV("0.0.3", "op.v0.0.1")
+
+			err := packagemanifestsCmd{outputDir: dir, quiet: true}.runCheckGraph()
+			Expect(err).To(MatchError(`replaces graph of package op has 2 problem(s):
+  - CSV op.v0.0.1 replaces op.v0.0.0, which does not exist
+  - channel "alpha" has multiple heads: op.v0.0.2 and op.v0.0.3`))
+		})
+		It("succeeds for a consistent graph", func() {
+			writeFile("op.package.yaml", "packageName: op\ndefaultChannel: alpha\nchannels:\n- name: alpha\n  currentCSV: op.v0.0.2\n")
+			writeCSV("0.0.1", "")
+			writeCSV("0.0.2", "op.v0.0.1")
+
+			Expect(packagemanifestsCmd{outputDir: dir, quiet: true}.runCheckGraph()).To(Succeed())
+		})
+		It("fails with the paths of duplicate CSV names and mismatched versions", func() {
+			writeFile("op.package.yaml", "packageName: op\ndefaultChannel: alpha\nchannels:\n- name: alpha\n  currentCSV: op.v0.0.2\n")
+			writeCSV("0.0.1", "")
+			writeNamedCSV("0.0.2", "op.v0.0.1", "0.0.2", "")
+			writeNamedCSV("0.0.3", "op.v0.0.3", "0.0.4", "op.v0.0.1")
+			writeFile(filepath.Join("0.0.3", "op.crd.yaml"), "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\n")
+
+			err := packagemanifestsCmd{outputDir: dir, quiet: true}.runCheckGraph()
+			Expect(err).To(MatchError(`version directories in ` + dir + ` have 2 problem(s):
+  - 0.0.3/op.clusterserviceversion.yaml: CSV version 0.0.4 does not match its directory 0.0.3
+  - CSV name op.v0.0.1 is used by more than one version directory: 0.0.1/op.clusterserviceversion.yaml, 0.0.2/op.clusterserviceversion.yaml`))
+		})
+		It("ignores build metadata in CSV versions", func() {
+			writeFile("op.package.yaml", "packageName: op\ndefaultChannel: alpha\nchannels:\n- name: alpha\n  currentCSV: op.v0.0.1\n")
+			writeNamedCSV("0.0.1", "op.v0.0.1", "0.0.1+build.5", "")
+
+			Expect(packagemanifestsCmd{outputDir: dir, quiet: true}.runCheckGraph()).To(Succeed())
+		})
+		It("checks the version directories of channel directories", func() {
+			writeFile("op.package.yaml", "packageName: op\ndefaultChannel: stable\nchannels:\n"+
+				"- name: alpha\n  currentCSV: op.v0.1.0\n- name: stable\n  currentCSV: op.v0.0.2\n")
+			writeNamedCSV(filepath.Join("stable", "0.0.1"), "op.v0.0.1", "0.0.1", "")
+			writeNamedCSV(filepath.Join("stable", "0.0.2"), "op.v0.0.2", "0.0.2", "op.v0.0.1")
+			writeNamedCSV(filepath.Join("alpha", "0.1.0"), "op.v0.1.0", "0.1.1", "op.v0.0.2")
+
+			err := packagemanifestsCmd{outputDir: dir, quiet: true}.runCheckGraph()
+			Expect(err).To(MatchError(`version directories in ` + dir + ` have 1 problem(s):
+  - alpha/0.1.0/op.clusterserviceversion.yaml: CSV version 0.1.1 does not match its directory 0.1.0`))
+
+			writeNamedCSV(filepath.Join("alpha", "0.1.0"), "op.v0.1.0", "0.1.0", "op.v0.0.2")
+			Expect(packagemanifestsCmd{outputDir: dir, quiet: true}.runCheckGraph()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,75 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+// validateContainerImages returns an error if a container name or image in images,
+// set by --image, is invalid.
+func validateContainerImages(images map[string]string) error {
+	for name, image := range images {
+		if name == "" {
+			return fmt.Errorf("--image %q must be of the form container=image", "="+image)
+		}
+		if _, err := reference.ParseNormalizedNamed(image); err != nil {
+			return fmt.Errorf("--image %s=%s is not a valid image reference: %v", name, image, err)
+		}
+	}
+	return nil
+}
+
+// setContainerImages sets the image of every container, including init containers, of collected
+// Deployments whose name is a key of images to that key's value. Each named container must be found
+// in at least one Deployment, since a misspelled name would otherwise leave an image unchanged.
+func setContainerImages(col *collector.Manifests, images map[string]string) error {
+	found := make(map[string]bool, len(images))
+	for i := range col.Deployments {
+		dep := &col.Deployments[i]
+		podSpec := &dep.Spec.Template.Spec
+		for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+			for j := range containers {
+				image, isSet := images[containers[j].Name]
+				if !isSet {
+					continue
+				}
+				log.Debugf("Setting Deployment %q container %q image to %q", dep.GetName(), containers[j].Name, image)
+				containers[j].Image = image
+				found[containers[j].Name] = true
+			}
+		}
+	}
+
+	var missing []string
+	for name := range images {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("--image containers were not found in any collected Deployment: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
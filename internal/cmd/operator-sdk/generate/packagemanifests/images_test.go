@@ -0,0 +1,69 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("Setting container images", func() {
+	const manifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  template:
+    spec:
+      containers:
+      - name: kube-rbac-proxy
+        image: gcr.io/kubebuilder/kube-rbac-proxy:v0.8.0
+      - name: manager
+        image: controller:latest
+`
+
+	var col *collector.Manifests
+	BeforeEach(func() {
+		col = &collector.Manifests{}
+		Expect(col.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+	})
+
+	It("sets the image of each named container", func() {
+		Expect(setContainerImages(col, map[string]string{
+			"manager":         "quay.io/example/memcached-operator:v0.0.1",
+			"kube-rbac-proxy": "registry.example.com/kube-rbac-proxy:v0.8.0",
+		})).To(Succeed())
+		containers := col.Deployments[0].Spec.Template.Spec.Containers
+		Expect(containers[0].Image).To(Equal("registry.example.com/kube-rbac-proxy:v0.8.0"))
+		Expect(containers[1].Image).To(Equal("quay.io/example/memcached-operator:v0.0.1"))
+	})
+	It("fails if a named container is not found", func() {
+		err := setContainerImages(col, map[string]string{
+			"manager": "quay.io/example/memcached-operator:v0.0.1",
+			"proxy":   "registry.example.com/kube-rbac-proxy:v0.8.0",
+		})
+		Expect(err).To(MatchError("--image containers were not found in any collected Deployment: proxy"))
+	})
+	It("fails to validate an invalid image", func() {
+		Expect(validateContainerImages(map[string]string{"manager": "Invalid:Image"})).To(
+			MatchError(ContainSubstring("is not a valid image reference")))
+		Expect(validateContainerImages(map[string]string{"": "controller:latest"})).To(
+			MatchError(ContainSubstring("must be of the form container=image")))
+	})
+})
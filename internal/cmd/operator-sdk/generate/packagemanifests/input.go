@@ -0,0 +1,369 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+// fromDirInput is the set of manifest directories resolved from a kubebuilder-style config directory.
+type fromDirInput struct {
+	// crdsDir contains CustomResourceDefinitions.
+	crdsDir string
+	// dirs contain all other manifests.
+	dirs []string
+}
+
+// resolveFromDir resolves the CRD, RBAC, manager, webhook, and sample directories in dir, which is
+// laid out like a kubebuilder project's "config" directory. CRDs are read from "crd/bases" if it exists,
+// otherwise "crd". The "crd", "rbac", and "manager" subdirectories are required.
+func resolveFromDir(dir string) (in fromDirInput, err error) {
+	if !isDir(dir) {
+		return in, fmt.Errorf("--from-dir %s is not a directory", dir)
+	}
+
+	in.crdsDir = filepath.Join(dir, "crd", "bases")
+	if !isDir(in.crdsDir) {
+		in.crdsDir = filepath.Join(dir, "crd")
+	}
+	required := []string{in.crdsDir, filepath.Join(dir, "rbac"), filepath.Join(dir, "manager")}
+	var missing []string
+	for _, subDir := range required {
+		if !isDir(subDir) {
+			missing = append(missing, subDir)
+		}
+	}
+	if len(missing) != 0 {
+		return in, fmt.Errorf("--from-dir %s is missing expected directories %s; it must be laid out like a "+
+			"kubebuilder project's config directory, otherwise set --deploy-dir and --crds-dir",
+			dir, strings.Join(missing, ", "))
+	}
+
+	in.dirs = required[1:]
+	for _, optional := range []string{"webhook", "samples"} {
+		if subDir := filepath.Join(dir, optional); isDir(subDir) {
+			in.dirs = append(in.dirs, subDir)
+		}
+	}
+	return in, nil
+}
+
+// gitInput is a set of manifests at path in a git repository at ref.
+type gitInput struct {
+	repo string
+	ref  string
+	path string
+}
+
+// parseGitInput parses an input of the form "repo@ref:path". The last "@" separates
+// repo from ref so SSH URLs like "git@github.com:org/repo.git@v1.0.0:config" are accepted.
+// path is optional and defaults to the repository root.
+func parseGitInput(input string) (in gitInput, err error) {
+	i := strings.LastIndex(input, "@")
+	if i <= 0 || i == len(input)-1 {
+		return in, fmt.Errorf("git input %q must be of the form repo@ref[:path]", input)
+	}
+	in.repo = input[:i]
+	refPath := strings.SplitN(input[i+1:], ":", 2)
+	in.ref = refPath[0]
+	if len(refPath) == 2 {
+		in.path = refPath[1]
+	}
+	if in.ref == "" {
+		return in, fmt.Errorf("git input %q must contain a ref", input)
+	}
+	// git would parse these as options.
+	if strings.HasPrefix(in.repo, "-") || strings.HasPrefix(in.ref, "-") {
+		return in, fmt.Errorf("git input %q repo and ref must not start with '-'", input)
+	}
+	// Only a ".." element leaves the repository, so a directory like "..config" is allowed.
+	p := filepath.ToSlash(filepath.Clean(in.path))
+	if filepath.IsAbs(in.path) || p == ".." || strings.HasPrefix(p, "../") {
+		return in, fmt.Errorf("git input %q path must be relative to the repository root", input)
+	}
+	return in, nil
+}
+
+// gitCacheDir is the directory under the user's cache directory that fetched commits are cached in.
+const gitCacheDir = "operator-sdk/git-inputs"
+
+// commitSHARegexp matches full SHA-1 and SHA-256 commit names, which unlike other refs cannot move.
+var commitSHARegexp = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// fetch shallow-fetches in.ref from in.repo, then returns the directory containing in.path, and a function
+// removing the fetched files unless they are cached. Only commit SHAs are cached, in a directory only the
+// current user can access; other refs, ex. branches or tags, may move, so are fetched again each time.
+func (in gitInput) fetch() (manifestsDir string, cleanup func(), err error) {
+	cleanup = func() {}
+	root, err := gitCacheRoot()
+	if err != nil {
+		return "", cleanup, err
+	}
+
+	var dir string
+	if root != "" && commitSHARegexp.MatchString(in.ref) {
+		h := sha256.Sum256([]byte(in.repo + "@" + in.ref))
+		dir = filepath.Join(root, hex.EncodeToString(h[:8]))
+		if isDir(filepath.Join(dir, ".git")) {
+			log.Debugf("Using cached clone of %s@%s in %s", in.repo, in.ref, dir)
+		} else if err := in.fetchCached(dir); err != nil {
+			return "", cleanup, err
+		}
+	} else {
+		// TempDir creates directories only the current user can access.
+		if dir, err = ioutil.TempDir(root, "fetch-"); err != nil {
+			return "", cleanup, err
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+		if err := in.fetchTo(dir); err != nil {
+			cleanup()
+			return "", func() {}, err
+		}
+	}
+
+	manifestsDir = filepath.Join(dir, in.path)
+	if !isDir(manifestsDir) {
+		cleanup()
+		return "", func() {}, fmt.Errorf("path %q does not exist in %s@%s", in.path, in.repo, in.ref)
+	}
+	return manifestsDir, cleanup, nil
+}
+
+// gitCacheRoot creates and returns the directory in the user's cache directory that fetched refs are
+// written to, only accessible by the current user, or "" if there is no user cache directory.
+func gitCacheRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		log.Debugf("Not caching git inputs: %v", err)
+		return "", nil
+	}
+	root := filepath.Join(cacheDir, filepath.FromSlash(gitCacheDir))
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return "", err
+	}
+	return root, nil
+}
+
+// fetchCached fetches in.ref into dir, through a staging directory so failed fetches are not cached.
+func (in gitInput) fetchCached(dir string) error {
+	staging, err := ioutil.TempDir(filepath.Dir(dir), "fetch-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	if err := in.fetchTo(staging); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, dir); err != nil && !isDir(filepath.Join(dir, ".git")) {
+		return err
+	}
+	return nil
+}
+
+// fetchTo shallow-fetches in.ref from in.repo into the existing directory dir.
+func (in gitInput) fetchTo(dir string) error {
+	// "git fetch" is used instead of "git clone --branch" so commit SHAs can be fetched too.
+	// "--" ends options, so repo and ref are never parsed as options.
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "--", "origin", in.repo},
+		{"fetch", "--quiet", "--depth", "1", "--", "origin", in.ref},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("error fetching %s@%s: git %s: %v\n%s",
+				in.repo, in.ref, strings.Join(args, " "), err, out)
+		}
+	}
+	return nil
+}
+
+// isDir returns true if path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// existingDir returns dir, set by flag, if it is an existing directory, otherwise an empty string.
+func existingDir(flag, dir string) string {
+	if dir == "" || isDir(dir) {
+		return dir
+	}
+	log.Infof("Ignoring %s %s, which does not exist", flag, dir)
+	return ""
+}
+
+// hasManifests returns true if col holds any object.
+func hasManifests(col *collector.Manifests) bool {
+	return len(col.ClusterServiceVersions) != 0 || len(col.Roles) != 0 || len(col.ClusterRoles) != 0 ||
+		len(col.RoleBindings) != 0 || len(col.ClusterRoleBindings) != 0 || len(col.Deployments) != 0 ||
+		len(col.ServiceAccounts) != 0 || len(col.Services) != 0 || len(col.V1CustomResourceDefinitions) != 0 ||
+		len(col.V1beta1CustomResourceDefinitions) != 0 || len(col.ValidatingWebhooks) != 0 ||
+		len(col.MutatingWebhooks) != 0 || len(col.CustomResources) != 0 || len(col.Others) != 0
+}
+
+const (
+	// urlInputTimeout is the maximum time spent downloading a single URL input.
+	urlInputTimeout = 30 * time.Second
+	// urlInputMaxBytes is the maximum size of a single URL input.
+	urlInputMaxBytes = 32 << 20
+)
+
+// urlInputMediaTypes are the response content types accepted for a URL input. Release assets
+// are commonly served as "application/octet-stream", so generic types are accepted too.
+var urlInputMediaTypes = map[string]struct{}{
+	"application/yaml":         {},
+	"application/x-yaml":       {},
+	"text/yaml":                {},
+	"text/x-yaml":              {},
+	"text/plain":               {},
+	"application/octet-stream": {},
+}
+
+// validateInputURL returns an error if rawURL is not an absolute HTTP(S) URL.
+func validateInputURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid input URL %q: %v", rawURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("input URL %q must be an absolute http or https URL", rawURL)
+	}
+	return nil
+}
+
+// fetchURL downloads the multi-document YAML at rawURL. Downloads that take longer than
+// urlInputTimeout, exceed urlInputMaxBytes, return a non-2xx status, or are not of a
+// YAML-compatible content type result in an error.
+func fetchURL(rawURL string) ([]byte, error) {
+	client := &http.Client{Timeout: urlInputTimeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("error downloading %s: unexpected status %s", rawURL, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("error downloading %s: invalid content type %q: %v", rawURL, contentType, err)
+		}
+		if _, isYAML := urlInputMediaTypes[mediaType]; !isYAML {
+			return nil, fmt.Errorf("error downloading %s: content type %q is not YAML", rawURL, mediaType)
+		}
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, urlInputMaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %v", rawURL, err)
+	}
+	if len(b) > urlInputMaxBytes {
+		return nil, fmt.Errorf("error downloading %s: size exceeds %d bytes", rawURL, urlInputMaxBytes)
+	}
+	return b, nil
+}
+
+const (
+	// inputPrecedenceStdin gives objects read from stdin precedence over those read from directories.
+	inputPrecedenceStdin = "stdin"
+	// inputPrecedenceDir gives objects read from directories precedence over those read from stdin.
+	inputPrecedenceDir = "dir"
+)
+
+// validateInputPrecedence returns an error if precedence is not a known input precedence.
+// An empty precedence is the default, inputPrecedenceDir.
+func validateInputPrecedence(precedence string) error {
+	switch precedence {
+	case "", inputPrecedenceStdin, inputPrecedenceDir:
+		return nil
+	}
+	return fmt.Errorf("--input-precedence must be one of %q or %q", inputPrecedenceStdin, inputPrecedenceDir)
+}
+
+// validateInputFormat returns an error if format is not a known input format.
+// An empty format is the default, collector.InputFormatAuto.
+func validateInputFormat(format string) error {
+	names := make([]string, 0, len(collector.InputFormats))
+	for _, f := range collector.InputFormats {
+		if format == "" || collector.InputFormat(format) == f {
+			return nil
+		}
+		names = append(names, string(f))
+	}
+	return fmt.Errorf("--input-format must be one of: %s", strings.Join(names, ", "))
+}
+
+// inputFormatOrDefault returns format as an input format, defaulting to collector.InputFormatAuto.
+func inputFormatOrDefault(format string) collector.InputFormat {
+	if format == "" {
+		return collector.InputFormatAuto
+	}
+	return collector.InputFormat(format)
+}
+
+// overrideInput adds the objects in inCol, read from input, to col, overriding objects with the same
+// kind and name read from earlier inputs, and warns about the overridden objects.
+func overrideInput(col, inCol *collector.Manifests, input string) error {
+	overridden, err := col.Override(inCol)
+	if err != nil {
+		return err
+	}
+	if len(overridden) != 0 {
+		log.Warnf("Objects read from earlier inputs are overridden by objects with the same kind and name "+
+			"read from %s: %s", input, strings.Join(overridden, ", "))
+	}
+	return nil
+}
+
+// mergeInputs merges manifests read from stdin into stdinCol and from all other inputs into dirCol.
+// Objects in the input given precedence override objects with the same kind and name in the other,
+// and are warned about.
+func mergeInputs(stdinCol, dirCol *collector.Manifests, precedence string) (*collector.Manifests, error) {
+	col, over, colName, overName := stdinCol, dirCol, "stdin", "directories"
+	if precedence == inputPrecedenceStdin {
+		col, over, colName, overName = dirCol, stdinCol, "directories", "stdin"
+	}
+	overridden, err := col.Override(over)
+	if err != nil {
+		return nil, err
+	}
+	if len(overridden) != 0 {
+		log.Warnf("Objects read from %s are overridden by objects with the same kind and name read from %s "+
+			"(see --input-precedence): %s", colName, overName, strings.Join(overridden, ", "))
+	}
+	return col, nil
+}
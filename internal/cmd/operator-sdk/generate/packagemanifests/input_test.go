@@ -0,0 +1,340 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("Reading manifests from inputs", func() {
+	Describe("parseGitInput", func() {
+		It("parses an HTTPS repo with a ref and path", func() {
+			in, err := parseGitInput("https://github.com/org/repo.git@v0.1.0:deploy/manifests")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(in).To(Equal(gitInput{repo: "https://github.com/org/repo.git", ref: "v0.1.0", path: "deploy/manifests"}))
+		})
+		It("parses an SSH repo with a ref and no path", func() {
+			in, err := parseGitInput("git@github.com:org/repo.git@3f2c1ab")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(in).To(Equal(gitInput{repo: "git@github.com:org/repo.git", ref: "3f2c1ab"}))
+		})
+		It("fails without a ref", func() {
+			_, err := parseGitInput("https://github.com/org/repo.git")
+			Expect(err).To(HaveOccurred())
+			_, err = parseGitInput("https://github.com/org/repo.git@:deploy")
+			Expect(err).To(HaveOccurred())
+		})
+		It("fails with a path outside the repository", func() {
+			_, err := parseGitInput("https://github.com/org/repo.git@v0.1.0:../deploy")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be relative to the repository root"))
+			_, err = parseGitInput("https://github.com/org/repo.git@v0.1.0:deploy/../..")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be relative to the repository root"))
+		})
+		It("parses a path to a directory whose name starts with ..", func() {
+			in, err := parseGitInput("https://github.com/org/repo.git@v0.1.0:..config/manifests")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(in.path).To(Equal("..config/manifests"))
+		})
+		It("fails with a repo or ref git would parse as an option", func() {
+			_, err := parseGitInput("--upload-pack=touch /tmp/pwned@v0.1.0")
+			Expect(err).To(MatchError(ContainSubstring("must not start with '-'")))
+			_, err = parseGitInput("https://github.com/org/repo.git@--upload-pack=touch /tmp/pwned")
+			Expect(err).To(MatchError(ContainSubstring("must not start with '-'")))
+		})
+	})
+
+	Describe("gitInput.fetch", func() {
+		var tmp, repo, prevCacheHome string
+		git := func(args ...string) string {
+			cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"},
+				args...)...)
+			cmd.Dir = repo
+			out, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(out))
+			return strings.TrimSpace(string(out))
+		}
+		commit := func(image string) string {
+			Expect(ioutil.WriteFile(filepath.Join(repo, "manifests.yaml"),
+				[]byte("apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: "+image+"\n"), 0644)).To(Succeed())
+			git("add", "-A")
+			git("commit", "--quiet", "-m", image)
+			return git("rev-parse", "HEAD")
+		}
+		BeforeEach(func() {
+			if _, err := exec.LookPath("git"); err != nil {
+				Skip("git is not installed")
+			}
+			var err error
+			tmp, err = ioutil.TempDir("", "packagemanifests-git-")
+			Expect(err).NotTo(HaveOccurred())
+			repo = filepath.Join(tmp, "repo")
+			Expect(os.Mkdir(repo, 0755)).To(Succeed())
+			git("init", "--quiet", "--initial-branch", "main")
+			git("config", "uploadpack.allowReachableSHA1InWant", "true")
+			prevCacheHome = os.Getenv("XDG_CACHE_HOME")
+			Expect(os.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))).To(Succeed())
+		})
+		AfterEach(func() {
+			Expect(os.Setenv("XDG_CACHE_HOME", prevCacheHome)).To(Succeed())
+			Expect(os.RemoveAll(tmp)).To(Succeed())
+		})
+
+		collect := func(dir string) []string {
+			col := &collector.Manifests{}
+			Expect(col.UpdateFromDirs(dir, "")).To(Succeed())
+			var names []string
+			for _, sa := range col.ServiceAccounts {
+				names = append(names, sa.GetName())
+			}
+			return names
+		}
+
+		It("fetches a branch again each time, and removes it once done", func() {
+			commit("first")
+			dir, cleanup, err := gitInput{repo: repo, ref: "main"}.fetch()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(collect(dir)).To(Equal([]string{"first"}))
+			cleanup()
+			Expect(dir).NotTo(BeADirectory())
+
+			commit("second")
+			dir, cleanup, err = gitInput{repo: repo, ref: "main"}.fetch()
+			Expect(err).NotTo(HaveOccurred())
+			defer cleanup()
+			Expect(collect(dir)).To(Equal([]string{"second"}))
+		})
+		It("caches a commit in a directory only the current user can access", func() {
+			sha := commit("first")
+			dir, cleanup, err := gitInput{repo: repo, ref: sha}.fetch()
+			Expect(err).NotTo(HaveOccurred())
+			cleanup()
+			Expect(collect(dir)).To(Equal([]string{"first"}))
+			info, err := os.Stat(filepath.Join(tmp, "cache", "operator-sdk", "git-inputs"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0700)))
+
+			Expect(os.RemoveAll(repo)).To(Succeed())
+			cached, _, err := gitInput{repo: repo, ref: sha}.fetch()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(Equal(dir))
+		})
+	})
+
+	Describe("fetchURL", func() {
+		var (
+			server      *httptest.Server
+			status      int
+			contentType string
+			body        string
+		)
+		BeforeEach(func() {
+			status, contentType, body = http.StatusOK, "application/yaml", "kind: Role\n"
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", contentType)
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(body))
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("downloads YAML", func() {
+			b, err := fetchURL(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(Equal(body))
+		})
+		It("accepts generic content types used for release assets", func() {
+			contentType = "application/octet-stream"
+			_, err := fetchURL(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("fails on a non-2xx response", func() {
+			status = http.StatusNotFound
+			_, err := fetchURL(server.URL)
+			Expect(err).To(MatchError(ContainSubstring("unexpected status 404")))
+		})
+		It("fails on a non-YAML content type", func() {
+			contentType = "text/html; charset=utf-8"
+			_, err := fetchURL(server.URL)
+			Expect(err).To(MatchError(ContainSubstring(`content type "text/html" is not YAML`)))
+		})
+		It("fails if the response is too large", func() {
+			body = strings.Repeat("a", urlInputMaxBytes+1)
+			_, err := fetchURL(server.URL)
+			Expect(err).To(MatchError(ContainSubstring("size exceeds")))
+		})
+	})
+
+	Describe("validateInputURL", func() {
+		It("accepts HTTP and HTTPS URLs", func() {
+			Expect(validateInputURL("https://example.com/manifests.yaml")).To(Succeed())
+			Expect(validateInputURL("http://example.com/manifests.yaml")).To(Succeed())
+		})
+		It("rejects other URLs", func() {
+			Expect(validateInputURL("file:///tmp/manifests.yaml")).NotTo(Succeed())
+			Expect(validateInputURL("manifests.yaml")).NotTo(Succeed())
+		})
+	})
+
+	Describe("resolveFromDir", func() {
+		var configDir string
+		BeforeEach(func() {
+			configDir = filepath.Join("..", "..", "..", "..", "..", "testdata", "go", "v3", "memcached-operator", "config")
+		})
+
+		It("resolves the subdirectories of a kubebuilder config directory", func() {
+			in, err := resolveFromDir(configDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(in).To(Equal(fromDirInput{
+				crdsDir: filepath.Join(configDir, "crd", "bases"),
+				dirs: []string{
+					filepath.Join(configDir, "rbac"),
+					filepath.Join(configDir, "manager"),
+					filepath.Join(configDir, "webhook"),
+					filepath.Join(configDir, "samples"),
+				},
+			}))
+		})
+		It("fails with guidance if required subdirectories are missing", func() {
+			_, err := resolveFromDir(filepath.Join(configDir, "crd"))
+			Expect(err).To(MatchError(ContainSubstring("is missing expected directories")))
+			Expect(err).To(MatchError(ContainSubstring("otherwise set --deploy-dir and --crds-dir")))
+		})
+		It("fails if the directory does not exist", func() {
+			_, err := resolveFromDir(filepath.Join(configDir, "potato"))
+			Expect(err).To(MatchError(ContainSubstring("is not a directory")))
+		})
+	})
+
+	Describe("existingDir", func() {
+		It("returns existing and unset directories", func() {
+			Expect(existingDir("--deploy-dir", ".")).To(Equal("."))
+			Expect(existingDir("--crds-dir", "")).To(Equal(""))
+		})
+		It("returns nothing for a missing directory", func() {
+			Expect(existingDir("--crds-dir", filepath.Join("config", "potato"))).To(Equal(""))
+		})
+	})
+
+	Describe("hasManifests", func() {
+		It("returns true only if any object was collected", func() {
+			Expect(hasManifests(&collector.Manifests{})).To(BeFalse())
+			Expect(hasManifests(&collector.Manifests{Deployments: []appsv1.Deployment{
+				newInputDeployment("memcached-operator", "dir"),
+			}})).To(BeTrue())
+		})
+	})
+
+	Describe("mergeInputs", func() {
+		var stdinCol, dirCol *collector.Manifests
+		BeforeEach(func() {
+			stdinCol = &collector.Manifests{Deployments: []appsv1.Deployment{
+				newInputDeployment("memcached-operator", "stdin"), newInputDeployment("stdin-only", "stdin"),
+			}}
+			dirCol = &collector.Manifests{Deployments: []appsv1.Deployment{
+				newInputDeployment("memcached-operator", "dir"),
+			}}
+		})
+
+		It("overrides objects from stdin with objects from directories by default", func() {
+			Expect(validateInputPrecedence("")).To(Succeed())
+			col, err := mergeInputs(stdinCol, dirCol, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(col.Deployments).To(Equal([]appsv1.Deployment{
+				newInputDeployment("stdin-only", "stdin"), newInputDeployment("memcached-operator", "dir"),
+			}))
+		})
+		It("overrides objects from directories with objects from stdin", func() {
+			Expect(validateInputPrecedence(inputPrecedenceStdin)).To(Succeed())
+			col, err := mergeInputs(stdinCol, dirCol, inputPrecedenceStdin)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(col.Deployments).To(Equal([]appsv1.Deployment{
+				newInputDeployment("memcached-operator", "stdin"), newInputDeployment("stdin-only", "stdin"),
+			}))
+		})
+		It("fails to validate an unknown precedence", func() {
+			Expect(validateInputPrecedence("url")).To(MatchError(`--input-precedence must be one of "stdin" or "dir"`))
+		})
+	})
+
+	Describe("collectManifests", func() {
+		deployment := func(name, serviceAccountName string) string {
+			return "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: " + name +
+				"\nspec:\n  template:\n    spec:\n      serviceAccountName: " + serviceAccountName + "\n"
+		}
+		serve := func(body string) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/yaml")
+				_, _ = w.Write([]byte(body))
+			}))
+		}
+
+		It("overrides objects from earlier inputs with objects from later inputs", func() {
+			tmp, err := ioutil.TempDir("", "packagemanifests-collect-")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmp)
+			Expect(ioutil.WriteFile(filepath.Join(tmp, "deployment.yaml"),
+				[]byte(deployment("memcached-operator", "dir")), 0644)).To(Succeed())
+			first := serve(deployment("memcached-operator", "url-1") + "---\n" + deployment("url-only", "url-1"))
+			defer first.Close()
+			second := serve(deployment("memcached-operator", "url-2"))
+			defer second.Close()
+
+			c := packagemanifestsCmd{deployDir: tmp, inputURLs: []string{first.URL, second.URL}}
+			col, err := c.collectManifests()
+			Expect(err).NotTo(HaveOccurred())
+			var deployments []string
+			for _, d := range col.Deployments {
+				deployments = append(deployments, d.GetName()+" from "+d.Spec.Template.Spec.ServiceAccountName)
+			}
+			Expect(deployments).To(ConsistOf("memcached-operator from url-2", "url-only from url-1"))
+		})
+	})
+
+	Describe("validateInputFormat", func() {
+		It("accepts known formats and defaults to auto", func() {
+			for _, format := range []string{"", "yaml", "json", "auto"} {
+				Expect(validateInputFormat(format)).To(Succeed())
+			}
+			Expect(inputFormatOrDefault("")).To(Equal(collector.InputFormatAuto))
+			Expect(inputFormatOrDefault("json")).To(Equal(collector.InputFormatJSON))
+		})
+		It("fails for an unknown format", func() {
+			Expect(validateInputFormat("toml")).To(MatchError("--input-format must be one of: yaml, json, auto"))
+		})
+	})
+})
+
+func newInputDeployment(name, serviceAccountName string) (d appsv1.Deployment) {
+	d.ObjectMeta = metav1.ObjectMeta{Name: name}
+	d.Spec.Template.Spec.ServiceAccountName = serviceAccountName
+	return d
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+
+	metricsannotations "github.com/operator-framework/operator-sdk/internal/annotations/metrics"
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
+	sdkversion "github.com/operator-framework/operator-sdk/internal/version"
+)
+
+// inputsHashFileExt is the extension of the file a version's inputs hash is written to.
+// The file is hidden so package manifest loaders ignore it.
+const inputsHashFileExt = ".inputs-hash"
+
+// outputIndependentFlags are flags that do not affect generated files, so are not hashed.
+var outputIndependentFlags = map[string]struct{}{
+	"help":              {},
+	"quiet":             {},
+	"verbose":           {},
+	"skip-if-unchanged": {},
+	"cache":             {},
+}
+
+// setFlagValues records the values of all flags in fs that affect generated files.
+func (c *packagemanifestsCmd) setFlagValues(fs *pflag.FlagSet) {
+	c.flagValues = nil
+	fs.VisitAll(func(f *pflag.Flag) {
+		if _, ignored := outputIndependentFlags[f.Name]; !ignored {
+			c.flagValues = append(c.flagValues, f.Name+"="+f.Value.String())
+		}
+	})
+}
+
+// inputsHashPath returns the path of the file the current version's inputs hash is written to.
+func (c packagemanifestsCmd) inputsHashPath() string {
+	return filepath.Join(c.outputDir, "."+c.version+inputsHashFileExt)
+}
+
+// manifestsHash returns a hash of the manifests in col, flag values, SDK version, and with --git-annotations
+// the HEAD commit, which the generated files of the current version depend on.
+func (c packagemanifestsCmd) manifestsHash(col *collector.Manifests) (string, error) {
+	vcsRef := ""
+	if c.gitAnnotations {
+		vcsRef = headCommit(".")
+	}
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, v := range []interface{}{
+		sdkversion.GitVersion,
+		metricsannotations.MakeBundleObjectAnnotations(c.layout),
+		c.flagValues,
+		c.version,
+		c.fromVersion,
+		vcsRef,
+		col,
+	} {
+		if err := enc.Encode(v); err != nil {
+			return "", fmt.Errorf("error hashing inputs: %v", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// inputsHash returns a hash of manifestsHash and the current contents of the base package manifest,
+// template, channel config, and CSV patches. The base package manifest is also written when
+// --input-dir is --output-dir, so a hash written after generation will match the next run's hash
+// if nothing else changed.
+func (c packagemanifestsCmd) inputsHash(manifestsHash string) (string, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	if err := enc.Encode(manifestsHash); err != nil {
+		return "", fmt.Errorf("error hashing inputs: %v", err)
+	}
+	for _, path := range append([]string{
+		filepath.Join(c.inputDir, c.packageName+".package.yaml"),
+		c.packageTemplate,
+		c.channelConfig,
+	}, c.csvPatches...) {
+		if !genutil.IsExist(path) {
+			continue
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error hashing inputs: %v", err)
+		}
+		if err := enc.Encode(b); err != nil {
+			return "", fmt.Errorf("error hashing inputs: %v", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// inputsUnchanged returns true if hash matches the hash written by a prior run generating
+// the current version, and that version's directory still exists.
+func (c packagemanifestsCmd) inputsUnchanged(hash string) bool {
+	if !isDir(c.versionDir()) {
+		return false
+	}
+	b, err := ioutil.ReadFile(c.inputsHashPath())
+	return err == nil && string(bytes.TrimSpace(b)) == hash
+}
+
+// writeInputsHash writes hash for later runs to compare against.
+func (c packagemanifestsCmd) writeInputsHash(hash string) error {
+	_, fileMode, err := c.fileModes()
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(c.inputsHashPath(), []byte(hash+"\n"), fileMode)
+}
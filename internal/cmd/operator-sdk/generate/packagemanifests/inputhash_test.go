@@ -0,0 +1,117 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("Hashing inputs", func() {
+	var (
+		c      packagemanifestsCmd
+		col    *collector.Manifests
+		tmpDir string
+	)
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "packagemanifests-inputhash-")
+		Expect(err).NotTo(HaveOccurred())
+		c = packagemanifestsCmd{
+			inputDir:    tmpDir,
+			outputDir:   tmpDir,
+			packageName: "memcached-operator",
+			version:     "0.0.1",
+		}
+		col = &collector.Manifests{
+			Deployments: []appsv1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "memcached-operator"}}},
+		}
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("records only flags that affect output", func() {
+		cmd := NewCmd()
+		Expect(cmd.Flags().Parse([]string{"--quiet", "--skip-if-unchanged", "--channel", "beta"})).To(Succeed())
+		c.setFlagValues(cmd.Flags())
+		Expect(c.flagValues).To(ContainElement("channel=beta"))
+		for _, value := range c.flagValues {
+			Expect(value).NotTo(HavePrefix("quiet="))
+			Expect(value).NotTo(HavePrefix("skip-if-unchanged="))
+		}
+	})
+
+	It("changes when manifests, flags, or the base package manifest change", func() {
+		hash, err := c.manifestsHash(col)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.manifestsHash(col)).To(Equal(hash))
+
+		c.flagValues = []string{"channel=beta"}
+		Expect(c.manifestsHash(col)).NotTo(Equal(hash))
+		c.flagValues = nil
+		col.Deployments[0].SetName("other")
+		Expect(c.manifestsHash(col)).NotTo(Equal(hash))
+
+		inputsHash, err := c.inputsHash(hash)
+		Expect(err).NotTo(HaveOccurred())
+		pkgPath := filepath.Join(tmpDir, "memcached-operator.package.yaml")
+		Expect(ioutil.WriteFile(pkgPath, []byte("packageName: memcached-operator\n"), 0644)).To(Succeed())
+		Expect(c.inputsHash(hash)).NotTo(Equal(inputsHash))
+	})
+
+	It("changes when the HEAD commit changes with --git-annotations", func() {
+		wd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(tmpDir)).To(Succeed())
+		defer func() { Expect(os.Chdir(wd)).To(Succeed()) }()
+		commit := func() {
+			_, err := runGit(".", "-c", "user.name=test", "-c", "user.email=test@example.com",
+				"commit", "--quiet", "--allow-empty", "-m", "Commit")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err = runGit(".", "init", "--quiet")
+		Expect(err).NotTo(HaveOccurred())
+		commit()
+
+		hash, err := c.manifestsHash(col)
+		Expect(err).NotTo(HaveOccurred())
+		commit()
+		Expect(c.manifestsHash(col)).To(Equal(hash))
+		c.gitAnnotations = true
+		hash, err = c.manifestsHash(col)
+		Expect(err).NotTo(HaveOccurred())
+		commit()
+		Expect(c.manifestsHash(col)).NotTo(Equal(hash))
+	})
+
+	It("reports unchanged inputs only if a matching hash and the version directory exist", func() {
+		Expect(c.inputsUnchanged("abc")).To(BeFalse())
+		Expect(c.writeInputsHash("abc")).To(Succeed())
+		Expect(c.inputsUnchanged("abc")).To(BeFalse())
+
+		Expect(os.Mkdir(filepath.Join(tmpDir, c.version), 0755)).To(Succeed())
+		Expect(c.inputsUnchanged("abc")).To(BeTrue())
+		Expect(c.inputsUnchanged("def")).To(BeFalse())
+	})
+})
@@ -0,0 +1,275 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+// Results of a collected object in the generated package.
+const (
+	kindResultCSV     = "csv"
+	kindResultWritten = "written"
+	kindResultIgnored = "ignored"
+)
+
+// kindRow describes what generation does with a collected object.
+type kindRow struct {
+	gvk    schema.GroupVersionKind
+	name   string
+	result string
+	reason string
+}
+
+// runListKinds collects manifests and prints each collected object's kind and whether it is folded
+// into the CSV, written as an extra object, or ignored, without generating anything.
+func (c packagemanifestsCmd) runListKinds() error {
+	col, err := c.collectManifests()
+	if err != nil {
+		return err
+	}
+	rows, err := c.classifyKinds(col)
+	if err != nil {
+		return err
+	}
+	return printKinds(os.Stdout, rows)
+}
+
+// classifyKinds classifies each object in col as run would use it.
+func (c packagemanifestsCmd) classifyKinds(col *collector.Manifests) ([]kindRow, error) {
+	var rows []kindRow
+	add := func(obj client.Object, result, reason string) {
+		rows = append(rows, kindRow{gvk: obj.GetObjectKind().GroupVersionKind(), name: obj.GetName(), result: result, reason: reason})
+	}
+
+	objSelector, err := c.objectSelector()
+	if err != nil {
+		return nil, err
+	}
+	if objSelector != nil {
+		for _, key := range col.Select(objSelector, c.selectorIncludeCRDs) {
+			gk, name := splitObjectKey(key)
+			rows = append(rows, kindRow{gvk: gk.WithVersion(""), name: name, result: kindResultIgnored,
+				reason: "labels do not match --selector"})
+		}
+	}
+
+	selector, err := c.managerSelector()
+	if err != nil {
+		return nil, err
+	}
+	extraDeps, err := genutil.SplitManagerDeployments(col, c.managerDeployment, selector)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting the operator's Deployment: %v; "+
+			"set --manager-deployment or --manager-deployment-selector", err)
+	}
+
+	var extras extraObjects
+	if c.updateObjects && !c.csvOnly {
+		extras = c.extraObjects(col, extraDeps)
+	}
+	written, skippedSecrets := newObjectSet(extras.written), newObjectSet(extras.skippedSecrets)
+	_, _, rbacOut := col.SplitCSVPermissionsObjects(nil)
+	notInCSV := make(map[client.Object]struct{}, len(rbacOut))
+	for _, obj := range rbacOut {
+		notInCSV[obj] = struct{}{}
+	}
+
+	baseFound := false
+	for i := range col.ClusterServiceVersions {
+		csv := &col.ClusterServiceVersions[i]
+		switch {
+		case baseFound:
+			add(csv, kindResultIgnored, "a ClusterServiceVersion base was already collected")
+		case strings.HasPrefix(csv.GetName(), c.basePackage()+"."):
+			baseFound = true
+			add(csv, kindResultCSV, "base of the generated ClusterServiceVersion")
+		default:
+			add(csv, kindResultIgnored, fmt.Sprintf("name does not start with %q, so it is not a base", c.basePackage()+"."))
+		}
+	}
+	for i := range col.Deployments {
+		add(&col.Deployments[i], kindResultCSV, "install strategy Deployment")
+	}
+	if c.updateObjects && !c.csvOnly {
+		for i := range extras.deployments {
+			add(&extras.deployments[i], kindResultWritten, "not the operator's Deployment")
+		}
+		for i := range extras.skippedDeployments {
+			add(&extras.skippedDeployments[i], kindResultIgnored, "not the operator's Deployment, and kind is not supported in bundles: "+
+				"set --include-kinds to write it")
+		}
+	} else {
+		for i := range extraDeps {
+			add(&extraDeps[i], kindResultIgnored, "not the operator's Deployment, and other objects are not written")
+		}
+	}
+	for i := range col.V1CustomResourceDefinitions {
+		c.addCRDRow(add, &col.V1CustomResourceDefinitions[i], written)
+	}
+	for i := range col.V1beta1CustomResourceDefinitions {
+		c.addCRDRow(add, &col.V1beta1CustomResourceDefinitions[i], written)
+	}
+
+	var rbac []client.Object
+	for i := range col.Roles {
+		rbac = append(rbac, &col.Roles[i])
+	}
+	for i := range col.ClusterRoles {
+		rbac = append(rbac, &col.ClusterRoles[i])
+	}
+	for i := range col.RoleBindings {
+		rbac = append(rbac, &col.RoleBindings[i])
+	}
+	for i := range col.ClusterRoleBindings {
+		rbac = append(rbac, &col.ClusterRoleBindings[i])
+	}
+	for i := range col.ServiceAccounts {
+		rbac = append(rbac, &col.ServiceAccounts[i])
+	}
+	for _, obj := range rbac {
+		_, isOut := notInCSV[obj]
+		switch {
+		case !isOut:
+			add(obj, kindResultCSV, "permissions of an install strategy ServiceAccount")
+		case c.updateObjects && !c.csvOnly:
+			add(obj, kindResultWritten, "not bound to an install strategy ServiceAccount")
+		default:
+			add(obj, kindResultIgnored, "not bound to an install strategy ServiceAccount, and other objects are not written")
+		}
+	}
+
+	for i := range col.Services {
+		c.addWrittenRow(add, &col.Services[i], written, skippedSecrets, "")
+	}
+	for _, webhook := range col.ValidatingWebhooks {
+		rows = append(rows, kindRow{gvk: admissionWebhookGVK("ValidatingWebhookConfiguration"), name: webhook.Name,
+			result: kindResultCSV, reason: "webhook definition"})
+	}
+	for _, webhook := range col.MutatingWebhooks {
+		rows = append(rows, kindRow{gvk: admissionWebhookGVK("MutatingWebhookConfiguration"), name: webhook.Name,
+			result: kindResultCSV, reason: "webhook definition"})
+	}
+
+	crs := make(map[string]struct{}, len(col.CustomResources))
+	for _, cr := range col.CustomResources {
+		crs[cr.GroupVersionKind().String()+"/"+cr.GetName()] = struct{}{}
+	}
+	for i := range col.Others {
+		obj := &col.Others[i]
+		if _, isCR := crs[obj.GroupVersionKind().String()+"/"+obj.GetName()]; isCR && !written.has(obj) {
+			add(obj, kindResultCSV, "example in the alm-examples annotation")
+			continue
+		}
+		reason := ""
+		if supported, _ := bundle.IsSupported(obj.GetKind()); !supported {
+			reason = "kind is not supported in bundles: set --include-kinds to write it"
+		}
+		c.addWrittenRow(add, obj, written, skippedSecrets, reason)
+	}
+
+	if name := col.ScorecardConfig.Metadata.Name; name != "" {
+		rows = append(rows, kindRow{gvk: col.ScorecardConfig.GroupVersionKind(), name: name,
+			result: kindResultIgnored, reason: "scorecard configurations are not packaged"})
+	}
+
+	return rows, nil
+}
+
+// objectSet is a set of collected objects, keyed by pointer.
+type objectSet map[client.Object]struct{}
+
+// newObjectSet returns a set of objs.
+func newObjectSet(objs []client.Object) objectSet {
+	set := make(objectSet, len(objs))
+	for _, obj := range objs {
+		set[obj] = struct{}{}
+	}
+	return set
+}
+
+// has returns true if obj is in s.
+func (s objectSet) has(obj client.Object) bool {
+	_, ok := s[obj]
+	return ok
+}
+
+// addCRDRow adds crd to the rows with add, as either owned by the CSV and written, or required by it.
+func (c packagemanifestsCmd) addCRDRow(add func(client.Object, string, string), crd client.Object, written objectSet) {
+	switch {
+	case c.crdsExternal:
+		add(crd, kindResultCSV, "required API, not written with --crds-external")
+	case written.has(crd):
+		add(crd, kindResultCSV+","+kindResultWritten, "owned API")
+	default:
+		add(crd, kindResultCSV, "owned API, and other objects are not written")
+	}
+}
+
+// addWrittenRow adds obj to the rows with add as written if in written, and otherwise as ignored
+// with reason, or a reason derived from the flags that disable writing it.
+func (c packagemanifestsCmd) addWrittenRow(add func(client.Object, string, string), obj client.Object,
+	written, skippedSecrets objectSet, reason string) {
+
+	switch {
+	case written.has(obj):
+		add(obj, kindResultWritten, "")
+	case !c.updateObjects || c.csvOnly:
+		add(obj, kindResultIgnored, "other objects are not written")
+	case skippedSecrets.has(obj):
+		add(obj, kindResultIgnored, "Secrets are skipped: set --include-secrets to write them")
+	default:
+		add(obj, kindResultIgnored, reason)
+	}
+}
+
+// admissionWebhookGVK returns the GroupVersionKind of the admissionregistration.k8s.io/v1 configuration kind.
+func admissionWebhookGVK(kind string) schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: "admissionregistration.k8s.io", Version: "v1", Kind: kind}
+}
+
+// splitObjectKey splits a collector object key of the form "<kind>.<group> [<namespace>/]<name>".
+func splitObjectKey(key string) (schema.GroupKind, string) {
+	gk, name := key, ""
+	if i := strings.Index(key, " "); i >= 0 {
+		gk, name = key[:i], key[i+1:]
+	}
+	return schema.ParseGroupKind(gk), name
+}
+
+// printKinds writes rows as a table to w.
+func printKinds(w io.Writer, rows []kindRow) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 4, ' ', 0)
+	fmt.Fprintln(tw, "APIVERSION\tKIND\tNAME\tRESULT\tREASON")
+	for _, row := range rows {
+		apiVersion := row.gvk.GroupVersion().String()
+		if row.gvk.Version == "" {
+			apiVersion = row.gvk.Group
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", apiVersion, row.gvk.Kind, row.name, row.result, row.reason)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("Listing collected kinds", func() {
+	const manifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  template:
+    spec:
+      serviceAccountName: controller-manager
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: manager-role
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: manager-rolebinding
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: manager-role
+subjects:
+- kind: ServiceAccount
+  name: controller-manager
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: metrics-reader
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: manager-config
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+---
+apiVersion: autoscaling/v2beta2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: memcached-operator
+`
+
+	var c packagemanifestsCmd
+	BeforeEach(func() {
+		c = packagemanifestsCmd{packageName: "memcached-operator", updateObjects: true}
+	})
+
+	classify := func() map[string]kindRow {
+		col := &collector.Manifests{}
+		Expect(col.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+		rows, err := c.classifyKinds(col)
+		Expect(err).NotTo(HaveOccurred())
+		byName := make(map[string]kindRow, len(rows))
+		for _, row := range rows {
+			byName[row.gvk.Kind+"/"+row.name] = row
+		}
+		return byName
+	}
+
+	It("classifies objects as folded into the CSV, written, or ignored", func() {
+		rows := classify()
+		Expect(rows).To(HaveLen(7))
+		Expect(rows["Deployment/memcached-operator-controller-manager"].result).To(Equal(kindResultCSV))
+		Expect(rows["ClusterRole/manager-role"].result).To(Equal(kindResultCSV))
+		Expect(rows["ClusterRoleBinding/manager-rolebinding"].result).To(Equal(kindResultCSV))
+		Expect(rows["ClusterRole/metrics-reader"].result).To(Equal(kindResultWritten))
+		Expect(rows["ConfigMap/manager-config"].result).To(Equal(kindResultWritten))
+		Expect(rows["Secret/credentials"].result).To(Equal(kindResultIgnored))
+		Expect(rows["Secret/credentials"].reason).To(ContainSubstring("--include-secrets"))
+		Expect(rows["HorizontalPodAutoscaler/memcached-operator"].result).To(Equal(kindResultIgnored))
+		Expect(rows["HorizontalPodAutoscaler/memcached-operator"].reason).To(ContainSubstring("--include-kinds"))
+	})
+
+	It("lists Secrets as written with --include-secrets, as generation writes them", func() {
+		c.includeSecrets = true
+		rows := classify()
+		Expect(rows["Secret/credentials"].result).To(Equal(kindResultWritten))
+
+		col := &collector.Manifests{}
+		Expect(col.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+		extras := c.extraObjects(col, nil)
+		Expect(extras.secrets).To(HaveLen(1))
+		Expect(extras.written).To(ContainElement(extras.secrets[0]))
+	})
+
+	It("ignores objects not written with --update-objects=false", func() {
+		c.updateObjects = false
+		rows := classify()
+		Expect(rows["ClusterRole/metrics-reader"].result).To(Equal(kindResultIgnored))
+		Expect(rows["ConfigMap/manager-config"].result).To(Equal(kindResultIgnored))
+	})
+
+	It("lists objects not matching --selector as ignored", func() {
+		c.selector = "app=memcached"
+		rows := classify()
+		Expect(rows["ConfigMap/manager-config"].result).To(Equal(kindResultIgnored))
+		Expect(rows["ConfigMap/manager-config"].reason).To(ContainSubstring("--selector"))
+	})
+
+	It("prints a table", func() {
+		col := &collector.Manifests{}
+		Expect(col.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+		rows, err := c.classifyKinds(col)
+		Expect(err).NotTo(HaveOccurred())
+		buf := &bytes.Buffer{}
+		Expect(printKinds(buf, rows)).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(8))
+		Expect(lines[0]).To(MatchRegexp(`^APIVERSION\s+KIND\s+NAME\s+RESULT\s+REASON$`))
+		Expect(lines[1]).To(MatchRegexp(`^apps/v1\s+Deployment\s+memcached-operator-controller-manager\s+csv\s+`))
+	})
+})
@@ -0,0 +1,195 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blang/semver/v4"
+	apimanifests "github.com/operator-framework/api/pkg/manifests"
+	"sigs.k8s.io/yaml"
+
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+)
+
+// Formats of the --list-versions listing.
+const (
+	listFormatText = "text"
+	listFormatJSON = "json"
+)
+
+// listedPackage is the --list-versions listing of a package.
+type listedPackage struct {
+	PackageName    string          `json:"packageName"`
+	DefaultChannel string          `json:"defaultChannel,omitempty"`
+	Versions       []listedVersion `json:"versions"`
+}
+
+// listedVersion is a CSV in a version directory of a package.
+type listedVersion struct {
+	Version string `json:"version"`
+	CSVName string `json:"csvName"`
+	// Dir is the version directory relative to the output directory.
+	Dir      string   `json:"dir"`
+	Replaces string   `json:"replaces,omitempty"`
+	Skips    []string `json:"skips,omitempty"`
+	// Channels are the channels whose replaces graph includes the CSV.
+	Channels []string `json:"channels,omitempty"`
+	// HeadOf are the channels whose head is the CSV.
+	HeadOf []string `json:"headOf,omitempty"`
+}
+
+// runListVersions prints the versions in c.outputDir with their channels, replaces and skips fields,
+// and the channels each is the head of, without generating anything.
+func (c packagemanifestsCmd) runListVersions() error {
+	pkg, err := c.readVersions()
+	if err != nil {
+		return err
+	}
+	if c.listFormat == listFormatJSON {
+		b, err := json.MarshalIndent(pkg, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, string(b))
+		return err
+	}
+	return printVersions(os.Stdout, pkg)
+}
+
+// readVersions reads the package manifest and all version directories in c.outputDir, in either the
+// flat or --channel-dirs layout. Versions are sorted from lowest to highest.
+func (c packagemanifestsCmd) readVersions() (*listedPackage, error) {
+	path := filepath.Join(c.outputDir, c.packageName+".package.yaml")
+	if genutil.IsNotExist(path) {
+		return nil, withExitCode(exitInputNotFound, fmt.Errorf("no package manifest found at %s", path))
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, withExitCode(exitIO, fmt.Errorf("error reading package manifest %s: %v", path, err))
+	}
+	pkg := apimanifests.PackageManifest{}
+	if err := yaml.Unmarshal(b, &pkg); err != nil {
+		return nil, fmt.Errorf("error unmarshalling package manifest %s: %v", path, err)
+	}
+
+	versionDirs, channelDirs, err := validation.ReadPackageLayout(c.outputDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channelDirs {
+		versions, _, err := validation.ReadPackageLayout(filepath.Join(c.outputDir, channel))
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			versionDirs = append(versionDirs, filepath.Join(channel, version))
+		}
+	}
+
+	listed := &listedPackage{PackageName: pkg.PackageName, DefaultChannel: pkg.DefaultChannelName}
+	for _, dir := range versionDirs {
+		csvs, err := validation.ReadDirCSVs(filepath.Join(c.outputDir, dir))
+		if err != nil {
+			return nil, err
+		}
+		for _, fileName := range validation.SortedFileNames(csvs) {
+			csv := csvs[fileName]
+			listed.Versions = append(listed.Versions, listedVersion{
+				Version:  filepath.Base(dir),
+				CSVName:  csv.GetName(),
+				Dir:      dir,
+				Replaces: csv.Spec.Replaces,
+				Skips:    csv.Spec.Skips,
+			})
+		}
+	}
+	sort.SliceStable(listed.Versions, func(i, j int) bool {
+		// Directory names are semantic versions, as validation.ReadPackageLayout only returns those.
+		vi, vj := semver.MustParse(listed.Versions[i].Version), semver.MustParse(listed.Versions[j].Version)
+		if !vi.EQ(vj) {
+			return vi.LT(vj)
+		}
+		return listed.Versions[i].Dir < listed.Versions[j].Dir
+	})
+	setChannelMembership(listed.Versions, pkg.Channels)
+	return listed, nil
+}
+
+// setChannelMembership sets the channels of each version whose CSV is the head of a channel, or is
+// replaced or skipped by a CSV in a channel's replaces graph.
+func setChannelMembership(versions []listedVersion, channels []apimanifests.PackageChannel) {
+	byName := make(map[string][]*listedVersion, len(versions))
+	for i := range versions {
+		byName[versions[i].CSVName] = append(byName[versions[i].CSVName], &versions[i])
+	}
+	sorted := append([]apimanifests.PackageChannel(nil), channels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for _, channel := range sorted {
+		for _, v := range byName[channel.CurrentCSVName] {
+			v.HeadOf = append(v.HeadOf, channel.Name)
+		}
+		seen := make(map[string]bool)
+		queue := []string{channel.CurrentCSVName}
+		for len(queue) != 0 {
+			name := queue[0]
+			queue = queue[1:]
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			for _, v := range byName[name] {
+				v.Channels = append(v.Channels, channel.Name)
+				queue = append(queue, v.Replaces)
+				queue = append(queue, v.Skips...)
+			}
+		}
+	}
+}
+
+// printVersions prints pkg as a table of versions.
+func printVersions(w io.Writer, pkg *listedPackage) error {
+	fmt.Fprintf(w, "Package %s, default channel %s\n", pkg.PackageName, pkg.DefaultChannel)
+	tw := tabwriter.NewWriter(w, 8, 4, 4, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tCSV\tCHANNELS\tHEAD OF\tREPLACES\tSKIPS")
+	for _, v := range pkg.Versions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", v.Version, v.CSVName, listOrNone(v.Channels),
+			listOrNone(v.HeadOf), listOrNone([]string{v.Replaces}), listOrNone(v.Skips))
+	}
+	return tw.Flush()
+}
+
+// listOrNone returns the non-empty values of list joined by commas, or "-" if there are none.
+func listOrNone(list []string) string {
+	var values []string
+	for _, v := range list {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+)
+
+var _ = Describe("Listing package versions", func() {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+`
+
+	var tmp, outputDir string
+	newCmd := func(version, fromVersion, channelName string) packagemanifestsCmd {
+		return packagemanifestsCmd{
+			packageName: "memcached-operator",
+			version:     version,
+			fromVersion: fromVersion,
+			channelName: channelName,
+			inputDir:    outputDir,
+			outputDir:   outputDir,
+			deployDir:   filepath.Join(tmp, "deploy"),
+			quiet:       true,
+			listFormat:  listFormatText,
+			generator:   genpkg.NewGenerator(),
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-listversions-")
+		Expect(err).NotTo(HaveOccurred())
+		outputDir = filepath.Join(tmp, "packagemanifests")
+		deployDir := filepath.Join(tmp, "deploy")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "manifests.yaml"), []byte(deployment), 0644)).To(Succeed())
+		Expect(newCmd("0.1.0", "", "alpha").run()).To(Succeed())
+		Expect(newCmd("0.2.0", "0.1.0", "alpha").run()).To(Succeed())
+		Expect(newCmd("0.1.0", "", "stable").run()).To(Succeed())
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("lists each version with its channels, heads, and replaces", func() {
+		pkg, err := newCmd("", "", "").readVersions()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*pkg).To(Equal(listedPackage{
+			PackageName:    "memcached-operator",
+			DefaultChannel: "alpha",
+			Versions: []listedVersion{
+				{
+					Version:  "0.1.0",
+					CSVName:  "memcached-operator.v0.1.0",
+					Dir:      "0.1.0",
+					Channels: []string{"alpha", "stable"},
+					HeadOf:   []string{"stable"},
+				},
+				{
+					Version:  "0.2.0",
+					CSVName:  "memcached-operator.v0.2.0",
+					Dir:      "0.2.0",
+					Replaces: "memcached-operator.v0.1.0",
+					Channels: []string{"alpha"},
+					HeadOf:   []string{"alpha"},
+				},
+			},
+		}))
+
+		buf := &bytes.Buffer{}
+		Expect(printVersions(buf, pkg)).To(Succeed())
+		Expect(buf.String()).To(Equal(`Package memcached-operator, default channel alpha
+VERSION    CSV                          CHANNELS        HEAD OF    REPLACES                     SKIPS
+0.1.0      memcached-operator.v0.1.0    alpha,stable    stable     -                            -
+0.2.0      memcached-operator.v0.2.0    alpha           alpha      memcached-operator.v0.1.0    -
+`))
+	})
+	It("fails if there is no package manifest", func() {
+		c := newCmd("", "", "")
+		c.outputDir = tmp
+		_, err := c.readVersions()
+		Expect(err).To(MatchError(ContainSubstring("no package manifest found at")))
+		Expect(exitCode(err)).To(Equal(exitInputNotFound))
+	})
+	It("does not require --version, and fails with an unknown format or conflicting options", func() {
+		c := newCmd("", "", "")
+		c.listVersions = true
+		Expect(c.validate()).To(Succeed())
+
+		c.listFormat = "yaml"
+		Expect(c.validate()).To(MatchError("--list-format must be one of: text, json"))
+
+		c.listFormat = listFormatJSON
+		c.checkGraph = true
+		Expect(c.validate()).To(MatchError(ContainSubstring("cannot be set with --list-versions")))
+	})
+})
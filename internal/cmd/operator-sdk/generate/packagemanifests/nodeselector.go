@@ -0,0 +1,58 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+// validateNodeSelector returns an error if a key or value in nodeSelector, set by --node-selector,
+// is not a valid label key or value.
+func validateNodeSelector(nodeSelector map[string]string) error {
+	for k, v := range nodeSelector {
+		if errs := validation.IsQualifiedName(k); len(errs) != 0 {
+			return fmt.Errorf("--node-selector key %q is invalid: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+			return fmt.Errorf("--node-selector %s value %q is invalid: %s", k, v, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// setNodeSelectors adds nodeSelector to the pod node selector of every collected Deployment, overriding
+// values of the same keys. Other scheduling fields, ex. affinity and tolerations, are left unchanged.
+func setNodeSelectors(col *collector.Manifests, nodeSelector map[string]string) {
+	if len(nodeSelector) == 0 {
+		return
+	}
+	for i := range col.Deployments {
+		dep := &col.Deployments[i]
+		podSpec := &dep.Spec.Template.Spec
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = make(map[string]string, len(nodeSelector))
+		}
+		for k, v := range nodeSelector {
+			log.Debugf("Setting Deployment %q node selector %s=%s", dep.GetName(), k, v)
+			podSpec.NodeSelector[k] = v
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+)
+
+var _ = Describe("Pod scheduling of the CSV install strategy", func() {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: kubernetes.io/arch
+                operator: In
+                values:
+                - amd64
+                - arm64
+      nodeSelector:
+        kubernetes.io/os: windows
+        node-role.kubernetes.io/infra: ""
+      tolerations:
+      - effect: NoSchedule
+        key: node-role.kubernetes.io/infra
+        operator: Exists
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+`
+
+	var tmp, outputDir string
+	var c packagemanifestsCmd
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-nodeselector-")
+		Expect(err).NotTo(HaveOccurred())
+		deployDir := filepath.Join(tmp, "deploy")
+		outputDir = filepath.Join(tmp, "packagemanifests")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "manager.yaml"), []byte(deployment), 0644)).To(Succeed())
+		c = packagemanifestsCmd{
+			packageName:   "memcached-operator",
+			version:       "0.1.0",
+			channelName:   "alpha",
+			inputDir:      outputDir,
+			outputDir:     outputDir,
+			deployDir:     deployDir,
+			updateObjects: true,
+			quiet:         true,
+			generator:     genpkg.NewGenerator(),
+		}
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	podSpec := func() corev1.PodSpec {
+		csvs, err := validation.ReadDirCSVs(filepath.Join(outputDir, "0.1.0"))
+		Expect(err).NotTo(HaveOccurred())
+		csv := csvs["memcached-operator.clusterserviceversion.yaml"]
+		Expect(csv).NotTo(BeNil())
+		deps := csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs
+		Expect(deps).To(HaveLen(1))
+		return deps[0].Spec.Template.Spec
+	}
+
+	It("keeps the Deployment's affinity, tolerations, and node selector", func() {
+		Expect(c.run()).To(Succeed())
+		spec := podSpec()
+		Expect(spec.Affinity).NotTo(BeNil())
+		Expect(spec.Affinity.NodeAffinity).NotTo(BeNil())
+		terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		Expect(terms).To(Equal([]corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{
+				Key:      "kubernetes.io/arch",
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{"amd64", "arm64"},
+			}},
+		}}))
+		Expect(spec.Tolerations).To(Equal([]corev1.Toleration{{
+			Key:      "node-role.kubernetes.io/infra",
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		}}))
+		Expect(spec.NodeSelector).To(Equal(map[string]string{
+			"kubernetes.io/os":              "windows",
+			"node-role.kubernetes.io/infra": "",
+		}))
+	})
+	It("adds --node-selector labels, overriding values of the same keys", func() {
+		c.nodeSelector = map[string]string{"kubernetes.io/os": "linux", "example.com/zone": "a"}
+		Expect(c.run()).To(Succeed())
+		spec := podSpec()
+		Expect(spec.NodeSelector).To(Equal(map[string]string{
+			"kubernetes.io/os":              "linux",
+			"node-role.kubernetes.io/infra": "",
+			"example.com/zone":              "a",
+		}))
+		Expect(spec.Tolerations).To(HaveLen(1))
+		Expect(spec.Affinity).NotTo(BeNil())
+	})
+	It("fails to validate invalid label keys and values", func() {
+		Expect(validateNodeSelector(map[string]string{"kubernetes.io/os": "linux"})).To(Succeed())
+		Expect(validateNodeSelector(map[string]string{"-os": "linux"})).To(
+			MatchError(ContainSubstring(`--node-selector key "-os" is invalid`)))
+		Expect(validateNodeSelector(map[string]string{"kubernetes.io/os": "linux windows"})).To(
+			MatchError(ContainSubstring(`--node-selector kubernetes.io/os value "linux windows" is invalid`)))
+	})
+})
@@ -0,0 +1,250 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
+)
+
+// readVersionFiles returns the contents of all files in the generated version directory keyed by file name.
+func (c packagemanifestsCmd) readVersionFiles() (map[string][]byte, error) {
+	dir := c.versionDir()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(infos))
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[info.Name()] = b
+	}
+	return files, nil
+}
+
+// defaultChannel is the package manifest generator's default channel.
+const defaultChannel = "alpha"
+
+// bundleChannels returns the channels of the generated version, as a comma-separated list, and the
+// default channel of its package, read from the written package manifest to be written to bundle metadata.
+func (c packagemanifestsCmd) bundleChannels() (channels, defaultChannel string, err error) {
+	pkg, err := c.readVersions()
+	if err != nil {
+		return "", "", err
+	}
+	dir, err := filepath.Rel(c.outputDir, c.versionDir())
+	if err != nil {
+		return "", "", err
+	}
+	for _, v := range pkg.Versions {
+		if v.Dir == dir && len(v.Channels) != 0 {
+			return strings.Join(v.Channels, ","), pkg.DefaultChannel, nil
+		}
+	}
+	return "", "", fmt.Errorf("version %s is not in any channel of package %s", c.version, c.packageName)
+}
+
+// bundleLabels returns the standard operator bundle labels for the generated package.
+func (c packagemanifestsCmd) bundleLabels(channels, defaultChannel string) map[string]string {
+	labels := map[string]string{
+		bundle.MediatypeLabel: bundle.RegistryV1Type,
+		bundle.ManifestsLabel: bundle.ManifestsDir,
+		bundle.MetadataLabel:  bundle.MetadataDir,
+		bundle.PackageLabel:   c.packageName,
+		bundle.ChannelsLabel:  channels,
+	}
+	if defaultChannel != "" {
+		labels[bundle.ChannelDefaultLabel] = defaultChannel
+	}
+	return labels
+}
+
+// bundleAnnotations returns the contents of the bundle annotations file for the generated package.
+func (c packagemanifestsCmd) bundleAnnotations(channels, defaultChannel string) ([]byte, error) {
+	return bundle.GenerateAnnotations(bundle.RegistryV1Type, bundle.ManifestsDir, bundle.MetadataDir,
+		c.packageName, channels, defaultChannel)
+}
+
+// writeOCILayout packages the generated version directory as an operator bundle image
+// in an OCI image layout at c.ociOut. The image's single layer contains the version
+// directory's files under manifests/ and bundle annotations under metadata/.
+func (c packagemanifestsCmd) writeOCILayout() error {
+	files, err := c.readVersionFiles()
+	if err != nil {
+		return err
+	}
+	channels, defaultChannel, err := c.bundleChannels()
+	if err != nil {
+		return err
+	}
+	annotationsFile, err := c.bundleAnnotations(channels, defaultChannel)
+	if err != nil {
+		return err
+	}
+
+	layerFiles := make(map[string][]byte, len(files)+1)
+	for name, b := range files {
+		layerFiles[path.Join(bundle.ManifestsDir, name)] = b
+	}
+	layerFiles[path.Join(bundle.MetadataDir, bundle.AnnotationsFile)] = annotationsFile
+
+	dirMode, fileMode, err := c.fileModes()
+	if err != nil {
+		return err
+	}
+	return writeOCIImageLayout(c.ociOut, layerFiles, c.bundleLabels(channels, defaultChannel), c.version, dirMode, fileMode)
+}
+
+// writeOCIImageLayout writes a single-layer image containing files to an OCI image layout in dir.
+// labels are set on both the image config and manifest, and refName names the image in the index.
+// Directories and files are created with dirMode and fileMode, or the defaults if zero.
+func writeOCIImageLayout(dir string, files map[string][]byte, labels map[string]string, refName string,
+	dirMode, fileMode os.FileMode) error {
+	blobsDir := filepath.Join(dir, "blobs", digest.Canonical.String())
+	for _, d := range []string{dir, filepath.Dir(blobsDir), blobsDir} {
+		if err := fileutil.MkdirAll(d, dirMode); err != nil {
+			return err
+		}
+	}
+	writeBlob := func(mediaType string, b []byte) (ocispecv1.Descriptor, error) {
+		dgst := digest.FromBytes(b)
+		if err := fileutil.WriteFile(filepath.Join(blobsDir, dgst.Encoded()), b, fileMode); err != nil {
+			return ocispecv1.Descriptor{}, err
+		}
+		return ocispecv1.Descriptor{MediaType: mediaType, Digest: dgst, Size: int64(len(b))}, nil
+	}
+
+	layer, diffID, err := makeLayer(files)
+	if err != nil {
+		return err
+	}
+	layerDesc, err := writeBlob(ocispecv1.MediaTypeImageLayerGzip, layer)
+	if err != nil {
+		return err
+	}
+
+	config, err := json.Marshal(ocispecv1.Image{
+		Architecture: "amd64",
+		OS:           "linux",
+		Config:       ocispecv1.ImageConfig{Labels: labels},
+		RootFS:       ocispecv1.RootFS{Type: "layers", DiffIDs: []digest.Digest{diffID}},
+	})
+	if err != nil {
+		return err
+	}
+	configDesc, err := writeBlob(ocispecv1.MediaTypeImageConfig, config)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := json.Marshal(ocispecv1.Manifest{
+		Versioned:   specs.Versioned{SchemaVersion: 2},
+		Config:      configDesc,
+		Layers:      []ocispecv1.Descriptor{layerDesc},
+		Annotations: labels,
+	})
+	if err != nil {
+		return err
+	}
+	manifestDesc, err := writeBlob(ocispecv1.MediaTypeImageManifest, manifest)
+	if err != nil {
+		return err
+	}
+	manifestDesc.Annotations = map[string]string{ocispecv1.AnnotationRefName: refName}
+
+	index, err := json.Marshal(ocispecv1.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: []ocispecv1.Descriptor{manifestDesc},
+	})
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteFile(filepath.Join(dir, "index.json"), index, fileMode); err != nil {
+		return err
+	}
+	layout, err := json.Marshal(ocispecv1.ImageLayout{Version: ocispecv1.ImageLayoutVersion})
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(filepath.Join(dir, ocispecv1.ImageLayoutFile), layout, fileMode)
+}
+
+// makeLayer returns a gzipped tar archive of files and the digest of the uncompressed archive.
+// Entries are sorted and have no timestamps so identical files produce identical layers.
+func makeLayer(files map[string][]byte) (layer []byte, diffID digest.Digest, err error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tarBuf := &bytes.Buffer{}
+	tw := tar.NewWriter(tarBuf)
+	seenDirs := make(map[string]struct{})
+	for _, name := range names {
+		if dir := path.Dir(name); dir != "." {
+			if _, seen := seenDirs[dir]; !seen {
+				hdr := &tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755}
+				if err := tw.WriteHeader(hdr); err != nil {
+					return nil, "", err
+				}
+				seenDirs[dir] = struct{}{}
+			}
+		}
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(files[name]))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, "", err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", err
+	}
+	diffID = digest.FromBytes(tarBuf.Bytes())
+
+	gzBuf := &bytes.Buffer{}
+	gw := gzip.NewWriter(gzBuf)
+	if _, err := gw.Write(tarBuf.Bytes()); err != nil {
+		return nil, "", err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, "", err
+	}
+	return gzBuf.Bytes(), diffID, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+)
+
+var _ = Describe("Writing an OCI image layout", func() {
+	var (
+		c      packagemanifestsCmd
+		tmp    string
+		ociDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		ociDir = filepath.Join(tmp, "oci")
+		c = packagemanifestsCmd{
+			packageName: "memcached-operator",
+			version:     "0.0.1",
+			outputDir:   filepath.Join(tmp, "packagemanifests"),
+			channelName: "stable",
+			ociOut:      ociDir,
+		}
+		versionDir := filepath.Join(c.outputDir, c.version)
+		Expect(os.MkdirAll(versionDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(versionDir, "memcached-operator.clusterserviceversion.yaml"),
+			[]byte("kind: ClusterServiceVersion\nmetadata:\n  name: memcached-operator.v0.0.1\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(c.outputDir, "memcached-operator.package.yaml"), []byte("packageName: memcached-operator\n"+
+			"defaultChannel: alpha\nchannels:\n- name: alpha\n  currentCSV: memcached-operator.v0.0.1\n"+
+			"- name: stable\n  currentCSV: memcached-operator.v0.0.1\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(versionDir, "cache.example.com_memcacheds.yaml"), []byte("kind: CustomResourceDefinition\n"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("writes a bundle image with manifests, metadata, and labels", func() {
+		Expect(c.writeOCILayout()).To(Succeed())
+		Expect(filepath.Join(ociDir, ocispecv1.ImageLayoutFile)).To(BeAnExistingFile())
+
+		index := ocispecv1.Index{}
+		readJSONHelper(filepath.Join(ociDir, "index.json"), &index)
+		Expect(index.Manifests).To(HaveLen(1))
+		Expect(index.Manifests[0].Annotations).To(HaveKeyWithValue(ocispecv1.AnnotationRefName, "0.0.1"))
+
+		manifest := ocispecv1.Manifest{}
+		readJSONHelper(blobPathHelper(ociDir, index.Manifests[0]), &manifest)
+		Expect(manifest.Annotations).To(HaveKeyWithValue(bundle.PackageLabel, "memcached-operator"))
+		Expect(manifest.Annotations).To(HaveKeyWithValue(bundle.ChannelsLabel, "alpha,stable"))
+		Expect(manifest.Annotations).To(HaveKeyWithValue(bundle.ChannelDefaultLabel, "alpha"))
+		Expect(manifest.Layers).To(HaveLen(1))
+
+		config := ocispecv1.Image{}
+		readJSONHelper(blobPathHelper(ociDir, manifest.Config), &config)
+		Expect(config.Config.Labels).To(Equal(manifest.Annotations))
+
+		layer, err := ioutil.ReadFile(blobPathHelper(ociDir, manifest.Layers[0]))
+		Expect(err).NotTo(HaveOccurred())
+		gr, err := gzip.NewReader(bytes.NewReader(layer))
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		tr := tar.NewReader(gr)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			names = append(names, hdr.Name)
+		}
+		Expect(names).To(Equal([]string{
+			"manifests/",
+			"manifests/cache.example.com_memcacheds.yaml",
+			"manifests/memcached-operator.clusterserviceversion.yaml",
+			"metadata/",
+			"metadata/annotations.yaml",
+		}))
+	})
+	It("fails if the version is not in any channel of the written package manifest", func() {
+		Expect(ioutil.WriteFile(filepath.Join(c.outputDir, "memcached-operator.package.yaml"), []byte("packageName: memcached-operator\n"+
+			"defaultChannel: alpha\nchannels:\n- name: alpha\n  currentCSV: memcached-operator.v0.0.0\n"), 0644)).To(Succeed())
+		Expect(c.writeOCILayout()).To(MatchError("version 0.0.1 is not in any channel of package memcached-operator"))
+	})
+	It("produces identical layouts for identical inputs", func() {
+		Expect(c.writeOCILayout()).To(Succeed())
+		first, err := ioutil.ReadFile(filepath.Join(ociDir, "index.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.writeOCILayout()).To(Succeed())
+		second, err := ioutil.ReadFile(filepath.Join(ociDir, "index.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(Equal(first))
+	})
+})
+
+func readJSONHelper(path string, v interface{}) {
+	b, err := ioutil.ReadFile(path)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	ExpectWithOffset(1, json.Unmarshal(b, v)).To(Succeed())
+}
+
+func blobPathHelper(dir string, desc ocispecv1.Descriptor) string {
+	return filepath.Join(dir, "blobs", desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+}
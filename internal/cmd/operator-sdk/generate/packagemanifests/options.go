@@ -0,0 +1,271 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metricsannotations "github.com/operator-framework/operator-sdk/internal/annotations/metrics"
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
+)
+
+// openShiftVersionRe matches an OpenShift "major.minor" version, ex. 4.8.
+var openShiftVersionRe = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// validateOpenShiftVersion returns an error if version is not of the form "X.Y".
+func validateOpenShiftVersion(version string) error {
+	if !openShiftVersionRe.MatchString(version) {
+		return fmt.Errorf("OpenShift version %q must be of the form X.Y, ex. 4.8", version)
+	}
+	return nil
+}
+
+// getProperties parses values of the form "type=value" into OLM properties and appends
+// a max OpenShift version property if maxOpenShiftVersion is set. JSON object, array,
+// and string values are decoded so structured property values are preserved; all other
+// values are kept as strings, so "4.8" is not turned into a number.
+func getProperties(values []string, maxOpenShiftVersion string) (props []gencsv.Property, err error) {
+	for _, v := range values {
+		split := strings.SplitN(v, "=", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return nil, fmt.Errorf("OLM property %q must be of the form type=value", v)
+		}
+		prop := gencsv.Property{Type: split[0]}
+		prop.Value = split[1]
+		if strings.ContainsAny(split[1][:1], `{["`) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(split[1]), &value); err != nil {
+				return nil, fmt.Errorf("OLM property %q has an invalid JSON value: %v", v, err)
+			}
+			prop.Value = value
+		}
+		props = append(props, prop)
+	}
+	if maxOpenShiftVersion != "" {
+		if err := validateOpenShiftVersion(maxOpenShiftVersion); err != nil {
+			return nil, err
+		}
+		props = append(props, gencsv.Property{
+			Type:  gencsv.MaxOpenShiftVersionProperty,
+			Value: maxOpenShiftVersion,
+		})
+	}
+	return props, nil
+}
+
+// getCSVAnnotations returns the SDK metrics annotations for layout merged with extra. Values in extra
+// take precedence, so a downstream builder can replace the SDK's builder annotation with its own.
+// An error is returned if a key in extra is not a valid annotation key.
+func getCSVAnnotations(layout string, extra map[string]string) (map[string]string, error) {
+	annotations := metricsannotations.MakeBundleObjectAnnotations(layout)
+	for k, v := range extra {
+		if errs := validation.IsQualifiedName(k); len(errs) != 0 {
+			return nil, fmt.Errorf("metrics annotation key %q is invalid: %s", k, strings.Join(errs, "; "))
+		}
+		annotations[k] = v
+	}
+	return annotations, nil
+}
+
+const (
+	// archLabelPrefix and osLabelPrefix prefix the CSV labels OLM uses to select nodes
+	// an operator's Deployments can run on.
+	archLabelPrefix = "operatorframework.io/arch."
+	osLabelPrefix   = "operatorframework.io/os."
+	// platformLabelValue is the value of a supported architecture or OS label.
+	platformLabelValue = "supported"
+)
+
+var (
+	// knownArchs are architectures operator images are commonly built for.
+	knownArchs = map[string]struct{}{
+		"386": {}, "amd64": {}, "arm": {}, "arm64": {}, "ppc64le": {}, "s390x": {},
+	}
+	// knownOSes are operating systems operator images are commonly built for.
+	knownOSes = map[string]struct{}{
+		"linux": {}, "windows": {}, "darwin": {},
+	}
+)
+
+// getPlatformLabels returns OLM's supported architecture and OS CSV labels for archs and oses,
+// or an error if either contains an unknown value.
+func getPlatformLabels(archs, oses []string) (map[string]string, error) {
+	labels := make(map[string]string, len(archs)+len(oses))
+	for _, arch := range archs {
+		if _, known := knownArchs[arch]; !known {
+			return nil, fmt.Errorf("unknown architecture %q, must be one of: %s", arch, joinKeys(knownArchs))
+		}
+		labels[archLabelPrefix+arch] = platformLabelValue
+	}
+	for _, opSys := range oses {
+		if _, known := knownOSes[opSys]; !known {
+			return nil, fmt.Errorf("unknown OS %q, must be one of: %s", opSys, joinKeys(knownOSes))
+		}
+		labels[osLabelPrefix+opSys] = platformLabelValue
+	}
+	return labels, nil
+}
+
+// joinKeys returns the sorted keys of m joined by ", ".
+func joinKeys(m map[string]struct{}) string {
+	return strings.Join(sortedKeys(m), ", ")
+}
+
+// sortedKeys returns the keys of set in ascending order.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// registryFormat is a version of the package manifests format, which gates optional CSV fields
+// so packages can be published to catalogs built by operator-registry releases that do not understand them.
+type registryFormat struct {
+	// cleanup is true if the format supports spec.cleanup.
+	cleanup bool
+	// properties is true if the format supports the properties annotation.
+	properties bool
+}
+
+// defaultRegistryFormat is the latest format, which supports all fields.
+const defaultRegistryFormat = "v2"
+
+var registryFormats = map[string]registryFormat{
+	// v1 is understood by all operator-registry releases that support package manifests.
+	"v1": {},
+	// v2 adds spec.cleanup and the properties annotation.
+	"v2": {cleanup: true, properties: true},
+}
+
+// getRegistryFormat returns the format named name, or the default format if name is empty.
+func getRegistryFormat(name string) (registryFormat, error) {
+	if name == "" {
+		name = defaultRegistryFormat
+	}
+	format, known := registryFormats[name]
+	if !known {
+		names := make(map[string]struct{}, len(registryFormats))
+		for k := range registryFormats {
+			names[k] = struct{}{}
+		}
+		return registryFormat{}, fmt.Errorf("unknown registry format %q, must be one of: %s", name, joinKeys(names))
+	}
+	return format, nil
+}
+
+// defaultCSVPatchType is the default type of --csv-patch patches that are objects.
+const defaultCSVPatchType = "strategic"
+
+var csvPatchTypes = map[string]types.PatchType{
+	"strategic": types.StrategicMergePatchType,
+	"merge":     types.MergePatchType,
+}
+
+// getCSVPatchType returns the patch type named name, or the default type if name is empty.
+func getCSVPatchType(name string) (types.PatchType, error) {
+	if name == "" {
+		name = defaultCSVPatchType
+	}
+	patchType, known := csvPatchTypes[name]
+	if !known {
+		names := make(map[string]struct{}, len(csvPatchTypes))
+		for k := range csvPatchTypes {
+			names[k] = struct{}{}
+		}
+		return "", fmt.Errorf("unknown --csv-patch-type %q, must be one of: %s", name, joinKeys(names))
+	}
+	return patchType, nil
+}
+
+// stripUnsupported removes fields f does not support from obj if it is a CSV, warning about each.
+// These fields may come from a base, so are removed instead of failing generation.
+func (f registryFormat) stripUnsupported(obj client.Object) error {
+	csv, isCSV := obj.(*operatorsv1alpha1.ClusterServiceVersion)
+	if !isCSV {
+		return nil
+	}
+	if !f.cleanup && csv.Spec.Cleanup.Enabled {
+		log.Warnf("Removing spec.cleanup from ClusterServiceVersion %s: not supported by the registry format", csv.GetName())
+		csv.Spec.Cleanup.Enabled = false
+	}
+	if annotations := csv.GetAnnotations(); !f.properties && annotations[gencsv.PropertiesAnnotation] != "" {
+		log.Warnf("Removing the %q annotation from ClusterServiceVersion %s: not supported by the registry format",
+			gencsv.PropertiesAnnotation, csv.GetName())
+		delete(annotations, gencsv.PropertiesAnnotation)
+	}
+	return nil
+}
+
+// validateFileNaming returns an error if naming is not empty or a known file naming scheme.
+func validateFileNaming(naming string) error {
+	if naming == "" {
+		return nil
+	}
+	names := make([]string, len(genutil.FileNamings))
+	for i, n := range genutil.FileNamings {
+		if genutil.FileNaming(naming) == n {
+			return nil
+		}
+		names[i] = string(n)
+	}
+	return fmt.Errorf("--file-naming %q must be one of: %s", naming, strings.Join(names, ", "))
+}
+
+// parseFileMode parses value, an octal permission mode like 0640, for the flag named name.
+// An empty value returns a zero mode, which means the default mode is used.
+func parseFileMode(name, value string) (os.FileMode, error) {
+	if value == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return 0, fmt.Errorf("--%s %q must be an octal permission mode between 0001 and 0777, ex. 0640", name, value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// fileModes returns the modes set by --dir-mode and --file-mode.
+func (c packagemanifestsCmd) fileModes() (dirMode, fileMode os.FileMode, err error) {
+	if dirMode, err = parseFileMode("dir-mode", c.dirMode); err != nil {
+		return 0, 0, err
+	}
+	if fileMode, err = parseFileMode("file-mode", c.fileMode); err != nil {
+		return 0, 0, err
+	}
+	return dirMode, fileMode, nil
+}
+
+// yamlFormat returns the YAML format of written manifests set by --yaml-indent, --yaml-sort-keys,
+// and --prune-empty.
+func (c packagemanifestsCmd) yamlFormat() yamlutil.Options {
+	return yamlutil.Options{Indent: c.yamlIndent, SortKeys: c.yamlSortKeys, PruneEmpty: c.pruneEmpty}
+}
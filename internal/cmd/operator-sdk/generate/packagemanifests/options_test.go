@@ -0,0 +1,145 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	metricsannotations "github.com/operator-framework/operator-sdk/internal/annotations/metrics"
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
+)
+
+var _ = Describe("Parsing option values", func() {
+	Describe("getPlatformLabels", func() {
+		It("returns a supported label for each architecture and OS", func() {
+			labels, err := getPlatformLabels([]string{"amd64", "arm64"}, []string{"linux"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labels).To(Equal(map[string]string{
+				"operatorframework.io/arch.amd64": "supported",
+				"operatorframework.io/arch.arm64": "supported",
+				"operatorframework.io/os.linux":   "supported",
+			}))
+		})
+		It("returns no labels if none are set", func() {
+			labels, err := getPlatformLabels(nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labels).To(BeEmpty())
+		})
+		It("fails on an unknown architecture", func() {
+			_, err := getPlatformLabels([]string{"amd46"}, nil)
+			Expect(err).To(MatchError(ContainSubstring(`unknown architecture "amd46"`)))
+		})
+		It("fails on an unknown OS", func() {
+			_, err := getPlatformLabels(nil, []string{"linx"})
+			Expect(err).To(MatchError(ContainSubstring(`unknown OS "linx"`)))
+		})
+	})
+
+	Describe("getCSVAnnotations", func() {
+		It("merges extra annotations over the SDK's metrics annotations", func() {
+			annotations, err := getCSVAnnotations("go.kubebuilder.io/v3", map[string]string{
+				metricsannotations.BuilderObjectAnnotation: "downstream-builder-1.2",
+				"example.com/build-id":                     "1234",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(annotations).To(Equal(map[string]string{
+				metricsannotations.BuilderObjectAnnotation: "downstream-builder-1.2",
+				metricsannotations.LayoutObjectAnnotation:  "go.kubebuilder.io/v3",
+				"example.com/build-id":                     "1234",
+			}))
+		})
+		It("fails on an invalid key", func() {
+			_, err := getCSVAnnotations("", map[string]string{"example.com/build id": "1234"})
+			Expect(err).To(MatchError(ContainSubstring(`metrics annotation key "example.com/build id" is invalid`)))
+		})
+	})
+
+	Describe("parseFileMode", func() {
+		It("parses octal modes", func() {
+			Expect(parseFileMode("file-mode", "0640")).To(Equal(os.FileMode(0640)))
+			Expect(parseFileMode("dir-mode", "750")).To(Equal(os.FileMode(0750)))
+		})
+		It("returns a zero mode for an empty value", func() {
+			Expect(parseFileMode("file-mode", "")).To(Equal(os.FileMode(0)))
+		})
+		It("fails on invalid modes", func() {
+			for _, value := range []string{"0888", "rw-r-----", "01777", "0"} {
+				_, err := parseFileMode("file-mode", value)
+				Expect(err).To(MatchError(ContainSubstring("--file-mode %q must be an octal permission mode", value)))
+			}
+		})
+	})
+
+	Describe("validateFileNaming", func() {
+		It("accepts known schemes", func() {
+			for _, naming := range []string{"", "gvk", "kind-name", "name"} {
+				Expect(validateFileNaming(naming)).To(Succeed())
+			}
+		})
+		It("fails on an unknown scheme", func() {
+			Expect(validateFileNaming("kind")).To(MatchError(`--file-naming "kind" must be one of: gvk, kind-name, name`))
+		})
+	})
+
+	Describe("getRegistryFormat", func() {
+		It("returns the default format if unset", func() {
+			Expect(getRegistryFormat("")).To(Equal(registryFormats[defaultRegistryFormat]))
+			Expect(getRegistryFormat("v1")).To(Equal(registryFormat{}))
+		})
+		It("fails on an unknown format", func() {
+			_, err := getRegistryFormat("v0")
+			Expect(err).To(MatchError(`unknown registry format "v0", must be one of: v1, v2`))
+		})
+	})
+
+	Describe("getCSVPatchType", func() {
+		It("returns the default type if unset", func() {
+			Expect(getCSVPatchType("")).To(Equal(types.StrategicMergePatchType))
+			Expect(getCSVPatchType("merge")).To(Equal(types.MergePatchType))
+		})
+		It("fails on an unknown type", func() {
+			_, err := getCSVPatchType("json")
+			Expect(err).To(MatchError(`unknown --csv-patch-type "json", must be one of: merge, strategic`))
+		})
+	})
+
+	Describe("stripUnsupported", func() {
+		var csv *operatorsv1alpha1.ClusterServiceVersion
+		BeforeEach(func() {
+			csv = &operatorsv1alpha1.ClusterServiceVersion{ObjectMeta: metav1.ObjectMeta{
+				Name:        "memcached-operator.v0.0.1",
+				Annotations: map[string]string{gencsv.PropertiesAnnotation: "[]", "capabilities": "Basic Install"},
+			}}
+			csv.Spec.Cleanup.Enabled = true
+		})
+
+		It("removes cleanup and properties for format v1", func() {
+			Expect(registryFormats["v1"].stripUnsupported(csv)).To(Succeed())
+			Expect(csv.Spec.Cleanup.Enabled).To(BeFalse())
+			Expect(csv.GetAnnotations()).To(Equal(map[string]string{"capabilities": "Basic Install"}))
+		})
+		It("keeps all fields for format v2", func() {
+			Expect(registryFormats["v2"].stripUnsupported(csv)).To(Succeed())
+			Expect(csv.Spec.Cleanup.Enabled).To(BeTrue())
+			Expect(csv.GetAnnotations()).To(HaveKey(gencsv.PropertiesAnnotation))
+		})
+	})
+})
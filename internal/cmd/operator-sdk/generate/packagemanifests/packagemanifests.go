@@ -15,17 +15,30 @@
 package packagemanifests
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
-	metricsannotations "github.com/operator-framework/operator-sdk/internal/annotations/metrics"
 	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
 	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
 	"github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion/bases"
 	"github.com/operator-framework/operator-sdk/internal/generate/collector"
 	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
+	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
 )
 
 const (
@@ -44,11 +57,40 @@ will be applied to an empty CSV.
 
 There are two ways to pass the to-be-packaged set of manifests to this command: stdin via a Unix pipe,
 or in a directory using '--input-dir'. See command help for more information on these modes.
+If both stdin and a directory contain an object with the same kind and name, the directory's object
+overrides stdin's unless '--input-precedence stdin' is set.
 Passing a directory is useful for running 'generate packagemanifests' outside of a project or within a project
 that does not use kustomize and/or contains cluster-ready manifests on disk.
 
 Set '--version' to supply a semantic version for your new package.
 
+Set '--deprecate-version' to mark a prior version as deprecated. The CSV in that version's directory
+gets the annotation 'olm.deprecated', whose value is the deprecation message, ex.
+'version 0.1.0 of package memcached-operator is deprecated, upgrade to version 0.2.0'. No other field
+of the CSV changes.
+
+Set '--compare-with' to a directory, or a git reference of the form repo@ref[:path], containing the
+published package manifests to check the generated version is a compatible upgrade from the head of
+'--channel', or of the default channel. Removing a CRD not listed as required, no longer serving a CRD
+version, and narrowing a served version's schema, ex. removing a property or requiring a new one, are
+breaking changes that fail generation unless '--allow-breaking' is set. Added or removed permissions
+are warned about.
+
+Set '--batch' to a YAML file listing operators under 'operators', each a mapping of flag names to
+values like '--config', to generate all of them as separate runs would. Flags set on the command
+line apply to every operator that does not set them. Set '--batch-parallelism' to generate up to that
+many operators at once; operators with the same '--output-dir' are still generated one at a time.
+A summary of which operators succeeded is printed at the end, and the command exits with the code
+of the first operator listed that failed.
+
+The command exits with a code by class of failure, so scripts can branch on it:
+  1: unexpected internal error
+  2: invalid arguments or flags
+  3: an input file or directory does not exist
+  4: inputs or generated manifests failed a check, including '--strict', '--self-check',
+     '--check-graph', '--validate', and '--compare-with'
+  5: error reading or writing files
+
 More information on the package manifests format:
 https://github.com/operator-framework/operator-registry/#manifest-format
 `
@@ -66,6 +108,16 @@ https://github.com/operator-framework/operator-registry/#manifest-format
   Generating package manifests version 0.0.1
   ...
 
+  # Or render config/manifests in-process instead of piping from a kustomize binary:
+  $ operator-sdk generate packagemanifests --render --version 0.0.1
+  Generating package manifests version 0.0.1
+  ...
+
+  # Or render an operator's Helm chart with a values file:
+  $ operator-sdk generate packagemanifests --helm-chart charts/memcached-operator --helm-values values.yaml --version 0.0.1
+  Generating package manifests version 0.0.1
+  ...
+
   # If running outside of a project, make sure cluster-ready manifests are available on disk:
   $ tree deploy/
   deploy/
@@ -100,8 +152,22 @@ func (c *packagemanifestsCmd) setDefaults() (err error) {
 	if c.packageName, c.layout, err = genutil.GetPackageNameAndLayout(c.packageName); err != nil {
 		return err
 	}
+	if c.renamePackage != "" {
+		c.basePackageName, c.packageName = c.packageName, c.renamePackage
+	}
+
+	if c.versionFile != "" {
+		if c.version != "" {
+			return errors.New("--version and --version-file cannot both be set")
+		}
+		if len(c.versions) == 0 {
+			if c.version, err = readVersionFile(c.versionFile); err != nil {
+				return err
+			}
+		}
+	}
 
-	if !c.stdout && c.outputDir == "" {
+	if !c.stdout && c.singleFile == "" && c.outputDir == "" {
 		c.outputDir = defaultRootDir
 	}
 
@@ -110,15 +176,68 @@ func (c *packagemanifestsCmd) setDefaults() (err error) {
 	return nil
 }
 
+// validatePackageName returns an error naming the rule violated if the package name, resolved from
+// --package or the PROJECT file, or set by --rename-package, is not a valid OLM package name, which
+// the registry would reject: a DNS-1123 label of at most 63 lowercase alphanumeric characters or '-'
+// that starts and ends with an alphanumeric character.
+func (c packagemanifestsCmd) validatePackageName() error {
+	if errs := validation.IsDNS1123Label(c.packageName); len(errs) != 0 {
+		name := "package name"
+		if c.basePackageName != "" {
+			name = "--rename-package"
+		}
+		return fmt.Errorf("%s %q is invalid: %s", name, c.packageName, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
 // validate validates c for package manifests generation.
 func (c packagemanifestsCmd) validate() error {
 
-	if c.version != "" {
+	if c.listVersions {
+		if c.listFormat != listFormatText && c.listFormat != listFormatJSON {
+			return fmt.Errorf("--list-format must be one of: %s, %s", listFormatText, listFormatJSON)
+		}
+		if c.stdout || c.singleFile != "" || c.checkGraph || c.listKinds || c.validateOnly || c.selfCheck {
+			return errors.New("--stdout, --single-file, --check-graph, --list-kinds, --validate, and --self-check " +
+				"cannot be set with --list-versions")
+		}
+		return nil
+	}
+
+	if c.checkGraph {
+		if c.stdout {
+			return errors.New("--stdout cannot be set with --check-graph")
+		}
+		if c.validateOnly {
+			return errors.New("--validate cannot be set with --check-graph")
+		}
+		return nil
+	}
+
+	// Existing packages are only read by --list-versions and --check-graph, so their names are not checked.
+	if err := c.validatePackageName(); err != nil {
+		return err
+	}
+
+	if len(c.versions) != 0 {
+		if err := c.validateVersions(); err != nil {
+			return err
+		}
+	} else if c.version != "" {
 		if err := genutil.ValidateVersion(c.version); err != nil {
 			return err
 		}
-	} else {
-		return errors.New("--version must be set")
+	} else if !c.listKinds {
+		return errors.New("--version or --versions must be set")
+	}
+	if c.listKinds && (len(c.versions) != 0 || c.stdout || c.singleFile != "" || c.selfCheck) {
+		return errors.New("--versions, --stdout, --single-file, and --self-check cannot be set with --list-kinds")
+	}
+
+	if c.validateOnly && (len(c.versions) != 0 || c.stdout || c.singleFile != "" || c.selfCheck || c.listKinds || c.csvOnly) {
+		return errors.New("--versions, --stdout, --single-file, --self-check, --list-kinds, and --csv-only cannot be " +
+			"set with --validate")
 	}
 
 	if c.fromVersion != "" {
@@ -126,17 +245,90 @@ func (c packagemanifestsCmd) validate() error {
 			return err
 		}
 	}
+	if err := validateContainerImages(c.images); err != nil {
+		return err
+	}
+	if err := validateNodeSelector(c.nodeSelector); err != nil {
+		return err
+	}
+	if err := validateImagePullSecrets(c.imagePullSecrets); err != nil {
+		return err
+	}
+	if err := c.validateDeprecateVersions(); err != nil {
+		return err
+	}
+	if err := c.validateCompareWith(); err != nil {
+		return err
+	}
+	if c.replacesCSVName != "" {
+		if err := genutil.ValidateCSVName(c.replacesCSVName); err != nil {
+			return fmt.Errorf("invalid --replaces-csv-name: %v", err)
+		}
+	}
+	if c.csvVersion != "" {
+		if len(c.versions) != 0 {
+			return errors.New("--csv-version cannot be set with --versions")
+		}
+		if err := genutil.ValidateVersion(c.csvVersion); err != nil {
+			return err
+		}
+	}
 
 	if c.inputDir == "" {
 		return errors.New("--input-dir must be set")
 	}
 
-	if !genutil.IsPipeReader() {
+	if c.inputGit != "" {
+		if _, err := parseGitInput(c.inputGit); err != nil {
+			return err
+		}
+	}
+	for _, inputURL := range c.inputURLs {
+		if err := validateInputURL(inputURL); err != nil {
+			return err
+		}
+	}
+	if err := validateInputPrecedence(c.inputPrecedence); err != nil {
+		return err
+	}
+	if err := validateInputFormat(c.inputFormat); err != nil {
+		return err
+	}
+	if c.fromDir != "" {
+		if c.deployDir != "" || c.crdsDir != "" {
+			return errors.New("--deploy-dir and --crds-dir cannot be set with --from-dir")
+		}
+		if _, err := resolveFromDir(c.fromDir); err != nil {
+			return err
+		}
+	}
+	if c.render {
+		if c.deployDir != "" || c.crdsDir != "" || c.fromDir != "" {
+			return errors.New("--deploy-dir, --crds-dir, and --from-dir cannot be set with --render")
+		}
+		if c.kustomizeDir == "" {
+			return errors.New("--kustomize-dir must be set if --render is set")
+		}
+	}
+	if c.helmChart != "" {
+		if c.deployDir != "" || c.crdsDir != "" || c.fromDir != "" || c.render {
+			return errors.New("--deploy-dir, --crds-dir, --from-dir, and --render cannot be set with --helm-chart")
+		}
+		if !isDir(c.helmChart) {
+			return fmt.Errorf("--helm-chart %s is not a directory", c.helmChart)
+		}
+	} else if len(c.helmValues) != 0 {
+		return errors.New("--helm-values cannot be set without --helm-chart")
+	}
+	if len(c.versions) == 0 && !c.render && c.helmChart == "" && !c.ignoreMissingDirs && c.fromDir == "" && c.inputGit == "" &&
+		len(c.inputURLs) == 0 && !genutil.IsPipeReader() {
 		if c.deployDir == "" {
-			return errors.New("--deploy-dir must be set if not reading from stdin")
+			return errors.New("--deploy-dir must be set if not reading from stdin, --render, --helm-chart, --from-dir, " +
+				"--input-git, or --input-url")
 		}
 		if c.crdsDir == "" {
-			return errors.New("--crds-dir must be set if not reading from stdin")
+			return errors.New("--crds-dir must be set if not reading from stdin, --render, --helm-chart, --from-dir, " +
+				"--input-git, or --input-url")
 		}
 	}
 
@@ -144,92 +336,972 @@ func (c packagemanifestsCmd) validate() error {
 		if c.outputDir != "" {
 			return errors.New("--output-dir cannot be set if writing to stdout")
 		}
+		if c.ociOut != "" {
+			return errors.New("--oci-out cannot be set if writing to stdout")
+		}
+		if c.genDockerfile {
+			return errors.New("--gen-dockerfile cannot be set if writing to stdout")
+		}
+		if c.skipIfUnchanged {
+			return errors.New("--skip-if-unchanged cannot be set if writing to stdout")
+		}
+	}
+
+	if c.singleFile != "" {
+		if c.stdout || c.outputDir != "" {
+			return errors.New("--stdout and --output-dir cannot be set with --single-file")
+		}
+		if len(c.versions) != 0 || c.selfCheck {
+			return errors.New("--versions and --self-check cannot be set with --single-file")
+		}
+		if c.genDockerfile || c.skipIfUnchanged || c.channelConfig != "" {
+			return errors.New("--gen-dockerfile, --skip-if-unchanged, and --channel-config cannot be set with " +
+				"--single-file, since no package directory is written")
+		}
+	}
+
+	if c.csvOnly {
+		if len(c.versions) != 0 || c.singleFile != "" || c.selfCheck {
+			return errors.New("--versions, --single-file, and --self-check cannot be set with --csv-only")
+		}
+		if c.ociOut != "" || c.genDockerfile || c.skipIfUnchanged {
+			return errors.New("--oci-out, --gen-dockerfile, and --skip-if-unchanged cannot be set with --csv-only, " +
+				"since they write files other than the CSV")
+		}
+	}
+
+	if err := c.validateDockerfileDir(); err != nil {
+		return err
+	}
+
+	if c.checkPackageDir {
+		if c.stdout || c.singleFile != "" {
+			return errors.New("--check-package-dir cannot be set with --stdout or --single-file, " +
+				"since no package directory is written")
+		}
+		if err := c.validatePackageDir(); err != nil {
+			return err
+		}
+	}
+
+	if c.selfCheck {
+		if c.stdout {
+			return errors.New("--stdout cannot be set with --self-check")
+		}
+		if genutil.IsPipeReader() {
+			return errors.New("--self-check cannot be set if reading from stdin, which can only be read once")
+		}
+	}
+	// --strict generates in a scratch directory before generating for real, so reads inputs twice.
+	if c.strict && genutil.IsPipeReader() {
+		return errors.New("--strict cannot be set if reading from stdin, which can only be read once")
+	}
+
+	if c.sbomFile != "" {
+		if c.stdout || c.singleFile != "" {
+			return errors.New("--sbom cannot be set with --stdout or --single-file, since no package directory is written")
+		}
+		if len(c.versions) != 0 {
+			return errors.New("--sbom cannot be set with --versions")
+		}
+	}
+	if c.preserveComments && (c.stdout || c.singleFile != "") {
+		return errors.New("--preserve-comments cannot be set with --stdout or --single-file, " +
+			"since objects are not written to their own files")
+	}
+
+	if c.overwriteBase && !c.writeBase {
+		return errors.New("--overwrite-base can only be set if --write-base is set")
+	}
+	if c.writeBase && !c.overwriteBase && genutil.IsExist(c.baseCSVPath()) {
+		return fmt.Errorf("--write-base cannot overwrite existing base %s unless --overwrite-base is set", c.baseCSVPath())
+	}
+
+	if c.baseOverlay != "" && !isDir(c.baseOverlay) {
+		return fmt.Errorf("--base-overlay %s must be an existing directory", c.baseOverlay)
+	}
+
+	if c.maxCSVSize < 0 || c.maxObjectSize < 0 {
+		return errors.New("--max-csv-size and --max-object-size must not be negative")
+	}
+
+	if c.injectWatchNamespace && c.noWatchNamespaceEnv {
+		return errors.New("--inject-watch-namespace and --no-watch-namespace-env cannot both be set")
+	}
+
+	if c.pinDigestsOffline && !c.pinDigests {
+		return errors.New("--pin-digests-offline can only be set if --pin-digests is set")
+	}
+
+	if c.grantSCCUsers && !c.includeSCC {
+		return errors.New("--grant-scc-users can only be set if --include-scc is set")
+	}
+
+	if c.managerDeployment != "" && c.managerDeploymentSelector != "" {
+		return errors.New("--manager-deployment and --manager-deployment-selector cannot both be set")
+	}
+	if _, err := c.managerSelector(); err != nil {
+		return err
+	}
+	if c.selectorIncludeCRDs && c.selector == "" {
+		return errors.New("--selector-include-crds can only be set if --selector is set")
+	}
+	if _, err := c.objectSelector(); err != nil {
+		return err
 	}
 
 	if c.isDefaultChannel && c.channelName == "" {
 		return fmt.Errorf("--default-channel can only be set if --channel is set")
 	}
+	if c.channelConfig != "" {
+		if c.channelName != "" {
+			return errors.New("--channel and --default-channel cannot be set with --channel-config")
+		}
+		if _, err := genpkg.ReadChannelConfig(c.channelConfig); err != nil {
+			return err
+		}
+	}
+
+	if err := c.validateChannelDirs(); err != nil {
+		return err
+	}
+
+	if _, _, err := c.fileModes(); err != nil {
+		return err
+	}
+	if err := validateFileNaming(c.fileNaming); err != nil {
+		return err
+	}
+
+	if c.maxLineWidth < 0 {
+		return fmt.Errorf("--csv-max-line-width must not be negative")
+	}
+	if err := c.yamlFormat().Validate(); err != nil {
+		return fmt.Errorf("invalid --yaml-indent: %v", err)
+	}
+
+	if _, err := c.readCSVPatches(); err != nil {
+		return err
+	}
+	if _, err := c.readDependencies(); err != nil {
+		return err
+	}
+
+	if c.packageTemplate != "" {
+		if _, err := genpkg.ParseTemplate(c.packageTemplate); err != nil {
+			return err
+		}
+	}
+
+	if _, err := getProperties(c.olmProperties, c.maxOpenShiftVersion); err != nil {
+		return err
+	}
+
+	if _, err := getCSVPatchType(c.csvPatchType); err != nil {
+		return err
+	}
+
+	format, err := getRegistryFormat(c.registryFormat)
+	if err != nil {
+		return err
+	}
+	if !format.cleanup && c.cleanup != nil && *c.cleanup {
+		return fmt.Errorf("--cleanup-enabled cannot be set with --registry-format %s", c.registryFormat)
+	}
+	if !format.properties && (len(c.olmProperties) != 0 || c.maxOpenShiftVersion != "") {
+		return fmt.Errorf("--olm-property and --max-openshift-version cannot be set with --registry-format %s",
+			c.registryFormat)
+	}
+
+	if _, err := getPlatformLabels(c.archs, c.oses); err != nil {
+		return err
+	}
+
+	if _, err := getCSVAnnotations(c.layout, c.metricsAnnotations); err != nil {
+		return err
+	}
+	for k := range c.csvAnnotations {
+		if errs := validation.IsQualifiedName(k); len(errs) != 0 {
+			return fmt.Errorf("--csv-annotation key %q is invalid: %s", k, strings.Join(errs, "; "))
+		}
+	}
 
 	return nil
 }
 
-// run generates package manifests.
+// run generates package manifests. Only the package manifest, whose channel is merged into the existing
+// one, and the current version's directory are written, so other versions' directories in the output
+// directory are left unchanged when a version is added.
 func (c packagemanifestsCmd) run() error {
 
 	c.println("Generating package manifests version", c.version)
+	if c.csvOnly {
+		c.warnCSVOnlyIgnored()
+	}
 
-	if err := c.generatePackageManifest(); err != nil {
+	// The published version is compared with before anything is written, which may change it.
+	if c.compareWith != "" {
+		if err := c.checkCompatibility(); err != nil {
+			return err
+		}
+	}
+
+	col, extraDeps, err := c.collectInputs()
+	if err != nil {
 		return err
 	}
 
-	col := &collector.Manifests{}
-	if genutil.IsPipeReader() {
-		if err := col.UpdateFromReader(os.Stdin); err != nil {
+	manifestsHash := ""
+	if c.skipIfUnchanged {
+		if manifestsHash, err = c.manifestsHash(col); err != nil {
+			return err
+		}
+		hash, err := c.inputsHash(manifestsHash)
+		if err != nil {
 			return err
 		}
+		if c.inputsUnchanged(hash) {
+			c.println("Inputs of package manifests version", c.version, "are unchanged, skipping generation")
+			return nil
+		}
+	}
+
+	dirMode, fileMode, err := c.fileModes()
+	if err != nil {
+		return err
+	}
+
+	var resolve imageResolver
+	if c.pinDigests {
+		resolve = newImageResolver(context.Background(), c.pinDigestsOffline)
+	}
+	var generatedCSV *operatorsv1alpha1.ClusterServiceVersion
+	var captureBase gencsv.ObjectTransform
+	if c.writeBase {
+		captureBase = func(obj client.Object) error {
+			generatedCSV = obj.(*operatorsv1alpha1.ClusterServiceVersion).DeepCopy()
+			return nil
+		}
+	}
+	csvGen, opts, err := c.csvGenerator(col, resolve, captureBase)
+	if err != nil {
+		return err
 	}
-	if c.deployDir != "" {
-		if err := col.UpdateFromDirs(c.deployDir, c.crdsDir); err != nil {
+	opts = append(opts, gencsv.WithFileModes(dirMode, fileMode))
+	schema, err := c.readSchema()
+	if err != nil {
+		return err
+	}
+	stdout := genutil.NewMultiManifestWriter(os.Stdout)
+	// With --schema, the CSV is only written once it and all other objects are validated.
+	var csvBuf *bytes.Buffer
+	switch {
+	case schema != nil:
+		csvBuf = &bytes.Buffer{}
+		opts = append(opts, gencsv.WithWriter(csvBuf))
+	case c.stdout:
+		opts = append(opts, gencsv.WithWriter(stdout))
+	default:
+		opts = append(opts, gencsv.WithPackageWriter(c.versionsDir()))
+	}
+	writeCSV := func() error {
+		if csvBuf == nil {
+			return nil
+		}
+		if c.stdout {
+			_, err := stdout.Write(csvBuf.Bytes())
+			return err
+		}
+		if err := fileutil.MkdirAll(c.versionDir(), dirMode); err != nil {
+			return withExitCode(exitIO, err)
+		}
+		path := filepath.Join(c.versionDir(), gencsv.FileName(c.packageName))
+		return withExitCode(exitIO, fileutil.WriteFile(path, csvBuf.Bytes(), fileMode))
+	}
+	// The CSV is validated and inventoried as written, so it is captured after all other transforms have been applied.
+	var writtenCSV client.Object
+	if schema != nil || c.sbomFile != "" {
+		opts = append(opts, gencsv.WithObjectTransform(func(obj client.Object) error {
+			writtenCSV = obj
+			return nil
+		}))
+	}
+	if err := csvGen.Generate(opts...); err != nil {
+		if errors.Is(err, gencsv.ErrEmptyInstall) {
+			err = fmt.Errorf("%w: check that --deploy-dir contains the operator's Deployment, or set --allow-empty-install", err)
+		}
+		return fmt.Errorf("error generating ClusterServiceVersion: %w", err)
+	}
+	if generatedCSV != nil {
+		if err := c.writeBaseCSV(generatedCSV); err != nil {
 			return err
 		}
 	}
 
-	// If no CSV was initially read, a kustomize base can be used at the default base path.
-	// Only read from kustomizeDir if a base exists so users can still generate a barebones CSV.
-	baseCSVPath := filepath.Join(c.kustomizeDir, "bases", c.packageName+".clusterserviceversion.yaml")
-	if noCSVStdin := len(col.ClusterServiceVersions) == 0; noCSVStdin && genutil.IsExist(baseCSVPath) {
-		base, err := bases.ClusterServiceVersion{BasePath: baseCSVPath}.GetBase()
+	if c.crdsExternal {
+		// CRDs are shipped separately, so only the CSV references them.
+		col.V1CustomResourceDefinitions = nil
+		col.V1beta1CustomResourceDefinitions = nil
+	}
+
+	var schemaObjs []schemaObject
+	if schema != nil {
+		schemaObjs = append(schemaObjs, schemaObject{path: c.schemaPath(writtenCSV, gencsv.FileName(c.packageName)), obj: writtenCSV})
+	}
+
+	if c.csvOnly {
+		if schema != nil {
+			if err := validateSchema(schema, schemaObjs); err != nil {
+				return withExitCode(exitValidation, err)
+			}
+		}
+		if err := writeCSV(); err != nil {
+			return err
+		}
+		if c.sbomFile != "" {
+			if err := c.writeSBOM(writtenCSV.(*operatorsv1alpha1.ClusterServiceVersion), nil); err != nil {
+				return withExitCode(exitIO, fmt.Errorf("error writing SBOM: %v", err))
+			}
+		}
+		c.println("ClusterServiceVersion generated successfully in", c.versionDir())
+		return nil
+	}
+
+	// Objects written alongside the CSV, which are all checked before any is written.
+	var objs []client.Object
+	if c.updateObjects {
+		extras := c.extraObjects(col, extraDeps)
+		objs, extraDeps = extras.written, extras.deployments
+		for _, dep := range extras.skippedDeployments {
+			log.Warnf("Skipping Deployment %q: kind is not supported in bundles; select it with "+
+				"--manager-deployment-selector to add it to the install strategy, or set --include-kinds=Deployment "+
+				"to write it anyway", dep.GetName())
+		}
+		for i := range extras.deployments {
+			log.Debugf("Writing Deployment %q as an extra object", extras.deployments[i].GetName())
+			extras.deployments[i].SetNamespace("")
+		}
+		// Objects written alongside the CSV have their images pinned like the CSV's.
+		if resolve != nil {
+			if err := genutil.TransformObjects(objs, pinDigests(resolve)); err != nil {
+				return fmt.Errorf("error pinning image digests: %v", err)
+			}
+		}
+		var unqualified []string
+		if c.requireQualifiedImages {
+			for _, dep := range extras.deployments {
+				unqualified = append(unqualified,
+					gencsv.CheckQualifiedPodImages("deployment "+dep.GetName(), dep.Spec.Template.Spec)...)
+			}
+		}
+		if len(unqualified) != 0 {
+			return withExitCode(exitValidation, fmt.Errorf("images must be fully qualified with a registry host:\n  - %s",
+				strings.Join(unqualified, "\n  - ")))
+		}
+		for _, secret := range extras.secrets {
+			log.Warnf("Including Secret %q in the package; make sure it does not contain credentials", secret.GetName())
+		}
+		for _, secret := range extras.skippedSecrets {
+			log.Warnf("Skipping Secret %q: set --include-secrets to include Secrets in the package", secret.GetName())
+		}
+		genutil.SetLabels(objs, c.labels)
+		var oversized []string
+		for _, obj := range objs {
+			kind := obj.GetObjectKind().GroupVersionKind().Kind
+			if err := k8sutil.ValidateObjectMetadata(obj); err != nil {
+				return withExitCode(exitValidation, fmt.Errorf("%s %v", kind, err))
+			}
+			if err := k8sutil.CheckObjectSize(obj, c.maxObjectSize); err != nil {
+				oversized = append(oversized, fmt.Sprintf("%s %v", kind, err))
+			}
+		}
+		if len(oversized) != 0 {
+			return withExitCode(exitValidation, fmt.Errorf("objects are too large to install, see --max-object-size:\n  - %s",
+				strings.Join(oversized, "\n  - ")))
+		}
+		if schema != nil {
+			fileNames, err := genutil.MakeObjectFileNames(genutil.FileNaming(c.fileNaming), objs...)
+			if err != nil {
+				return err
+			}
+			for i, obj := range objs {
+				schemaObjs = append(schemaObjs, schemaObject{path: c.schemaPath(obj, fileNames[i]), obj: obj})
+			}
+		}
+	}
+
+	if schema != nil {
+		if err := validateSchema(schema, schemaObjs); err != nil {
+			return withExitCode(exitValidation, err)
+		}
+	}
+	// The package manifest's channels point to this version, so it is only written once the version is valid.
+	if err := c.generatePackageManifest(); err != nil {
+		return err
+	}
+	if err := writeCSV(); err != nil {
+		return err
+	}
+
+	if c.updateObjects {
+		if c.stdout {
+			if err := genutil.WriteObjectsFormatted(stdout, c.yamlFormat(), objs...); err != nil {
+				return err
+			}
+		} else {
+			dir := c.versionDir()
+			for _, obj := range objs {
+				log.Debugf("Writing %s %q to %s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), dir)
+			}
+			writeOpts := genutil.WriteOptions{
+				DirMode:    dirMode,
+				FileMode:   fileMode,
+				Format:     c.yamlFormat(),
+				FileNaming: genutil.FileNaming(c.fileNaming),
+			}
+			if c.preserveComments {
+				docs, err := c.sourceDocuments()
+				if err != nil {
+					return err
+				}
+				writeOpts.Verbatim = verbatimObjects(docs, objs)
+			}
+			if err := genutil.WriteObjectsToFiles(dir, writeOpts, objs...); err != nil {
+				return withExitCode(exitIO, err)
+			}
+		}
+	}
+
+	if c.ociOut != "" {
+		if err := c.writeOCILayout(); err != nil {
+			return withExitCode(exitIO, fmt.Errorf("error writing OCI image layout: %v", err))
+		}
+		c.println("Bundle image OCI layout written to", c.ociOut)
+	}
+
+	if c.genDockerfile {
+		if err := c.writeBundleDockerfile(); err != nil {
+			return withExitCode(exitIO, fmt.Errorf("error writing bundle Dockerfile: %v", err))
+		}
+		c.println("Bundle Dockerfile written to", filepath.Join(c.bundleDockerfileDir(), "bundle.Dockerfile"))
+	}
+
+	if c.sbomFile != "" {
+		if err := c.writeSBOM(writtenCSV.(*operatorsv1alpha1.ClusterServiceVersion), extraDeps); err != nil {
+			return withExitCode(exitIO, fmt.Errorf("error writing SBOM: %v", err))
+		}
+		c.println("SBOM written to", c.sbomFile)
+	}
+
+	if len(c.deprecateVersions) != 0 {
+		if err := c.writeDeprecations(); err != nil {
+			return err
+		}
+	}
+
+	if c.skipIfUnchanged {
+		hash, err := c.inputsHash(manifestsHash)
 		if err != nil {
-			return fmt.Errorf("error reading CSV base: %v", err)
+			return err
+		}
+		if err := c.writeInputsHash(hash); err != nil {
+			return withExitCode(exitIO, fmt.Errorf("error writing inputs hash: %v", err))
 		}
-		col.ClusterServiceVersions = append(col.ClusterServiceVersions, *base)
-	} else if noCSVStdin {
-		c.println("Building a ClusterServiceVersion without an existing base")
 	}
 
-	var opts []gencsv.Option
-	stdout := genutil.NewMultiManifestWriter(os.Stdout)
-	if c.stdout {
-		opts = append(opts, gencsv.WithWriter(stdout))
+	c.println("Package manifests generated successfully in", c.outputDir)
+
+	return nil
+}
+
+// extraObjects are collected objects written alongside the CSV, and those skipped.
+type extraObjects struct {
+	// written are all objects written, including deployments and secrets.
+	written []client.Object
+	// deployments are written Deployments that are not the operator's.
+	deployments []appsv1.Deployment
+	// secrets are written Secrets, which are only written with --include-secrets.
+	secrets []client.Object
+	// skippedDeployments are Deployments that are not the operator's, and whose kind is not supported in bundles.
+	skippedDeployments []appsv1.Deployment
+	// skippedSecrets are Secrets not written without --include-secrets.
+	skippedSecrets []client.Object
+}
+
+// extraObjects returns the objects in col and extraDeps, the Deployments that are not the operator's, that are
+// written alongside the CSV with --update-objects. Generation and --list-kinds both classify objects with it.
+func (c packagemanifestsCmd) extraObjects(col *collector.Manifests, extraDeps []appsv1.Deployment) extraObjects {
+	// Extra ServiceAccounts not supported by this command.
+	includeKinds := c.includeKinds
+	if c.includeSCC {
+		includeKinds = append(includeKinds, gencsv.SecurityContextConstraintsGroupKind.Kind)
+	}
+	var extras extraObjects
+	extras.deployments, extras.skippedDeployments = genutil.SplitIncludedDeployments(extraDeps, includeKinds)
+	objs := genutil.GetManifestObjectsWithKinds(col, nil, includeKinds)
+	for i := range extras.deployments {
+		objs = append(objs, &extras.deployments[i])
+	}
+	secrets, others := genutil.SplitSecrets(objs)
+	if c.includeSecrets {
+		extras.written, extras.secrets = objs, secrets
 	} else {
-		opts = append(opts, gencsv.WithPackageWriter(c.outputDir))
+		extras.written, extras.skippedSecrets = others, secrets
+	}
+	return extras
+}
+
+// csvGenerator returns a Generator of the version's ClusterServiceVersion from col, and options transforming
+// the CSV before it is written: pinning its images with resolve if set, then beforePatches if set, then
+// applying --csv-patch.
+func (c packagemanifestsCmd) csvGenerator(col *collector.Manifests, resolve imageResolver,
+	beforePatches gencsv.ObjectTransform) (gencsv.Generator, []gencsv.Option, error) {
+	format, err := getRegistryFormat(c.registryFormat)
+	if err != nil {
+		return gencsv.Generator{}, nil, err
+	}
+	opts := []gencsv.Option{gencsv.WithObjectTransform(format.stripUnsupported)}
+
+	props, err := getProperties(c.olmProperties, c.maxOpenShiftVersion)
+	if err != nil {
+		return gencsv.Generator{}, nil, err
+	}
+	deps, err := c.readDependencies()
+	if err != nil {
+		return gencsv.Generator{}, nil, err
 	}
 
+	// Platform labels only apply to the CSV.
+	csvLabels, err := getPlatformLabels(c.archs, c.oses)
+	if err != nil {
+		return gencsv.Generator{}, nil, err
+	}
+	for k, v := range c.labels {
+		csvLabels[k] = v
+	}
+
+	csvAnnotations, err := getCSVAnnotations(c.layout, c.metricsAnnotations)
+	if err != nil {
+		return gencsv.Generator{}, nil, err
+	}
+	if c.gitAnnotations {
+		for k, v := range gitAnnotations(".") {
+			csvAnnotations[k] = v
+		}
+	}
+	for k, v := range c.csvAnnotations {
+		csvAnnotations[k] = v
+	}
+
+	csvFormat := c.yamlFormat()
+	csvFormat.MaxLineWidth = c.maxLineWidth
+
 	csvGen := gencsv.Generator{
 		OperatorName: c.packageName,
 		Version:      c.version,
+		SpecVersion:  c.csvVersion,
 		FromVersion:  c.fromVersion,
+		Replaces:     c.replacesCSVName,
 		Collector:    col,
-		Annotations:  metricsannotations.MakeBundleObjectAnnotations(c.layout),
+		Annotations:  csvAnnotations,
+
+		ReconcileDescriptors:   c.reconcileDescriptors,
+		GrantSCCUsers:          c.grantSCCUsers,
+		Properties:             props,
+		Dependencies:           deps,
+		Labels:                 csvLabels,
+		InjectWatchNamespace:   c.injectWatchNamespace,
+		StripWatchNamespace:    c.noWatchNamespaceEnv,
+		ExternalCRDs:           c.crdsExternal,
+		CleanupEnabled:         c.cleanup,
+		RequireResources:       c.requireResources,
+		RequireServiceAccounts: c.requireSAs,
+		RequiredAnnotations:    c.requiredAnnotations,
+		MaxSize:                c.maxCSVSize,
+		AllowEmptyInstall:      c.allowEmptyInstall,
+		Format:                 csvFormat,
+
+		RequireQualifiedImages: c.requireQualifiedImages,
+		BaseOperatorName:       c.basePackageName,
 	}
-	if err := csvGen.Generate(opts...); err != nil {
-		return fmt.Errorf("error generating ClusterServiceVersion: %v", err)
+	if resolve != nil {
+		opts = append(opts, gencsv.WithObjectTransform(pinDigests(resolve)))
+	}
+	// The image is read once pinned, and --csv-annotation takes precedence over it.
+	if _, isSet := c.csvAnnotations[containerImageAnnotation]; c.gitAnnotations && !isSet {
+		opts = append(opts, gencsv.WithObjectTransform(setContainerImageAnnotation))
+	}
+	if beforePatches != nil {
+		opts = append(opts, gencsv.WithObjectTransform(beforePatches))
 	}
+	// Patches are applied last so they can override any generated field, and are not written to the base.
+	patches, err := c.readCSVPatches()
+	if err != nil {
+		return gencsv.Generator{}, nil, err
+	}
+	opts = append(opts, gencsv.WithPatches(patches...))
+	return csvGen, opts, nil
+}
 
-	if c.updateObjects {
-		// Extra ServiceAccounts not supported by this command.
-		objs := genutil.GetManifestObjects(col, nil)
-		if c.stdout {
-			if err := genutil.WriteObjects(stdout, objs...); err != nil {
-				return err
+// collectInputs collects the manifests the version is generated from, as --images, --node-selector,
+// --image-pull-secrets, and --selector change them, with a ClusterServiceVersion base. The returned
+// Deployments are not the operator's, so are not in the CSV's install strategy.
+func (c packagemanifestsCmd) collectInputs() (*collector.Manifests, []appsv1.Deployment, error) {
+	col, err := c.collectManifests()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := setContainerImages(col, c.images); err != nil {
+		return nil, nil, withExitCode(exitUsage, err)
+	}
+	setNodeSelectors(col, c.nodeSelector)
+	setImagePullSecrets(col, c.imagePullSecrets)
+
+	objSelector, err := c.objectSelector()
+	if err != nil {
+		return nil, nil, err
+	}
+	if objSelector != nil {
+		for _, key := range col.Select(objSelector, c.selectorIncludeCRDs) {
+			log.Debugf("Skipping %s: labels do not match --selector", key)
+		}
+	}
+
+	// If no CSV was initially read, a kustomize base can be used at the default base path.
+	// Only read from kustomizeDir if a base exists so users can still generate a barebones CSV.
+	baseCSVPath := c.baseCSVPath()
+	if noCSVStdin := len(col.ClusterServiceVersions) == 0; noCSVStdin && genutil.IsExist(baseCSVPath) {
+		base, err := bases.ClusterServiceVersion{BasePath: baseCSVPath, OverlayDir: c.baseOverlay}.GetBase()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading CSV base: %v", err)
+		}
+		log.Debugf("Using ClusterServiceVersion base %s", baseCSVPath)
+		col.ClusterServiceVersions = append(col.ClusterServiceVersions, *base)
+	} else if noCSVStdin {
+		log.Debugf("No ClusterServiceVersion base found at %s", baseCSVPath)
+		c.warnMissingBasesDir()
+		c.println("Building a ClusterServiceVersion without an existing base")
+		// Overlays need a base to merge onto, so use the default one the generator would.
+		if c.baseOverlay != "" {
+			base, err := bases.ClusterServiceVersion{OperatorName: c.basePackage(), OverlayDir: c.baseOverlay}.GetBase()
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading CSV base: %v", err)
 			}
-		} else {
-			dir := filepath.Join(c.outputDir, c.version)
-			if err := genutil.WriteObjectsToFiles(dir, objs...); err != nil {
-				return err
+			col.ClusterServiceVersions = append(col.ClusterServiceVersions, *base)
+		}
+	} else {
+		log.Debugf("Using ClusterServiceVersion %q from input manifests as a base", col.ClusterServiceVersions[0].GetName())
+		if c.baseOverlay != "" {
+			if err := bases.ApplyOverlays(&col.ClusterServiceVersions[0], c.baseOverlay); err != nil {
+				return nil, nil, fmt.Errorf("error reading CSV base: %v", err)
 			}
 		}
 	}
 
-	c.println("Package manifests generated successfully in", c.outputDir)
+	selector, err := c.managerSelector()
+	if err != nil {
+		return nil, nil, err
+	}
+	extraDeps, err := genutil.SplitManagerDeployments(col, c.managerDeployment, selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error selecting the operator's Deployment: %v; "+
+			"set --manager-deployment or --manager-deployment-selector", err)
+	}
+	return col, extraDeps, nil
+}
+
+// collectManifests collects manifests from all inputs, then removes their status, unless --keep-status
+// is set, and conversion webhook CA bundles, and normalizes CRD schemas if --normalize-crd-schemas is set.
+func (c packagemanifestsCmd) collectManifests() (*collector.Manifests, error) {
+	stdinCol := &collector.Manifests{}
+	if genutil.IsPipeReader() {
+		if err := stdinCol.UpdateFromReaderWithFormat(os.Stdin, inputFormatOrDefault(c.inputFormat)); err != nil {
+			return nil, err
+		}
+	}
+	cache := c.openManifestsCache()
+	dirCol := &collector.Manifests{}
+	deployDir, crdsDir := c.deployDir, c.crdsDir
+	if c.ignoreMissingDirs {
+		deployDir, crdsDir = existingDir("--deploy-dir", deployDir), existingDir("--crds-dir", crdsDir)
+		if deployDir == "" {
+			// CRDs are collected while walking the directory.
+			deployDir, crdsDir = crdsDir, ""
+		}
+	}
+	if deployDir != "" {
+		if err := dirCol.UpdateFromDirsWithCache(deployDir, crdsDir, cache); err != nil {
+			return nil, err
+		}
+	}
+	if c.render {
+		log.Debugf("Rendering kustomization %s", c.kustomizeDir)
+		if err := dirCol.UpdateFromKustomize(c.kustomizeDir); err != nil {
+			return nil, err
+		}
+	}
+	if c.helmChart != "" {
+		log.Debugf("Rendering Helm chart %s", c.helmChart)
+		if err := dirCol.UpdateFromHelmChart(c.helmChart, c.helmValues); err != nil {
+			return nil, err
+		}
+	}
+	if c.fromDir != "" {
+		in, err := resolveFromDir(c.fromDir)
+		if err != nil {
+			return nil, err
+		}
+		for i, dir := range in.dirs {
+			// CRDs only need to be collected once, and would be replaced by later calls.
+			crdsDir := ""
+			if i == 0 {
+				crdsDir = in.crdsDir
+			}
+			if err := dirCol.UpdateFromDirsWithCache(dir, crdsDir, cache); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Remote inputs are read after directories, so override their objects like a later --input-url
+	// overrides an earlier one.
+	if c.inputGit != "" {
+		in, err := parseGitInput(c.inputGit)
+		if err != nil {
+			return nil, err
+		}
+		dir, cleanup, err := in.fetch()
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		gitCol := &collector.Manifests{}
+		// CRDs are collected while walking dir.
+		if err := gitCol.UpdateFromDirsWithCache(dir, "", cache); err != nil {
+			return nil, err
+		}
+		if err := overrideInput(dirCol, gitCol, "--input-git "+c.inputGit); err != nil {
+			return nil, err
+		}
+	}
+	saveManifestsCache(cache)
+	for _, inputURL := range c.inputURLs {
+		b, err := fetchURL(inputURL)
+		if err != nil {
+			return nil, err
+		}
+		urlCol := &collector.Manifests{}
+		if err := urlCol.UpdateFromReader(bytes.NewReader(b)); err != nil {
+			return nil, fmt.Errorf("error reading manifests from %s: %v", inputURL, err)
+		}
+		if err := overrideInput(dirCol, urlCol, "--input-url "+inputURL); err != nil {
+			return nil, err
+		}
+	}
+	col, err := mergeInputs(stdinCol, dirCol, c.inputPrecedence)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ignoreMissingDirs && !hasManifests(col) {
+		return nil, errors.New("no manifests were read from any input, and --ignore-missing-dirs is set: " +
+			"check that --deploy-dir or --crds-dir exists")
+	}
+
+	if !c.keepStatus {
+		for _, key := range col.StripStatus() {
+			log.Debugf("Removed the status of %s: set --keep-status to keep it", key)
+		}
+	}
+
+	if !c.keepRuntimeMetadata {
+		for _, key := range col.StripRuntimeMetadata() {
+			log.Debugf("Removed the runtime metadata of %s: set --keep-runtime-metadata to keep it", key)
+		}
+	}
+
+	if c.upgradeAPIVersions {
+		upgraded, err := col.UpgradeAPIVersions()
+		if err != nil {
+			return nil, err
+		}
+		for _, conversion := range upgraded {
+			log.Warnf("Upgraded the deprecated API version of %s", conversion)
+		}
+	}
+
+	for _, key := range col.StripConversionCABundles() {
+		log.Debugf("Removed the conversion webhook CA bundle of %s, which OLM injects", key)
+	}
+
+	if c.normalizeCRDSchemas {
+		if err := col.NormalizeCRDSchemas(); err != nil {
+			return nil, err
+		}
+	}
+
+	return col, nil
+}
+
+// warnCSVOnlyIgnored logs a warning for each set flag that only configures files not written with --csv-only.
+func (c packagemanifestsCmd) warnCSVOnlyIgnored() {
+	ignored := []struct {
+		name  string
+		isSet bool
+	}{
+		{"--channel", c.channelName != ""},
+		{"--default-channel", c.isDefaultChannel},
+		{"--channel-config", c.channelConfig != ""},
+		{"--package-template", c.packageTemplate != ""},
+		{"--include-secrets", c.includeSecrets},
+		{"--include-kinds", len(c.includeKinds) != 0},
+	}
+	for _, flag := range ignored {
+		if flag.isSet {
+			log.Warnf("Ignoring %s, which only configures files not written with --csv-only", flag.name)
+		}
+	}
+}
+
+// readCSVPatches reads each patch in --csv-patch.
+func (c packagemanifestsCmd) readCSVPatches() ([]gencsv.Patch, error) {
+	patchType, err := getCSVPatchType(c.csvPatchType)
+	if err != nil {
+		return nil, err
+	}
+	patches := make([]gencsv.Patch, 0, len(c.csvPatches))
+	for _, path := range c.csvPatches {
+		p, err := gencsv.ReadPatch(path, patchType)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, p)
+	}
+	return patches, nil
+}
+
+// readDependencies reads --dependencies, returning no dependencies if unset.
+func (c packagemanifestsCmd) readDependencies() (gencsv.Dependencies, error) {
+	if c.dependenciesFile == "" {
+		return gencsv.Dependencies{}, nil
+	}
+	return gencsv.ReadDependencies(c.dependenciesFile)
+}
+
+// managerSelector parses --manager-deployment-selector, returning a nil selector if unset.
+func (c packagemanifestsCmd) managerSelector() (labels.Selector, error) {
+	if c.managerDeploymentSelector == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(c.managerDeploymentSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --manager-deployment-selector %q: %v", c.managerDeploymentSelector, err)
+	}
+	return selector, nil
+}
 
+// objectSelector parses --selector, returning a nil selector if unset.
+func (c packagemanifestsCmd) objectSelector() (labels.Selector, error) {
+	if c.selector == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(c.selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --selector %q: %v", c.selector, err)
+	}
+	return selector, nil
+}
+
+// basePackage returns the package name the CSV base is named after, which is --package
+// even if --rename-package is set.
+func (c packagemanifestsCmd) basePackage() string {
+	if c.basePackageName != "" {
+		return c.basePackageName
+	}
+	return c.packageName
+}
+
+// baseCSVPath returns the path of the ClusterServiceVersion base in --kustomize-dir.
+func (c packagemanifestsCmd) baseCSVPath() string {
+	return filepath.Join(c.kustomizeDir, "bases", c.basePackage()+".clusterserviceversion.yaml")
+}
+
+// warnMissingBasesDir logs a warning if --kustomize-dir was set but does not exist, or if it has no bases
+// directory, since a misspelled --kustomize-dir otherwise silently results in a barebones CSV. The default
+// --kustomize-dir not existing is expected for projects without a base.
+func (c packagemanifestsCmd) warnMissingBasesDir() {
+	dir, err := filepath.Abs(c.kustomizeDir)
+	if err != nil {
+		dir = c.kustomizeDir
+	}
+	switch {
+	case c.kustomizeDir == "":
+	case !isDir(dir):
+		if c.kustomizeDirSet {
+			log.Warnf("--kustomize-dir %s does not exist, so no ClusterServiceVersion base was read", dir)
+		}
+	case !isDir(filepath.Join(dir, "bases")):
+		log.Warnf("--kustomize-dir %s has no bases directory, so no ClusterServiceVersion base was read", dir)
+	}
+}
+
+// flagAnnotationKeys returns the keys of the CSV annotations set from flags, ex. --csv-annotation,
+// which are set on every generation so are not part of a base.
+func (c packagemanifestsCmd) flagAnnotationKeys() ([]string, error) {
+	annotations, err := getCSVAnnotations(c.layout, c.metricsAnnotations)
+	if err != nil {
+		return nil, err
+	}
+	for k := range c.csvAnnotations {
+		annotations[k] = ""
+	}
+	keys := make([]string, 0, len(annotations)+2)
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	if c.gitAnnotations {
+		keys = append(keys, repositoryAnnotation, vcsRefAnnotation, containerImageAnnotation)
+	}
+	return keys, nil
+}
+
+// writeBaseCSV writes a base extracted from csv to baseCSVPath(). Bases are project files,
+// so are written like 'generate kustomize manifests' writes them.
+func (c packagemanifestsCmd) writeBaseCSV(csv *operatorsv1alpha1.ClusterServiceVersion) error {
+	excluded, err := c.flagAnnotationKeys()
+	if err != nil {
+		return err
+	}
+	base := bases.Extract(c.basePackage(), csv, excluded...)
+	b, err := k8sutil.GetObjectBytes(base, func(obj interface{}) ([]byte, error) {
+		// Bases do not set spec.cleanup, which --cleanup-enabled sets, so its zero value is removed
+		// like 'generate kustomize manifests' removes it from the bases it writes.
+		unstructured.RemoveNestedField(obj.(map[string]interface{}), "spec", "cleanup")
+		return yaml.Marshal(obj)
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling CSV base: %v", err)
+	}
+
+	path := c.baseCSVPath()
+	if err := fileutil.MkdirAll(filepath.Dir(path), 0); err != nil {
+		return err
+	}
+	if err := fileutil.WriteFile(path, b, 0); err != nil {
+		return fmt.Errorf("error writing CSV base: %v", err)
+	}
+	log.Debugf("Wrote ClusterServiceVersion base %s", path)
 	return nil
 }
 
 func (c packagemanifestsCmd) generatePackageManifest() error {
+	dirMode, fileMode, err := c.fileModes()
+	if err != nil {
+		return err
+	}
+
 	//copy of genpkg withfilewriter()
 	//move out of internal util pkg?
-	if err := os.MkdirAll(c.outputDir, 0755); err != nil {
+	if err := fileutil.MkdirAll(c.outputDir, dirMode); err != nil {
 		return err
 	}
 
@@ -237,6 +1309,17 @@ func (c packagemanifestsCmd) generatePackageManifest() error {
 		BaseDir:          c.inputDir,
 		ChannelName:      c.channelName,
 		IsDefaultChannel: c.isDefaultChannel,
+		TemplatePath:     c.packageTemplate,
+		DirMode:          dirMode,
+		FileMode:         fileMode,
+		Format:           c.yamlFormat(),
+		// Versions later in --versions are written after this version's package manifest.
+		NewVersions: c.versions,
+	}
+	if c.channelConfig != "" {
+		if opts.ChannelConfig, err = genpkg.ReadChannelConfig(c.channelConfig); err != nil {
+			return err
+		}
 	}
 
 	if err := c.generator.Generate(c.packageName, c.version, c.outputDir, opts); err != nil {
@@ -16,13 +16,20 @@ package packagemanifests
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion/bases"
 	"github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
 	"github.com/operator-framework/operator-sdk/internal/generate/packagemanifest/packagemanifestfakes"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
 )
 
 var _ = Describe("Running a generate packagemanifests command", func() {
@@ -49,10 +56,13 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 		testDataDir = filepath.Join("..", "..", "..", "..", "..", "testdata", "go", "v3", "memcached-operator")
 	})
 	Describe("validate", func() {
+		BeforeEach(func() {
+			c.packageName = "memcached-operator"
+		})
 		It("fails if no version is provided", func() {
 			err := c.validate()
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("version must be set"))
+			Expect(err.Error()).To(ContainSubstring("--version or --versions must be set"))
 		})
 		It("fails if a non-parsable version is provided", func() {
 			c.version = "potato"
@@ -69,6 +79,14 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("1.0.a is not a valid semantic version"))
 		})
+		It("fails if an a non-parsable csv-version is provided", func() {
+			c.version = versionOne
+			c.csvVersion = "0.0.1+build..5"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("0.0.1+build..5 is not a valid semantic version"))
+		})
 		It("fails if an input-dir is not provided", func() {
 			c.version = versionOne
 
@@ -95,6 +113,15 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("must be set if not reading from stdin"))
 		})
+		It("allows crds-dir to not be set if ignore-missing-dirs is set", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.kustomizeDir = kustomizeDir
+			c.deployDir = deployDir
+			c.ignoreMissingDirs = true
+
+			Expect(c.validate()).To(Succeed())
+		})
 		It("allows deply-dir and crds-dir to not be set if reading from a pipe such as stdin", func() {
 			c.version = versionOne
 			c.inputDir = inputDir
@@ -121,6 +148,66 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("output-dir cannot be set if writing to stdout"))
 		})
+		It("fails if skip-if-unchanged is set while set to write to stdout", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.stdout = true
+			c.skipIfUnchanged = true
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--skip-if-unchanged cannot be set if writing to stdout"))
+		})
+		It("fails if output-dir is set with single-file", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.singleFile = "package.yaml"
+			c.outputDir = "output/"
+
+			err := c.validate()
+			Expect(err).To(MatchError(ContainSubstring("--stdout and --output-dir cannot be set with --single-file")))
+		})
+		It("fails if gen-dockerfile is set with csv-only", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.csvOnly = true
+			Expect(c.validate()).To(Succeed())
+
+			c.genDockerfile = true
+			err := c.validate()
+			Expect(err).To(MatchError(ContainSubstring("--oci-out, --gen-dockerfile, and --skip-if-unchanged cannot be set with --csv-only")))
+		})
+		It("fails if deploy-dir is set with from-dir", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.fromDir = "config"
+			c.deployDir = deployDir
+
+			err := c.validate()
+			Expect(err).To(MatchError(ContainSubstring("cannot be set with --from-dir")))
+		})
+		It("does not require a version or inputs with check-graph", func() {
+			c.checkGraph = true
+			Expect(c.validate()).To(Succeed())
+
+			c.stdout = true
+			Expect(c.validate()).To(MatchError(ContainSubstring("--stdout cannot be set with --check-graph")))
+		})
+		It("fails if --validate is set with an option that does not write a package directory", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.validateOnly = true
+			c.selfCheck = true
+			Expect(c.validate()).To(MatchError(ContainSubstring("cannot be set with --validate")))
+		})
 		It("fails if default-channel is set but channel is not provided", func() {
 			c.version = versionOne
 			c.inputDir = inputDir
@@ -133,6 +220,342 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("default-channel can only be set if --channel is set"))
 		})
+		It("fails if channel-config is set with channel", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.channelName = "stable"
+			c.channelConfig = "channels.yaml"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--channel and --default-channel cannot be set with --channel-config"))
+		})
+		It("fails if channel-config cannot be read", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.channelConfig = "not-a-channels.yaml"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error reading channel config"))
+		})
+		It("fails if a csv-patch cannot be read", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.csvPatches = []string{"not-a-patch.yaml"}
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error reading CSV patch"))
+		})
+		It("fails if dependencies cannot be read", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.dependenciesFile = "not-a-dependencies.yaml"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("error reading dependencies file"))
+		})
+		It("fails if base-overlay is not a directory", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.baseOverlay = "not-an-overlay-dir"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--base-overlay not-an-overlay-dir must be an existing directory"))
+		})
+		It("fails if a maximum size is negative", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.maxObjectSize = -1
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--max-csv-size and --max-object-size must not be negative"))
+		})
+		It("fails if a --csv-annotation key is invalid", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.csvAnnotations = map[string]string{"vcs ref": "0123abc"}
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`--csv-annotation key "vcs ref" is invalid`))
+		})
+		It("fails if a --rename-package name is invalid", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.basePackageName, c.packageName = "memcached-operator", "Partner_Operator"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`--rename-package "Partner_Operator" is invalid`))
+		})
+		It("fails if a --replaces-csv-name is not a CSV name", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.replacesCSVName = "memcached-operator"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid --replaces-csv-name"))
+		})
+		It("succeeds if --version is a prerelease", func() {
+			c.version = "0.3.0-rc.1"
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+
+			Expect(c.validate()).To(Succeed())
+		})
+		It("fails if an invalid max-openshift-version is provided", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.maxOpenShiftVersion = "4.8.1"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be of the form X.Y"))
+		})
+		It("fails if an olm-property is not of the form type=value", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.olmProperties = []string{"olm.maxOpenShiftVersion"}
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be of the form type=value"))
+		})
+		It("fails if csv-max-line-width is negative", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.maxLineWidth = -1
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--csv-max-line-width must not be negative"))
+		})
+		It("fails if yaml-indent is out of range", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.yamlIndent = 1
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid --yaml-indent: indent 1 must be between 2 and 9"))
+		})
+		It("fails if overwrite-base is set without write-base", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.overwriteBase = true
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--overwrite-base can only be set if --write-base is set"))
+		})
+		It("succeeds if render is set without deploy-dir or crds-dir", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.kustomizeDir = kustomizeDir
+			c.render = true
+
+			Expect(c.validate()).To(Succeed())
+		})
+		It("fails if render is set with deploy-dir", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.kustomizeDir = kustomizeDir
+			c.render = true
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--deploy-dir, --crds-dir, and --from-dir cannot be set with --render"))
+		})
+		It("fails if helm-chart is set with deploy-dir", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.helmChart = "chart"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot be set with --helm-chart"))
+		})
+		It("fails if helm-values is set without helm-chart", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.helmValues = []string{"values.yaml"}
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--helm-values cannot be set without --helm-chart"))
+		})
+		It("fails if stdout is set with self-check", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.stdout = true
+			c.selfCheck = true
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--stdout cannot be set with --self-check"))
+		})
+		It("fails if strict is set while reading from a pipe such as stdin", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.kustomizeDir = kustomizeDir
+			c.strict = true
+			r, w, err := os.Pipe()
+			Expect(err).NotTo(HaveOccurred())
+			defer w.Close()
+			origStdin := os.Stdin
+			defer func() { os.Stdin = origStdin }()
+			os.Stdin = r
+
+			err = c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--strict cannot be set if reading from stdin"))
+		})
+		It("fails if selector is invalid", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.selector = "operator memcached"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`invalid --selector "operator memcached"`))
+		})
+		It("fails if selector-include-crds is set without selector", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.selectorIncludeCRDs = true
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--selector-include-crds can only be set if --selector is set"))
+		})
+		It("fails if pin-digests-offline is set without pin-digests", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.pinDigestsOffline = true
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--pin-digests-offline can only be set if --pin-digests is set"))
+		})
+		It("fails if grant-scc-users is set without include-scc", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.grantSCCUsers = true
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--grant-scc-users can only be set if --include-scc is set"))
+
+			c.includeSCC = true
+			Expect(c.validate()).To(Succeed())
+		})
+		It("fails if write-base would overwrite an existing base unless overwrite-base is set", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.kustomizeDir = filepath.Join(testDataDir, "config", "manifests")
+			c.packageName = "memcached-operator"
+			c.writeBase = true
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unless --overwrite-base is set"))
+
+			c.overwriteBase = true
+			Expect(c.validate()).To(Succeed())
+		})
+		It("fails if both manager-deployment and manager-deployment-selector are set", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.managerDeployment = "controller-manager"
+			c.managerDeploymentSelector = "control-plane=controller-manager"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot both be set"))
+		})
+		It("fails if manager-deployment-selector is invalid", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.managerDeploymentSelector = "control plane"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid --manager-deployment-selector"))
+		})
+		It("fails if registry-format does not support set fields", func() {
+			c.version = versionOne
+			c.inputDir = inputDir
+			c.deployDir = deployDir
+			c.crdsDir = crdsDir
+			c.registryFormat = "v1"
+			c.maxOpenShiftVersion = "4.8"
+
+			err := c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot be set with --registry-format v1"))
+
+			c.maxOpenShiftVersion = ""
+			cleanup := true
+			c.cleanup = &cleanup
+			err = c.validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("--cleanup-enabled cannot be set with --registry-format v1"))
+		})
 		It("validates successfully", func() {
 			c.version = versionOne
 			c.fromVersion = "0.1.2"
@@ -156,6 +579,44 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 			err := c.validate()
 			Expect(err).NotTo(HaveOccurred())
 		})
+		It("fails if the package name has uppercase characters", func() {
+			c.packageName = "Apricot-Operator"
+
+			Expect(c.setDefaults()).To(Succeed())
+			err := c.validate()
+			Expect(err).To(MatchError(ContainSubstring(`package name "Apricot-Operator" is invalid: ` +
+				"a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-'")))
+		})
+		It("fails if the package name has underscores", func() {
+			c.packageName = "apricot_operator"
+
+			Expect(c.setDefaults()).To(Succeed())
+			err := c.validate()
+			Expect(err).To(MatchError(ContainSubstring(`package name "apricot_operator" is invalid`)))
+		})
+		It("fails if the package name is longer than 63 characters", func() {
+			c.packageName = strings.Repeat("apricot-", 8)
+
+			Expect(c.setDefaults()).To(Succeed())
+			err := c.validate()
+			Expect(err).To(MatchError(ContainSubstring("must be no more than 63 characters")))
+		})
+		It("fails if the renamed package name is invalid", func() {
+			c.packageName = "apricot"
+			c.renamePackage = "Partner_Apricot"
+
+			Expect(c.setDefaults()).To(Succeed())
+			err := c.validate()
+			Expect(err).To(MatchError(ContainSubstring(`--rename-package "Partner_Apricot" is invalid`)))
+		})
+		It("does not check the package name with --check-graph or --list-versions", func() {
+			c.packageName = "Apricot-Operator"
+			Expect(c.setDefaults()).To(Succeed())
+			c.checkGraph = true
+			Expect(c.validate()).To(Succeed())
+			c.checkGraph, c.listVersions, c.listFormat = false, true, listFormatText
+			Expect(c.validate()).To(Succeed())
+		})
 	})
 	Describe("setDefaults", func() {
 		Context("no project file is present", func() {
@@ -182,6 +643,16 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(c.outputDir).To(Equal(""))
 			})
+			It("renames the package, keeping the base's package name", func() {
+				c.packageName = "apricot"
+				c.renamePackage = "partner-apricot"
+
+				err := c.setDefaults()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(c.packageName).To(Equal("partner-apricot"))
+				Expect(c.baseCSVPath()).To(HaveSuffix("apricot.clusterserviceversion.yaml"))
+				Expect(c.baseCSVPath()).NotTo(HaveSuffix("partner-apricot.clusterserviceversion.yaml"))
+			})
 		})
 		Context("a valid project file is present", func() {
 			BeforeEach(func() {
@@ -230,6 +701,8 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 			c.generator = &fakeGen
 			c.inputDir = "banana/"
 			c.isDefaultChannel = true
+			c.packageTemplate = "package.yaml.tmpl"
+			c.yamlSortKeys = true
 			c.outputDir = os.TempDir()
 			c.packageName = "cherry"
 			c.version = "1.2.3"
@@ -246,6 +719,8 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 				BaseDir:          c.inputDir,
 				ChannelName:      c.channelName,
 				IsDefaultChannel: c.isDefaultChannel,
+				TemplatePath:     c.packageTemplate,
+				Format:           yamlutil.Options{SortKeys: true},
 			}))
 		})
 		It("bubbles up errors from the generator", func() {
@@ -257,4 +732,66 @@ var _ = Describe("Running a generate packagemanifests command", func() {
 			Expect(err.Error()).To(ContainSubstring(potatoErr.Error()))
 		})
 	})
+
+	Describe("--verbose", func() {
+		It("counts repeated flags", func() {
+			cmd := NewCmd()
+			Expect(cmd.Flags().Parse([]string{"--verbose", "--verbose"})).To(Succeed())
+			verbosity, err := cmd.Flags().GetCount("verbose")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verbosity).To(Equal(2))
+		})
+		It("maps counts to log levels", func() {
+			_, ok := logLevel(0)
+			Expect(ok).To(BeFalse())
+			level, ok := logLevel(1)
+			Expect(ok).To(BeTrue())
+			Expect(level).To(Equal(log.DebugLevel))
+			level, ok = logLevel(2)
+			Expect(ok).To(BeTrue())
+			Expect(level).To(Equal(log.TraceLevel))
+			level, _ = logLevel(3)
+			Expect(level).To(Equal(log.TraceLevel))
+		})
+	})
+})
+
+var _ = Describe("Writing a ClusterServiceVersion base", func() {
+	It("does not extract annotations set from flags", func() {
+		tmp, err := ioutil.TempDir("", "packagemanifests-write-base-")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmp)
+
+		c := packagemanifestsCmd{
+			packageName:        "memcached-operator",
+			kustomizeDir:       tmp,
+			layout:             "go.kubebuilder.io/v3",
+			metricsAnnotations: map[string]string{"example.com/metrics": "on"},
+			csvAnnotations:     map[string]string{"example.com/build": "42"},
+			gitAnnotations:     true,
+		}
+		csv := bases.New("memcached-operator")
+		for k, v := range map[string]string{
+			"capabilities":                           "Full Lifecycle",
+			"example.com/metrics":                    "on",
+			"example.com/build":                      "42",
+			"operators.operatorframework.io/builder": "operator-sdk-v1.0.0",
+			"repository":                             "https://github.com/example/memcached-operator",
+			"vcs-ref":                                "0123abc",
+			"containerImage":                         "quay.io/example/memcached-operator:v0.0.1",
+		} {
+			csv.Annotations[k] = v
+		}
+		Expect(c.writeBaseCSV(csv)).To(Succeed())
+
+		b, err := ioutil.ReadFile(c.baseCSVPath())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).NotTo(ContainSubstring("cleanup"))
+		base := &operatorsv1alpha1.ClusterServiceVersion{}
+		Expect(yaml.Unmarshal(b, base)).To(Succeed())
+		Expect(base.GetAnnotations()).To(Equal(map[string]string{
+			"capabilities": "Full Lifecycle",
+			"alm-examples": "[]",
+		}))
+	})
 })
@@ -0,0 +1,112 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
+)
+
+// sourceDocument is an object's YAML document in a source file.
+type sourceDocument struct {
+	// doc is the object's document, which it is decoded from.
+	doc []byte
+	// file is written for the object: the whole file if the object is its only one, which keeps
+	// comments in their own document, ex. a license header followed by "---", or otherwise doc.
+	file []byte
+}
+
+// sourceDocuments returns the document of each object in the files of --deploy-dir and --crds-dir,
+// keyed by sourceKey.
+func (c packagemanifestsCmd) sourceDocuments() (map[string]sourceDocument, error) {
+	docs := make(map[string]sourceDocument)
+	for _, dir := range []string{c.deployDir, c.crdsDir} {
+		if !isDir(dir) {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			fileDocs := make(map[string][]byte)
+			scanner := k8sutil.NewYAMLScanner(bytes.NewReader(b))
+			for scanner.Scan() {
+				u := unstructured.Unstructured{}
+				if err := yaml.Unmarshal(scanner.Bytes(), &u.Object); err != nil || u.GetKind() == "" {
+					continue
+				}
+				fileDocs[sourceKey(&u)] = append([]byte(nil), scanner.Bytes()...)
+			}
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("error reading %s: %v", path, err)
+			}
+			for key, doc := range fileDocs {
+				file := doc
+				if len(fileDocs) == 1 {
+					file = b
+				}
+				docs[key] = sourceDocument{doc: doc, file: file}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error reading source files in %s: %w", dir, err)
+		}
+	}
+	return docs, nil
+}
+
+// sourceKey returns the key of obj's source document, "<group>/<version>, Kind=<kind> <namespace>/<name>".
+func sourceKey(obj client.Object) string {
+	return fmt.Sprintf("%s %s/%s", obj.GetObjectKind().GroupVersionKind(), obj.GetNamespace(), obj.GetName())
+}
+
+// verbatimObjects returns the source file to write for each of objs decoded unchanged from its
+// document in docs. Objects the generator modified, ex. by setting labels, are not returned.
+func verbatimObjects(docs map[string]sourceDocument, objs []client.Object) map[client.Object][]byte {
+	verbatim := make(map[client.Object][]byte)
+	for _, obj := range objs {
+		src, hasSource := docs[sourceKey(obj)]
+		if !hasSource {
+			continue
+		}
+		decoded := reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+		if err := yaml.Unmarshal(src.doc, decoded); err != nil {
+			continue
+		}
+		if !equality.Semantic.DeepEqual(decoded, obj) {
+			log.Debugf("Re-serializing %s %q, which was modified", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+			continue
+		}
+		verbatim[obj] = src.file
+	}
+	return verbatim
+}
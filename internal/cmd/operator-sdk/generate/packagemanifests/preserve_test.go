@@ -0,0 +1,139 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+)
+
+var _ = Describe("Preserving comments of unchanged objects", func() {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+`
+	const crd = `# Copyright 2021 Example Authors.
+#
+# Licensed under the Apache License, Version 2.0.
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    listKind: MemcachedList
+    plural: memcacheds
+    singular: memcached
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        # Memcached's fields are not validated yet.
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+`
+	const service = `# Exposes the operator's metrics.
+apiVersion: v1
+kind: Service
+metadata:
+  name: metrics-service
+spec:
+  ports:
+  - port: 8443
+`
+
+	var tmp, deployDir, outputDir string
+	var c packagemanifestsCmd
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-preserve-")
+		Expect(err).NotTo(HaveOccurred())
+		deployDir = filepath.Join(tmp, "deploy")
+		outputDir = filepath.Join(tmp, "packagemanifests")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		for name, content := range map[string]string{
+			"manager.yaml": deployment,
+			"crd.yaml":     crd,
+			"service.yaml": service,
+		} {
+			Expect(ioutil.WriteFile(filepath.Join(deployDir, name), []byte(content), 0644)).To(Succeed())
+		}
+		c = packagemanifestsCmd{
+			packageName:      "memcached-operator",
+			version:          "0.1.0",
+			channelName:      "alpha",
+			inputDir:         outputDir,
+			outputDir:        outputDir,
+			deployDir:        deployDir,
+			crdsDir:          deployDir,
+			updateObjects:    true,
+			preserveComments: true,
+			quiet:            true,
+			generator:        genpkg.NewGenerator(),
+		}
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	readOutput := func(fileName string) string {
+		b, err := ioutil.ReadFile(filepath.Join(outputDir, "0.1.0", fileName))
+		Expect(err).NotTo(HaveOccurred())
+		return string(b)
+	}
+
+	It("copies the source file of an unchanged CRD, keeping its comment header", func() {
+		Expect(c.run()).To(Succeed())
+		Expect(readOutput("cache.example.com_memcacheds.yaml")).To(Equal(crd))
+		Expect(readOutput("metrics-service_v1_service.yaml")).To(Equal(service))
+	})
+	It("re-serializes modified objects", func() {
+		c.labels = map[string]string{"app.kubernetes.io/part-of": "memcached"}
+		Expect(c.run()).To(Succeed())
+		out := readOutput("cache.example.com_memcacheds.yaml")
+		Expect(out).NotTo(ContainSubstring("Copyright"))
+		Expect(out).To(ContainSubstring("app.kubernetes.io/part-of: memcached"))
+	})
+	It("re-serializes all objects if not set", func() {
+		c.preserveComments = false
+		Expect(c.run()).To(Succeed())
+		Expect(readOutput("cache.example.com_memcacheds.yaml")).NotTo(ContainSubstring("Copyright"))
+	})
+})
@@ -0,0 +1,87 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+const presetCommunityOperators = "community-operators"
+
+// presets are named sets of flag values, keyed by flag name. A preset only sets flags not set on
+// the command line, so any of its values can be overridden.
+var presets = map[string]map[string]string{
+	// Conventions of the community-operators catalog, in which each package is written to a directory
+	// named after the package and CSVs must set the annotations OperatorHub displays. Channels are
+	// always sorted by name, so no flag is needed for that convention.
+	presetCommunityOperators: {
+		"check-package-dir":  "true",
+		"require-annotation": "capabilities,categories,containerImage,description,repository",
+	},
+}
+
+// presetNames returns the names of all presets, sorted.
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPreset sets each flag in fs from the preset named name, unless that flag was already set.
+func applyPreset(fs *pflag.FlagSet, name string) error {
+	if name == "" {
+		return nil
+	}
+	values, ok := presets[name]
+	if !ok {
+		return fmt.Errorf("unknown --preset %q, must be one of: %s", name, strings.Join(presetNames(), ", "))
+	}
+	flagNames := make([]string, 0, len(values))
+	for flagName := range values {
+		flagNames = append(flagNames, flagName)
+	}
+	sort.Strings(flagNames)
+	for _, flagName := range flagNames {
+		if fs.Changed(flagName) {
+			continue
+		}
+		if err := fs.Set(flagName, values[flagName]); err != nil {
+			return fmt.Errorf("error setting --%s from --preset %s: %v", flagName, name, err)
+		}
+	}
+	return nil
+}
+
+// validatePackageDir returns an error if c.outputDir is not named after c.packageName,
+// as catalogs laid out by package require.
+func (c packagemanifestsCmd) validatePackageDir() error {
+	dir, err := filepath.Abs(c.outputDir)
+	if err != nil {
+		return err
+	}
+	if filepath.Base(dir) != c.packageName {
+		return fmt.Errorf("--output-dir %s must be named after package %s if --check-package-dir is set",
+			c.outputDir, c.packageName)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+)
+
+var _ = Describe("Applying a preset", func() {
+	var (
+		c  *packagemanifestsCmd
+		fs *pflag.FlagSet
+	)
+	BeforeEach(func() {
+		c = &packagemanifestsCmd{}
+		fs = pflag.NewFlagSet("packagemanifests", pflag.ContinueOnError)
+		c.addFlagsTo(fs)
+	})
+
+	It("does nothing if no preset is set", func() {
+		Expect(applyPreset(fs, "")).To(Succeed())
+		Expect(c.checkPackageDir).To(BeFalse())
+		Expect(c.requiredAnnotations).To(BeEmpty())
+	})
+	It("sets the flags of the community-operators preset", func() {
+		Expect(fs.Parse([]string{"--preset", "community-operators"})).To(Succeed())
+		Expect(applyPreset(fs, c.preset)).To(Succeed())
+		Expect(c.checkPackageDir).To(BeTrue())
+		Expect(c.requiredAnnotations).To(Equal([]string{
+			"capabilities", "categories", "containerImage", "description", "repository",
+		}))
+	})
+	It("keeps flags set on the command line", func() {
+		Expect(fs.Parse([]string{
+			"--preset", "community-operators", "--check-package-dir=false", "--require-annotation", "support",
+		})).To(Succeed())
+		Expect(applyPreset(fs, c.preset)).To(Succeed())
+		Expect(c.checkPackageDir).To(BeFalse())
+		Expect(c.requiredAnnotations).To(Equal([]string{"support"}))
+	})
+	It("fails for an unknown preset", func() {
+		Expect(applyPreset(fs, "foo")).To(MatchError(`unknown --preset "foo", must be one of: community-operators`))
+	})
+
+	Describe("validatePackageDir", func() {
+		It("succeeds if the output directory is named after the package", func() {
+			c.outputDir, c.packageName = filepath.Join("operators", "memcached-operator"), "memcached-operator"
+			Expect(c.validatePackageDir()).To(Succeed())
+		})
+		It("fails if the output directory is not named after the package", func() {
+			c.outputDir, c.packageName = "packagemanifests", "memcached-operator"
+			Expect(c.validatePackageDir()).To(MatchError("--output-dir packagemanifests must be named after " +
+				"package memcached-operator if --check-package-dir is set"))
+		})
+	})
+})
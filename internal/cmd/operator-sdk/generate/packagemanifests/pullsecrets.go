@@ -0,0 +1,64 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+// validateImagePullSecrets returns an error if a name in names, set by --image-pull-secret,
+// is not a valid Secret name.
+func validateImagePullSecrets(names []string) error {
+	for _, name := range names {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+			return fmt.Errorf("--image-pull-secret %q is invalid: %s", name, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// setImagePullSecrets adds a reference to each Secret in names to the image pull secrets of every
+// collected Deployment's pods, so the CSV's install strategy can pull from private registries.
+// Secrets a Deployment already references are not added again.
+func setImagePullSecrets(col *collector.Manifests, names []string) {
+	for i := range col.Deployments {
+		dep := &col.Deployments[i]
+		podSpec := &dep.Spec.Template.Spec
+		for _, name := range names {
+			if hasImagePullSecret(podSpec.ImagePullSecrets, name) {
+				continue
+			}
+			log.Debugf("Adding image pull secret %q to Deployment %q", name, dep.GetName())
+			podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, corev1.LocalObjectReference{Name: name})
+		}
+	}
+}
+
+// hasImagePullSecret returns true if refs references the Secret name.
+func hasImagePullSecret(refs []corev1.LocalObjectReference, name string) bool {
+	for _, ref := range refs {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
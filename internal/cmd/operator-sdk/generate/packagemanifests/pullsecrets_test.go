@@ -0,0 +1,94 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+)
+
+var _ = Describe("Setting image pull secrets", func() {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      imagePullSecrets:
+      - name: registry-credentials
+      containers:
+      - image: registry.example.com/memcached-operator:v0.0.1
+        name: manager
+`
+
+	var tmp, outputDir string
+	var c packagemanifestsCmd
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-pullsecrets-")
+		Expect(err).NotTo(HaveOccurred())
+		deployDir := filepath.Join(tmp, "deploy")
+		outputDir = filepath.Join(tmp, "packagemanifests")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "manager.yaml"), []byte(deployment), 0644)).To(Succeed())
+		c = packagemanifestsCmd{
+			packageName: "memcached-operator",
+			version:     "0.1.0",
+			channelName: "alpha",
+			inputDir:    outputDir,
+			outputDir:   outputDir,
+			deployDir:   deployDir,
+			quiet:       true,
+			generator:   genpkg.NewGenerator(),
+		}
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("adds each pull secret to the CSV install strategy Deployment once", func() {
+		c.imagePullSecrets = []string{"registry-credentials", "mirror-credentials", "mirror-credentials"}
+		Expect(c.run()).To(Succeed())
+
+		csvs, err := validation.ReadDirCSVs(filepath.Join(outputDir, "0.1.0"))
+		Expect(err).NotTo(HaveOccurred())
+		deps := csvs["memcached-operator.clusterserviceversion.yaml"].Spec.InstallStrategy.StrategySpec.DeploymentSpecs
+		Expect(deps).To(HaveLen(1))
+		Expect(deps[0].Spec.Template.Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{
+			{Name: "registry-credentials"},
+			{Name: "mirror-credentials"},
+		}))
+	})
+	It("fails to validate an invalid Secret name", func() {
+		Expect(validateImagePullSecrets([]string{"registry-credentials"})).To(Succeed())
+		Expect(validateImagePullSecrets([]string{"Registry_Credentials"})).To(
+			MatchError(ContainSubstring(`--image-pull-secret "Registry_Credentials" is invalid`)))
+	})
+})
@@ -0,0 +1,196 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/distribution/reference"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
+	sdkversion "github.com/operator-framework/operator-sdk/internal/version"
+)
+
+// sbom is a CycloneDX software bill of materials, of which only the fields --sbom writes are declared.
+type sbom struct {
+	BOMFormat   string          `json:"bomFormat"`
+	SpecVersion string          `json:"specVersion"`
+	Version     int             `json:"version"`
+	Metadata    sbomMetadata    `json:"metadata"`
+	Components  []sbomComponent `json:"components"`
+}
+
+type sbomMetadata struct {
+	Tools     []sbomTool    `json:"tools"`
+	Component sbomComponent `json:"component"`
+}
+
+type sbomTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type sbomComponent struct {
+	Type    string     `json:"type"`
+	Name    string     `json:"name"`
+	Version string     `json:"version,omitempty"`
+	Hashes  []sbomHash `json:"hashes,omitempty"`
+}
+
+type sbomHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// sbomImages returns the images of csv's install strategy deployments, its related images, and the
+// images of extraDeps, sorted and without duplicates.
+func sbomImages(csv *operatorsv1alpha1.ClusterServiceVersion, extraDeps []appsv1.Deployment) []string {
+	seen := make(map[string]struct{})
+	addPod := func(podSpec corev1.PodSpec) {
+		for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+			for _, container := range containers {
+				seen[container.Image] = struct{}{}
+			}
+		}
+	}
+	if csv != nil {
+		for _, depSpec := range csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+			addPod(depSpec.Spec.Template.Spec)
+		}
+		for _, relatedImage := range csv.Spec.RelatedImages {
+			seen[relatedImage.Image] = struct{}{}
+		}
+	}
+	for _, dep := range extraDeps {
+		addPod(dep.Spec.Template.Spec)
+	}
+	delete(seen, "")
+
+	images := make([]string, 0, len(seen))
+	for image := range seen {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// imageComponent returns image as a container component, whose version is its digest or tag.
+func imageComponent(image string) sbomComponent {
+	component := sbomComponent{Type: "container", Name: image}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return component
+	}
+	component.Name = named.Name()
+	if digested, isDigested := named.(reference.Digested); isDigested {
+		component.Version = digested.Digest().String()
+	} else if tagged, isTagged := named.(reference.Tagged); isTagged {
+		component.Version = tagged.Tag()
+	}
+	return component
+}
+
+// sbomFiles returns the paths, relative to c.outputDir, of the files generated for the current version:
+// the package manifest unless --csv-only is set, the version directory's files, and the bundle
+// Dockerfile and metadata in --dockerfile-dir if --gen-dockerfile is set.
+func (c packagemanifestsCmd) sbomFiles() ([]string, error) {
+	var paths []string
+	if !c.csvOnly {
+		paths = append(paths, c.packageName+".package.yaml")
+	}
+	files, err := c.readVersionFiles()
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(c.outputDir, c.versionDir())
+	if err != nil {
+		return nil, err
+	}
+	for name := range files {
+		paths = append(paths, filepath.Join(rel, name))
+	}
+	if c.genDockerfile {
+		outputDir, err := filepath.Abs(c.outputDir)
+		if err != nil {
+			return nil, err
+		}
+		dockerfileDir, err := filepath.Abs(c.bundleDockerfileDir())
+		if err != nil {
+			return nil, err
+		}
+		if rel, err = filepath.Rel(outputDir, dockerfileDir); err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.Join(rel, bundle.DockerFile),
+			filepath.Join(rel, bundle.MetadataDir, bundle.AnnotationsFile))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// writeSBOM writes a CycloneDX SBOM to --sbom listing images referenced by csv and extraDeps,
+// and the files generated for the current version with their SHA-256 hashes.
+func (c packagemanifestsCmd) writeSBOM(csv *operatorsv1alpha1.ClusterServiceVersion, extraDeps []appsv1.Deployment) error {
+	doc := sbom{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata: sbomMetadata{
+			Tools:     []sbomTool{{Name: "operator-sdk", Version: sdkversion.GitVersion}},
+			Component: sbomComponent{Type: "application", Name: c.packageName, Version: c.version},
+		},
+		Components: []sbomComponent{},
+	}
+	for _, image := range sbomImages(csv, extraDeps) {
+		doc.Components = append(doc.Components, imageComponent(image))
+	}
+
+	paths, err := c.sbomFiles()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(filepath.Join(c.outputDir, path))
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(b)
+		doc.Components = append(doc.Components, sbomComponent{
+			Type:   "file",
+			Name:   filepath.ToSlash(path),
+			Hashes: []sbomHash{{Alg: "SHA-256", Content: hex.EncodeToString(sum[:])}},
+		})
+	}
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling SBOM: %v", err)
+	}
+	_, fileMode, err := c.fileModes()
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(c.sbomFile, append(b, '\n'), fileMode)
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Writing an SBOM", func() {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	podSpec := func(images ...string) corev1.PodSpec {
+		spec := corev1.PodSpec{}
+		for _, image := range images {
+			spec.Containers = append(spec.Containers, corev1.Container{Name: "c", Image: image})
+		}
+		return spec
+	}
+	csv := &operatorsv1alpha1.ClusterServiceVersion{}
+	csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = []operatorsv1alpha1.StrategyDeploymentSpec{{}}
+	csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[0].Spec.Template.Spec = podSpec(
+		"quay.io/example/operator:v0.0.1", "gcr.io/kubebuilder/kube-rbac-proxy:v0.8.0")
+	csv.Spec.RelatedImages = []operatorsv1alpha1.RelatedImage{
+		{Name: "memcached", Image: "docker.io/library/memcached@" + digest},
+		{Name: "operator", Image: "quay.io/example/operator:v0.0.1"},
+	}
+	extraDep := appsv1.Deployment{}
+	extraDep.Spec.Template.Spec = podSpec("quay.io/example/webhook:v0.0.1")
+
+	It("lists images of deployments and related images once", func() {
+		Expect(sbomImages(csv, []appsv1.Deployment{extraDep})).To(Equal([]string{
+			"docker.io/library/memcached@" + digest,
+			"gcr.io/kubebuilder/kube-rbac-proxy:v0.8.0",
+			"quay.io/example/operator:v0.0.1",
+			"quay.io/example/webhook:v0.0.1",
+		}))
+	})
+
+	It("versions image components by digest or tag", func() {
+		Expect(imageComponent("memcached@" + digest)).To(Equal(
+			sbomComponent{Type: "container", Name: "docker.io/library/memcached", Version: digest}))
+		Expect(imageComponent("quay.io/example/operator:v0.0.1")).To(Equal(
+			sbomComponent{Type: "container", Name: "quay.io/example/operator", Version: "v0.0.1"}))
+	})
+
+	It("writes images and generated files with their hashes", func() {
+		outputDir, err := ioutil.TempDir("", "packagemanifests-sbom-")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(outputDir)
+		Expect(os.MkdirAll(filepath.Join(outputDir, "0.0.1"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(outputDir, "memcached-operator.package.yaml"), []byte("a"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(outputDir, "0.0.1", "memcached-operator.clusterserviceversion.yaml"),
+			[]byte("b"), 0644)).To(Succeed())
+
+		c := packagemanifestsCmd{
+			outputDir:   outputDir,
+			version:     "0.0.1",
+			packageName: "memcached-operator",
+			sbomFile:    filepath.Join(outputDir, "sbom.json"),
+		}
+		Expect(c.writeSBOM(csv, nil)).To(Succeed())
+
+		b, err := ioutil.ReadFile(c.sbomFile)
+		Expect(err).NotTo(HaveOccurred())
+		doc := sbom{}
+		Expect(json.Unmarshal(b, &doc)).To(Succeed())
+		Expect(doc.BOMFormat).To(Equal("CycloneDX"))
+		Expect(doc.Metadata.Component).To(Equal(
+			sbomComponent{Type: "application", Name: "memcached-operator", Version: "0.0.1"}))
+		Expect(doc.Components).To(HaveLen(5))
+		Expect(doc.Components[3:]).To(Equal([]sbomComponent{
+			{Type: "file", Name: "0.0.1/memcached-operator.clusterserviceversion.yaml", Hashes: []sbomHash{
+				{Alg: "SHA-256", Content: "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"}}},
+			{Type: "file", Name: "memcached-operator.package.yaml", Hashes: []sbomHash{
+				{Alg: "SHA-256", Content: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}}},
+		}))
+	})
+})
@@ -0,0 +1,95 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// schemaPathSep separates the fields of a violation's context, since field names may contain "." or "/".
+const schemaPathSep = "\x00"
+
+// schemaObject is a written object to validate against a schema, and the path it was written to.
+type schemaObject struct {
+	path string
+	obj  client.Object
+}
+
+// readSchema reads the JSON schema at --schema, if set. References in the schema are resolved relative to it.
+func (c packagemanifestsCmd) readSchema() (*gojsonschema.Schema, error) {
+	if c.schemaFile == "" {
+		return nil, nil
+	}
+	path := c.schemaFile
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading --schema: %v", err)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(absPath)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading --schema %s: %v", path, err)
+	}
+	return schema, nil
+}
+
+// schemaPath returns the path obj was written to as fileName, or its kind and name if written to stdout.
+func (c packagemanifestsCmd) schemaPath(obj client.Object, fileName string) string {
+	if c.stdout {
+		return obj.GetObjectKind().GroupVersionKind().Kind + "/" + obj.GetName()
+	}
+	return filepath.Join(c.versionDir(), fileName)
+}
+
+// validateSchema validates each of objs against schema, returning an error listing the path and
+// JSON pointer of each violation, ex. "basic.clusterserviceversion.yaml#/metadata/name".
+func validateSchema(schema *gojsonschema.Schema, objs []schemaObject) error {
+	var msgs []string
+	for _, o := range objs {
+		result, err := schema.Validate(gojsonschema.NewGoLoader(o.obj))
+		if err != nil {
+			return fmt.Errorf("error validating %s against --schema: %v", o.path, err)
+		}
+		for _, resultErr := range result.Errors() {
+			msgs = append(msgs, fmt.Sprintf("%s#%s: %s", o.path, jsonPointer(resultErr.Context()), resultErr.Description()))
+		}
+	}
+	if len(msgs) != 0 {
+		return fmt.Errorf("manifests do not match --schema:\n  - %s", strings.Join(msgs, "\n  - "))
+	}
+	return nil
+}
+
+// jsonPointer returns the RFC 6901 JSON pointer of a violation's context, ex. "(root).metadata.name"
+// is "/metadata/name". The root's pointer is empty.
+func jsonPointer(ctx *gojsonschema.JsonContext) string {
+	if ctx == nil {
+		return ""
+	}
+	// The first field is always the root.
+	fields := strings.Split(ctx.String(schemaPathSep), schemaPathSep)[1:]
+	if len(fields) == 0 {
+		return ""
+	}
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	for i, field := range fields {
+		fields[i] = escaper.Replace(field)
+	}
+	return "/" + strings.Join(fields, "/")
+}
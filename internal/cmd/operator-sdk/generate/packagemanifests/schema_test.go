@@ -0,0 +1,158 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/xeipuuv/gojsonschema"
+	corev1 "k8s.io/api/core/v1"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+)
+
+var _ = Describe("Validating manifests against --schema", func() {
+	// Require CSVs to set an owner annotation, and forbid images from other registries.
+	const schemaFile = `{
+  "if": {"properties": {"kind": {"const": "ClusterServiceVersion"}}},
+  "then": {
+    "properties": {
+      "metadata": {
+        "properties": {"annotations": {"required": ["example.com/owner"]}},
+        "required": ["annotations"]
+      }
+    }
+  },
+  "properties": {
+    "metadata": {
+      "properties": {
+        "annotations": {"additionalProperties": {"type": "string", "not": {"pattern": "^quay.io/"}}}
+      }
+    }
+  }
+}`
+
+	var (
+		c      packagemanifestsCmd
+		tmp    string
+		schema *gojsonschema.Schema
+		csv    *operatorsv1alpha1.ClusterServiceVersion
+		cm     *corev1.ConfigMap
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-schema-")
+		Expect(err).NotTo(HaveOccurred())
+		c = packagemanifestsCmd{schemaFile: filepath.Join(tmp, "schema.json"), outputDir: "packagemanifests", version: "0.0.1"}
+		Expect(ioutil.WriteFile(c.schemaFile, []byte(schemaFile), 0644)).To(Succeed())
+		schema, err = c.readSchema()
+		Expect(err).NotTo(HaveOccurred())
+
+		csv = &operatorsv1alpha1.ClusterServiceVersion{}
+		csv.SetGroupVersionKind(operatorsv1alpha1.SchemeGroupVersion.WithKind("ClusterServiceVersion"))
+		csv.SetName("memcached-operator.v0.0.1")
+		csv.SetAnnotations(map[string]string{"example.com/owner": "team-a"})
+		cm = &corev1.ConfigMap{}
+		cm.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+		cm.SetName("config")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	objects := func() []schemaObject {
+		return []schemaObject{
+			{path: c.schemaPath(csv, "memcached-operator.clusterserviceversion.yaml"), obj: csv},
+			{path: c.schemaPath(cm, "config_v1_configmap.yaml"), obj: cm},
+		}
+	}
+
+	It("succeeds if every manifest matches", func() {
+		Expect(validateSchema(schema, objects())).To(Succeed())
+	})
+	It("reports the path and JSON pointer of each violation", func() {
+		csv.SetAnnotations(map[string]string{"containerImage": "quay.io/example/memcached-operator:v0.0.1"})
+		cm.SetAnnotations(map[string]string{"example.com/base": "quay.io/example/base"})
+		err := validateSchema(schema, objects())
+		Expect(err).To(MatchError(And(
+			HavePrefix("manifests do not match --schema:\n"),
+			ContainSubstring("  - packagemanifests/0.0.1/memcached-operator.clusterserviceversion.yaml#/metadata/annotations: "+
+				"example.com/owner is required"),
+			ContainSubstring("  - packagemanifests/0.0.1/memcached-operator.clusterserviceversion.yaml#/metadata/annotations/containerImage: "),
+			// "/" in a field name is escaped.
+			ContainSubstring("  - packagemanifests/0.0.1/config_v1_configmap.yaml#/metadata/annotations/example.com~1base: "),
+		)))
+	})
+	It("names manifests written to stdout by kind and name", func() {
+		c.stdout = true
+		cm.SetAnnotations(map[string]string{"example.com/base": "quay.io/example/base"})
+		err := validateSchema(schema, objects())
+		Expect(err).To(MatchError(ContainSubstring("  - ConfigMap/config#/metadata/annotations/example.com~1base: ")))
+	})
+	It("writes no manifest to the version directory if any does not match", func() {
+		deployDir := filepath.Join(tmp, "deploy")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "manifests.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`), 0644)).To(Succeed())
+		c.packageName, c.channelName, c.deployDir = "memcached-operator", "alpha", deployDir
+		c.outputDir, c.inputDir = filepath.Join(tmp, "packagemanifests"), filepath.Join(tmp, "packagemanifests")
+		c.updateObjects, c.quiet, c.generator = true, true, genpkg.NewGenerator()
+
+		err := c.run()
+		Expect(err).To(MatchError(ContainSubstring(filepath.Join(c.outputDir, "0.0.1", "memcached-operator.clusterserviceversion.yaml") +
+			"#/metadata/annotations: example.com/owner is required")))
+		Expect(exitCode(err)).To(Equal(exitValidation))
+		Expect(filepath.Join(c.outputDir, "0.0.1")).NotTo(BeADirectory())
+		Expect(filepath.Join(c.outputDir, "memcached-operator.package.yaml")).NotTo(BeAnExistingFile())
+
+		c.csvAnnotations = map[string]string{"example.com/owner": "team-a"}
+		Expect(c.run()).To(Succeed())
+		Expect(filepath.Join(c.outputDir, "0.0.1", "memcached-operator.clusterserviceversion.yaml")).To(BeAnExistingFile())
+		Expect(filepath.Join(c.outputDir, "0.0.1", "config_v1_configmap.yaml")).To(BeAnExistingFile())
+		Expect(filepath.Join(c.outputDir, "memcached-operator.package.yaml")).To(BeAnExistingFile())
+	})
+	It("fails on a schema that cannot be read", func() {
+		Expect(ioutil.WriteFile(c.schemaFile, []byte(`{"type": 1}`), 0644)).To(Succeed())
+		_, err := c.readSchema()
+		Expect(err).To(MatchError(HavePrefix("error reading --schema " + c.schemaFile)))
+	})
+})
@@ -0,0 +1,187 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+// runSelfCheck generates the package into a temporary directory, then regenerates it from the same
+// inputs on top of the first run's output, and returns an error describing every file and field
+// that differs between the runs. A regeneration with unchanged inputs should be a no-op.
+func (c packagemanifestsCmd) runSelfCheck() error {
+	tmp, err := ioutil.TempDir("", "packagemanifests-self-check-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmp); err != nil {
+			log.Warnf("Error removing self-check directory %s: %v", tmp, err)
+		}
+	}()
+
+	// Only the package is checked, so nothing is written outside of tmp.
+	check := c
+	check.outputDir = tmp
+	check.selfCheck = false
+	check.quiet = true
+	check.skipIfUnchanged = false
+	check.writeBase = false
+	check.ociOut = ""
+	check.sbomFile = ""
+	check.genDockerfile = false
+	// Prior versions are not in tmp, so cannot be deprecated.
+	check.deprecateVersions = nil
+	// Both runs decode their inputs, so nondeterministic decoding is not hidden by cached manifests.
+	check.cache = false
+	generate := packagemanifestsCmd.run
+	if len(c.versions) != 0 {
+		generate = packagemanifestsCmd.runVersions
+	}
+
+	if err := generate(check); err != nil {
+		return fmt.Errorf("error generating package: %w", err)
+	}
+	first, err := readFiles(tmp)
+	if err != nil {
+		return err
+	}
+	if err := generate(check); err != nil {
+		return fmt.Errorf("error regenerating package: %w", err)
+	}
+	second, err := readFiles(tmp)
+	if err != nil {
+		return err
+	}
+
+	if diffs := diffFiles(first, second); len(diffs) != 0 {
+		return withExitCode(exitValidation, fmt.Errorf("regenerating package %s with unchanged inputs changed %d file(s):\n  - %s",
+			c.packageName, len(diffs), strings.Join(diffs, "\n  - ")))
+	}
+
+	c.println("Regenerating package", c.packageName, "with unchanged inputs is a no-op")
+	return nil
+}
+
+// readFiles returns the contents of all files in dir keyed by their path relative to dir.
+func readFiles(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = b
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading files in %s: %v", dir, err)
+	}
+	return files, nil
+}
+
+// diffFiles returns a description of each file added, removed, or changed in second compared to first,
+// sorted by file path. Changed YAML files are described by the fields that differ.
+func diffFiles(first, second map[string][]byte) (diffs []string) {
+	paths := make(map[string]struct{}, len(first))
+	for path := range first {
+		paths[path] = struct{}{}
+	}
+	for path := range second {
+		paths[path] = struct{}{}
+	}
+	for _, path := range sortedKeys(paths) {
+		a, inFirst := first[path]
+		b, inSecond := second[path]
+		switch {
+		case !inFirst:
+			diffs = append(diffs, path+": added")
+		case !inSecond:
+			diffs = append(diffs, path+": removed")
+		case !bytes.Equal(a, b):
+			var objA, objB interface{}
+			if yaml.Unmarshal(a, &objA) != nil || yaml.Unmarshal(b, &objB) != nil {
+				diffs = append(diffs, path+": changed")
+				continue
+			}
+			fields := diffFields("", objA, objB)
+			if len(fields) == 0 {
+				// Only formatting or key order changed.
+				diffs = append(diffs, path+": changed formatting")
+				continue
+			}
+			diffs = append(diffs, fmt.Sprintf("%s: changed %s", path, strings.Join(fields, ", ")))
+		}
+	}
+	return diffs
+}
+
+// diffFields returns the paths, ex. "metadata.annotations.createdAt", of the fields that differ
+// between a and b, values unmarshaled from YAML. prefix is the path of a and b.
+func diffFields(prefix string, a, b interface{}) (fields []string) {
+	mapA, isMapA := a.(map[string]interface{})
+	mapB, isMapB := b.(map[string]interface{})
+	if isMapA && isMapB {
+		keys := make(map[string]struct{}, len(mapA))
+		for k := range mapA {
+			keys[k] = struct{}{}
+		}
+		for k := range mapB {
+			keys[k] = struct{}{}
+		}
+		for _, k := range sortedKeys(keys) {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			fields = append(fields, diffFields(path, mapA[k], mapB[k])...)
+		}
+		return fields
+	}
+
+	listA, isListA := a.([]interface{})
+	listB, isListB := b.([]interface{})
+	if isListA && isListB && len(listA) == len(listB) {
+		for i := range listA {
+			fields = append(fields, diffFields(prefix+"["+strconv.Itoa(i)+"]", listA[i], listB[i])...)
+		}
+		return fields
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		if prefix == "" {
+			prefix = "<root>"
+		}
+		fields = append(fields, prefix)
+	}
+	return fields
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checking regeneration is a no-op", func() {
+	Describe("diffFiles", func() {
+		const csv = `apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  annotations:
+    createdAt: "2021-06-01T00:00:00Z"
+  name: memcached-operator.v0.0.1
+spec:
+  keywords:
+  - memcached
+  - cache
+`
+
+		It("returns nothing for identical files", func() {
+			files := map[string][]byte{"0.0.1/memcached-operator.clusterserviceversion.yaml": []byte(csv)}
+			Expect(diffFiles(files, files)).To(BeEmpty())
+		})
+		It("describes added, removed, and changed files", func() {
+			first := map[string][]byte{
+				"0.0.1/memcached-operator.clusterserviceversion.yaml": []byte(csv),
+				"0.0.1/memcached-operator-config_v1_configmap.yaml":   []byte("kind: ConfigMap\n"),
+				"memcached-operator.package.yaml":                     []byte("packageName: memcached-operator\n"),
+			}
+			second := map[string][]byte{
+				"0.0.1/memcached-operator.clusterserviceversion.yaml": []byte(`apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  annotations:
+    createdAt: "2021-06-02T00:00:00Z"
+  name: memcached-operator.v0.0.1
+spec:
+  keywords:
+  - cache
+  - memcached
+`),
+				"0.0.1/dup0_memcached-operator-config_v1_configmap.yaml": []byte("kind: ConfigMap\n"),
+				"memcached-operator.package.yaml":                        []byte("{packageName: memcached-operator}\n"),
+			}
+			Expect(diffFiles(first, second)).To(Equal([]string{
+				"0.0.1/dup0_memcached-operator-config_v1_configmap.yaml: added",
+				"0.0.1/memcached-operator-config_v1_configmap.yaml: removed",
+				"0.0.1/memcached-operator.clusterserviceversion.yaml: changed " +
+					"metadata.annotations.createdAt, spec.keywords[0], spec.keywords[1]",
+				"memcached-operator.package.yaml: changed formatting",
+			}))
+		})
+	})
+
+	Describe("diffFields", func() {
+		It("returns the paths of added, removed, and changed fields", func() {
+			a := map[string]interface{}{"a": "1", "b": []interface{}{"x"}, "c": "3"}
+			b := map[string]interface{}{"a": "1", "b": []interface{}{"x", "y"}, "d": "4"}
+			Expect(diffFields("", a, b)).To(Equal([]string{"b", "c", "d"}))
+		})
+		It("returns the root if values are not objects", func() {
+			Expect(diffFields("", "a", "b")).To(Equal([]string{"<root>"}))
+		})
+	})
+})
@@ -0,0 +1,100 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
+)
+
+// runSingleFile generates the package into a temporary directory, then writes every generated
+// file to --single-file as one multi-document YAML stream, ex. for a GitOps tool to apply.
+func (c packagemanifestsCmd) runSingleFile() error {
+	tmp, err := ioutil.TempDir("", "packagemanifests-single-file-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmp); err != nil {
+			log.Warnf("Error removing directory %s: %v", tmp, err)
+		}
+	}()
+
+	gen := c
+	gen.outputDir = tmp
+	gen.singleFile = ""
+	gen.quiet = true
+	if err := gen.run(); err != nil {
+		return err
+	}
+	files, err := readFiles(tmp)
+	if err != nil {
+		return err
+	}
+
+	_, fileMode, err := c.fileModes()
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteFile(c.singleFile, joinDocuments(files), fileMode); err != nil {
+		return fmt.Errorf("error writing %s: %v", c.singleFile, err)
+	}
+
+	c.println("Package manifests written to", c.singleFile)
+	return nil
+}
+
+// joinDocuments returns the YAML files in files, keyed by path, as one multi-document stream:
+// the package manifest first, then CSVs, then all other files, each group sorted by path.
+func joinDocuments(files map[string][]byte) []byte {
+	rank := func(path string) int {
+		switch {
+		case strings.HasSuffix(path, ".package.yaml"):
+			return 0
+		case strings.HasSuffix(path, ".clusterserviceversion.yaml"):
+			return 1
+		}
+		return 2
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		if filepath.Ext(path) == ".yaml" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if ri, rj := rank(paths[i]), rank(paths[j]); ri != rj {
+			return ri < rj
+		}
+		return paths[i] < paths[j]
+	})
+
+	buf := &bytes.Buffer{}
+	for _, path := range paths {
+		buf.WriteString("---\n")
+		buf.Write(bytes.TrimSpace(files[path]))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
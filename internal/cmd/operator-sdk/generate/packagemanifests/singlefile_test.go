@@ -0,0 +1,77 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("Writing a package to a single file", func() {
+	Describe("joinDocuments", func() {
+		files := map[string][]byte{
+			"memcached-operator.package.yaml": []byte("packageName: memcached-operator\n"),
+			"0.0.1/memcached-operator.clusterserviceversion.yaml": []byte(`apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  name: memcached-operator.v0.0.1
+`),
+			"0.0.1/cache.example.com_memcacheds.yaml": []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+`),
+			"0.0.1/memcached-operator-config_v1_configmap.yaml": []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: memcached-operator-config
+`),
+			"0.0.1/README.md": []byte("# Not a manifest\n"),
+		}
+
+		It("joins all YAML files with the package manifest and CSV first", func() {
+			Expect(string(joinDocuments(files))).To(Equal(`---
+packageName: memcached-operator
+---
+apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  name: memcached-operator.v0.0.1
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: memcached-operator-config
+`))
+		})
+		It("writes every object so it can be collected again", func() {
+			col := &collector.Manifests{}
+			Expect(col.UpdateFromReader(bytes.NewReader(joinDocuments(files)))).To(Succeed())
+			Expect(col.ClusterServiceVersions).To(HaveLen(1))
+			Expect(col.V1CustomResourceDefinitions).To(HaveLen(1))
+			Expect(col.Others).To(HaveLen(1))
+			Expect(col.Others[0].GetName()).To(Equal("memcached-operator-config"))
+		})
+	})
+})
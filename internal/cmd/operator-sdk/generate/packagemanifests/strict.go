@@ -0,0 +1,155 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// strictly returns a func running gen with c, or if --strict is set, one that fails if gen logs any warning.
+// Warnings do not stop gen, so every warning, followed by gen's error if any, is reported in one list.
+// With --strict, gen first runs in a scratch copy of c's output directory, and only runs with c if it
+// found no problems, so a failed run writes nothing.
+func (c packagemanifestsCmd) strictly(gen func(packagemanifestsCmd) error) func() error {
+	if !c.strict {
+		return func() error { return gen(c) }
+	}
+	return func() error {
+		problems, err := captureWarnings(func() error { return c.runScratch(gen) })
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		if len(problems) == 0 {
+			return gen(c)
+		}
+		// Warnings are failed checks, unless generation itself failed for another reason.
+		code := exitValidation
+		if err != nil {
+			code = exitCode(err)
+		}
+		return withExitCode(code, fmt.Errorf("--strict found %d problem(s):\n  - %s",
+			len(problems), strings.Join(problems, "\n  - ")))
+	}
+}
+
+// runScratch runs gen with a copy of c that writes to a temporary copy of c.outputDir, and writes
+// no files outside of it.
+func (c packagemanifestsCmd) runScratch(gen func(packagemanifestsCmd) error) error {
+	tmp, err := ioutil.TempDir("", "packagemanifests-strict-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmp); err != nil {
+			log.Warnf("Error removing directory %s: %v", tmp, err)
+		}
+	}()
+	outputDir := filepath.Join(tmp, "output")
+	if isDir(c.outputDir) {
+		if err := copyFiles(c.outputDir, outputDir); err != nil {
+			return err
+		}
+	}
+
+	scratch := c
+	scratch.outputDir = outputDir
+	scratch.quiet = true
+	scratch.stdout = false
+	if c.singleFile != "" {
+		scratch.singleFile = filepath.Join(tmp, filepath.Base(c.singleFile))
+	}
+	scratch.skipIfUnchanged = false
+	scratch.writeBase = false
+	scratch.ociOut = ""
+	scratch.sbomFile = ""
+	scratch.genDockerfile = false
+	return gen(scratch)
+}
+
+// captureWarnings runs gen and returns the message of each distinct warning logged while it ran,
+// in the order they were logged, and gen's error.
+func captureWarnings(gen func() error) ([]string, error) {
+	entries, err := captureLogs([]log.Level{log.WarnLevel}, gen)
+	messages := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		messages = append(messages, entry.message)
+	}
+	return messages, err
+}
+
+// captureLogs runs gen and returns each distinct entry logged at levels while it ran, in the order
+// they were logged, and gen's error. Entries are still logged as usual.
+func captureLogs(levels []log.Level, gen func() error) ([]logEntry, error) {
+	hook := &logHook{levels: levels}
+	logger := log.StandardLogger()
+	hooks := make(log.LevelHooks)
+	for level, levelHooks := range logger.Hooks {
+		hooks[level] = append([]log.Hook{}, levelHooks...)
+	}
+	hooks.Add(hook)
+	prevHooks := logger.ReplaceHooks(hooks)
+	err := gen()
+	logger.ReplaceHooks(prevHooks)
+	return hook.recorded(), err
+}
+
+// logEntry is the level and message of a logged entry.
+type logEntry struct {
+	level   log.Level
+	message string
+}
+
+// logHook is a logrus hook recording the level and message of each entry logged at levels.
+type logHook struct {
+	levels []log.Level
+
+	mu      sync.Mutex
+	entries []logEntry
+	seen    map[logEntry]struct{}
+}
+
+func (h *logHook) Levels() []log.Level {
+	return h.levels
+}
+
+func (h *logHook) Fire(entry *log.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Generating more than once, ex. with --self-check, logs the same entries again.
+	e := logEntry{level: entry.Level, message: entry.Message}
+	if _, seen := h.seen[e]; seen {
+		return nil
+	}
+	if h.seen == nil {
+		h.seen = make(map[logEntry]struct{})
+	}
+	h.seen[e] = struct{}{}
+	h.entries = append(h.entries, e)
+	return nil
+}
+
+// recorded returns the recorded entries in the order they were logged.
+func (h *logHook) recorded() []logEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]logEntry{}, h.entries...)
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Running in strict mode", func() {
+	var c packagemanifestsCmd
+
+	warn := func(packagemanifestsCmd) error {
+		log.Warn("CRD memcacheds.cache.example.com uses apiextensions.k8s.io/v1beta1, which is deprecated")
+		log.Warn("ClusterServiceVersion has no provider")
+		return nil
+	}
+
+	BeforeEach(func() {
+		c = packagemanifestsCmd{strict: true}
+	})
+
+	It("does not change generation unless --strict is set", func() {
+		c.strict = false
+		Expect(c.strictly(warn)()).To(Succeed())
+	})
+	It("succeeds if no warnings are logged", func() {
+		Expect(c.strictly(func(packagemanifestsCmd) error { return nil })()).To(Succeed())
+	})
+	It("reports every warning logged, once each", func() {
+		err := c.strictly(func(c packagemanifestsCmd) error {
+			_ = warn(c)
+			return warn(c)
+		})()
+		Expect(err).To(MatchError("--strict found 2 problem(s):\n" +
+			"  - CRD memcacheds.cache.example.com uses apiextensions.k8s.io/v1beta1, which is deprecated\n" +
+			"  - ClusterServiceVersion has no provider"))
+	})
+	It("reports generation's error after the warnings", func() {
+		err := c.strictly(func(c packagemanifestsCmd) error {
+			_ = warn(c)
+			return errors.New("no Deployments were collected")
+		})()
+		Expect(err).To(MatchError(HaveSuffix("has no provider\n  - no Deployments were collected")))
+	})
+	It("stops recording warnings once generation ends", func() {
+		hooks := len(log.StandardLogger().Hooks[log.WarnLevel])
+		Expect(c.strictly(warn)()).NotTo(Succeed())
+		Expect(log.StandardLogger().Hooks[log.WarnLevel]).To(HaveLen(hooks))
+	})
+
+	Context("writing files", func() {
+		var tmp, path string
+		write := func(c packagemanifestsCmd) error {
+			return ioutil.WriteFile(filepath.Join(c.outputDir, "written.yaml"), []byte("a: b\n"), 0644)
+		}
+
+		BeforeEach(func() {
+			var err error
+			tmp, err = ioutil.TempDir("", "packagemanifests-strict-")
+			Expect(err).NotTo(HaveOccurred())
+			c.outputDir = filepath.Join(tmp, "packagemanifests")
+			Expect(os.Mkdir(c.outputDir, 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(c.outputDir, "existing.yaml"), []byte("c: d\n"), 0644)).To(Succeed())
+			path = filepath.Join(c.outputDir, "written.yaml")
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(tmp)).To(Succeed())
+		})
+
+		It("writes nothing if a problem is found", func() {
+			Expect(c.strictly(func(c packagemanifestsCmd) error {
+				Expect(filepath.Join(c.outputDir, "existing.yaml")).To(BeAnExistingFile())
+				_ = warn(c)
+				return write(c)
+			})()).NotTo(Succeed())
+			Expect(path).NotTo(BeAnExistingFile())
+		})
+		It("writes to the output directory if no problem is found", func() {
+			Expect(c.strictly(write)()).To(Succeed())
+			Expect(path).To(BeAnExistingFile())
+		})
+	})
+
+	Context("with a missing --kustomize-dir", func() {
+		var tmp string
+		warnMissingBasesDir := func(c packagemanifestsCmd) error {
+			c.warnMissingBasesDir()
+			return nil
+		}
+
+		BeforeEach(func() {
+			var err error
+			tmp, err = ioutil.TempDir("", "packagemanifests-kustomize-dir-")
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(tmp)).To(Succeed())
+		})
+
+		It("fails if a --kustomize-dir that was set does not exist", func() {
+			c.kustomizeDir, c.kustomizeDirSet = filepath.Join(tmp, "config", "manfiests"), true
+			Expect(c.strictly(warnMissingBasesDir)()).To(MatchError("--strict found 1 problem(s):\n" +
+				"  - --kustomize-dir " + c.kustomizeDir + " does not exist, so no ClusterServiceVersion base was read"))
+		})
+		It("succeeds if the default --kustomize-dir does not exist", func() {
+			c.kustomizeDir = filepath.Join(tmp, "config", "manifests")
+			Expect(c.strictly(warnMissingBasesDir)()).To(Succeed())
+		})
+		It("fails if --kustomize-dir has no bases directory", func() {
+			c.kustomizeDir = tmp
+			Expect(c.strictly(warnMissingBasesDir)()).To(MatchError(ContainSubstring(
+				"--kustomize-dir " + tmp + " has no bases directory")))
+			Expect(os.Mkdir(filepath.Join(tmp, "bases"), 0755)).To(Succeed())
+			Expect(c.strictly(warnMissingBasesDir)()).To(Succeed())
+		})
+	})
+})
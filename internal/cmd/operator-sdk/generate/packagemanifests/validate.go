@@ -0,0 +1,158 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+)
+
+// runValidate prints the results of validating the package c would generate, and returns an error if
+// any is an error, or with --strict, if there are any results.
+func (c packagemanifestsCmd) runValidate() error {
+	results, err := c.validateResults()
+	if err != nil {
+		return err
+	}
+	if err := printResults(os.Stdout, results); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Severity == validation.SeverityError || c.strict {
+			failed++
+		}
+	}
+	if failed != 0 {
+		return withExitCode(exitValidation, fmt.Errorf("package %s version %s has %d problem(s)",
+			c.packageName, c.version, failed))
+	}
+	c.println("Package", c.packageName, "version", c.version, "is valid")
+	return nil
+}
+
+// validateResults returns the problems found by checks of the manifests and ClusterServiceVersion c would
+// generate, and of the package c would generate, which is generated into a temporary copy of c.outputDir.
+func (c packagemanifestsCmd) validateResults() ([]validation.Result, error) {
+	// Only problems are reported, so how inputs were collected is not printed.
+	quiet := c
+	quiet.quiet = true
+	col, _, err := quiet.collectInputs()
+	if err != nil {
+		return nil, err
+	}
+	var resolve imageResolver
+	if c.pinDigests {
+		resolve = newImageResolver(context.Background(), c.pinDigestsOffline)
+	}
+	csvGen, opts, err := c.csvGenerator(col, resolve, nil)
+	if err != nil {
+		return nil, err
+	}
+	cfg := validation.Config{
+		Collector:              col,
+		Generator:              csvGen,
+		Options:                opts,
+		RequireQualifiedImages: c.requireQualifiedImages,
+	}
+
+	tmp, err := ioutil.TempDir("", "packagemanifests-validate-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmp); err != nil {
+			log.Warnf("Error removing validation directory %s: %v", tmp, err)
+		}
+	}()
+	if isDir(c.outputDir) {
+		if err := copyFiles(c.outputDir, tmp); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the package is generated, so nothing is written outside of tmp.
+	check := c
+	check.outputDir = tmp
+	check.validateOnly = false
+	check.quiet = true
+	check.skipIfUnchanged = false
+	check.writeBase = false
+	check.ociOut = ""
+	check.sbomFile = ""
+	check.genDockerfile = false
+	// Images are checked in the collected manifests, so are reported once per container with their Deployment.
+	check.requireQualifiedImages = false
+	var genResults []validation.Result
+	var checkErr gencsv.CheckError
+	switch err := check.run(); {
+	case err == nil:
+		cfg.PackageDir = tmp
+	case exitCode(err) != exitValidation:
+		return nil, err
+	case !errors.As(err, &checkErr):
+		// Failed ClusterServiceVersion checks are found by Validate, but not failed checks of other objects.
+		genResults = append(genResults, validation.Result{Severity: validation.SeverityError, Message: err.Error()})
+	}
+
+	results, err := validation.Validate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return append(results, genResults...), nil
+}
+
+// copyFiles copies all files in src to the same paths relative to dst.
+func copyFiles(src, dst string) error {
+	files, err := readFiles(src)
+	if err != nil {
+		return err
+	}
+	for rel, b := range files {
+		path := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, b, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printResults writes results as a table to w.
+func printResults(w io.Writer, results []validation.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 8, 4, 4, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tOBJECT\tMESSAGE")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Severity, result.Object, result.Message)
+	}
+	return tw.Flush()
+}
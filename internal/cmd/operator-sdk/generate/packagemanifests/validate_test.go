@@ -0,0 +1,178 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	genpkg "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+	"github.com/operator-framework/operator-sdk/internal/generate/validation"
+)
+
+var _ = Describe("Validating package manifests", func() {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+`
+	const crd = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    listKind: MemcachedList
+    plural: memcacheds
+    singular: memcached
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+`
+
+	var tmp, deployDir, outputDir string
+	var c packagemanifestsCmd
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "packagemanifests-validate-")
+		Expect(err).NotTo(HaveOccurred())
+		deployDir = filepath.Join(tmp, "deploy")
+		outputDir = filepath.Join(tmp, "packagemanifests")
+		Expect(os.Mkdir(deployDir, 0755)).To(Succeed())
+		c = packagemanifestsCmd{
+			packageName:   "memcached-operator",
+			version:       "0.1.0",
+			channelName:   "alpha",
+			inputDir:      outputDir,
+			outputDir:     outputDir,
+			deployDir:     deployDir,
+			crdsDir:       deployDir,
+			updateObjects: true,
+			quiet:         true,
+			validateOnly:  true,
+			generator:     genpkg.NewGenerator(),
+		}
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	writeManifests := func(docs ...string) {
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "manifests.yaml"),
+			[]byte(strings.Join(docs, "---\n")), 0644)).To(Succeed())
+	}
+	errorMessages := func(results []validation.Result) (msgs []string) {
+		for _, result := range results {
+			if result.Severity == validation.SeverityError {
+				msgs = append(msgs, result.Message)
+			}
+		}
+		return msgs
+	}
+
+	It("finds no errors in valid inputs, and writes nothing", func() {
+		writeManifests(deployment, crd)
+		results, err := c.validateResults()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(errorMessages(results)).To(BeEmpty())
+		Expect(outputDir).NotTo(BeADirectory())
+	})
+	It("reports unqualified images once, with their Deployment", func() {
+		writeManifests(strings.Replace(deployment, "quay.io/example/", "", 1), crd)
+		c.requireQualifiedImages = true
+		results, err := c.validateResults()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(ContainElement(validation.Result{
+			Severity: validation.SeverityError,
+			Message: `deployment memcached-operator-controller-manager container manager image ` +
+				`"memcached-operator:v0.0.1" has no registry host`,
+			Object: validation.ObjectRef{Kind: "Deployment", Name: "memcached-operator-controller-manager"},
+		}))
+		Expect(errorMessages(results)).To(HaveLen(1))
+	})
+	It("reports an empty install strategy as an error", func() {
+		writeManifests(crd)
+		results, err := c.validateResults()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(errorMessages(results)).To(ConsistOf(ContainSubstring("install strategy has no deployments")))
+	})
+	It("checks the replaces graph with the new version added, without changing existing versions", func() {
+		writeManifests(deployment, crd)
+		Expect(os.MkdirAll(outputDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(outputDir, "memcached-operator.package.yaml"), []byte(`channels:
+- currentCSV: memcached-operator.v0.1.0
+  name: alpha
+defaultChannel: alpha
+packageName: memcached-operator
+`), 0644)).To(Succeed())
+		before, err := readFiles(outputDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		c.version = "0.2.0"
+		c.fromVersion = "0.1.0"
+		results, err := c.validateResults()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(errorMessages(results)).To(ContainElement(ContainSubstring("memcached-operator.v0.1.0")))
+		Expect(readFiles(outputDir)).To(Equal(before))
+	})
+	It("returns an error if the checks cannot be run", func() {
+		c.deployDir = filepath.Join(tmp, "missing")
+		_, err := c.validateResults()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("prints a table", func() {
+		buf := &bytes.Buffer{}
+		Expect(printResults(buf, []validation.Result{
+			{
+				Severity: validation.SeverityWarning,
+				Message:  "image has no registry host",
+				Object:   validation.ObjectRef{Kind: "Deployment", Name: "manager"},
+			},
+			{Severity: validation.SeverityError, Message: "replaces graph has a cycle"},
+		})).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(3))
+		Expect(lines[0]).To(MatchRegexp(`^SEVERITY\s+OBJECT\s+MESSAGE$`))
+		Expect(lines[1]).To(MatchRegexp(`^warning\s+Deployment/manager\s+image has no registry host$`))
+		Expect(lines[2]).To(MatchRegexp(`^error\s+replaces graph has a cycle$`))
+	})
+})
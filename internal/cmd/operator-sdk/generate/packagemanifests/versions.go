@@ -0,0 +1,176 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	apimanifests "github.com/operator-framework/api/pkg/manifests"
+	"sigs.k8s.io/yaml"
+
+	genutil "github.com/operator-framework/operator-sdk/internal/cmd/operator-sdk/generate/internal"
+)
+
+// validateVersions validates --versions and --version-input, and that no flag setting
+// a single version's inputs or outputs is set with them.
+func (c packagemanifestsCmd) validateVersions() error {
+	conflicts := []struct {
+		name  string
+		isSet bool
+	}{
+		{"--version-file", c.versionFile != ""},
+		{"--version", c.version != ""},
+		{"--from-version", c.fromVersion != ""},
+		{"--replaces-csv-name", c.replacesCSVName != ""},
+		{"--deprecate-version", len(c.deprecateVersions) != 0},
+		{"--compare-with", c.compareWith != ""},
+		{"--deploy-dir", c.deployDir != ""},
+		{"--crds-dir", c.crdsDir != ""},
+		{"--from-dir", c.fromDir != ""},
+		{"--helm-chart", c.helmChart != ""},
+		{"--input-git", c.inputGit != ""},
+		{"--input-url", len(c.inputURLs) != 0},
+		{"--stdout", c.stdout},
+		{"--oci-out", c.ociOut != ""},
+		{"--gen-dockerfile", c.genDockerfile},
+	}
+	for _, conflict := range conflicts {
+		if conflict.isSet {
+			return fmt.Errorf("%s cannot be set with --versions", conflict.name)
+		}
+	}
+	if genutil.IsPipeReader() {
+		return errors.New("manifests cannot be read from stdin with --versions")
+	}
+
+	seen := make(map[string]struct{}, len(c.versions))
+	for _, version := range c.versions {
+		if err := genutil.ValidateVersion(version); err != nil {
+			return err
+		}
+		if _, isDup := seen[version]; isDup {
+			return fmt.Errorf("version %s is set more than once in --versions", version)
+		}
+		seen[version] = struct{}{}
+		if dir := c.versionInputs[version]; dir == "" {
+			return fmt.Errorf("--version-input must be set for version %s", version)
+		} else if genutil.IsNotExist(dir) {
+			return withExitCode(exitInputNotFound, fmt.Errorf("--version-input %s for version %s does not exist", dir, version))
+		} else if !isDir(dir) {
+			return fmt.Errorf("--version-input %s for version %s is not a directory", dir, version)
+		}
+	}
+	for version := range c.versionInputs {
+		if _, isSet := seen[version]; !isSet {
+			return fmt.Errorf("--version-input is set for version %s, which is not in --versions", version)
+		}
+	}
+	return nil
+}
+
+// runVersions generates package manifests for each version in --versions from lowest to highest,
+// reading manifests from that version's --version-input directory. Each version replaces the
+// previous one, so the package's channel is left pointing at the highest version.
+func (c packagemanifestsCmd) runVersions() error {
+	versions, err := sortVersions(c.versions)
+	if err != nil {
+		return err
+	}
+	// Without a channel, the package manifest generator leaves existing channels unchanged,
+	// so versions would not be added to the package. A channel config sets all channels instead.
+	channelName := c.channelName
+	if channelName == "" && c.channelConfig == "" {
+		if channelName, err = c.baseDefaultChannel(); err != nil {
+			return err
+		}
+	}
+
+	fromVersion := ""
+	for _, version := range versions {
+		vc := c
+		vc.version = version
+		vc.fromVersion = fromVersion
+		vc.channelName = channelName
+		// CRDs are collected while walking the input directory.
+		vc.deployDir = c.versionInputs[version]
+		if err := vc.run(); err != nil {
+			return fmt.Errorf("error generating version %s: %w", version, err)
+		}
+		fromVersion = version
+	}
+	return nil
+}
+
+// baseDefaultChannel returns the default channel of the package manifest in --input-dir,
+// or the package manifest generator's default channel if none exists.
+func (c packagemanifestsCmd) baseDefaultChannel() (string, error) {
+	path := filepath.Join(c.inputDir, c.packageName+".package.yaml")
+	if genutil.IsNotExist(path) {
+		return defaultChannel, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading package manifest %s: %v", path, err)
+	}
+	pkg := apimanifests.PackageManifest{}
+	if err := yaml.Unmarshal(b, &pkg); err != nil {
+		return "", fmt.Errorf("error unmarshalling package manifest %s: %v", path, err)
+	}
+	if pkg.DefaultChannelName == "" {
+		return defaultChannel, nil
+	}
+	return pkg.DefaultChannelName, nil
+}
+
+// sortVersions returns versions sorted from lowest to highest semantic version.
+func sortVersions(versions []string) ([]string, error) {
+	if len(versions) == 0 {
+		return nil, errors.New("no versions to sort")
+	}
+	parsed := make(semver.Versions, 0, len(versions))
+	for _, version := range versions {
+		v, err := semver.Parse(version)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, v)
+	}
+	sort.Sort(parsed)
+	sorted := make([]string, 0, len(parsed))
+	for _, v := range parsed {
+		sorted = append(sorted, v.String())
+	}
+	return sorted, nil
+}
+
+// readVersionFile returns the semantic version in the file at path, ex. a release tool's VERSION file,
+// with surrounding whitespace trimmed.
+func readVersionFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading version file: %w", err)
+	}
+	version := strings.TrimSpace(string(b))
+	if err := genutil.ValidateVersion(version); err != nil {
+		return "", fmt.Errorf("invalid version in %s: %v", path, err)
+	}
+	return version, nil
+}
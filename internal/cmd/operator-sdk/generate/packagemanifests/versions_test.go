@@ -0,0 +1,133 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packagemanifests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generating multiple versions", func() {
+	var (
+		c      packagemanifestsCmd
+		tmpDir string
+	)
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "packagemanifests-versions-")
+		Expect(err).NotTo(HaveOccurred())
+		c = packagemanifestsCmd{
+			inputDir:      tmpDir,
+			packageName:   "memcached-operator",
+			versions:      []string{"0.2.0", "0.1.0"},
+			versionInputs: map[string]string{"0.1.0": tmpDir, "0.2.0": tmpDir},
+		}
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Describe("validateVersions", func() {
+		It("succeeds if each version has an input directory", func() {
+			Expect(c.validateVersions()).To(Succeed())
+			Expect(c.validate()).To(Succeed())
+		})
+		It("fails if a single version flag is set", func() {
+			c.version = "0.3.0"
+			Expect(c.validateVersions()).To(MatchError("--version cannot be set with --versions"))
+			c.version = ""
+			c.deployDir = tmpDir
+			Expect(c.validateVersions()).To(MatchError("--deploy-dir cannot be set with --versions"))
+		})
+		It("fails if a version is invalid or duplicated", func() {
+			c.versions = []string{"0.1"}
+			Expect(c.validateVersions()).To(MatchError(ContainSubstring("0.1 is not a valid semantic version")))
+			c.versions = []string{"0.1.0", "0.2.0", "0.1.0"}
+			Expect(c.validateVersions()).To(MatchError("version 0.1.0 is set more than once in --versions"))
+		})
+		It("fails if a version's input is missing or not a directory", func() {
+			delete(c.versionInputs, "0.2.0")
+			Expect(c.validateVersions()).To(MatchError("--version-input must be set for version 0.2.0"))
+			c.versionInputs["0.2.0"] = filepath.Join(tmpDir, "missing")
+			err := c.validateVersions()
+			Expect(err).To(MatchError(ContainSubstring("for version 0.2.0 does not exist")))
+			Expect(exitCode(err)).To(Equal(exitInputNotFound))
+			c.versionInputs["0.2.0"] = filepath.Join(tmpDir, "VERSION")
+			Expect(ioutil.WriteFile(c.versionInputs["0.2.0"], []byte("0.2.0\n"), 0644)).To(Succeed())
+			Expect(c.validateVersions()).To(MatchError(ContainSubstring("for version 0.2.0 is not a directory")))
+		})
+		It("fails if an input is set for a version not in --versions", func() {
+			c.versionInputs["0.3.0"] = tmpDir
+			Expect(c.validateVersions()).To(MatchError(
+				"--version-input is set for version 0.3.0, which is not in --versions"))
+		})
+	})
+
+	Describe("sortVersions", func() {
+		It("sorts versions by semantic version", func() {
+			Expect(sortVersions([]string{"0.10.0", "0.2.0", "0.2.0-alpha.1", "1.0.0"})).To(Equal(
+				[]string{"0.2.0-alpha.1", "0.2.0", "0.10.0", "1.0.0"}))
+		})
+	})
+
+	Describe("baseDefaultChannel", func() {
+		It("returns the generator's default channel if no package manifest exists", func() {
+			Expect(c.baseDefaultChannel()).To(Equal(defaultChannel))
+		})
+		It("returns the existing package manifest's default channel", func() {
+			pkg := "packageName: memcached-operator\ndefaultChannel: stable\nchannels:\n" +
+				"- name: stable\n  currentCSV: memcached-operator.v0.0.1\n"
+			path := filepath.Join(tmpDir, "memcached-operator.package.yaml")
+			Expect(ioutil.WriteFile(path, []byte(pkg), 0644)).To(Succeed())
+			Expect(c.baseDefaultChannel()).To(Equal("stable"))
+		})
+	})
+
+	Describe("readVersionFile", func() {
+		writeVersionFile := func(content string) string {
+			path := filepath.Join(tmpDir, "VERSION")
+			Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+			return path
+		}
+		It("returns the trimmed version", func() {
+			Expect(readVersionFile(writeVersionFile("  0.3.0\n"))).To(Equal("0.3.0"))
+		})
+		It("fails if the version is invalid", func() {
+			path := writeVersionFile("v0.3.0\n")
+			_, err := readVersionFile(path)
+			Expect(err).To(MatchError(ContainSubstring("invalid version in " + path)))
+		})
+		It("fails if the file does not exist", func() {
+			_, err := readVersionFile(filepath.Join(tmpDir, "VERSION"))
+			Expect(err).To(MatchError(ContainSubstring("error reading version file")))
+		})
+		It("sets the version from the file, and conflicts with --version and --versions", func() {
+			c.versions = nil
+			c.versionFile = writeVersionFile("0.3.0\n")
+			Expect(c.setDefaults()).To(Succeed())
+			Expect(c.version).To(Equal("0.3.0"))
+			Expect(c.setDefaults()).To(MatchError("--version and --version-file cannot both be set"))
+
+			c.version = ""
+			c.versions = []string{"0.1.0", "0.2.0"}
+			Expect(c.setDefaults()).To(Succeed())
+			Expect(c.validateVersions()).To(MatchError("--version-file cannot be set with --versions"))
+		})
+	})
+})
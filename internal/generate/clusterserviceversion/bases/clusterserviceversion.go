@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/yaml"
 
+	metricsannotations "github.com/operator-framework/operator-sdk/internal/annotations/metrics"
 	"github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion/bases/definitions"
 	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
 	"github.com/operator-framework/operator-sdk/internal/util/projutil"
@@ -46,6 +47,8 @@ type ClusterServiceVersion struct {
 	GVKs []schema.GroupVersionKind
 	// Interactive turns on an interactive prompt.
 	Interactive bool
+	// OverlayDir contains partial CSV fragments deep-merged onto the base, in file name order.
+	OverlayDir string
 
 	// Fields for input to the base.
 	DisplayName  string
@@ -76,6 +79,12 @@ func (b ClusterServiceVersion) GetBase() (base *v1alpha1.ClusterServiceVersion,
 		base = b.newBase()
 	}
 
+	if b.OverlayDir != "" {
+		if err := ApplyOverlays(base, b.OverlayDir); err != nil {
+			return nil, err
+		}
+	}
+
 	// Interactively fill in UI metadata.
 	if b.Interactive {
 		meta := &uiMetadata{}
@@ -104,6 +113,47 @@ func New(operatorName string) *v1alpha1.ClusterServiceVersion {
 	return b.newBase()
 }
 
+// generatedAnnotations are set on a CSV by generators, so are not extracted into a base.
+var generatedAnnotations = []string{
+	"alm-examples",
+	"olm.deprecated",
+	"olm.properties",
+	"olm.skipRange",
+	metricsannotations.BuilderObjectAnnotation,
+	metricsannotations.LayoutObjectAnnotation,
+}
+
+// Extract returns a base named for operatorName containing only csv's metadata, ex. UI metadata
+// and install modes. Generated data like the version, install strategy, API definitions, and
+// generated annotations are not extracted, so the base can be used to generate any version.
+// Annotations in excludedAnnotations, ex. those a caller sets on every generation, are not extracted either.
+func Extract(operatorName string, csv *v1alpha1.ClusterServiceVersion, excludedAnnotations ...string) *v1alpha1.ClusterServiceVersion {
+	base := New(operatorName)
+	for k, v := range csv.GetAnnotations() {
+		base.Annotations[k] = v
+	}
+	for _, k := range append(generatedAnnotations, excludedAnnotations...) {
+		delete(base.Annotations, k)
+	}
+	base.Annotations["alm-examples"] = "[]"
+
+	spec := csv.Spec.DeepCopy()
+	base.Spec.DisplayName = spec.DisplayName
+	base.Spec.Description = spec.Description
+	base.Spec.Keywords = spec.Keywords
+	base.Spec.Maintainers = spec.Maintainers
+	base.Spec.Provider = spec.Provider
+	base.Spec.Links = spec.Links
+	base.Spec.Icon = spec.Icon
+	base.Spec.Maturity = spec.Maturity
+	base.Spec.MinKubeVersion = spec.MinKubeVersion
+	base.Spec.NativeAPIs = spec.NativeAPIs
+	if len(spec.InstallModes) != 0 {
+		base.Spec.InstallModes = spec.InstallModes
+	}
+	return base
+}
+
 // setDefaults sets default values in b using b's existing values.
 func (b *ClusterServiceVersion) setDefaults() {
 	if b.DisplayName == "" {
@@ -0,0 +1,55 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bases
+
+import (
+	"github.com/blang/semver/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/operator-framework/api/pkg/lib/version"
+	"github.com/operator-framework/api/pkg/operators/v1alpha1"
+)
+
+var _ = Describe("Extract", func() {
+	It("keeps only metadata from a generated CSV", func() {
+		csv := New("memcached-operator")
+		csv.SetName("memcached-operator.v0.2.0")
+		csv.Annotations["capabilities"] = "Full Lifecycle"
+		csv.Annotations["alm-examples"] = `[{"kind":"Memcached"}]`
+		csv.Annotations["olm.skipRange"] = "<0.2.0"
+		csv.Annotations["operators.operatorframework.io/builder"] = "operator-sdk-v1.0.0"
+		csv.Annotations["olm.properties"] = `[{"type":"olm.maxOpenShiftVersion","value":"4.8"}]`
+		csv.Annotations["vcs-ref"] = "0123abc"
+		csv.Spec.DisplayName = "Memcached Operator"
+		csv.Spec.Icon = []v1alpha1.Icon{{Data: "aWNvbg==", MediaType: "image/png"}}
+		csv.Spec.Version = version.OperatorVersion{Version: semver.MustParse("0.2.0")}
+		csv.Spec.Replaces = "memcached-operator.v0.1.0"
+		csv.Spec.InstallStrategy.StrategyName = v1alpha1.InstallStrategyNameDeployment
+		csv.Spec.CustomResourceDefinitions.Owned = []v1alpha1.CRDDescription{{Name: "memcacheds.cache.example.com"}}
+
+		base := Extract("memcached-operator", csv, "vcs-ref")
+		Expect(base.GetName()).To(Equal("memcached-operator.v0.0.0"))
+		Expect(base.GetAnnotations()).To(Equal(map[string]string{
+			"capabilities": "Full Lifecycle",
+			"alm-examples": "[]",
+		}))
+		Expect(base.Spec.DisplayName).To(Equal("Memcached Operator"))
+		Expect(base.Spec.Icon).To(Equal(csv.Spec.Icon))
+		Expect(base.Spec.Version).To(Equal(version.OperatorVersion{}))
+		Expect(base.Spec.Replaces).To(BeEmpty())
+		Expect(base.Spec.InstallStrategy).To(Equal(v1alpha1.NamedInstallStrategy{}))
+		Expect(base.Spec.CustomResourceDefinitions).To(Equal(v1alpha1.CustomResourceDefinitions{}))
+	})
+})
@@ -0,0 +1,151 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bases
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// ApplyOverlays deep-merges each YAML or JSON fragment in dir onto base, in file name order.
+// Objects are merged key by key; any other value, including a list, replaces the base's value.
+// A fragment that sets an object or list where base has a value of another type, or vice versa,
+// is an error, as is a merged base that no longer decodes as a ClusterServiceVersion.
+func ApplyOverlays(base *v1alpha1.ClusterServiceVersion, dir string) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading base overlay directory: %v", err)
+	}
+	b, err := json.Marshal(base)
+	if err != nil {
+		return err
+	}
+	merged := map[string]interface{}{}
+	if err := json.Unmarshal(b, &merged); err != nil {
+		return err
+	}
+
+	// ReadDir sorts by file name.
+	for _, info := range infos {
+		switch ext := strings.ToLower(filepath.Ext(info.Name())); {
+		case info.IsDir(), ext != ".yaml" && ext != ".yml" && ext != ".json":
+			continue
+		}
+		path := filepath.Join(dir, info.Name())
+		overlay, err := readOverlay(path)
+		if err != nil {
+			return err
+		}
+		if err := mergeObjects(merged, overlay, ""); err != nil {
+			return fmt.Errorf("error merging base overlay %s: %v", path, err)
+		}
+	}
+
+	if b, err = json.Marshal(merged); err != nil {
+		return err
+	}
+	// Unknown fields are likely misspelled, and would otherwise be dropped silently.
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	out := &v1alpha1.ClusterServiceVersion{}
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("overlaid ClusterServiceVersion base is invalid: %v", err)
+	}
+	*base = *out
+	return nil
+}
+
+// readOverlay returns the object in the YAML or JSON fragment at path.
+func readOverlay(path string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading base overlay: %v", err)
+	}
+	data, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base overlay %s: %v", path, err)
+	}
+	overlay := map[string]interface{}{}
+	if data = bytes.TrimSpace(data); bytes.Equal(data, []byte("null")) {
+		// An empty fragment changes nothing.
+		return overlay, nil
+	}
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("base overlay %s must be an object", path)
+	}
+	return overlay, nil
+}
+
+// mergeObjects merges src into dst, where path is the field path of dst for errors.
+func mergeObjects(dst, src map[string]interface{}, path string) error {
+	for key, srcValue := range src {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		dstValue, hasValue := dst[key]
+		if !hasValue || dstValue == nil || srcValue == nil {
+			dst[key] = srcValue
+			continue
+		}
+		dstObj, dstIsObj := dstValue.(map[string]interface{})
+		srcObj, srcIsObj := srcValue.(map[string]interface{})
+		dstType, srcType := jsonTypeName(dstValue), jsonTypeName(srcValue)
+		switch {
+		case dstIsObj && srcIsObj:
+			if err := mergeObjects(dstObj, srcObj, fieldPath); err != nil {
+				return err
+			}
+		case dstType != srcType && (isComposite(dstValue) || isComposite(srcValue)):
+			return fmt.Errorf("field %s is %s in the base but %s in the overlay", fieldPath, dstType, srcType)
+		default:
+			dst[key] = srcValue
+		}
+	}
+	return nil
+}
+
+// isComposite returns true if v is an object or a list.
+func isComposite(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// jsonTypeName returns the JSON type of v, a value decoded by encoding/json.
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return "null"
+}
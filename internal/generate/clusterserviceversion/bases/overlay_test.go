@@ -0,0 +1,98 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bases
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ApplyOverlays", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "base-overlay-")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeFragment := func(name, content string) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+	}
+
+	It("deep-merges fragments onto the base in file name order", func() {
+		writeFragment("metadata.yaml", `metadata:
+  annotations:
+    categories: Database
+spec:
+  displayName: Memcached Operator
+`)
+		writeFragment("spec-keywords.json", `{"spec": {"keywords": ["cache"], "displayName": "Memcached"}}`)
+		writeFragment("README.md", "not a fragment")
+
+		base := New("memcached-operator")
+		Expect(ApplyOverlays(base, dir)).To(Succeed())
+		Expect(base.GetName()).To(Equal("memcached-operator.v0.0.0"))
+		Expect(base.GetAnnotations()).To(HaveKeyWithValue("categories", "Database"))
+		Expect(base.GetAnnotations()).To(HaveKeyWithValue("capabilities", "Basic Install"))
+		Expect(base.Spec.DisplayName).To(Equal("Memcached"))
+		Expect(base.Spec.Keywords).To(Equal([]string{"cache"}))
+		Expect(base.Spec.Maturity).To(Equal("alpha"))
+	})
+
+	It("is applied by GetBase", func() {
+		writeFragment("spec.yaml", "spec:\n  maturity: stable\n")
+
+		base, err := ClusterServiceVersion{OperatorName: "memcached-operator", OverlayDir: dir}.GetBase()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(base.Spec.Maturity).To(Equal("stable"))
+	})
+
+	It("returns an error for a fragment that does not parse", func() {
+		writeFragment("bad.yaml", "spec: [")
+
+		err := ApplyOverlays(New("memcached-operator"), dir)
+		Expect(err).To(MatchError(ContainSubstring("error parsing base overlay " + filepath.Join(dir, "bad.yaml"))))
+	})
+
+	It("returns an error for a fragment that is not an object", func() {
+		writeFragment("list.yaml", "- spec: {}\n")
+
+		err := ApplyOverlays(New("memcached-operator"), dir)
+		Expect(err).To(MatchError(ContainSubstring("must be an object")))
+	})
+
+	It("returns an error for a type conflict with the base", func() {
+		writeFragment("spec.yaml", "spec:\n  keywords:\n    name: cache\n")
+
+		err := ApplyOverlays(New("memcached-operator"), dir)
+		Expect(err).To(MatchError(ContainSubstring("field spec.keywords is a list in the base but an object in the overlay")))
+	})
+
+	It("returns an error for an unknown field", func() {
+		writeFragment("spec.yaml", "spec:\n  displayNmae: Memcached\n")
+
+		err := ApplyOverlays(New("memcached-operator"), dir)
+		Expect(err).To(MatchError(ContainSubstring("overlaid ClusterServiceVersion base is invalid")))
+	})
+})
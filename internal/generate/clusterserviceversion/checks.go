@@ -0,0 +1,350 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+// ErrEmptyInstall is wrapped by the CheckError returned by Generator.Generate if the ClusterServiceVersion's
+// install strategy has no deployments and Generator.AllowEmptyInstall is not set.
+var ErrEmptyInstall = errors.New("install strategy has no deployments")
+
+// CheckError is returned by Generator.Generate if the generated ClusterServiceVersion fails a check,
+// so callers can tell invalid inputs from other errors.
+type CheckError struct {
+	err error
+	// summary describes the failed check listing problems, if it lists any.
+	summary  string
+	problems []string
+}
+
+func (e CheckError) Error() string {
+	return e.err.Error()
+}
+
+func (e CheckError) Unwrap() error {
+	return e.err
+}
+
+// Problems returns each problem e lists prefixed by its summary, or e's message if it lists none.
+func (e CheckError) Problems() []string {
+	if len(e.problems) == 0 {
+		return []string{e.Error()}
+	}
+	problems := make([]string, 0, len(e.problems))
+	for _, problem := range e.problems {
+		problems = append(problems, e.summary+": "+problem)
+	}
+	return problems
+}
+
+// checkErrorf returns a CheckError with a message formatted like fmt.Errorf.
+func checkErrorf(format string, args ...interface{}) error {
+	return CheckError{err: fmt.Errorf(format, args...)}
+}
+
+// checkErrorList returns a CheckError with summary followed by each of problems on its own line.
+func checkErrorList(summary string, problems []string) error {
+	return CheckError{
+		err:      fmt.Errorf("%s:\n  - %s", summary, strings.Join(problems, "\n  - ")),
+		summary:  summary,
+		problems: problems,
+	}
+}
+
+// checkOwnedCRDVersions returns a message for each owned CRD description in csv whose version
+// is not served by the matching CRD in c. These descriptions are dropped when CRDs are applied
+// to csv, so hand-written descriptors for a retired version would otherwise be lost silently.
+// Descriptions for CRDs not in c are not checked.
+func checkOwnedCRDVersions(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersion) (mismatches []string) {
+	// CRD name to version name to whether that version is served.
+	crdVersions := make(map[string]map[string]bool)
+	for _, crd := range c.V1CustomResourceDefinitions {
+		versions := make(map[string]bool, len(crd.Spec.Versions))
+		for _, ver := range crd.Spec.Versions {
+			versions[ver.Name] = ver.Served
+		}
+		crdVersions[crd.GetName()] = versions
+	}
+	for _, crd := range c.V1beta1CustomResourceDefinitions {
+		versions := make(map[string]bool, len(crd.Spec.Versions))
+		if len(crd.Spec.Versions) == 0 && crd.Spec.Version != "" {
+			versions[crd.Spec.Version] = true
+		}
+		for _, ver := range crd.Spec.Versions {
+			versions[ver.Name] = ver.Served
+		}
+		crdVersions[crd.GetName()] = versions
+	}
+
+	for _, owned := range csv.Spec.CustomResourceDefinitions.Owned {
+		versions, hasCRD := crdVersions[owned.Name]
+		if !hasCRD {
+			continue
+		}
+		served, hasVersion := versions[owned.Version]
+		switch {
+		case !hasVersion:
+			mismatches = append(mismatches, fmt.Sprintf("owned CRD %s version %s does not exist in the CRD's versions",
+				owned.Name, owned.Version))
+		case !served:
+			mismatches = append(mismatches, fmt.Sprintf("owned CRD %s version %s is not served",
+				owned.Name, owned.Version))
+		}
+	}
+	return mismatches
+}
+
+// checkInstallModes returns a message if csv does not support any install mode.
+func checkInstallModes(csv *operatorsv1alpha1.ClusterServiceVersion) (msgs []string) {
+	for _, mode := range csv.Spec.InstallModes {
+		if mode.Supported {
+			return nil
+		}
+	}
+	return []string{"no install mode is supported, so the operator cannot be installed"}
+}
+
+// checkRequiredAnnotations returns each key in keys that csv does not annotate with a non-empty value.
+func checkRequiredAnnotations(csv *operatorsv1alpha1.ClusterServiceVersion, keys []string) (missing []string) {
+	for _, key := range keys {
+		if csv.GetAnnotations()[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// checkDeploymentResources returns a message for each container in c's Deployments
+// that does not request both CPU and memory.
+func checkDeploymentResources(c *collector.Manifests) (msgs []string) {
+	for _, dep := range c.Deployments {
+		podSpec := dep.Spec.Template.Spec
+		containers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+		for _, container := range containers {
+			var missing []string
+			for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+				if _, hasRequest := container.Resources.Requests[name]; !hasRequest {
+					missing = append(missing, string(name))
+				}
+			}
+			if len(missing) != 0 {
+				msgs = append(msgs, fmt.Sprintf("deployment %s container %s has no %s requests",
+					dep.GetName(), container.Name, strings.Join(missing, " or ")))
+			}
+		}
+	}
+	return msgs
+}
+
+// checkServiceAccounts returns a message for each Deployment in c whose ServiceAccount is neither
+// collected nor "default", which every namespace has. OLM only creates collected ServiceAccounts,
+// so the operator's pods would fail to be created once installed.
+func checkServiceAccounts(c *collector.Manifests) (msgs []string) {
+	sas := make(map[string]struct{}, len(c.ServiceAccounts))
+	for _, sa := range c.ServiceAccounts {
+		sas[sa.GetName()] = struct{}{}
+	}
+	for _, dep := range c.Deployments {
+		saName := dep.Spec.Template.Spec.ServiceAccountName
+		if saName == "" || saName == "default" {
+			continue
+		}
+		if _, hasSA := sas[saName]; !hasSA {
+			msgs = append(msgs, fmt.Sprintf("deployment %s service account %s was not collected", dep.GetName(), saName))
+		}
+	}
+	return msgs
+}
+
+// checkQualifiedImages returns a message for each image in csv's deployments and related images
+// that is not fully qualified with a registry host, ex. busybox or controller:latest.
+func checkQualifiedImages(csv *operatorsv1alpha1.ClusterServiceVersion) (msgs []string) {
+	for _, depSpec := range csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+		msgs = append(msgs, CheckQualifiedPodImages("deployment "+depSpec.Name, depSpec.Spec.Template.Spec)...)
+	}
+	for _, relatedImage := range csv.Spec.RelatedImages {
+		if !isQualifiedImage(relatedImage.Image) {
+			msgs = append(msgs, fmt.Sprintf("related image %s image %q has no registry host", relatedImage.Name, relatedImage.Image))
+		}
+	}
+	return msgs
+}
+
+// CheckQualifiedPodImages returns a message, prefixed by location, for each container image in podSpec
+// that is not fully qualified with a registry host.
+func CheckQualifiedPodImages(location string, podSpec corev1.PodSpec) (msgs []string) {
+	containers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, container := range containers {
+		if !isQualifiedImage(container.Image) {
+			msgs = append(msgs, fmt.Sprintf("%s container %s image %q has no registry host", location, container.Name, container.Image))
+		}
+	}
+	return msgs
+}
+
+// isQualifiedImage returns true if image's first path component is a registry host, which like
+// the container runtime's rule contains a "." or ":", or is localhost. Otherwise the runtime
+// resolves image from its default registry, which disconnected clusters cannot reach.
+func isQualifiedImage(image string) bool {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return false
+	}
+	host := image[:i]
+	return strings.ContainsAny(host, ".:") || host == "localhost"
+}
+
+// checkConversionWebhookPorts returns a message for each CRD conversion webhook whose service port
+// is not exposed by its Service in c, or whose target port is not a container port of the Deployment
+// selected by that Service. OLM routes conversion requests to these ports, so a mismatch only surfaces
+// once the operator is installed. Webhooks whose Service or Deployment is not in c are not checked.
+func checkConversionWebhookPorts(c *collector.Manifests) (msgs []string) {
+	for i := range c.Services {
+		svc := &c.Services[i]
+		crdToConfig := getConvWebhookCRDNamesAndConfig(c, svc.GetName())
+		if len(crdToConfig) == 0 || svc.Spec.Type == corev1.ServiceTypeExternalName || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		dep := findDeployment(c, findMatchingDepNameFromService(c, svc))
+
+		crdNames := make([]string, 0, len(crdToConfig))
+		for crdName := range crdToConfig {
+			crdNames = append(crdNames, crdName)
+		}
+		sort.Strings(crdNames)
+		for _, crdName := range crdNames {
+			var port int32 = 443
+			if p := crdToConfig[crdName].ClientConfig.Service.Port; p != nil {
+				port = *p
+			}
+			svcPort, hasPort := findServicePort(svc, port)
+			if !hasPort {
+				msgs = append(msgs, fmt.Sprintf("CRD %s conversion webhook port %d is not exposed by service %s",
+					crdName, port, svc.GetName()))
+				continue
+			}
+			if dep == nil {
+				continue
+			}
+			targetPort := svcPort.TargetPort
+			if targetPort == (intstr.IntOrString{}) {
+				// An unset target port defaults to the service port.
+				targetPort = intstr.FromInt(int(port))
+			}
+			if !hasContainerPort(dep, targetPort) {
+				msgs = append(msgs, fmt.Sprintf("CRD %s conversion webhook target port %s of service %s "+
+					"is not a container port of deployment %s", crdName, targetPort.String(), svc.GetName(), dep.GetName()))
+			}
+		}
+	}
+	return msgs
+}
+
+// checkConversionStrategies returns a message for each CRD in c owned by csv whose conversion strategy
+// does not match csv's conversion webhook definitions: a Webhook strategy requires a definition
+// listing the CRD, and a None strategy requires that none does. OLM rejects either mismatch only
+// once the operator is installed.
+func checkConversionStrategies(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersion) (msgs []string) {
+	// CRD name to the conversion webhook definition listing it.
+	webhookDefs := make(map[string]string)
+	for _, def := range csv.Spec.WebhookDefinitions {
+		if def.Type != operatorsv1alpha1.ConversionWebhook {
+			continue
+		}
+		for _, crdName := range def.ConversionCRDs {
+			webhookDefs[crdName] = def.GenerateName
+		}
+	}
+	owned := make(map[string]struct{}, len(csv.Spec.CustomResourceDefinitions.Owned))
+	for _, desc := range csv.Spec.CustomResourceDefinitions.Owned {
+		owned[desc.Name] = struct{}{}
+	}
+
+	// CRD name to whether its conversion strategy is Webhook.
+	strategies := make(map[string]bool)
+	for _, crd := range c.V1CustomResourceDefinitions {
+		strategies[crd.GetName()] = crd.Spec.Conversion != nil && crd.Spec.Conversion.Strategy == apiextv1.WebhookConverter
+	}
+	for _, crd := range c.V1beta1CustomResourceDefinitions {
+		strategies[crd.GetName()] = crd.Spec.Conversion != nil && crd.Spec.Conversion.Strategy == apiextv1beta1.WebhookConverter
+	}
+
+	crdNames := make([]string, 0, len(strategies))
+	for crdName := range strategies {
+		if _, isOwned := owned[crdName]; isOwned {
+			crdNames = append(crdNames, crdName)
+		}
+	}
+	sort.Strings(crdNames)
+	for _, crdName := range crdNames {
+		defName, hasDef := webhookDefs[crdName]
+		switch isWebhook := strategies[crdName]; {
+		case isWebhook && !hasDef:
+			msgs = append(msgs, fmt.Sprintf("CRD %s conversion strategy is Webhook, but no conversion webhook "+
+				"definition lists it: check that the CRD's webhook service is collected", crdName))
+		case !isWebhook && hasDef:
+			msgs = append(msgs, fmt.Sprintf("CRD %s conversion strategy is None, but conversion webhook "+
+				"definition %s lists it", crdName, defName))
+		}
+	}
+	return msgs
+}
+
+// findDeployment returns the Deployment in c named name, or nil if none exists.
+func findDeployment(c *collector.Manifests, name string) *appsv1.Deployment {
+	for i, dep := range c.Deployments {
+		if name != "" && dep.GetName() == name {
+			return &c.Deployments[i]
+		}
+	}
+	return nil
+}
+
+// findServicePort returns svc's port with port number port, if any.
+func findServicePort(svc *corev1.Service, port int32) (corev1.ServicePort, bool) {
+	for _, svcPort := range svc.Spec.Ports {
+		if svcPort.Port == port {
+			return svcPort, true
+		}
+	}
+	return corev1.ServicePort{}, false
+}
+
+// hasContainerPort returns true if any of dep's containers expose port, by name or number.
+func hasContainerPort(dep *appsv1.Deployment, port intstr.IntOrString) bool {
+	for _, container := range dep.Spec.Template.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if (port.Type == intstr.String && containerPort.Name == port.StrVal) ||
+				(port.Type == intstr.Int && containerPort.ContainerPort == port.IntVal) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("Generation-time checks", func() {
+	var (
+		c   *collector.Manifests
+		csv *operatorsv1alpha1.ClusterServiceVersion
+	)
+
+	BeforeEach(func() {
+		c = &collector.Manifests{}
+		csv = &operatorsv1alpha1.ClusterServiceVersion{}
+	})
+
+	Describe("checkOwnedCRDVersions", func() {
+		BeforeEach(func() {
+			c.V1CustomResourceDefinitions = []apiextv1.CustomResourceDefinition{{
+				ObjectMeta: metav1.ObjectMeta{Name: "memcacheds.cache.example.com"},
+				Spec: apiextv1.CustomResourceDefinitionSpec{
+					Versions: []apiextv1.CustomResourceDefinitionVersion{
+						{Name: "v1alpha2", Served: true, Storage: true},
+						{Name: "v1alpha1", Served: false},
+					},
+				},
+			}}
+			c.V1beta1CustomResourceDefinitions = []apiextv1beta1.CustomResourceDefinition{{
+				ObjectMeta: metav1.ObjectMeta{Name: "dummies.cache.example.com"},
+				Spec:       apiextv1beta1.CustomResourceDefinitionSpec{Version: "v1"},
+			}}
+		})
+
+		It("returns nothing when all owned versions are served", func() {
+			csv.Spec.CustomResourceDefinitions.Owned = []operatorsv1alpha1.CRDDescription{
+				{Name: "memcacheds.cache.example.com", Version: "v1alpha2"},
+				{Name: "dummies.cache.example.com", Version: "v1"},
+				{Name: "others.cache.example.com", Version: "v1"},
+			}
+			Expect(checkOwnedCRDVersions(c, csv)).To(BeEmpty())
+		})
+		It("lists owned versions that are unserved or do not exist", func() {
+			csv.Spec.CustomResourceDefinitions.Owned = []operatorsv1alpha1.CRDDescription{
+				{Name: "memcacheds.cache.example.com", Version: "v1alpha1"},
+				{Name: "memcacheds.cache.example.com", Version: "v1beta1"},
+				{Name: "dummies.cache.example.com", Version: "v2"},
+			}
+			Expect(checkOwnedCRDVersions(c, csv)).To(Equal([]string{
+				"owned CRD memcacheds.cache.example.com version v1alpha1 is not served",
+				"owned CRD memcacheds.cache.example.com version v1beta1 does not exist in the CRD's versions",
+				"owned CRD dummies.cache.example.com version v2 does not exist in the CRD's versions",
+			}))
+		})
+	})
+
+	Describe("checkInstallModes", func() {
+		It("returns nothing if an install mode is supported", func() {
+			csv.Spec.InstallModes = []operatorsv1alpha1.InstallMode{
+				{Type: operatorsv1alpha1.InstallModeTypeOwnNamespace, Supported: false},
+				{Type: operatorsv1alpha1.InstallModeTypeAllNamespaces, Supported: true},
+			}
+			Expect(checkInstallModes(csv)).To(BeEmpty())
+		})
+		It("returns a message if no install mode is supported", func() {
+			csv.Spec.InstallModes = []operatorsv1alpha1.InstallMode{
+				{Type: operatorsv1alpha1.InstallModeTypeAllNamespaces, Supported: false},
+			}
+			Expect(checkInstallModes(csv)).To(HaveLen(1))
+		})
+	})
+
+	Describe("checkRequiredAnnotations", func() {
+		It("returns keys that are not set or are empty", func() {
+			csv.SetAnnotations(map[string]string{"capabilities": "Basic Install", "categories": ""})
+			Expect(checkRequiredAnnotations(csv, []string{"capabilities", "categories", "repository"})).To(Equal([]string{
+				"categories", "repository",
+			}))
+		})
+		It("returns nothing if all keys are set", func() {
+			csv.SetAnnotations(map[string]string{"capabilities": "Basic Install"})
+			Expect(checkRequiredAnnotations(csv, []string{"capabilities"})).To(BeEmpty())
+		})
+	})
+
+	Describe("checkDeploymentResources", func() {
+		newDeployment := func(containers ...corev1.Container) appsv1.Deployment {
+			dep := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controller-manager"}}
+			dep.Spec.Template.Spec.Containers = containers
+			return dep
+		}
+		requests := corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("20Mi"),
+			},
+		}
+
+		It("returns nothing if all containers request CPU and memory", func() {
+			c.Deployments = []appsv1.Deployment{newDeployment(corev1.Container{Name: "manager", Resources: requests})}
+			Expect(checkDeploymentResources(c)).To(BeEmpty())
+		})
+		It("returns a message for containers without requests", func() {
+			cpuOnly := corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			}
+			c.Deployments = []appsv1.Deployment{newDeployment(
+				corev1.Container{Name: "manager", Resources: requests},
+				corev1.Container{Name: "kube-rbac-proxy"},
+				corev1.Container{Name: "sidecar", Resources: cpuOnly},
+			)}
+			Expect(checkDeploymentResources(c)).To(Equal([]string{
+				"deployment controller-manager container kube-rbac-proxy has no cpu or memory requests",
+				"deployment controller-manager container sidecar has no memory requests",
+			}))
+		})
+	})
+
+	Describe("checkServiceAccounts", func() {
+		newDeployment := func(name, saName string) appsv1.Deployment {
+			dep := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			dep.Spec.Template.Spec.ServiceAccountName = saName
+			return dep
+		}
+
+		It("returns nothing if service accounts are collected or default", func() {
+			c.ServiceAccounts = []corev1.ServiceAccount{{ObjectMeta: metav1.ObjectMeta{Name: "controller-manager"}}}
+			c.Deployments = []appsv1.Deployment{
+				newDeployment("controller-manager", "controller-manager"),
+				newDeployment("webhook", ""),
+				newDeployment("metrics", "default"),
+			}
+			Expect(checkServiceAccounts(c)).To(BeEmpty())
+		})
+		It("returns a message for each service account that was not collected", func() {
+			c.Deployments = []appsv1.Deployment{newDeployment("controller-manager", "controller-manager")}
+			Expect(checkServiceAccounts(c)).To(Equal([]string{
+				"deployment controller-manager service account controller-manager was not collected",
+			}))
+		})
+	})
+
+	Describe("checkQualifiedImages", func() {
+		It("returns a message for each image without a registry host", func() {
+			csv := &operatorsv1alpha1.ClusterServiceVersion{}
+			podSpec := corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+				Containers: []corev1.Container{
+					{Name: "manager", Image: "controller:latest"},
+					{Name: "kube-rbac-proxy", Image: "gcr.io/kubebuilder/kube-rbac-proxy:v0.8.0"},
+					{Name: "local", Image: "localhost/memcached-operator:v0.0.1"},
+					{Name: "mirror", Image: "registry:5000/memcached-operator:v0.0.1"},
+					{Name: "hub", Image: "library/memcached:1.6"},
+				},
+			}
+			csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = []operatorsv1alpha1.StrategyDeploymentSpec{
+				{Name: "controller-manager", Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec}}},
+			}
+			csv.Spec.RelatedImages = []operatorsv1alpha1.RelatedImage{
+				{Name: "memcached", Image: "memcached@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+				{Name: "proxy", Image: "gcr.io/kubebuilder/kube-rbac-proxy:v0.8.0"},
+			}
+			Expect(checkQualifiedImages(csv)).To(Equal([]string{
+				`deployment controller-manager container init image "busybox" has no registry host`,
+				`deployment controller-manager container manager image "controller:latest" has no registry host`,
+				`deployment controller-manager container hub image "library/memcached:1.6" has no registry host`,
+				`related image memcached image "memcached@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef" ` +
+					`has no registry host`,
+			}))
+		})
+	})
+
+	Describe("checkConversionWebhookPorts", func() {
+		labels := map[string]string{"control-plane": "controller-manager"}
+		BeforeEach(func() {
+			path := "/convert"
+			crd := apiextv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "memcacheds.cache.example.com"}}
+			crd.Spec.Conversion = &apiextv1.CustomResourceConversion{
+				Strategy: apiextv1.WebhookConverter,
+				Webhook: &apiextv1.WebhookConversion{
+					ClientConfig: &apiextv1.WebhookClientConfig{
+						Service: &apiextv1.ServiceReference{Name: "webhook-service", Path: &path},
+					},
+				},
+			}
+			svc := corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "webhook-service"}}
+			svc.Spec.Selector = labels
+			svc.Spec.Ports = []corev1.ServicePort{{Port: 443, TargetPort: intstr.FromInt(9443)}}
+			dep := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controller-manager"}}
+			dep.Spec.Template.SetLabels(labels)
+			dep.Spec.Template.Spec.Containers = []corev1.Container{
+				{Name: "manager", Ports: []corev1.ContainerPort{{Name: "webhook-server", ContainerPort: 9443}}},
+			}
+			c.V1CustomResourceDefinitions = []apiextv1.CustomResourceDefinition{crd}
+			c.Services = []corev1.Service{svc}
+			c.Deployments = []appsv1.Deployment{dep}
+		})
+
+		It("returns nothing if the webhook port is served", func() {
+			Expect(checkConversionWebhookPorts(c)).To(BeEmpty())
+			c.Services[0].Spec.Ports[0].TargetPort = intstr.FromString("webhook-server")
+			Expect(checkConversionWebhookPorts(c)).To(BeEmpty())
+		})
+		It("returns a message if the service does not expose the webhook port", func() {
+			port := int32(8443)
+			c.V1CustomResourceDefinitions[0].Spec.Conversion.Webhook.ClientConfig.Service.Port = &port
+			Expect(checkConversionWebhookPorts(c)).To(Equal([]string{
+				"CRD memcacheds.cache.example.com conversion webhook port 8443 is not exposed by service webhook-service",
+			}))
+		})
+		It("returns a message if the deployment does not expose the target port", func() {
+			c.Services[0].Spec.Ports[0].TargetPort = intstr.FromString("webhook")
+			Expect(checkConversionWebhookPorts(c)).To(Equal([]string{
+				"CRD memcacheds.cache.example.com conversion webhook target port webhook of service webhook-service " +
+					"is not a container port of deployment controller-manager",
+			}))
+		})
+		It("does not check webhooks whose deployment is not collected", func() {
+			c.Services[0].Spec.Ports[0].TargetPort = intstr.FromString("webhook")
+			c.Deployments = nil
+			Expect(checkConversionWebhookPorts(c)).To(BeEmpty())
+		})
+	})
+
+	Describe("checkConversionStrategies", func() {
+		const crdName = "memcacheds.cache.example.com"
+		BeforeEach(func() {
+			crd := apiextv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: crdName}}
+			crd.Spec.Conversion = &apiextv1.CustomResourceConversion{Strategy: apiextv1.WebhookConverter}
+			c.V1CustomResourceDefinitions = []apiextv1.CustomResourceDefinition{crd}
+			csv.Spec.CustomResourceDefinitions.Owned = []operatorsv1alpha1.CRDDescription{{Name: crdName}}
+			csv.Spec.WebhookDefinitions = []operatorsv1alpha1.WebhookDescription{
+				{Type: operatorsv1alpha1.ConversionWebhook, GenerateName: "cmemcacheds.kb.io", ConversionCRDs: []string{crdName}},
+			}
+		})
+
+		It("returns nothing if strategies match webhook definitions", func() {
+			Expect(checkConversionStrategies(c, csv)).To(BeEmpty())
+			c.V1CustomResourceDefinitions[0].Spec.Conversion.Strategy = apiextv1.NoneConverter
+			csv.Spec.WebhookDefinitions = nil
+			Expect(checkConversionStrategies(c, csv)).To(BeEmpty())
+			c.V1CustomResourceDefinitions[0].Spec.Conversion = nil
+			Expect(checkConversionStrategies(c, csv)).To(BeEmpty())
+		})
+		It("returns a message if a Webhook strategy CRD has no webhook definition", func() {
+			csv.Spec.WebhookDefinitions[0].Type = operatorsv1alpha1.ValidatingAdmissionWebhook
+			Expect(checkConversionStrategies(c, csv)).To(Equal([]string{
+				"CRD memcacheds.cache.example.com conversion strategy is Webhook, but no conversion webhook " +
+					"definition lists it: check that the CRD's webhook service is collected",
+			}))
+		})
+		It("returns a message if a None strategy CRD has a webhook definition", func() {
+			crd := apiextv1beta1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: crdName}}
+			crd.Spec.Conversion = &apiextv1beta1.CustomResourceConversion{Strategy: apiextv1beta1.NoneConverter}
+			c.V1CustomResourceDefinitions = nil
+			c.V1beta1CustomResourceDefinitions = []apiextv1beta1.CustomResourceDefinition{crd}
+			Expect(checkConversionStrategies(c, csv)).To(Equal([]string{
+				"CRD memcacheds.cache.example.com conversion strategy is None, but conversion webhook " +
+					"definition cmemcacheds.kb.io lists it",
+			}))
+		})
+		It("does not check CRDs the CSV does not own", func() {
+			csv.Spec.CustomResourceDefinitions.Owned = nil
+			csv.Spec.WebhookDefinitions = nil
+			Expect(checkConversionStrategies(c, csv)).To(BeEmpty())
+		})
+	})
+
+	Describe("CheckError", func() {
+		It("returns each listed problem prefixed by its summary", func() {
+			err := checkErrorList("invalid CSV", []string{"a", "b"})
+			Expect(err).To(MatchError("invalid CSV:\n  - a\n  - b"))
+			Expect(err.(CheckError).Problems()).To(Equal([]string{"invalid CSV: a", "invalid CSV: b"}))
+		})
+		It("returns its message if it lists no problems", func() {
+			err := checkErrorf("install strategy has no deployments")
+			Expect(err.(CheckError).Problems()).To(Equal([]string{"install strategy has no deployments"}))
+		})
+	})
+})
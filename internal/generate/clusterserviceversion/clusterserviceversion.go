@@ -17,6 +17,7 @@ package clusterserviceversion
 import (
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -24,11 +25,15 @@ import (
 	"github.com/operator-framework/api/pkg/operators/v1alpha1"
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion/bases"
 	"github.com/operator-framework/operator-sdk/internal/generate/collector"
 	genutil "github.com/operator-framework/operator-sdk/internal/generate/internal"
+	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
 	"github.com/operator-framework/operator-sdk/internal/util/projutil"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
 )
 
 const (
@@ -45,22 +50,86 @@ var (
 type Generator struct {
 	// OperatorName is the operator's name, ex. app-operator.
 	OperatorName string
+	// BaseOperatorName, if set, is the operator name of the CSV base to use from Collector, ex. when
+	// publishing the operator under another name. The CSV is still named after OperatorName.
+	BaseOperatorName string
 	// Version is the CSV current version.
 	Version string
+	// SpecVersion, if set, overrides the CSV's spec.version, ex. to add build metadata.
+	// The CSV is still named after Version.
+	SpecVersion string
 	// FromVersion is the version of a previous CSV to upgrade from.
 	FromVersion string
-	// Collector holds all manifests relevant to the Generator.
-	Collector *collector.Manifests
+	// Replaces, if set, is the name of the previous CSV to upgrade from, which takes precedence over
+	// the name derived from FromVersion, ex. if the previous CSV was published under another name.
+	Replaces string
+	// Collector returns all manifests relevant to the Generator.
+	Collector collector.Collector
 	// Annotations are applied to the resulting CSV.
 	Annotations map[string]string
+	// Labels are applied to the resulting CSV, overriding existing labels with the same key.
+	Labels map[string]string
 	// ExtraServiceAccounts are ServiceAccount names to consider when matching
 	// {Cluster}Roles to include in a CSV via their Bindings.
 	ExtraServiceAccounts []string
+	// GrantSCCUsers grants ServiceAccounts that are users of OpenShift SecurityContextConstraints in Collector
+	// the use of those SCCs in the CSV's cluster permissions. Otherwise only (Cluster)Roles bound to
+	// ServiceAccounts grant the use of SCCs, like any other permission.
+	GrantSCCUsers bool
+	// ReconcileDescriptors reconciles owned CRD spec and status descriptors
+	// with the schemas of CustomResourceDefinitions in Collector.
+	ReconcileDescriptors bool
+	// Properties are OLM properties merged into the resulting CSV's properties annotation.
+	Properties []Property
+	// Dependencies are APIs and packages of other operators that the operator requires. Required APIs are
+	// added to the CSV's required CRD descriptions, and all are added to its properties annotation.
+	Dependencies Dependencies
+	// InjectWatchNamespace adds a WATCH_NAMESPACE environment variable referencing the CSV's
+	// target namespaces to each deployment's manager container if not already present.
+	InjectWatchNamespace bool
+	// StripWatchNamespace removes WATCH_NAMESPACE environment variables referencing the CSV's target
+	// namespaces from each deployment, ex. for operators that only support AllNamespaces.
+	StripWatchNamespace bool
+	// ExternalCRDs marks CustomResourceDefinitions in Collector as required instead of owned,
+	// for CRDs shipped separately from the operator.
+	ExternalCRDs bool
+	// CleanupEnabled sets the CSV's spec.cleanup.enabled if not nil, otherwise the base's value is kept.
+	CleanupEnabled *bool
+	// RequireResources returns an error if any container of a Deployment in Collector
+	// does not request CPU and memory.
+	RequireResources bool
+	// RequireServiceAccounts returns an error if any Deployment in Collector runs as a ServiceAccount
+	// that was not collected, which is otherwise a warning.
+	RequireServiceAccounts bool
+	// RequireQualifiedImages returns an error if any image of the resulting CSV's deployments or related
+	// images is not fully qualified with a registry host, ex. busybox, as disconnected installs require.
+	RequireQualifiedImages bool
+	// RequiredAnnotations are annotation keys the resulting CSV must set to a non-empty value,
+	// ex. those a catalog requires. They are checked after all transforms are applied.
+	RequiredAnnotations []string
+	// MaxSize is the maximum size in bytes of the resulting CSV serialized as JSON, as an API server stores it.
+	// Zero disables the check.
+	MaxSize int
+	// Format configures how the written CSV's YAML is formatted, ex. wrapping long descriptions.
+	Format yamlutil.Options
+	// AllowEmptyInstall allows generating a CSV whose install strategy has no deployments,
+	// which OLM installs as an operator that does nothing.
+	AllowEmptyInstall bool
 
 	// Func that returns the writer the generated CSV's bytes are written to.
 	getWriter func() (io.Writer, error)
+	// Modes of directories and files created by bundle and package writers. Zero modes use defaults.
+	dirMode, fileMode os.FileMode
+	// Functions applied to the generated CSV before it is written.
+	transforms []ObjectTransform
+	// Func called with each warning of the Generator's checks, which are logged if nil.
+	warn func(msg string)
 }
 
+// ObjectTransform mutates a generated object before it is written,
+// ex. to replace image tags with digests.
+type ObjectTransform func(obj client.Object) error
+
 // Option is a function that modifies a Generator.
 type Option func(*Generator) error
 
@@ -78,9 +147,9 @@ func WithWriter(w io.Writer) Option {
 // <dir>/manifests.
 func WithBundleWriter(dir string) Option {
 	return func(g *Generator) error {
-		fileName := makeCSVFileName(g.OperatorName)
+		fileName := FileName(g.OperatorName)
 		g.getWriter = func() (io.Writer, error) {
-			return genutil.Open(filepath.Join(dir, bundle.ManifestsDir), fileName)
+			return genutil.Open(filepath.Join(dir, bundle.ManifestsDir), fileName, g.dirMode, g.fileMode)
 		}
 		return nil
 	}
@@ -90,14 +159,50 @@ func WithBundleWriter(dir string) Option {
 // <dir>/<version>.
 func WithPackageWriter(dir string) Option {
 	return func(g *Generator) error {
-		fileName := makeCSVFileName(g.OperatorName)
+		fileName := FileName(g.OperatorName)
 		g.getWriter = func() (io.Writer, error) {
-			return genutil.Open(filepath.Join(dir, g.Version), fileName)
+			return genutil.Open(filepath.Join(dir, g.Version), fileName, g.dirMode, g.fileMode)
 		}
 		return nil
 	}
 }
 
+// WithFileModes sets the modes of directories and files created by a Generator's bundle or package writer.
+// A zero mode uses the default.
+func WithFileModes(dirMode, fileMode os.FileMode) Option {
+	return func(g *Generator) error {
+		g.dirMode, g.fileMode = dirMode, fileMode
+		return nil
+	}
+}
+
+// WithObjectTransform adds transform to the functions applied, in order, to the generated CSV
+// after all other changes are made and before it is written.
+func WithObjectTransform(transform ObjectTransform) Option {
+	return func(g *Generator) error {
+		g.transforms = append(g.transforms, transform)
+		return nil
+	}
+}
+
+// WithWarningHandler sets a function a Generator calls with each warning of its checks instead of logging it,
+// ex. to report warnings with other problems.
+func WithWarningHandler(handle func(msg string)) Option {
+	return func(g *Generator) error {
+		g.warn = handle
+		return nil
+	}
+}
+
+// warnf calls g's warning handler with a message formatted like fmt.Sprintf, or logs it if g has none.
+func (g Generator) warnf(format string, args ...interface{}) {
+	if g.warn == nil {
+		log.Warnf(format, args...)
+		return
+	}
+	g.warn(fmt.Sprintf(format, args...))
+}
+
 // Generate configures the generator with col and opts then runs it.
 func (g *Generator) Generate(opts ...Option) (err error) {
 	for _, opt := range opts {
@@ -115,14 +220,41 @@ func (g *Generator) Generate(opts ...Option) (err error) {
 		return err
 	}
 
-	// Add extra annotations to csv
+	// Add extra annotations and labels to csv
 	g.setAnnotations(csv)
+	g.setLabels(csv)
+	props := append(append([]Property{}, g.Properties...), g.Dependencies.properties()...)
+	if err := setProperties(csv, props); err != nil {
+		return err
+	}
+	for _, transform := range g.transforms {
+		if err := transform(csv); err != nil {
+			return fmt.Errorf("error transforming ClusterServiceVersion %s: %v", csv.GetName(), err)
+		}
+	}
+	// Transforms may add related images, so normalize them afterwards.
+	normalizeRelatedImages(csv)
+	if g.RequireQualifiedImages {
+		if msgs := checkQualifiedImages(csv); len(msgs) != 0 {
+			return checkErrorList("images must be fully qualified with a registry host", msgs)
+		}
+	}
+	if err := k8sutil.ValidateObjectMetadata(csv); err != nil {
+		return checkErrorf("ClusterServiceVersion %v", err)
+	}
+	if missing := checkRequiredAnnotations(csv, g.RequiredAnnotations); len(missing) != 0 {
+		return checkErrorf("ClusterServiceVersion %s is missing required annotations: %s",
+			csv.GetName(), strings.Join(missing, ", "))
+	}
+	if err := k8sutil.CheckObjectSize(csv, g.MaxSize); err != nil {
+		return checkErrorf("ClusterServiceVersion %v", err)
+	}
 
 	w, err := g.getWriter()
 	if err != nil {
 		return err
 	}
-	return genutil.WriteObject(w, csv)
+	return genutil.WriteObjectFormatted(w, csv, g.Format)
 }
 
 // setSDKAnnotations adds SDK metric labels to the base if they do not exist.
@@ -137,45 +269,152 @@ func (g Generator) setAnnotations(csv *v1alpha1.ClusterServiceVersion) {
 	csv.SetAnnotations(annotations)
 }
 
+// setLabels adds labels to csv, overriding existing labels with the same key.
+func (g Generator) setLabels(csv *v1alpha1.ClusterServiceVersion) {
+	if len(g.Labels) == 0 {
+		return
+	}
+	labels := csv.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range g.Labels {
+		labels[k] = v
+	}
+	csv.SetLabels(labels)
+}
+
 // generate runs a configured Generator.
 func (g *Generator) generate() (base *operatorsv1alpha1.ClusterServiceVersion, err error) {
 	if g.Collector == nil {
 		return nil, fmt.Errorf("cannot generate CSV without a manifests collection")
 	}
+	col, err := g.Collector.Collect()
+	if err != nil {
+		return nil, fmt.Errorf("error collecting manifests: %v", err)
+	}
 
+	baseOperatorName := g.OperatorName
+	if g.BaseOperatorName != "" {
+		baseOperatorName = g.BaseOperatorName
+	}
 	// Search for a CSV in the collector with a name matching the package name.
-	csvNamePrefix := g.OperatorName + "."
-	for _, csv := range g.Collector.ClusterServiceVersions {
+	csvNamePrefix := baseOperatorName + "."
+	for _, csv := range col.ClusterServiceVersions {
 		if base == nil && strings.HasPrefix(csv.GetName(), csvNamePrefix) {
 			base = csv.DeepCopy()
 		}
 	}
 
-	// Use a default base if none was supplied.
+	// Use a default base, named after the CSV, if none was supplied.
 	if base == nil {
 		base = bases.New(g.OperatorName)
+		baseOperatorName = g.OperatorName
+	}
+	// The base's name should encode the base's version; a mismatch means it was edited by hand.
+	baseName := base.GetName()
+	// Build metadata, ex. set by SpecVersion, is not part of the name.
+	nameVersion := base.Spec.Version.Version
+	nameVersion.Build = nil
+	if expName := genutil.MakeCSVName(baseOperatorName, nameVersion.String()); baseName != expName {
+		g.warnf("ClusterServiceVersion base name %q does not match its version, expected %q", baseName, expName)
 	}
 	if g.Version != "" {
-		// Use the existing version/name unless g.Version is set.
-		base.SetName(genutil.MakeCSVName(g.OperatorName, g.Version))
+		// Use the existing version unless g.Version is set.
 		if base.Spec.Version.Version, err = semver.Parse(g.Version); err != nil {
 			return nil, err
 		}
 	}
-	if g.FromVersion != "" {
+	// Always name the CSV after its version so the two cannot drift.
+	base.SetName(genutil.MakeCSVName(g.OperatorName, base.Spec.Version.String()))
+	if baseName != base.GetName() {
+		log.Debugf("Renamed ClusterServiceVersion base %q to %q", baseName, base.GetName())
+	}
+	if g.SpecVersion != "" {
+		if base.Spec.Version.Version, err = semver.Parse(g.SpecVersion); err != nil {
+			return nil, err
+		}
+	}
+	if g.Replaces != "" {
+		base.Spec.Replaces = g.Replaces
+	} else if g.FromVersion != "" {
 		base.Spec.Replaces = genutil.MakeCSVName(g.OperatorName, g.FromVersion)
 	}
+	if g.CleanupEnabled != nil {
+		base.Spec.Cleanup.Enabled = *g.CleanupEnabled
+	}
+
+	if g.RequireResources {
+		if msgs := checkDeploymentResources(col); len(msgs) != 0 {
+			return nil, checkErrorList("containers must request resources", msgs)
+		}
+	}
+
+	if msgs := checkServiceAccounts(col); len(msgs) != 0 {
+		if g.RequireServiceAccounts {
+			return nil, checkErrorList("service accounts of deployments must be collected", msgs)
+		}
+		for _, msg := range msgs {
+			g.warnf("ClusterServiceVersion %s: %s, so OLM will not create it", base.GetName(), msg)
+		}
+	}
+
+	if msgs := checkConversionWebhookPorts(col); len(msgs) != 0 {
+		return nil, checkErrorList("conversion webhooks must be served by the operator", msgs)
+	}
+
+	// Owned CRD descriptions are rebuilt from collected CRDs, so check them beforehand.
+	for _, msg := range checkOwnedCRDVersions(col, base) {
+		g.warnf("ClusterServiceVersion %s: %s", base.GetName(), msg)
+	}
 
-	if err := ApplyTo(g.Collector, base, g.ExtraServiceAccounts); err != nil {
+	if err := applyTo(col, base, g.ExtraServiceAccounts, g.warnf); err != nil {
+		return nil, err
+	}
+	if g.GrantSCCUsers {
+		applySecurityContextConstraints(col, &base.Spec.InstallStrategy.StrategySpec, g.ExtraServiceAccounts)
+	}
+	if !g.AllowEmptyInstall && len(base.Spec.InstallStrategy.StrategySpec.DeploymentSpecs) == 0 {
+		return nil, checkErrorf("ClusterServiceVersion %s %w", base.GetName(), ErrEmptyInstall)
+	}
+	// Webhook definitions are built when CRDs and webhooks are applied, so check them afterwards.
+	if msgs := checkConversionStrategies(col, base); len(msgs) != 0 {
+		return nil, checkErrorList("conversion webhooks must match CRD conversion strategies", msgs)
+	}
+
+	if g.InjectWatchNamespace {
+		injectWatchNamespace(base)
+	}
+	if g.StripWatchNamespace {
+		stripWatchNamespace(base)
+	}
+
+	for _, msg := range checkInstallModes(base) {
+		g.warnf("ClusterServiceVersion %s: %s", base.GetName(), msg)
+	}
+
+	if g.ReconcileDescriptors {
+		if err := reconcileDescriptors(col, base); err != nil {
+			return nil, fmt.Errorf("error reconciling CRD descriptors: %v", err)
+		}
+	}
+
+	if g.ExternalCRDs {
+		if err := requireOwnedCRDs(col, base); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyDependencies(base, g.Dependencies); err != nil {
 		return nil, err
 	}
 
 	return base, nil
 }
 
-// makeCSVFileName returns a CSV file name containing name.
-func makeCSVFileName(name string) string {
-	return strings.ToLower(name) + csvYamlFileExt
+// FileName returns the name of the file a Generator's bundle or package writer writes the CSV of
+// operatorName to.
+func FileName(operatorName string) string {
+	return strings.ToLower(operatorName) + csvYamlFileExt
 }
 
 // requiresInteraction checks if the combination of ilvl and basePath existence
@@ -16,6 +16,7 @@ package clusterserviceversion
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -28,12 +29,17 @@ import (
 	"github.com/operator-framework/api/pkg/operators/v1alpha1"
 	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
 	"github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion/bases"
 	"github.com/operator-framework/operator-sdk/internal/generate/collector"
 	genutil "github.com/operator-framework/operator-sdk/internal/generate/internal"
 	"github.com/operator-framework/operator-sdk/internal/util/projutil"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
 )
 
 var (
@@ -125,6 +131,73 @@ var _ = Describe("Testing CRDs with single version", func() {
 					Expect(g.Generate(opts...)).ToNot(HaveOccurred())
 					Expect(buf.String()).To(MatchYAML(newCSVUIMetaStr))
 				})
+				It("should write a ClusterServiceVersion manifest with wrapped lines to an io.Writer", func() {
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    col,
+						Format:       yamlutil.Options{MaxLineWidth: 60},
+					}
+					Expect(g.Generate(WithWriter(buf))).ToNot(HaveOccurred())
+					Expect(buf.String()).To(MatchYAML(newCSVUIMetaStr))
+					Expect(buf.String()).To(ContainSubstring(": >-\n"))
+				})
+				It("should apply object transforms in order before writing", func() {
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    col,
+					}
+					setAnnotation := func(value string) ObjectTransform {
+						return func(obj client.Object) error {
+							annotations := obj.GetAnnotations()
+							annotations["example.com/transformed"] = value
+							obj.SetAnnotations(annotations)
+							return nil
+						}
+					}
+					opts := []Option{
+						WithWriter(buf),
+						WithObjectTransform(setAnnotation("first")),
+						WithObjectTransform(setAnnotation("second")),
+					}
+					Expect(g.Generate(opts...)).ToNot(HaveOccurred())
+					Expect(buf.String()).To(ContainSubstring("example.com/transformed: second\n"))
+				})
+				It("should return transform errors without writing", func() {
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    col,
+					}
+					transform := func(client.Object) error { return errors.New("no digest for image") }
+					err := g.Generate(WithWriter(buf), WithObjectTransform(transform))
+					Expect(err).To(MatchError(ContainSubstring("no digest for image")))
+					Expect(buf.Len()).To(BeZero())
+				})
+				It("should return an error for an invalid annotation key without writing", func() {
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    col,
+						Annotations:  map[string]string{"-example.com/foo": "bar"},
+					}
+					err := g.Generate(WithWriter(buf))
+					Expect(err).To(MatchError(ContainSubstring(`metadata.annotations: Invalid value: "-example.com/foo"`)))
+					Expect(buf.Len()).To(BeZero())
+				})
+				It("should return an error for a CSV larger than the maximum size without writing", func() {
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    col,
+						MaxSize:      1024,
+					}
+					err := g.Generate(WithWriter(buf))
+					Expect(err).To(MatchError(MatchRegexp(`^ClusterServiceVersion "memcached-operator.v0.0.1" is \d+ bytes ` +
+						`serialized, which exceeds the maximum of 1024 bytes$`)))
+					Expect(buf.Len()).To(BeZero())
+				})
 				It("should write a ClusterServiceVersion manifest to a bundle file", func() {
 					g = Generator{
 						OperatorName: operatorName,
@@ -135,7 +208,7 @@ var _ = Describe("Testing CRDs with single version", func() {
 						WithBundleWriter(tmp),
 					}
 					Expect(g.Generate(opts...)).ToNot(HaveOccurred())
-					outputFile := filepath.Join(tmp, bundle.ManifestsDir, makeCSVFileName(operatorName))
+					outputFile := filepath.Join(tmp, bundle.ManifestsDir, FileName(operatorName))
 					Expect(outputFile).To(BeAnExistingFile())
 					Expect(readFileHelper(outputFile)).To(MatchYAML(newCSVUIMetaStr))
 				})
@@ -149,7 +222,7 @@ var _ = Describe("Testing CRDs with single version", func() {
 						WithPackageWriter(tmp),
 					}
 					Expect(g.Generate(opts...)).ToNot(HaveOccurred())
-					outputFile := filepath.Join(tmp, g.Version, makeCSVFileName(operatorName))
+					outputFile := filepath.Join(tmp, g.Version, FileName(operatorName))
 					Expect(outputFile).To(BeAnExistingFile())
 					Expect(readFileHelper(outputFile)).To(MatchYAML(newCSVUIMetaStr))
 				})
@@ -217,6 +290,19 @@ var _ = Describe("Testing CRDs with single version", func() {
 					csvExp.Spec.Version.Patch = 3
 					Expect(csv).To(Equal(csvExp))
 				})
+				It("should set '.spec.replaces' to Replaces instead of deriving it from FromVersion", func() {
+					col.ClusterServiceVersions = []v1alpha1.ClusterServiceVersion{*baseCSVUIMeta}
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      "0.0.3",
+						FromVersion:  "0.0.2",
+						Replaces:     "memcached-operator-legacy.v0.0.2",
+						Collector:    col,
+					}
+					csv, err := g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(csv.Spec.Replaces).To(Equal("memcached-operator-legacy.v0.0.2"))
+				})
 				It("should return a new object with version set", func() {
 					col.ClusterServiceVersions = []v1alpha1.ClusterServiceVersion{*baseCSVUIMeta}
 					g = Generator{
@@ -238,22 +324,218 @@ var _ = Describe("Testing CRDs with single version", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(csv).To(Equal(newCSVUIMeta))
 				})
+				It("should return an object named after the new version", func() {
+					baseCSVIn := baseCSV.DeepCopy()
+					baseCSVIn.SetName("foo.v0.1.0")
+					baseCSVIn.Spec.Version.Version = semver.MustParse("0.1.0")
+					col.ClusterServiceVersions = []v1alpha1.ClusterServiceVersion{*baseCSVIn}
+					g = Generator{
+						OperatorName: "foo",
+						Version:      "0.2.0",
+						Collector:    col,
+					}
+					csv, err := g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(csv.GetName()).To(Equal("foo.v0.2.0"))
+					Expect(csv.Spec.Version.String()).To(Equal("0.2.0"))
+				})
+				It("should return an object named after the version with the spec version set", func() {
+					baseCSVIn := baseCSV.DeepCopy()
+					baseCSVIn.SetName("foo.v0.1.0")
+					baseCSVIn.Spec.Version.Version = semver.MustParse("0.1.0+build.4")
+					col.ClusterServiceVersions = []v1alpha1.ClusterServiceVersion{*baseCSVIn}
+					g = Generator{
+						OperatorName: "foo",
+						Version:      "0.2.0",
+						SpecVersion:  "0.2.0+build.5",
+						Collector:    col,
+					}
+					csv, err := g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(csv.GetName()).To(Equal("foo.v0.2.0"))
+					Expect(csv.Spec.Version.String()).To(Equal("0.2.0+build.5"))
+				})
+				It("should return an object named after the base version if the base name is stale", func() {
+					baseCSVIn := baseCSV.DeepCopy()
+					baseCSVIn.SetName("foo.v0.1.0")
+					baseCSVIn.Spec.Version.Version = semver.MustParse("0.1.1")
+					col.ClusterServiceVersions = []v1alpha1.ClusterServiceVersion{*baseCSVIn}
+					g = Generator{
+						OperatorName: "foo",
+						Collector:    col,
+					}
+					csv, err := g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(csv.GetName()).To(Equal("foo.v0.1.1"))
+				})
+				It("should return an object with cleanup set or preserved", func() {
+					baseCSVIn := baseCSV.DeepCopy()
+					baseCSVIn.Spec.Cleanup.Enabled = true
+					col.ClusterServiceVersions = []v1alpha1.ClusterServiceVersion{*baseCSVIn}
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    col,
+					}
+					csv, err := g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(csv.Spec.Cleanup.Enabled).To(BeTrue())
+
+					disabled := false
+					g.CleanupEnabled = &disabled
+					csv, err = g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(csv.Spec.Cleanup.Enabled).To(BeFalse())
+				})
+				It("should require container resources if configured", func() {
+					dep := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controller-manager"}}
+					dep.Spec.Template.Spec.Containers = []corev1.Container{{Name: "manager"}}
+					g = Generator{
+						OperatorName:     operatorName,
+						Version:          zeroZeroOne,
+						Collector:        &collector.Manifests{Deployments: []appsv1.Deployment{dep}},
+						RequireResources: true,
+					}
+					_, err := g.generate()
+					Expect(err).To(MatchError(ContainSubstring("container manager has no cpu or memory requests")))
+
+					dep.Spec.Template.Spec.Containers[0].Resources.Requests = corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("100m"),
+						corev1.ResourceMemory: resource.MustParse("20Mi"),
+					}
+					g.Collector = &collector.Manifests{Deployments: []appsv1.Deployment{dep}}
+					_, err = g.generate()
+					Expect(err).NotTo(HaveOccurred())
+				})
+				It("should not set WATCH_NAMESPACE if configured", func() {
+					dep := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controller-manager"}}
+					dep.Spec.Template.Spec.Containers = []corev1.Container{{
+						Name: "manager",
+						Env:  []corev1.EnvVar{{Name: WatchNamespaceEnv, Value: ""}},
+					}}
+					g = Generator{
+						OperatorName:        operatorName,
+						Version:             zeroZeroOne,
+						Collector:           &collector.Manifests{Deployments: []appsv1.Deployment{dep}},
+						StripWatchNamespace: true,
+					}
+					csv, err := g.generate()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs).To(HaveLen(1))
+					Expect(csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[0].Spec.Template.Spec.Containers[0].Env).To(BeEmpty())
+				})
+				It("should fail on an empty install strategy unless allowed", func() {
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    &collector.Manifests{},
+					}
+					_, err := g.generate()
+					Expect(err).To(MatchError("ClusterServiceVersion " + operatorName + ".v0.0.1 install strategy has no deployments"))
+					Expect(err).To(MatchError(ErrEmptyInstall))
+					Expect(err).To(BeAssignableToTypeOf(CheckError{}))
+
+					g.AllowEmptyInstall = true
+					csv, err := g.generate()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs).To(BeEmpty())
+				})
+				It("should pass warnings to the handler if one is set", func() {
+					baseCSVIn := baseCSV.DeepCopy()
+					baseCSVIn.SetName(operatorName + ".v9.9.9")
+					var warnings []string
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector: &collector.Manifests{
+							ClusterServiceVersions: []v1alpha1.ClusterServiceVersion{*baseCSVIn},
+							Deployments:            []appsv1.Deployment{{ObjectMeta: metav1.ObjectMeta{Name: "controller-manager"}}},
+						},
+					}
+					Expect(WithWarningHandler(func(msg string) { warnings = append(warnings, msg) })(&g)).To(Succeed())
+					_, err := g.generate()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(warnings).To(ContainElement(HavePrefix(
+						`ClusterServiceVersion base name "` + operatorName + `.v9.9.9" does not match its version`)))
+				})
+				It("should warn about uncollected service accounts unless they are required", func() {
+					dep := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controller-manager"}}
+					dep.Spec.Template.Spec.ServiceAccountName = "controller-manager"
+					var warnings []string
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    &collector.Manifests{Deployments: []appsv1.Deployment{dep}},
+					}
+					Expect(WithWarningHandler(func(msg string) { warnings = append(warnings, msg) })(&g)).To(Succeed())
+					_, err := g.generate()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(warnings).To(ContainElement(HaveSuffix(
+						"deployment controller-manager service account controller-manager was not collected, " +
+							"so OLM will not create it")))
+
+					g.RequireServiceAccounts = true
+					_, err = g.generate()
+					Expect(err).To(MatchError("service accounts of deployments must be collected:\n" +
+						"  - deployment controller-manager service account controller-manager was not collected"))
+					Expect(err).To(BeAssignableToTypeOf(CheckError{}))
+				})
+				It("should collect manifests from any Collector", func() {
+					dep := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "controller-manager"}}
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector: collectorFunc(func() (*collector.Manifests, error) {
+							return &collector.Manifests{Deployments: []appsv1.Deployment{dep}}, nil
+						}),
+					}
+					csv, err := g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs).To(HaveLen(1))
+					Expect(csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[0].Name).To(Equal("controller-manager"))
+
+					g.Collector = collectorFunc(func() (*collector.Manifests, error) {
+						return nil, errors.New("connection refused")
+					})
+					_, err = g.generate()
+					Expect(err).To(MatchError("error collecting manifests: connection refused"))
+				})
+				It("should return an object with labels added", func() {
+					baseCSVIn := baseCSV.DeepCopy()
+					baseCSVIn.SetLabels(map[string]string{"keep": "me", "build": "4"})
+					col.ClusterServiceVersions = []v1alpha1.ClusterServiceVersion{*baseCSVIn}
+					g = Generator{
+						OperatorName: operatorName,
+						Version:      zeroZeroOne,
+						Collector:    col,
+						Labels:       map[string]string{"build": "5", "app.kubernetes.io/part-of": "memcached"},
+					}
+					csv, err := g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					g.setLabels(csv)
+					Expect(csv.GetLabels()).To(Equal(map[string]string{
+						"keep":                      "me",
+						"build":                     "5",
+						"app.kubernetes.io/part-of": "memcached",
+					}))
+				})
 			})
 
 			Context("to update an existing ClusterServiceVersion", func() {
 				It("should return an updated object", func() {
+					col := &collector.Manifests{
+						ClusterServiceVersions: []v1alpha1.ClusterServiceVersion{*newCSVUIMeta},
+					}
 					g = Generator{
 						OperatorName: operatorName,
 						Version:      zeroZeroOne,
-						Collector: &collector.Manifests{
-							ClusterServiceVersions: []v1alpha1.ClusterServiceVersion{*newCSVUIMeta},
-						},
+						Collector:    col,
 					}
 					// Update the input's and expected CSV's Deployment image.
-					collectManifestsFromFileHelper(g.Collector, goBasicOperatorPath)
-					Expect(len(g.Collector.Deployments)).To(BeNumerically(">=", 1))
+					collectManifestsFromFileHelper(col, goBasicOperatorPath)
+					Expect(len(col.Deployments)).To(BeNumerically(">=", 1))
 					imageTag := "controller:v" + g.Version
-					modifyDepImageHelper(&g.Collector.Deployments[0].Spec, imageTag)
+					modifyDepImageHelper(&col.Deployments[0].Spec, imageTag)
 					updatedCSV := updateCSV(newCSVUIMeta, modifyCSVDepImageHelper(imageTag))
 
 					csv, err := g.generate()
@@ -279,6 +561,22 @@ var _ = Describe("Testing CRDs with single version", func() {
 					Expect(csv).To(Equal(upgradeCSV(newCSVUIMeta, g.OperatorName, g.Version)))
 				})
 			})
+
+			Context("to publish an existing ClusterServiceVersion under another name", func() {
+				It("should use the base of the original name", func() {
+					col.ClusterServiceVersions = []v1alpha1.ClusterServiceVersion{*newCSVUIMeta}
+					g = Generator{
+						OperatorName:     "partner-operator",
+						BaseOperatorName: operatorName,
+						Version:          zeroZeroOne,
+						Collector:        col,
+					}
+					csv, err := g.generate()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(csv.GetName()).To(Equal("partner-operator.v0.0.1"))
+					Expect(csv.Spec.DisplayName).To(Equal(newCSVUIMeta.Spec.DisplayName))
+				})
+			})
 		})
 	})
 
@@ -377,6 +675,11 @@ var _ = Describe("Testing CRDs with multiple version", func() {
 	})
 })
 
+// collectorFunc is a collector.Collector that calls itself to collect manifests.
+type collectorFunc func() (*collector.Manifests, error)
+
+func (f collectorFunc) Collect() (*collector.Manifests, error) { return f() }
+
 func collectManifestsFromFileHelper(col *collector.Manifests, path string) {
 	f, err := os.Open(path)
 	ExpectWithOffset(1, err).ToNot(HaveOccurred())
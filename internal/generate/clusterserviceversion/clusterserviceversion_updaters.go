@@ -39,6 +39,12 @@ import (
 // ApplyTo applies relevant manifests in c to csv, sorts the applied updates,
 // and validates the result.
 func ApplyTo(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersion, extraSAs []string) error {
+	return applyTo(c, csv, extraSAs, log.Warnf)
+}
+
+// applyTo is ApplyTo, calling warnf with each validation warning.
+func applyTo(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersion, extraSAs []string,
+	warnf func(format string, args ...interface{})) error {
 	// Apply manifests to the CSV object.
 	if err := apply(c, csv, extraSAs); err != nil {
 		return err
@@ -47,7 +53,7 @@ func ApplyTo(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersio
 	// Set fields required by namespaced operators. This is a no-op for cluster-scoped operators.
 	setNamespacedFields(csv)
 
-	return validate(csv)
+	return validate(csv, warnf)
 }
 
 // apply applies relevant manifests in c to csv.
@@ -82,8 +88,9 @@ func getCSVInstallStrategy(csv *operatorsv1alpha1.ClusterServiceVersion) operato
 // This service account exists in every namespace as the default.
 const defaultServiceAccountName = "default"
 
-// applyRoles applies Roles to strategy's permissions field by combining Roles bound to ServiceAccounts
-// into one set of permissions.
+// applyRoles applies Roles and ClusterRoles bound by RoleBindings to strategy's permissions field by combining
+// them per ServiceAccount into one set of permissions. Permissions follow the binding's kind, not the role's:
+// a ClusterRole bound by a RoleBinding only grants access within the binding's namespace.
 func applyRoles(c *collector.Manifests, objs []client.Object, strategy *operatorsv1alpha1.StrategyDetailsDeployment, extraSAs []string) { //nolint:dupl
 	roleSet := make(map[string]rbacv1.Role)
 	cRoleSet := make(map[string]rbacv1.ClusterRole)
@@ -102,7 +109,7 @@ func applyRoles(c *collector.Manifests, objs []client.Object, strategy *operator
 	for _, binding := range c.RoleBindings {
 		for _, subject := range binding.Subjects {
 			perm, hasSA := saToPermissions[subject.Name]
-			if subject.Kind != "ServiceAccount" || !hasSA {
+			if subject.Kind != "ServiceAccount" || !hasSA || !isRBACGroup(binding.RoleRef.APIGroup) {
 				continue
 			}
 			var (
@@ -122,6 +129,8 @@ func applyRoles(c *collector.Manifests, objs []client.Object, strategy *operator
 				continue
 			}
 			if hasRole {
+				log.Debugf("Adding %s %q to spec.install.spec.permissions of ServiceAccount %q via RoleBinding %q",
+					binding.RoleRef.Kind, binding.RoleRef.Name, subject.Name, binding.GetName())
 				perm.Rules = append(perm.Rules, rules...)
 				saToPermissions[subject.Name] = perm
 			}
@@ -132,6 +141,7 @@ func applyRoles(c *collector.Manifests, objs []client.Object, strategy *operator
 	perms := []operatorsv1alpha1.StrategyDeploymentPermissions{}
 	for _, perm := range saToPermissions {
 		if len(perm.Rules) != 0 {
+			perm.Rules = sortRules(perm.Rules)
 			perms = append(perms, perm)
 		}
 	}
@@ -141,8 +151,8 @@ func applyRoles(c *collector.Manifests, objs []client.Object, strategy *operator
 	strategy.Permissions = perms
 }
 
-// applyClusterRoles applies ClusterRoles to strategy's clusterPermissions field by combining ClusterRoles
-// bound to ServiceAccounts into one set of clusterPermissions.
+// applyClusterRoles applies ClusterRoles bound by ClusterRoleBindings to strategy's clusterPermissions field
+// by combining them per ServiceAccount into one set of clusterPermissions.
 func applyClusterRoles(c *collector.Manifests, objs []client.Object, strategy *operatorsv1alpha1.StrategyDetailsDeployment, extraSAs []string) { //nolint:dupl
 	roleSet := make(map[string]rbacv1.ClusterRole)
 	for i := range objs {
@@ -161,7 +171,14 @@ func applyClusterRoles(c *collector.Manifests, objs []client.Object, strategy *o
 			if !hasSA || subject.Kind != "ServiceAccount" {
 				continue
 			}
+			// A ClusterRoleBinding can only reference a ClusterRole. A ClusterRole referenced by a RoleBinding
+			// only grants access within the binding's namespace, and is applied to permissions by applyRoles.
+			if binding.RoleRef.Kind != "ClusterRole" || !isRBACGroup(binding.RoleRef.APIGroup) {
+				continue
+			}
 			if role, hasRole := roleSet[binding.RoleRef.Name]; hasRole {
+				log.Debugf("Adding ClusterRole %q to spec.install.spec.clusterPermissions of ServiceAccount %q via ClusterRoleBinding %q",
+					binding.RoleRef.Name, subject.Name, binding.GetName())
 				perm.Rules = append(perm.Rules, role.Rules...)
 				saToPermissions[subject.Name] = perm
 			}
@@ -172,6 +189,7 @@ func applyClusterRoles(c *collector.Manifests, objs []client.Object, strategy *o
 	perms := []operatorsv1alpha1.StrategyDeploymentPermissions{}
 	for _, perm := range saToPermissions {
 		if len(perm.Rules) != 0 {
+			perm.Rules = sortRules(perm.Rules)
 			perms = append(perms, perm)
 		}
 	}
@@ -181,6 +199,56 @@ func applyClusterRoles(c *collector.Manifests, objs []client.Object, strategy *o
 	strategy.ClusterPermissions = perms
 }
 
+// isRBACGroup returns true if apiGroup, of a binding's roleRef, is the RBAC API group.
+func isRBACGroup(apiGroup string) bool {
+	return apiGroup == "" || apiGroup == rbacv1.GroupName
+}
+
+// sortRules returns a copy of rules with each rule's lists sorted, sorted by those lists, so permissions
+// do not depend on the order Roles, their rules, and their bindings are collected in.
+func sortRules(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	sorted := make([]rbacv1.PolicyRule, len(rules))
+	for i := range rules {
+		rule := rules[i].DeepCopy()
+		for _, list := range ruleLists(*rule) {
+			sort.Strings(list)
+		}
+		sorted[i] = *rule
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		listsI, listsJ := ruleLists(sorted[i]), ruleLists(sorted[j])
+		for k := range listsI {
+			if c := compareStrings(listsI[k], listsJ[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return sorted
+}
+
+// ruleLists returns rule's lists in the order rules are sorted by.
+func ruleLists(rule rbacv1.PolicyRule) [][]string {
+	return [][]string{rule.APIGroups, rule.Resources, rule.ResourceNames, rule.NonResourceURLs, rule.Verbs}
+}
+
+// compareStrings compares a and b element-wise, returning -1, 0, or 1.
+// A list that is a prefix of another is ordered first.
+func compareStrings(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
 // initPermissionSet initializes a map of ServiceAccount name to permissions, which are empty.
 func initPermissionSet(deps []appsv1.Deployment, extraSAs []string) map[string]operatorsv1alpha1.StrategyDeploymentPermissions {
 	saToPermissions := make(map[string]operatorsv1alpha1.StrategyDeploymentPermissions)
@@ -202,6 +270,7 @@ func initPermissionSet(deps []appsv1.Deployment, extraSAs []string) map[string]o
 func applyDeployments(c *collector.Manifests, strategy *operatorsv1alpha1.StrategyDetailsDeployment) {
 	depSpecs := []operatorsv1alpha1.StrategyDeploymentSpec{}
 	for _, dep := range c.Deployments {
+		log.Debugf("Adding Deployment %q to spec.install.spec.deployments", dep.GetName())
 		depSpecs = append(depSpecs, operatorsv1alpha1.StrategyDeploymentSpec{
 			Name: dep.GetName(),
 			Spec: dep.Spec,
@@ -228,6 +297,74 @@ func setNamespacedFields(csv *operatorsv1alpha1.ClusterServiceVersion) {
 	}
 }
 
+// managerContainerName is the name of the manager container in SDK-scaffolded Deployments.
+const managerContainerName = "manager"
+
+// injectWatchNamespace sets WATCH_NAMESPACE to the CSV's target namespaces in the manager container
+// of each of csv's deployments, adding the variable if it does not exist. The manager container
+// is the container named "manager", or the only container if there is one.
+func injectWatchNamespace(csv *operatorsv1alpha1.ClusterServiceVersion) {
+	envVar := newFieldRefEnvVar(WatchNamespaceEnv, TargetNamespacesRef)
+	for _, dep := range csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+		containers := dep.Spec.Template.Spec.Containers
+		idx := -1
+		for i, c := range containers {
+			if c.Name == managerContainerName {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 && len(containers) == 1 {
+			idx = 0
+		}
+		if idx == -1 {
+			log.Warnf("Not injecting %s into deployment %q: no container named %q",
+				WatchNamespaceEnv, dep.Name, managerContainerName)
+			continue
+		}
+		setContainerEnvVar(&containers[idx], envVar)
+	}
+}
+
+// stripWatchNamespace removes WATCH_NAMESPACE environment variables referencing the CSV's target namespaces
+// from all containers of csv's deployments. Variables with other values are kept.
+func stripWatchNamespace(csv *operatorsv1alpha1.ClusterServiceVersion) {
+	for _, dep := range csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+		podSpec := &dep.Spec.Template.Spec
+		for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+			for i := range containers {
+				env := containers[i].Env[:0]
+				for _, ev := range containers[i].Env {
+					if ev.Name == WatchNamespaceEnv && ev.ValueFrom != nil && ev.ValueFrom.FieldRef != nil &&
+						ev.ValueFrom.FieldRef.FieldPath == TargetNamespacesRef {
+						log.Debugf("Removing %s from deployment %q container %q", WatchNamespaceEnv, dep.Name, containers[i].Name)
+						continue
+					}
+					env = append(env, ev)
+				}
+				if len(env) == 0 {
+					env = nil
+				}
+				containers[i].Env = env
+			}
+		}
+	}
+}
+
+// setContainerEnvVar overwrites all references to ev.Name in c with ev, or appends ev if c has none.
+func setContainerEnvVar(c *corev1.Container, ev corev1.EnvVar) {
+	found := false
+	for i := range c.Env {
+		if c.Env[i].Name == ev.Name {
+			c.Env[i] = ev
+			found = true
+		}
+	}
+	if !found {
+		c.Env = append(c.Env, ev)
+	}
+}
+
 // setContainerEnvVarIfExists overwrites all references to ev.Name with ev.
 func setContainerEnvVarIfExists(spec *appsv1.DeploymentSpec, ev corev1.EnvVar) {
 	for _, c := range spec.Template.Spec.Containers {
@@ -278,6 +415,7 @@ func applyCustomResourceDefinitions(c *collector.Manifests, csv *operatorsv1alph
 	}
 
 	for _, defKey := range defKeys {
+		log.Debugf("Adding CustomResourceDefinition %s version %s to spec.customresourcedefinitions.owned", defKey.Name, defKey.Version)
 		if owned, ownedExists := descMap[defKey]; ownedExists {
 			ownedDescs = append(ownedDescs, owned)
 		} else {
@@ -292,6 +430,35 @@ func applyCustomResourceDefinitions(c *collector.Manifests, csv *operatorsv1alph
 	csv.Spec.CustomResourceDefinitions.Owned = ownedDescs
 }
 
+// requireOwnedCRDs moves csv's owned CRD descriptions to customresourcedefinitions.required,
+// replacing required descriptions of the same CRD version. An error is returned if c contains
+// no CustomResourceDefinitions, since the CSV would not record the CRDs it depends on.
+func requireOwnedCRDs(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersion) error {
+	if len(c.V1CustomResourceDefinitions) == 0 && len(c.V1beta1CustomResourceDefinitions) == 0 {
+		return errors.New("no CustomResourceDefinitions were collected to mark as required")
+	}
+
+	crds := &csv.Spec.CustomResourceDefinitions
+	owned := make(map[string]struct{}, len(crds.Owned))
+	for _, desc := range crds.Owned {
+		owned[desc.Name+"/"+desc.Version] = struct{}{}
+	}
+	required := make([]operatorsv1alpha1.CRDDescription, 0, len(crds.Required)+len(crds.Owned))
+	for _, desc := range crds.Required {
+		if _, isOwned := owned[desc.Name+"/"+desc.Version]; !isOwned {
+			required = append(required, desc)
+		}
+	}
+	for _, desc := range crds.Owned {
+		log.Debugf("Moving CustomResourceDefinition %s version %s to spec.customresourcedefinitions.required",
+			desc.Name, desc.Version)
+		required = append(required, desc)
+	}
+	crds.Required = required
+	crds.Owned = nil
+	return nil
+}
+
 // applyWebhooks updates csv's webhookDefinitions with any mutating and validating webhooks in the collector.
 func applyWebhooks(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersion) {
 	webhookDescriptions := []operatorsv1alpha1.WebhookDescription{}
@@ -630,6 +797,7 @@ func findMatchingDepNameFromService(c *collector.Manifests, ws *corev1.Service)
 func applyCustomResources(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersion) error {
 	examples := []json.RawMessage{}
 	for _, cr := range c.CustomResources {
+		log.Debugf("Adding %s %q to %q annotation", cr.GetKind(), cr.GetName(), "alm-examples")
 		crBytes, err := cr.MarshalJSON()
 		if err != nil {
 			return err
@@ -649,30 +817,26 @@ func applyCustomResources(c *collector.Manifests, csv *operatorsv1alpha1.Cluster
 	return nil
 }
 
-// validate will validate csv using the api validation library.
+// validate will validate csv using the api validation library, calling warnf with each warning.
 // More info: https://github.com/operator-framework/api
-func validate(csv *operatorsv1alpha1.ClusterServiceVersion) error {
+func validate(csv *operatorsv1alpha1.ClusterServiceVersion, warnf func(format string, args ...interface{})) error {
 	if csv == nil {
 		return errors.New("empty ClusterServiceVersion")
 	}
 
-	hasErrors := false
+	var errs []string
 	results := validation.ClusterServiceVersionValidator.Validate(csv)
 	for _, r := range results {
 		for _, w := range r.Warnings {
-			log.Warnf("ClusterServiceVersion validation: [%s] %s", w.Type, w.Detail)
+			warnf("ClusterServiceVersion validation: [%s] %s", w.Type, w.Detail)
 		}
 		for _, e := range r.Errors {
-			log.Errorf("ClusterServiceVersion validation: [%s] %s", e.Type, e.Detail)
-		}
-		if r.HasError() {
-			hasErrors = true
+			errs = append(errs, fmt.Sprintf("[%s] %s", e.Type, e.Detail))
 		}
 	}
-	if hasErrors {
-		return errors.New("invalid generated ClusterServiceVersion")
+	if len(errs) != 0 {
+		return checkErrorList("invalid generated ClusterServiceVersion", errs)
 	}
-
 	return nil
 }
 
@@ -15,6 +15,8 @@
 package clusterserviceversion
 
 import (
+	"encoding/json"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
@@ -115,6 +117,90 @@ var _ = Describe("apply functions", func() {
 					{ServiceAccountName: extraSAName, Rules: cRole3Rules},
 				}))
 			})
+			It("adds the same sorted permissions regardless of Role, rule, and binding order", func() {
+				roleName2 := "role-2"
+				apps := rbacv1.PolicyRule{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"watch", "get", "list"}}
+				status := rbacv1.PolicyRule{APIGroups: []string{"my.group"}, Resources: []string{"memcacheds/status"}, Verbs: []string{"update", "get"}}
+				memcacheds := rbacv1.PolicyRule{APIGroups: []string{"my.group"}, Resources: []string{"memcacheds"}, Verbs: []string{"list", "create"}}
+				generate := func(reverse bool) []operatorsv1alpha1.StrategyDeploymentPermissions {
+					c = &collector.Manifests{}
+					c.Deployments = []appsv1.Deployment{newDeploymentWithServiceAccount(depName1, saName1)}
+					perms := []client.Object{newRole(roleName1, status, apps), newRole(roleName2, memcacheds)}
+					c.RoleBindings = []rbacv1.RoleBinding{
+						newRoleBinding("role-binding", newRoleRef(roleName1), newServiceAccountSubject(saName1)),
+						newRoleBinding("role-binding-2", newRoleRef(roleName2), newServiceAccountSubject(saName1)),
+					}
+					if reverse {
+						perms = []client.Object{newRole(roleName2, memcacheds), newRole(roleName1, apps, status)}
+						c.RoleBindings[0], c.RoleBindings[1] = c.RoleBindings[1], c.RoleBindings[0]
+					}
+					strategy = &operatorsv1alpha1.StrategyDetailsDeployment{}
+					applyRoles(c, perms, strategy, nil)
+					return strategy.Permissions
+				}
+				expPerms := []operatorsv1alpha1.StrategyDeploymentPermissions{{
+					ServiceAccountName: saName1,
+					Rules: []rbacv1.PolicyRule{
+						{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "list", "watch"}},
+						{APIGroups: []string{"my.group"}, Resources: []string{"memcacheds"}, Verbs: []string{"create", "list"}},
+						{APIGroups: []string{"my.group"}, Resources: []string{"memcacheds/status"}, Verbs: []string{"get", "update"}},
+					},
+				}}
+				Expect(generate(false)).To(Equal(expPerms))
+				Expect(generate(true)).To(Equal(expPerms))
+				// Collected Roles are not modified.
+				Expect(apps.Verbs).To(Equal([]string{"watch", "get", "list"}))
+			})
+		})
+
+		Context("permissions follow the binding kind", func() {
+			var csv *operatorsv1alpha1.ClusterServiceVersion
+			rules := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}}
+
+			BeforeEach(func() {
+				c.Deployments = []appsv1.Deployment{newDeploymentWithServiceAccount(depName1, saName1)}
+				c.ClusterRoles = []rbacv1.ClusterRole{*newClusterRole(cRoleName1, rules...)}
+				csv = &operatorsv1alpha1.ClusterServiceVersion{}
+				csv.Spec.InstallStrategy.StrategyName = operatorsv1alpha1.InstallStrategyNameDeployment
+			})
+
+			It("adds a ClusterRole bound by a RoleBinding to namespaced permissions", func() {
+				c.RoleBindings = []rbacv1.RoleBinding{
+					newRoleBinding("role-binding", newClusterRoleRef(cRoleName1), newServiceAccountSubject(saName1)),
+				}
+				Expect(apply(c, csv, nil)).To(Succeed())
+				Expect(csv.Spec.InstallStrategy.StrategySpec.Permissions).To(Equal([]operatorsv1alpha1.StrategyDeploymentPermissions{
+					{ServiceAccountName: saName1, Rules: rules},
+				}))
+				Expect(csv.Spec.InstallStrategy.StrategySpec.ClusterPermissions).To(BeEmpty())
+			})
+			It("adds a ClusterRole bound by both binding kinds to both permission sets", func() {
+				c.RoleBindings = []rbacv1.RoleBinding{
+					newRoleBinding("role-binding", newClusterRoleRef(cRoleName1), newServiceAccountSubject(saName1)),
+				}
+				c.ClusterRoleBindings = []rbacv1.ClusterRoleBinding{
+					newClusterRoleBinding("cluster-role-binding", newClusterRoleRef(cRoleName1), newServiceAccountSubject(saName1)),
+				}
+				Expect(apply(c, csv, nil)).To(Succeed())
+				exp := []operatorsv1alpha1.StrategyDeploymentPermissions{{ServiceAccountName: saName1, Rules: rules}}
+				Expect(csv.Spec.InstallStrategy.StrategySpec.Permissions).To(Equal(exp))
+				Expect(csv.Spec.InstallStrategy.StrategySpec.ClusterPermissions).To(Equal(exp))
+			})
+			It("does not add a ClusterRole to cluster permissions via a ClusterRoleBinding referencing a Role", func() {
+				perms := []client.Object{newClusterRole(cRoleName1, rules...)}
+				c.ClusterRoleBindings = []rbacv1.ClusterRoleBinding{
+					newClusterRoleBinding("cluster-role-binding", newRoleRef(cRoleName1), newServiceAccountSubject(saName1)),
+				}
+				applyClusterRoles(c, perms, strategy, nil)
+				Expect(strategy.ClusterPermissions).To(BeEmpty())
+			})
+			It("does not add roles referenced outside the RBAC API group", func() {
+				c.RoleBindings = []rbacv1.RoleBinding{
+					newRoleBinding("role-binding", newRef(cRoleName1, "ClusterRole", "example.com"), newServiceAccountSubject(saName1)),
+				}
+				applyRoles(c, []client.Object{newClusterRole(cRoleName1, rules...)}, strategy, nil)
+				Expect(strategy.Permissions).To(BeEmpty())
+			})
 		})
 
 		Context("collector contains no {Cluster}Roles", func() {
@@ -136,6 +222,108 @@ var _ = Describe("apply functions", func() {
 	})
 })
 
+var _ = Describe("injectWatchNamespace", func() {
+	var csv *operatorsv1alpha1.ClusterServiceVersion
+	expEnv := corev1.EnvVar{
+		Name: WatchNamespaceEnv,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: TargetNamespacesRef},
+		},
+	}
+	newCSVWithContainers := func(containers ...corev1.Container) *operatorsv1alpha1.ClusterServiceVersion {
+		dep := operatorsv1alpha1.StrategyDeploymentSpec{Name: "dep"}
+		dep.Spec.Template.Spec.Containers = containers
+		csv := &operatorsv1alpha1.ClusterServiceVersion{}
+		csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = []operatorsv1alpha1.StrategyDeploymentSpec{dep}
+		return csv
+	}
+	containers := func() []corev1.Container {
+		return csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[0].Spec.Template.Spec.Containers
+	}
+
+	It("adds WATCH_NAMESPACE to the manager container", func() {
+		csv = newCSVWithContainers(
+			corev1.Container{Name: "kube-rbac-proxy"},
+			corev1.Container{Name: "manager", Env: []corev1.EnvVar{{Name: "FOO", Value: "bar"}}},
+		)
+		injectWatchNamespace(csv)
+		Expect(containers()[0].Env).To(BeEmpty())
+		Expect(containers()[1].Env).To(Equal([]corev1.EnvVar{{Name: "FOO", Value: "bar"}, expEnv}))
+	})
+	It("overwrites an existing WATCH_NAMESPACE", func() {
+		csv = newCSVWithContainers(corev1.Container{Name: "manager", Env: []corev1.EnvVar{{Name: WatchNamespaceEnv, Value: "foo"}}})
+		injectWatchNamespace(csv)
+		Expect(containers()[0].Env).To(Equal([]corev1.EnvVar{expEnv}))
+	})
+	It("adds WATCH_NAMESPACE to the only container", func() {
+		csv = newCSVWithContainers(corev1.Container{Name: "operator"})
+		injectWatchNamespace(csv)
+		Expect(containers()[0].Env).To(Equal([]corev1.EnvVar{expEnv}))
+	})
+	It("does not add WATCH_NAMESPACE without a manager container", func() {
+		csv = newCSVWithContainers(corev1.Container{Name: "foo"}, corev1.Container{Name: "bar"})
+		injectWatchNamespace(csv)
+		Expect(containers()[0].Env).To(BeEmpty())
+		Expect(containers()[1].Env).To(BeEmpty())
+	})
+})
+
+var _ = Describe("stripWatchNamespace", func() {
+	It("removes WATCH_NAMESPACE referencing the target namespaces from all containers", func() {
+		watchNamespace := newFieldRefEnvVar(WatchNamespaceEnv, TargetNamespacesRef)
+		foo := corev1.EnvVar{Name: "FOO", Value: "bar"}
+		dep := operatorsv1alpha1.StrategyDeploymentSpec{Name: "dep"}
+		dep.Spec.Template.Spec.InitContainers = []corev1.Container{{Name: "init", Env: []corev1.EnvVar{watchNamespace}}}
+		dep.Spec.Template.Spec.Containers = []corev1.Container{
+			{Name: "manager", Env: []corev1.EnvVar{foo, watchNamespace}},
+			{Name: "sidecar", Env: []corev1.EnvVar{{Name: WatchNamespaceEnv, Value: "foo"}}},
+		}
+		csv := &operatorsv1alpha1.ClusterServiceVersion{}
+		csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = []operatorsv1alpha1.StrategyDeploymentSpec{dep}
+
+		stripWatchNamespace(csv)
+		podSpec := csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[0].Spec.Template.Spec
+		Expect(podSpec.InitContainers[0].Env).To(BeNil())
+		Expect(podSpec.Containers[0].Env).To(Equal([]corev1.EnvVar{foo}))
+		Expect(podSpec.Containers[1].Env).To(Equal([]corev1.EnvVar{{Name: WatchNamespaceEnv, Value: "foo"}}))
+	})
+})
+
+var _ = Describe("requireOwnedCRDs", func() {
+	var (
+		c   *collector.Manifests
+		csv *operatorsv1alpha1.ClusterServiceVersion
+	)
+	BeforeEach(func() {
+		c = &collector.Manifests{
+			V1CustomResourceDefinitions: []apiextv1.CustomResourceDefinition{{}},
+		}
+		csv = &operatorsv1alpha1.ClusterServiceVersion{}
+	})
+
+	It("moves owned CRDs to required, replacing required entries of the same version", func() {
+		csv.Spec.CustomResourceDefinitions = operatorsv1alpha1.CustomResourceDefinitions{
+			Owned: []operatorsv1alpha1.CRDDescription{
+				{Name: "foos.example.com", Version: "v1", Kind: "Foo", DisplayName: "Foo"},
+			},
+			Required: []operatorsv1alpha1.CRDDescription{
+				{Name: "bars.example.com", Version: "v1", Kind: "Bar"},
+				{Name: "foos.example.com", Version: "v1", Kind: "Foo"},
+			},
+		}
+		Expect(requireOwnedCRDs(c, csv)).To(Succeed())
+		Expect(csv.Spec.CustomResourceDefinitions.Owned).To(BeEmpty())
+		Expect(csv.Spec.CustomResourceDefinitions.Required).To(Equal([]operatorsv1alpha1.CRDDescription{
+			{Name: "bars.example.com", Version: "v1", Kind: "Bar"},
+			{Name: "foos.example.com", Version: "v1", Kind: "Foo", DisplayName: "Foo"},
+		}))
+	})
+	It("fails if no CRDs were collected", func() {
+		c.V1CustomResourceDefinitions = nil
+		Expect(requireOwnedCRDs(c, csv)).To(MatchError(ContainSubstring("no CustomResourceDefinitions were collected")))
+	})
+})
+
 var _ = Describe("applyCustomResourceDefinitions", func() {
 	var c *collector.Manifests
 
@@ -271,6 +459,41 @@ var _ = Describe("applyCustomResourceDefinitions", func() {
 	})
 })
 
+var _ = Describe("applyWebhooks", func() {
+	It("never writes a CA bundle to webhook definitions, since OLM injects it", func() {
+		labels := map[string]string{"operator-name": "test-operator"}
+		path := "/convert"
+		crd := apiextv1.CustomResourceDefinition{}
+		crd.SetName("memcacheds.cache.example.com")
+		crd.Spec.Conversion = &apiextv1.CustomResourceConversion{
+			Strategy: apiextv1.WebhookConverter,
+			Webhook: &apiextv1.WebhookConversion{
+				ClientConfig: &apiextv1.WebhookClientConfig{
+					CABundle: []byte("stale-ca-bundle"),
+					Service:  &apiextv1.ServiceReference{Name: "webhook-service", Path: &path},
+				},
+				ConversionReviewVersions: []string{"v1"},
+			},
+		}
+		webhook := admissionregv1.ValidatingWebhook{Name: "vmemcached.kb.io"}
+		webhook.ClientConfig.CABundle = []byte("stale-ca-bundle")
+		webhook.ClientConfig.Service = &admissionregv1.ServiceReference{Name: "webhook-service"}
+		c := &collector.Manifests{
+			Deployments:                 []appsv1.Deployment{newDeployment("controller-manager", labels)},
+			Services:                    []corev1.Service{newService("webhook-service", labels)},
+			V1CustomResourceDefinitions: []apiextv1.CustomResourceDefinition{crd},
+			ValidatingWebhooks:          []admissionregv1.ValidatingWebhook{webhook},
+		}
+
+		csv := &operatorsv1alpha1.ClusterServiceVersion{}
+		applyWebhooks(c, csv)
+		Expect(csv.Spec.WebhookDefinitions).To(HaveLen(2))
+		b, err := json.Marshal(csv.Spec.WebhookDefinitions)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).NotTo(ContainSubstring("caBundle"))
+	})
+})
+
 var _ = Describe("findMatchingDeploymentAndServiceForWebhook", func() {
 
 	var (
@@ -0,0 +1,182 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/markbates/inflect"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// GVKRequiredProperty and PackageRequiredProperty are the OLM property types declaring
+	// that an operator requires an API or a package provided by another operator.
+	GVKRequiredProperty     = "olm.gvk.required"
+	PackageRequiredProperty = "olm.package.required"
+)
+
+// Dependencies are APIs and packages provided by other operators that an operator requires.
+type Dependencies struct {
+	GVKs     []registry.GVKDependency
+	Packages []registry.PackageDependency
+}
+
+// ReadDependencies reads the dependencies file at path, which has the format of a bundle's
+// metadata/dependencies.yaml.
+func ReadDependencies(path string) (Dependencies, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Dependencies{}, fmt.Errorf("error reading dependencies file: %v", err)
+	}
+	return ParseDependencies(path, b)
+}
+
+// ParseDependencies parses b, a dependencies file named name.
+func ParseDependencies(name string, b []byte) (deps Dependencies, err error) {
+	file := registry.DependenciesFile{}
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return Dependencies{}, fmt.Errorf("error parsing dependencies file %s: %v", name, err)
+	}
+	for i, dep := range file.Dependencies {
+		switch dep.Type {
+		case registry.GVKType:
+			gvk := registry.GVKDependency{}
+			if err := decodeDependencyValue(dep.Value, &gvk); err != nil {
+				return Dependencies{}, fmt.Errorf("dependency %d in %s has an invalid %s value: %v", i, name, dep.Type, err)
+			}
+			if msgs := validateGVKDependency(gvk); len(msgs) != 0 {
+				return Dependencies{}, fmt.Errorf("dependency %d in %s has an invalid %s value: %s",
+					i, name, dep.Type, strings.Join(msgs, "; "))
+			}
+			deps.GVKs = append(deps.GVKs, gvk)
+		case registry.PackageType:
+			pkg := registry.PackageDependency{}
+			if err := decodeDependencyValue(dep.Value, &pkg); err != nil {
+				return Dependencies{}, fmt.Errorf("dependency %d in %s has an invalid %s value: %v", i, name, dep.Type, err)
+			}
+			if errs := pkg.Validate(); len(errs) != 0 {
+				msgs := make([]string, len(errs))
+				for j, err := range errs {
+					msgs[j] = err.Error()
+				}
+				return Dependencies{}, fmt.Errorf("dependency %d in %s has an invalid %s value: %s",
+					i, name, dep.Type, strings.Join(msgs, "; "))
+			}
+			deps.Packages = append(deps.Packages, pkg)
+		default:
+			return Dependencies{}, fmt.Errorf("dependency %d in %s has unsupported type %q, must be one of: %s, %s",
+				i, name, dep.Type, registry.GVKType, registry.PackageType)
+		}
+	}
+	return deps, nil
+}
+
+// decodeDependencyValue decodes value into v, failing on unknown fields, which are likely misspelled.
+func decodeDependencyValue(value json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// kindRe matches a Kubernetes kind, ex. EtcdCluster.
+var kindRe = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// validateGVKDependency returns a message for each invalid field of gvk.
+func validateGVKDependency(gvk registry.GVKDependency) (msgs []string) {
+	for _, msg := range validation.IsDNS1123Subdomain(gvk.Group) {
+		msgs = append(msgs, fmt.Sprintf("group %q: %s", gvk.Group, msg))
+	}
+	for _, msg := range validation.IsDNS1035Label(gvk.Version) {
+		msgs = append(msgs, fmt.Sprintf("version %q: %s", gvk.Version, msg))
+	}
+	if !kindRe.MatchString(gvk.Kind) {
+		msgs = append(msgs, fmt.Sprintf("kind %q must be an upper camel case name, ex. EtcdCluster", gvk.Kind))
+	}
+	return msgs
+}
+
+// applyDependencies adds a required CRD description to csv for each API in deps not already required.
+// Descriptions are matched by group, version, and kind, since a required description's name is derived
+// from its kind, which may not pluralize correctly; one in the base with the correct name is kept.
+// An API csv owns cannot also be required.
+func applyDependencies(csv *operatorsv1alpha1.ClusterServiceVersion, deps Dependencies) error {
+	crds := &csv.Spec.CustomResourceDefinitions
+	owned := make(map[string]struct{}, len(crds.Owned))
+	for _, desc := range crds.Owned {
+		owned[descriptionKey(desc)] = struct{}{}
+	}
+	required := make(map[string]struct{}, len(crds.Required))
+	for _, desc := range crds.Required {
+		required[descriptionKey(desc)] = struct{}{}
+	}
+	for _, gvk := range deps.GVKs {
+		desc := operatorsv1alpha1.CRDDescription{
+			// This is how CRD descriptions are named from GVKs when a base is generated.
+			Name:        fmt.Sprintf("%s.%s", inflect.Pluralize(strings.ToLower(gvk.Kind)), gvk.Group),
+			Version:     gvk.Version,
+			Kind:        gvk.Kind,
+			DisplayName: gvk.Kind,
+		}
+		key := descriptionKey(desc)
+		if _, isOwned := owned[key]; isOwned {
+			return fmt.Errorf("ClusterServiceVersion %s owns required API %s/%s %s",
+				csv.GetName(), gvk.Group, gvk.Version, gvk.Kind)
+		}
+		if _, isRequired := required[key]; isRequired {
+			continue
+		}
+		log.Debugf("Adding CustomResourceDefinition %s version %s to spec.customresourcedefinitions.required",
+			desc.Name, desc.Version)
+		crds.Required = append(crds.Required, desc)
+		required[key] = struct{}{}
+	}
+	return nil
+}
+
+// descriptionKey returns the group, version, and kind of desc, whose name is of the form <plural>.<group>.
+func descriptionKey(desc operatorsv1alpha1.CRDDescription) string {
+	group := desc.Name
+	if i := strings.Index(group, "."); i >= 0 {
+		group = group[i+1:]
+	}
+	return group + "/" + desc.Version + "/" + desc.Kind
+}
+
+// properties returns an OLM required property for each API and package in deps.
+func (deps Dependencies) properties() (props []Property) {
+	for _, gvk := range deps.GVKs {
+		props = append(props, Property{
+			Type:  GVKRequiredProperty,
+			Value: map[string]string{"group": gvk.Group, "kind": gvk.Kind, "version": gvk.Version},
+		})
+	}
+	for _, pkg := range deps.Packages {
+		props = append(props, Property{
+			Type:  PackageRequiredProperty,
+			Value: map[string]string{"packageName": pkg.PackageName, "versionRange": pkg.Version},
+		})
+	}
+	return props
+}
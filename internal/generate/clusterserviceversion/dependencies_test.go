@@ -0,0 +1,118 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+var _ = Describe("Dependencies", func() {
+	const depsFile = `dependencies:
+- type: olm.gvk
+  value:
+    group: etcd.database.coreos.com
+    kind: EtcdCluster
+    version: v1beta2
+- type: olm.package
+  value:
+    packageName: prometheus
+    version: ">0.27.0"
+`
+	etcd := registry.GVKDependency{Group: "etcd.database.coreos.com", Kind: "EtcdCluster", Version: "v1beta2"}
+
+	Describe("ParseDependencies", func() {
+		It("parses API and package dependencies", func() {
+			deps, err := ParseDependencies("deps.yaml", []byte(depsFile))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deps.GVKs).To(Equal([]registry.GVKDependency{etcd}))
+			Expect(deps.Packages).To(Equal([]registry.PackageDependency{{PackageName: "prometheus", Version: ">0.27.0"}}))
+		})
+		It("fails on an invalid GVK", func() {
+			_, err := ParseDependencies("deps.yaml", []byte(`dependencies:
+- type: olm.gvk
+  value: {group: Etcd_Database, kind: etcdCluster, version: v1beta2}
+`))
+			Expect(err).To(MatchError(And(
+				ContainSubstring("dependency 0 in deps.yaml has an invalid olm.gvk value"),
+				ContainSubstring(`group "Etcd_Database"`),
+				ContainSubstring(`kind "etcdCluster" must be an upper camel case name`),
+			)))
+		})
+		It("fails on a misspelled field", func() {
+			_, err := ParseDependencies("deps.yaml", []byte(`dependencies:
+- type: olm.gvk
+  value: {group: etcd.database.coreos.com, knid: EtcdCluster, version: v1beta2}
+`))
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "knid"`)))
+		})
+		It("fails on an invalid package version range", func() {
+			_, err := ParseDependencies("deps.yaml", []byte(`dependencies:
+- type: olm.package
+  value: {packageName: prometheus, version: latest}
+`))
+			Expect(err).To(MatchError(ContainSubstring("Invalid semver format version")))
+		})
+		It("fails on an unsupported type", func() {
+			_, err := ParseDependencies("deps.yaml", []byte("dependencies:\n- type: olm.label\n  value: {label: foo}\n"))
+			Expect(err).To(MatchError(ContainSubstring(`unsupported type "olm.label"`)))
+		})
+	})
+
+	Describe("applyDependencies", func() {
+		var csv *operatorsv1alpha1.ClusterServiceVersion
+
+		BeforeEach(func() {
+			csv = &operatorsv1alpha1.ClusterServiceVersion{}
+			csv.SetName("memcached-operator.v0.0.1")
+		})
+
+		It("adds required CRD descriptions for required APIs not already required", func() {
+			// The base names this description correctly, which the kind does not pluralize to.
+			prometheus := operatorsv1alpha1.CRDDescription{
+				Name: "prometheuses.monitoring.coreos.com", Version: "v1", Kind: "Prometheus", DisplayName: "Prometheus",
+			}
+			csv.Spec.CustomResourceDefinitions.Required = []operatorsv1alpha1.CRDDescription{prometheus}
+			deps := Dependencies{GVKs: []registry.GVKDependency{
+				{Group: "monitoring.coreos.com", Kind: "Prometheus", Version: "v1"},
+				etcd,
+				etcd,
+			}}
+			Expect(applyDependencies(csv, deps)).To(Succeed())
+			Expect(csv.Spec.CustomResourceDefinitions.Required).To(Equal([]operatorsv1alpha1.CRDDescription{
+				prometheus,
+				{Name: "etcdclusters.etcd.database.coreos.com", Version: "v1beta2", Kind: "EtcdCluster", DisplayName: "EtcdCluster"},
+			}))
+		})
+		It("fails if an owned API is required", func() {
+			csv.Spec.CustomResourceDefinitions.Owned = []operatorsv1alpha1.CRDDescription{
+				{Name: "etcdclusters.etcd.database.coreos.com", Version: "v1beta2", Kind: "EtcdCluster"},
+			}
+			err := applyDependencies(csv, Dependencies{GVKs: []registry.GVKDependency{etcd}})
+			Expect(err).To(MatchError("ClusterServiceVersion memcached-operator.v0.0.1 owns required API " +
+				"etcd.database.coreos.com/v1beta2 EtcdCluster"))
+		})
+		It("returns required properties for the properties annotation", func() {
+			deps, err := ParseDependencies("deps.yaml", []byte(depsFile))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(setProperties(csv, deps.properties())).To(Succeed())
+			Expect(csv.GetAnnotations()).To(HaveKeyWithValue(PropertiesAnnotation, `[`+
+				`{"type":"olm.gvk.required","value":{"group":"etcd.database.coreos.com","kind":"EtcdCluster","version":"v1beta2"}},`+
+				`{"type":"olm.package.required","value":{"packageName":"prometheus","versionRange":">0.27.0"}}]`))
+		})
+	})
+})
@@ -0,0 +1,177 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	"sort"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
+)
+
+// reconcileDescriptors reconciles spec and status descriptors of each owned CRD description in csv
+// with the OpenAPI v3 schema of the matching CRD version in c. Existing descriptors keep their
+// hand-written displayName and x-descriptors, and have empty descriptions filled in from the schema,
+// which carries kubebuilder marker comments. Descriptors whose path no longer exists in the schema
+// are pruned, and top-level spec and status fields without a descriptor are added.
+func reconcileDescriptors(c *collector.Manifests, csv *operatorsv1alpha1.ClusterServiceVersion) error {
+	schemas, err := getVersionSchemas(c)
+	if err != nil {
+		return err
+	}
+
+	for i, owned := range csv.Spec.CustomResourceDefinitions.Owned {
+		schema, hasSchema := schemas[crdVersionKey{name: owned.Name, version: owned.Version}]
+		if !hasSchema || schema == nil {
+			log.Debugf("Skipping descriptor reconciliation for %s %s: no schema found", owned.Name, owned.Version)
+			continue
+		}
+		owned.SpecDescriptors = reconcileSpecDescriptors(owned.Name, owned.SpecDescriptors, schema.Properties["spec"])
+		owned.StatusDescriptors = reconcileStatusDescriptors(owned.Name, owned.StatusDescriptors, schema.Properties["status"])
+		csv.Spec.CustomResourceDefinitions.Owned[i] = owned
+	}
+
+	return nil
+}
+
+// crdVersionKey identifies a single version of a CRD.
+type crdVersionKey struct {
+	name, version string
+}
+
+// getVersionSchemas returns the OpenAPI v3 schema of every version of every CRD in c.
+func getVersionSchemas(c *collector.Manifests) (map[crdVersionKey]*apiextv1.JSONSchemaProps, error) {
+	crds := append([]apiextv1.CustomResourceDefinition{}, c.V1CustomResourceDefinitions...)
+	for i := range c.V1beta1CustomResourceDefinitions {
+		crd, err := k8sutil.Convertv1beta1Tov1CustomResourceDefinition(&c.V1beta1CustomResourceDefinitions[i])
+		if err != nil {
+			return nil, err
+		}
+		crds = append(crds, *crd)
+	}
+
+	schemas := make(map[crdVersionKey]*apiextv1.JSONSchemaProps)
+	for _, crd := range crds {
+		for _, ver := range crd.Spec.Versions {
+			if ver.Schema != nil {
+				schemas[crdVersionKey{name: crd.GetName(), version: ver.Name}] = ver.Schema.OpenAPIV3Schema
+			}
+		}
+	}
+	return schemas, nil
+}
+
+// reconcileSpecDescriptors reconciles descs with the "spec" schema.
+func reconcileSpecDescriptors(crdName string, descs []operatorsv1alpha1.SpecDescriptor, schema apiextv1.JSONSchemaProps) (out []operatorsv1alpha1.SpecDescriptor) {
+	described := make(map[string]struct{}, len(descs))
+	for _, desc := range descs {
+		field, found := lookupSchemaPath(schema, desc.Path)
+		if !found {
+			log.Infof("Pruning spec descriptor %q from %s: field no longer exists in schema", desc.Path, crdName)
+			continue
+		}
+		if desc.Description == "" && field != nil {
+			desc.Description = field.Description
+		}
+		described[desc.Path] = struct{}{}
+		out = append(out, desc)
+	}
+	for _, name := range newTopLevelFields(schema, described) {
+		out = append(out, operatorsv1alpha1.SpecDescriptor{
+			Path:        name,
+			DisplayName: k8sutil.GetDisplayName(name),
+			Description: schema.Properties[name].Description,
+		})
+	}
+	return out
+}
+
+// reconcileStatusDescriptors reconciles descs with the "status" schema.
+func reconcileStatusDescriptors(crdName string, descs []operatorsv1alpha1.StatusDescriptor, schema apiextv1.JSONSchemaProps) (out []operatorsv1alpha1.StatusDescriptor) {
+	described := make(map[string]struct{}, len(descs))
+	for _, desc := range descs {
+		field, found := lookupSchemaPath(schema, desc.Path)
+		if !found {
+			log.Infof("Pruning status descriptor %q from %s: field no longer exists in schema", desc.Path, crdName)
+			continue
+		}
+		if desc.Description == "" && field != nil {
+			desc.Description = field.Description
+		}
+		described[desc.Path] = struct{}{}
+		out = append(out, desc)
+	}
+	for _, name := range newTopLevelFields(schema, described) {
+		out = append(out, operatorsv1alpha1.StatusDescriptor{
+			Path:        name,
+			DisplayName: k8sutil.GetDisplayName(name),
+			Description: schema.Properties[name].Description,
+		})
+	}
+	return out
+}
+
+// newTopLevelFields returns the sorted names of schema's properties that are not in described.
+func newTopLevelFields(schema apiextv1.JSONSchemaProps, described map[string]struct{}) (names []string) {
+	for name := range schema.Properties {
+		if _, hasDesc := described[name]; !hasDesc {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupSchemaPath walks schema along a descriptor path, ex. "nodes[0].name", returning the field's schema
+// and whether the path exists. Paths that descend into a schema that does not declare its properties,
+// such as one preserving unknown fields, are considered to exist but have no field schema.
+func lookupSchemaPath(schema apiextv1.JSONSchemaProps, path string) (*apiextv1.JSONSchemaProps, bool) {
+	current := &schema
+	for _, segment := range strings.Split(path, ".") {
+		// Array indices address the items schema of the field they are attached to.
+		name := segment
+		isIndexed := false
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, isIndexed = segment[:i], true
+		}
+
+		if len(current.Properties) == 0 {
+			return nil, opaqueSchema(current)
+		}
+		prop, hasProp := current.Properties[name]
+		if !hasProp {
+			return nil, false
+		}
+		current = &prop
+		if isIndexed {
+			if current.Items == nil || current.Items.Schema == nil {
+				return nil, opaqueSchema(current)
+			}
+			current = current.Items.Schema
+		}
+	}
+	return current, true
+}
+
+// opaqueSchema returns true if schema's properties cannot be known from the schema alone.
+func opaqueSchema(schema *apiextv1.JSONSchemaProps) bool {
+	return (schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields) ||
+		schema.AdditionalProperties != nil || schema.Type == "" || schema.Type == "object"
+}
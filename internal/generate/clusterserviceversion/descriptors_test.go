@@ -0,0 +1,120 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("reconcileDescriptors", func() {
+	var (
+		c   *collector.Manifests
+		csv *operatorsv1alpha1.ClusterServiceVersion
+	)
+
+	BeforeEach(func() {
+		c = &collector.Manifests{
+			V1CustomResourceDefinitions: []apiextv1.CustomResourceDefinition{
+				newCRDWithSchema("memcacheds.cache.example.com", "v1alpha1", apiextv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiextv1.JSONSchemaProps{
+						"spec": {
+							Type: "object",
+							Properties: map[string]apiextv1.JSONSchemaProps{
+								"size": {Type: "integer", Description: "Size is the number of replicas."},
+								"nodes": {Type: "array", Items: &apiextv1.JSONSchemaPropsOrArray{
+									Schema: &apiextv1.JSONSchemaProps{
+										Type: "object",
+										Properties: map[string]apiextv1.JSONSchemaProps{
+											"name": {Type: "string", Description: "Name of the node."},
+										},
+									},
+								}},
+							},
+						},
+						"status": {
+							Type: "object",
+							Properties: map[string]apiextv1.JSONSchemaProps{
+								"phase": {Type: "string", Description: "Phase of the deployment."},
+							},
+						},
+					},
+				}),
+			},
+		}
+		csv = &operatorsv1alpha1.ClusterServiceVersion{}
+		csv.Spec.CustomResourceDefinitions.Owned = []operatorsv1alpha1.CRDDescription{
+			{
+				Name:    "memcacheds.cache.example.com",
+				Version: "v1alpha1",
+				Kind:    "Memcached",
+				SpecDescriptors: []operatorsv1alpha1.SpecDescriptor{
+					{Path: "size", DisplayName: "Cluster Size", XDescriptors: []string{"urn:alm:descriptor:com.tectonic.ui:podCount"}},
+					{Path: "nodes[0].name", DisplayName: "Node Name", Description: "Hand-written."},
+					{Path: "removedField", DisplayName: "Removed"},
+				},
+				StatusDescriptors: []operatorsv1alpha1.StatusDescriptor{
+					{Path: "oldPhase", DisplayName: "Old Phase"},
+				},
+			},
+		}
+	})
+
+	It("preserves hand-written fields, fills descriptions, prunes removed fields, and adds new fields", func() {
+		Expect(reconcileDescriptors(c, csv)).To(Succeed())
+		owned := csv.Spec.CustomResourceDefinitions.Owned[0]
+		Expect(owned.SpecDescriptors).To(Equal([]operatorsv1alpha1.SpecDescriptor{
+			{
+				Path:         "size",
+				DisplayName:  "Cluster Size",
+				Description:  "Size is the number of replicas.",
+				XDescriptors: []string{"urn:alm:descriptor:com.tectonic.ui:podCount"},
+			},
+			{Path: "nodes[0].name", DisplayName: "Node Name", Description: "Hand-written."},
+			{Path: "nodes", DisplayName: "Nodes"},
+		}))
+		Expect(owned.StatusDescriptors).To(Equal([]operatorsv1alpha1.StatusDescriptor{
+			{Path: "phase", DisplayName: "Phase", Description: "Phase of the deployment."},
+		}))
+	})
+	It("leaves descriptors for CRD versions without a schema untouched", func() {
+		c.V1CustomResourceDefinitions[0].Spec.Versions[0].Schema = nil
+		expected := csv.DeepCopy()
+		Expect(reconcileDescriptors(c, csv)).To(Succeed())
+		Expect(csv).To(Equal(expected))
+	})
+})
+
+func newCRDWithSchema(name, version string, schema apiextv1.JSONSchemaProps) apiextv1.CustomResourceDefinition {
+	return apiextv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: apiextv1.CustomResourceDefinitionSpec{
+			Versions: []apiextv1.CustomResourceDefinitionVersion{
+				{
+					Name:    version,
+					Served:  true,
+					Storage: true,
+					Schema:  &apiextv1.CustomResourceValidation{OpenAPIV3Schema: &schema},
+				},
+			},
+		},
+	}
+}
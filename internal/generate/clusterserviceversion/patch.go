@@ -0,0 +1,127 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Patch is applied to a generated CSV, for fields no Generator option sets. A patch that is an object
+// is a strategic merge patch, which replaces lists since CSV types declare no merge keys, or a JSON merge
+// patch (RFC 7386), which also removes fields set to null. A patch that is a list is a JSON patch
+// (RFC 6902), whose operations can edit individual list elements.
+type Patch struct {
+	// Name identifies the patch in errors, ex. its file path.
+	Name string
+
+	data      []byte
+	patchType types.PatchType
+	jsonPatch jsonpatch.Patch
+}
+
+// ReadPatch reads the YAML or JSON patch at path. An object is read as a patch of objectType.
+func ReadPatch(path string, objectType types.PatchType) (Patch, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Patch{}, fmt.Errorf("error reading CSV patch: %v", err)
+	}
+	return ParsePatch(path, b, objectType)
+}
+
+// ParsePatch parses b, a YAML or JSON patch named name. An object is parsed as a patch of objectType,
+// either types.StrategicMergePatchType or types.MergePatchType.
+func ParsePatch(name string, b []byte, objectType types.PatchType) (Patch, error) {
+	if objectType != types.StrategicMergePatchType && objectType != types.MergePatchType {
+		return Patch{}, fmt.Errorf("CSV patch %s cannot be an object of type %s", name, objectType)
+	}
+	data, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return Patch{}, fmt.Errorf("error parsing CSV patch %s: %v", name, err)
+	}
+	p := Patch{Name: name, data: data, patchType: objectType}
+	switch data = bytes.TrimSpace(data); {
+	case bytes.HasPrefix(data, []byte("[")):
+		p.patchType = types.JSONPatchType
+		if p.jsonPatch, err = jsonpatch.DecodePatch(data); err != nil {
+			return Patch{}, fmt.Errorf("error parsing CSV patch %s as a JSON patch: %v", name, err)
+		}
+	case !bytes.HasPrefix(data, []byte("{")):
+		return Patch{}, fmt.Errorf("CSV patch %s must be an object or a list of JSON patch operations", name)
+	}
+	return p, nil
+}
+
+// apply returns a copy of csv patched with p.
+func (p Patch) apply(csv *operatorsv1alpha1.ClusterServiceVersion) (*operatorsv1alpha1.ClusterServiceVersion, error) {
+	original, err := json.Marshal(csv)
+	if err != nil {
+		return nil, err
+	}
+	var patched []byte
+	switch p.patchType {
+	case types.JSONPatchType:
+		patched, err = p.jsonPatch.Apply(original)
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, p.data)
+	default:
+		patched, err = strategicpatch.StrategicMergePatch(original, p.data, operatorsv1alpha1.ClusterServiceVersion{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown fields are likely misspelled, and would otherwise be dropped silently.
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	out := &operatorsv1alpha1.ClusterServiceVersion{}
+	if err := dec.Decode(out); err != nil {
+		return nil, fmt.Errorf("patched ClusterServiceVersion is invalid: %v", err)
+	}
+	return out, nil
+}
+
+// WithPatches adds a transform that applies each patch, in order, to the generated CSV. The patched CSV
+// must keep its kind and name, since its file and package reference them, and must pass validation.
+func WithPatches(patches ...Patch) Option {
+	return func(g *Generator) error {
+		return WithObjectTransform(func(obj client.Object) error {
+			csv, isCSV := obj.(*operatorsv1alpha1.ClusterServiceVersion)
+			if !isCSV || len(patches) == 0 {
+				return nil
+			}
+			for _, p := range patches {
+				patched, err := p.apply(csv)
+				if err != nil {
+					return fmt.Errorf("error applying CSV patch %s: %v", p.Name, err)
+				}
+				if patched.GroupVersionKind() != csv.GroupVersionKind() || patched.GetName() != csv.GetName() {
+					return fmt.Errorf("CSV patch %s must not change the ClusterServiceVersion's apiVersion, kind, or name", p.Name)
+				}
+				*csv = *patched
+			}
+			return validate(csv, g.warnf)
+		})(g)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	"github.com/blang/semver/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion/bases"
+)
+
+var _ = Describe("Patching a generated CSV", func() {
+	var (
+		csv       *operatorsv1alpha1.ClusterServiceVersion
+		transform ObjectTransform
+	)
+	BeforeEach(func() {
+		csv = bases.New("memcached-operator")
+		csv.Spec.Keywords = []string{"memcached"}
+		csv.Spec.Version.Version = semver.MustParse("0.0.1")
+		csv.Spec.InstallStrategy.StrategyName = operatorsv1alpha1.InstallStrategyNameDeployment
+		csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = []operatorsv1alpha1.StrategyDeploymentSpec{
+			{Name: "memcached-operator-controller-manager"},
+		}
+	})
+	withTypedPatches := func(objectType types.PatchType, patches ...string) ObjectTransform {
+		var ps []Patch
+		for i, patch := range patches {
+			p, err := ParsePatch(string(rune('a'+i))+".yaml", []byte(patch), objectType)
+			Expect(err).NotTo(HaveOccurred())
+			ps = append(ps, p)
+		}
+		g := &Generator{}
+		Expect(WithPatches(ps...)(g)).To(Succeed())
+		return g.transforms[0]
+	}
+	withPatches := func(patches ...string) ObjectTransform {
+		return withTypedPatches(types.StrategicMergePatchType, patches...)
+	}
+
+	It("applies strategic merge patches in order", func() {
+		transform = withPatches(
+			"spec:\n  keywords: [cache]\n  provider:\n    name: Example\n",
+			"metadata:\n  annotations:\n    support: Example\nspec:\n  provider:\n    url: https://example.com\n",
+		)
+		Expect(transform(csv)).To(Succeed())
+		Expect(csv.Spec.Keywords).To(Equal([]string{"cache"}))
+		Expect(csv.Spec.Provider).To(Equal(operatorsv1alpha1.AppLink{Name: "Example", URL: "https://example.com"}))
+		Expect(csv.GetAnnotations()).To(HaveKeyWithValue("support", "Example"))
+	})
+	It("applies JSON merge patches", func() {
+		csv.SetAnnotations(map[string]string{"support": "Example", "capabilities": "Basic Install"})
+		transform = withTypedPatches(types.MergePatchType,
+			"metadata:\n  annotations:\n    support: null\nspec:\n  keywords: [cache]\n",
+		)
+		Expect(transform(csv)).To(Succeed())
+		Expect(csv.Spec.Keywords).To(Equal([]string{"cache"}))
+		Expect(csv.GetAnnotations()).To(Equal(map[string]string{"capabilities": "Basic Install"}))
+	})
+	It("applies JSON patches", func() {
+		transform = withPatches(`[{"op": "add", "path": "/spec/keywords/-", "value": "cache"}]`)
+		Expect(transform(csv)).To(Succeed())
+		Expect(csv.Spec.Keywords).To(Equal([]string{"memcached", "cache"}))
+	})
+	It("fails if a patch sets an unknown field", func() {
+		transform = withPatches("spec:\n  keyword: [cache]\n")
+		Expect(transform(csv)).To(MatchError(ContainSubstring(`unknown field "keyword"`)))
+	})
+	It("fails if a patch changes the CSV's name", func() {
+		transform = withPatches("metadata:\n  name: foo.v0.0.1\n")
+		Expect(transform(csv)).To(MatchError("CSV patch a.yaml must not change the ClusterServiceVersion's " +
+			"apiVersion, kind, or name"))
+	})
+	It("fails if the patched CSV is invalid", func() {
+		transform = withPatches(`[{"op": "remove", "path": "/spec/install"}]`)
+		Expect(transform(csv)).To(MatchError("invalid generated ClusterServiceVersion:\n  - [FieldNotFound] required field missing"))
+	})
+	It("fails to parse a patch that is not an object or list", func() {
+		_, err := ParsePatch("a.yaml", []byte("potato"), types.StrategicMergePatchType)
+		Expect(err).To(MatchError("CSV patch a.yaml must be an object or a list of JSON patch operations"))
+	})
+	It("fails to parse a patch with an unsupported object type", func() {
+		_, err := ParsePatch("a.yaml", []byte("spec: {}"), types.JSONPatchType)
+		Expect(err).To(MatchError("CSV patch a.yaml cannot be an object of type application/json-patch+json"))
+	})
+})
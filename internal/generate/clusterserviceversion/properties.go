@@ -0,0 +1,82 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+)
+
+const (
+	// PropertiesAnnotation is the CSV annotation containing a JSON list of OLM properties.
+	PropertiesAnnotation = "olm.properties"
+	// MaxOpenShiftVersionProperty is the OLM property type declaring the maximum OpenShift version
+	// an operator can be installed on.
+	MaxOpenShiftVersionProperty = "olm.maxOpenShiftVersion"
+)
+
+// Property is an OLM property, ex. {"type": "olm.maxOpenShiftVersion", "value": "4.8"}.
+type Property struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// setProperties merges props into csv's properties annotation. Properties already
+// in the annotation are overridden by a property in props of the same type.
+func setProperties(csv *operatorsv1alpha1.ClusterServiceVersion, props []Property) error {
+	if len(props) == 0 {
+		return nil
+	}
+
+	annotations := csv.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	var existing []Property
+	if value, hasProps := annotations[PropertiesAnnotation]; hasProps && value != "" {
+		if err := json.Unmarshal([]byte(value), &existing); err != nil {
+			return fmt.Errorf("error parsing existing %q annotation: %v", PropertiesAnnotation, err)
+		}
+	}
+
+	merged := make([]Property, 0, len(existing)+len(props))
+	overridden := make(map[string]struct{}, len(props))
+	for _, prop := range props {
+		overridden[prop.Type] = struct{}{}
+	}
+	for _, prop := range existing {
+		if _, isOverridden := overridden[prop.Type]; !isOverridden {
+			merged = append(merged, prop)
+		}
+	}
+	merged = append(merged, props...)
+
+	// Do not escape characters like ">" in version ranges, so the annotation stays readable.
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(merged); err != nil {
+		return err
+	}
+	annotations[PropertiesAnnotation] = strings.TrimSpace(buf.String())
+	csv.SetAnnotations(annotations)
+
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+)
+
+var _ = Describe("setProperties", func() {
+	var csv *operatorsv1alpha1.ClusterServiceVersion
+
+	BeforeEach(func() {
+		csv = &operatorsv1alpha1.ClusterServiceVersion{}
+	})
+
+	It("does nothing when no properties are set", func() {
+		Expect(setProperties(csv, nil)).To(Succeed())
+		Expect(csv.GetAnnotations()).To(BeNil())
+	})
+	It("adds properties to a CSV without annotations", func() {
+		props := []Property{{Type: MaxOpenShiftVersionProperty, Value: "4.8"}}
+		Expect(setProperties(csv, props)).To(Succeed())
+		Expect(csv.GetAnnotations()).To(HaveKeyWithValue(PropertiesAnnotation,
+			`[{"type":"olm.maxOpenShiftVersion","value":"4.8"}]`))
+	})
+	It("merges properties with existing ones, overriding by type", func() {
+		csv.SetAnnotations(map[string]string{
+			PropertiesAnnotation: `[{"type":"olm.maxOpenShiftVersion","value":"4.6"},{"type":"foo","value":{"bar":"baz"}}]`,
+		})
+		props := []Property{{Type: MaxOpenShiftVersionProperty, Value: "4.8"}}
+		Expect(setProperties(csv, props)).To(Succeed())
+		Expect(csv.GetAnnotations()).To(HaveKeyWithValue(PropertiesAnnotation,
+			`[{"type":"foo","value":{"bar":"baz"}},{"type":"olm.maxOpenShiftVersion","value":"4.8"}]`))
+	})
+	It("fails on an invalid existing annotation", func() {
+		csv.SetAnnotations(map[string]string{PropertiesAnnotation: "not json"})
+		props := []Property{{Type: "foo", Value: "bar"}}
+		Expect(setProperties(csv, props)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,42 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	"sort"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+)
+
+// normalizeRelatedImages removes related images of csv whose image is listed earlier, keeping the
+// first name listed for each image, then sorts them by name. Related images listed in a base and
+// added by a transform, ex. digest pinning, would otherwise be duplicated and ordered by source.
+func normalizeRelatedImages(csv *operatorsv1alpha1.ClusterServiceVersion) {
+	if len(csv.Spec.RelatedImages) == 0 {
+		return
+	}
+	seen := make(map[string]struct{}, len(csv.Spec.RelatedImages))
+	relatedImages := make([]operatorsv1alpha1.RelatedImage, 0, len(csv.Spec.RelatedImages))
+	for _, relatedImage := range csv.Spec.RelatedImages {
+		if _, hasImage := seen[relatedImage.Image]; !hasImage {
+			relatedImages = append(relatedImages, relatedImage)
+			seen[relatedImage.Image] = struct{}{}
+		}
+	}
+	sort.SliceStable(relatedImages, func(i, j int) bool {
+		return relatedImages[i].Name < relatedImages[j].Name
+	})
+	csv.Spec.RelatedImages = relatedImages
+}
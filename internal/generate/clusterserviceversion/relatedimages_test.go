@@ -0,0 +1,66 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+)
+
+var _ = Describe("normalizeRelatedImages", func() {
+	const (
+		operator = "quay.io/example/operator:v1"
+		proxy    = "gcr.io/kubebuilder/kube-rbac-proxy:v0.8.0"
+		db       = "quay.io/example/db:v1"
+	)
+	var csv *operatorsv1alpha1.ClusterServiceVersion
+
+	BeforeEach(func() {
+		csv = &operatorsv1alpha1.ClusterServiceVersion{}
+	})
+
+	It("does nothing when no related images are set", func() {
+		normalizeRelatedImages(csv)
+		Expect(csv.Spec.RelatedImages).To(BeNil())
+	})
+	It("dedupes manual and derived images by image, keeping the first name, and sorts by name", func() {
+		// Manually listed images, then images derived from deployments.
+		csv.Spec.RelatedImages = []operatorsv1alpha1.RelatedImage{
+			{Name: "operator", Image: operator},
+			{Name: "db", Image: db},
+			{Name: "manager", Image: operator},
+			{Name: "kube-rbac-proxy", Image: proxy},
+			{Name: "operator", Image: operator},
+		}
+		normalizeRelatedImages(csv)
+		Expect(csv.Spec.RelatedImages).To(Equal([]operatorsv1alpha1.RelatedImage{
+			{Name: "db", Image: db},
+			{Name: "kube-rbac-proxy", Image: proxy},
+			{Name: "operator", Image: operator},
+		}))
+	})
+	It("keeps images with the same name in the order they are listed", func() {
+		csv.Spec.RelatedImages = []operatorsv1alpha1.RelatedImage{
+			{Name: "manager", Image: db},
+			{Name: "manager", Image: operator},
+		}
+		normalizeRelatedImages(csv)
+		Expect(csv.Spec.RelatedImages).To(Equal([]operatorsv1alpha1.RelatedImage{
+			{Name: "manager", Image: db},
+			{Name: "manager", Image: operator},
+		}))
+	})
+})
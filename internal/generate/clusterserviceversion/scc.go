@@ -0,0 +1,113 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+// SecurityContextConstraintsGroupKind is the group and kind of OpenShift SecurityContextConstraints (SCCs).
+var SecurityContextConstraintsGroupKind = schema.GroupKind{
+	Group: "security.openshift.io",
+	Kind:  "SecurityContextConstraints",
+}
+
+// serviceAccountUserPrefix prefixes the user name of a ServiceAccount, system:serviceaccount:<namespace>:<name>.
+const serviceAccountUserPrefix = "system:serviceaccount:"
+
+// IsSecurityContextConstraints returns true if obj is an OpenShift SecurityContextConstraints.
+func IsSecurityContextConstraints(obj *unstructured.Unstructured) bool {
+	return obj.GroupVersionKind().GroupKind() == SecurityContextConstraintsGroupKind
+}
+
+// applySecurityContextConstraints grants each ServiceAccount in strategy's deployments or extraSAs that is
+// a user of an SCC in c the use of that SCC in strategy's clusterPermissions. SCC users name a ServiceAccount's
+// namespace, which is not known until OLM installs the operator, so the grant must be made through RBAC.
+// ServiceAccounts granted an SCC by a ClusterRole already have that ClusterRole's rules applied.
+func applySecurityContextConstraints(c *collector.Manifests, strategy *operatorsv1alpha1.StrategyDetailsDeployment, extraSAs []string) {
+	saToPermissions := initPermissionSet(c.Deployments, extraSAs)
+	for _, perm := range strategy.ClusterPermissions {
+		saToPermissions[perm.ServiceAccountName] = perm
+	}
+
+	changed := false
+	for i := range c.Others {
+		scc := &c.Others[i]
+		if !IsSecurityContextConstraints(scc) {
+			continue
+		}
+		users, _, _ := unstructured.NestedStringSlice(scc.Object, "users")
+		for _, user := range users {
+			if !strings.HasPrefix(user, serviceAccountUserPrefix) {
+				continue
+			}
+			// The namespace is replaced by OLM's install namespace, so only the name is matched.
+			parts := strings.Split(strings.TrimPrefix(user, serviceAccountUserPrefix), ":")
+			saName := parts[len(parts)-1]
+			perm, hasSA := saToPermissions[saName]
+			if !hasSA {
+				continue
+			}
+			rule := rbacv1.PolicyRule{
+				APIGroups:     []string{SecurityContextConstraintsGroupKind.Group},
+				Resources:     []string{"securitycontextconstraints"},
+				ResourceNames: []string{scc.GetName()},
+				Verbs:         []string{"use"},
+			}
+			if hasRule(perm.Rules, rule) {
+				continue
+			}
+			log.Debugf("Adding use of SecurityContextConstraints %q to spec.install.spec.clusterPermissions of ServiceAccount %q",
+				scc.GetName(), saName)
+			perm.Rules = sortRules(append(perm.Rules, rule))
+			saToPermissions[saName] = perm
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	perms := []operatorsv1alpha1.StrategyDeploymentPermissions{}
+	for _, perm := range saToPermissions {
+		if len(perm.Rules) != 0 {
+			perms = append(perms, perm)
+		}
+	}
+	sort.Slice(perms, func(i, j int) bool {
+		return perms[i].ServiceAccountName < perms[j].ServiceAccountName
+	})
+	strategy.ClusterPermissions = perms
+}
+
+// hasRule returns true if rules contains rule.
+func hasRule(rules []rbacv1.PolicyRule, rule rbacv1.PolicyRule) bool {
+	for _, r := range rules {
+		if reflect.DeepEqual(r, rule) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterserviceversion
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("applySecurityContextConstraints", func() {
+	const (
+		saName    = "memcached-operator-controller-manager"
+		cRoleName = "memcached-operator-scc"
+	)
+	useRule := func(sccName string) rbacv1.PolicyRule {
+		return rbacv1.PolicyRule{
+			APIGroups:     []string{"security.openshift.io"},
+			Resources:     []string{"securitycontextconstraints"},
+			ResourceNames: []string{sccName},
+			Verbs:         []string{"use"},
+		}
+	}
+	newSCC := func(name string, users ...interface{}) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "security.openshift.io/v1",
+			"kind":       "SecurityContextConstraints",
+			"metadata":   map[string]interface{}{"name": name},
+			"users":      users,
+		}}
+	}
+
+	var (
+		c   *collector.Manifests
+		csv *operatorsv1alpha1.ClusterServiceVersion
+	)
+
+	BeforeEach(func() {
+		c = &collector.Manifests{}
+		c.Deployments = []appsv1.Deployment{newDeploymentWithServiceAccount("dep-1", saName)}
+		csv = &operatorsv1alpha1.ClusterServiceVersion{}
+		csv.Spec.InstallStrategy.StrategyName = operatorsv1alpha1.InstallStrategyNameDeployment
+	})
+
+	It("keeps the use of an SCC granted by a bound ClusterRole", func() {
+		c.Others = []unstructured.Unstructured{newSCC("memcached-scc")}
+		c.ClusterRoles = []rbacv1.ClusterRole{*newClusterRole(cRoleName, useRule("memcached-scc"))}
+		c.ClusterRoleBindings = []rbacv1.ClusterRoleBinding{
+			newClusterRoleBinding("scc-binding", newClusterRoleRef(cRoleName), newServiceAccountSubject(saName)),
+		}
+		Expect(apply(c, csv, nil)).To(Succeed())
+		strategy := &csv.Spec.InstallStrategy.StrategySpec
+		applySecurityContextConstraints(c, strategy, nil)
+		Expect(strategy.ClusterPermissions).To(Equal([]operatorsv1alpha1.StrategyDeploymentPermissions{
+			{ServiceAccountName: saName, Rules: []rbacv1.PolicyRule{useRule("memcached-scc")}},
+		}))
+	})
+	It("grants the use of an SCC to its ServiceAccount users once", func() {
+		c.Others = []unstructured.Unstructured{
+			newSCC("memcached-scc", "system:serviceaccount:memcached-operator-system:"+saName),
+			newSCC("other-scc", "system:serviceaccount:default:other", "system:admin"),
+		}
+		c.ClusterRoles = []rbacv1.ClusterRole{*newClusterRole(cRoleName, useRule("memcached-scc"))}
+		c.ClusterRoleBindings = []rbacv1.ClusterRoleBinding{
+			newClusterRoleBinding("scc-binding", newClusterRoleRef(cRoleName), newServiceAccountSubject(saName)),
+		}
+		extraUser := "system:serviceaccount:default:extra"
+		c.Others = append(c.Others, newSCC("extra-scc", extraUser))
+		Expect(apply(c, csv, []string{"extra"})).To(Succeed())
+		strategy := &csv.Spec.InstallStrategy.StrategySpec
+		applySecurityContextConstraints(c, strategy, []string{"extra"})
+		Expect(strategy.ClusterPermissions).To(Equal([]operatorsv1alpha1.StrategyDeploymentPermissions{
+			{ServiceAccountName: "extra", Rules: []rbacv1.PolicyRule{useRule("extra-scc")}},
+			{ServiceAccountName: saName, Rules: []rbacv1.PolicyRule{useRule("memcached-scc")}},
+		}))
+	})
+	It("ignores objects that are not SCCs", func() {
+		cm := newSCC("memcached-scc", "system:serviceaccount:default:"+saName)
+		cm.SetAPIVersion("v1")
+		cm.SetKind("ConfigMap")
+		c.Others = []unstructured.Unstructured{cm}
+		strategy := &csv.Spec.InstallStrategy.StrategySpec
+		applySecurityContextConstraints(c, strategy, nil)
+		Expect(strategy.ClusterPermissions).To(BeEmpty())
+	})
+})
@@ -0,0 +1,232 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"math"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stableGroupVersions maps kinds of deprecated API versions to the stable API version that replaces them.
+// Only kinds whose stable schema accepts the deprecated object unchanged are converted. Fields the stable
+// version requires or defaults differently, ex. a workload's selector or update strategy, are set when unset
+// by UpgradeAPIVersions. Others, ex. networking.k8s.io/v1beta1 Ingresses, need manual changes.
+var stableGroupVersions = func() map[schema.GroupVersionKind]schema.GroupVersion {
+	table := make(map[schema.GroupVersionKind]schema.GroupVersion)
+	add := func(stable schema.GroupVersion, deprecated []schema.GroupVersion, kinds ...string) {
+		for _, gv := range deprecated {
+			for _, kind := range kinds {
+				table[gv.WithKind(kind)] = stable
+			}
+		}
+	}
+	add(schema.GroupVersion{Group: "rbac.authorization.k8s.io", Version: "v1"},
+		[]schema.GroupVersion{
+			{Group: "rbac.authorization.k8s.io", Version: "v1alpha1"},
+			{Group: "rbac.authorization.k8s.io", Version: "v1beta1"},
+		},
+		"Role", "ClusterRole", "RoleBinding", "ClusterRoleBinding")
+	add(appsv1.SchemeGroupVersion,
+		[]schema.GroupVersion{
+			{Group: "apps", Version: "v1beta1"},
+			{Group: "apps", Version: "v1beta2"},
+		},
+		"Deployment", "DaemonSet", "ReplicaSet", "StatefulSet")
+	add(appsv1.SchemeGroupVersion,
+		[]schema.GroupVersion{{Group: "extensions", Version: "v1beta1"}},
+		"Deployment", "DaemonSet", "ReplicaSet")
+	add(schema.GroupVersion{Group: "networking.k8s.io", Version: "v1"},
+		[]schema.GroupVersion{{Group: "extensions", Version: "v1beta1"}},
+		"NetworkPolicy")
+	add(schema.GroupVersion{Group: "scheduling.k8s.io", Version: "v1"},
+		[]schema.GroupVersion{
+			{Group: "scheduling.k8s.io", Version: "v1alpha1"},
+			{Group: "scheduling.k8s.io", Version: "v1beta1"},
+		},
+		"PriorityClass")
+	add(schema.GroupVersion{Group: "storage.k8s.io", Version: "v1"},
+		[]schema.GroupVersion{{Group: "storage.k8s.io", Version: "v1beta1"}},
+		"StorageClass")
+	add(schema.GroupVersion{Group: "coordination.k8s.io", Version: "v1"},
+		[]schema.GroupVersion{{Group: "coordination.k8s.io", Version: "v1beta1"}},
+		"Lease")
+	return table
+}()
+
+// UpgradeAPIVersions sets the apiVersion of all objects in c of a deprecated API version with a stable
+// equivalent, ex. rbac.authorization.k8s.io/v1beta1 ClusterRoles, to the stable version. Workloads without a
+// selector get one matching their pod template's labels, which deprecated versions defaulted to but
+// apps/v1 requires, and unset fields apps/v1 defaults differently, ex. extensions/v1beta1 DaemonSets'
+// OnDelete update strategy, are set to the deprecated version's default so workloads behave the same.
+// extensions/v1beta1 Deployments are collected as Deployments. Each object upgraded is returned as
+// "<kind>.<group> <namespace>/<name> from <apiVersion> to <apiVersion>".
+func (c *Manifests) UpgradeAPIVersions() (upgraded []string, err error) {
+	upgrade := func(obj client.Object) schema.GroupVersionKind {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		stable, isDeprecated := stableGroupVersions[gvk]
+		if !isDeprecated {
+			return schema.GroupVersionKind{}
+		}
+		obj.GetObjectKind().SetGroupVersionKind(stable.WithKind(gvk.Kind))
+		upgraded = append(upgraded, fmt.Sprintf("%s from %s to %s",
+			objectKey(gvk.GroupKind(), obj.GetNamespace(), obj.GetName()), gvk.GroupVersion(), stable))
+		return gvk
+	}
+	for i := range c.Roles {
+		upgrade(&c.Roles[i])
+	}
+	for i := range c.ClusterRoles {
+		upgrade(&c.ClusterRoles[i])
+	}
+	for i := range c.RoleBindings {
+		upgrade(&c.RoleBindings[i])
+	}
+	for i := range c.ClusterRoleBindings {
+		upgrade(&c.ClusterRoleBindings[i])
+	}
+	for i := range c.Deployments {
+		dep := &c.Deployments[i]
+		from := upgrade(dep)
+		if from.Empty() {
+			continue
+		}
+		setDeprecatedDeploymentDefaults(dep, from.GroupVersion())
+		if dep.Spec.Selector != nil {
+			continue
+		}
+		if labels := dep.Spec.Template.GetLabels(); len(labels) != 0 {
+			dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: copyStringMap(labels)}
+		}
+	}
+
+	others := make([]unstructured.Unstructured, 0, len(c.Others))
+	for i := range c.Others {
+		other := &c.Others[i]
+		from := upgrade(other)
+		if from.Empty() {
+			others = append(others, *other)
+			continue
+		}
+		if other.GetAPIVersion() == appsv1.SchemeGroupVersion.String() {
+			if err := defaultWorkloadSelector(other); err != nil {
+				return nil, err
+			}
+			if err := setDeprecatedUpdateStrategy(other, from); err != nil {
+				return nil, err
+			}
+		}
+		// Deprecated Deployments of another group than apps are not collected as Deployments.
+		if other.GroupVersionKind().GroupKind() == deploymentGK {
+			dep := appsv1.Deployment{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(other.Object, &dep); err != nil {
+				return nil, fmt.Errorf("error converting Deployment %q to %s: %v", other.GetName(), appsv1.SchemeGroupVersion, err)
+			}
+			setDeprecatedDeploymentDefaults(&dep, from.GroupVersion())
+			c.Deployments = append(c.Deployments, dep)
+			continue
+		}
+		others = append(others, *other)
+	}
+	c.Others = others
+
+	// Custom Resources are a subset of Others, so must be found again.
+	c.filter()
+
+	return upgraded, nil
+}
+
+// defaultWorkloadSelector sets the selector of workload u, if unset, to match its pod template's labels.
+func defaultWorkloadSelector(u *unstructured.Unstructured) error {
+	if _, hasSelector, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "selector"); hasSelector {
+		return nil
+	}
+	labels, _, err := unstructured.NestedStringMap(u.Object, "spec", "template", "metadata", "labels")
+	if err != nil {
+		return fmt.Errorf("error reading %s %q pod template labels: %v", u.GetKind(), u.GetName(), err)
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return unstructured.SetNestedStringMap(u.Object, labels, "spec", "selector", "matchLabels")
+}
+
+// setDeprecatedDeploymentDefaults sets each field of dep that is unset and that the deprecated API version
+// from defaults differently than apps/v1 to from's default.
+func setDeprecatedDeploymentDefaults(dep *appsv1.Deployment, from schema.GroupVersion) {
+	spec := &dep.Spec
+	switch from {
+	case schema.GroupVersion{Group: "extensions", Version: "v1beta1"}:
+		// Old ReplicaSets are all retained and there is no progress deadline.
+		if spec.RevisionHistoryLimit == nil {
+			spec.RevisionHistoryLimit = pointer.Int32Ptr(math.MaxInt32)
+		}
+		if spec.ProgressDeadlineSeconds == nil {
+			spec.ProgressDeadlineSeconds = pointer.Int32Ptr(math.MaxInt32)
+		}
+		if spec.Strategy.Type == "" || spec.Strategy.Type == appsv1.RollingUpdateDeploymentStrategyType {
+			if spec.Strategy.RollingUpdate == nil {
+				spec.Strategy.RollingUpdate = &appsv1.RollingUpdateDeployment{}
+			}
+			one := intstr.FromInt(1)
+			if spec.Strategy.RollingUpdate.MaxUnavailable == nil {
+				spec.Strategy.RollingUpdate.MaxUnavailable = &one
+			}
+			if spec.Strategy.RollingUpdate.MaxSurge == nil {
+				spec.Strategy.RollingUpdate.MaxSurge = &one
+			}
+		}
+	case schema.GroupVersion{Group: "apps", Version: "v1beta1"}:
+		if spec.RevisionHistoryLimit == nil {
+			spec.RevisionHistoryLimit = pointer.Int32Ptr(2)
+		}
+	}
+}
+
+// setDeprecatedUpdateStrategy sets the update strategy type of workload u, if unset, to OnDelete if the
+// deprecated kind from defaulted to it, as extensions/v1beta1 DaemonSets and apps/v1beta1 StatefulSets do.
+// apps/v1 defaults to RollingUpdate instead, which would replace all pods on each update.
+func setDeprecatedUpdateStrategy(u *unstructured.Unstructured, from schema.GroupVersionKind) error {
+	switch from {
+	case schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "DaemonSet"},
+		schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "StatefulSet"}:
+	default:
+		return nil
+	}
+	if _, hasType, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "updateStrategy", "type"); hasType {
+		return nil
+	}
+	if err := unstructured.SetNestedField(u.Object, "OnDelete", "spec", "updateStrategy", "type"); err != nil {
+		return fmt.Errorf("error setting %s %q update strategy: %v", u.GetKind(), u.GetName(), err)
+	}
+	return nil
+}
+
+// copyStringMap returns a copy of m.
+func copyStringMap(m map[string]string) map[string]string {
+	cp := make(map[string]string, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"math"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("UpgradeAPIVersions", func() {
+	const manifests = `apiVersion: rbac.authorization.k8s.io/v1beta1
+kind: ClusterRole
+metadata:
+  name: manager-role
+rules:
+- apiGroups:
+  - cache.example.com
+  resources:
+  - memcacheds
+  verbs:
+  - get
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+  namespace: system
+spec:
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: quay.io/example/memcached-operator:v0.0.1
+        name: manager
+---
+apiVersion: apps/v1beta2
+kind: DaemonSet
+metadata:
+  name: node-agent
+spec:
+  selector:
+    matchLabels:
+      app: node-agent
+  template:
+    metadata:
+      labels:
+        app: node-agent
+---
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: metrics
+`
+
+	var c *Manifests
+	BeforeEach(func() {
+		c = &Manifests{}
+		Expect(c.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+		Expect(c.Deployments).To(BeEmpty())
+	})
+
+	It("upgrades deprecated API versions to their stable equivalents", func() {
+		upgraded, err := c.UpgradeAPIVersions()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(upgraded).To(Equal([]string{
+			"ClusterRole.rbac.authorization.k8s.io manager-role from rbac.authorization.k8s.io/v1beta1 to rbac.authorization.k8s.io/v1",
+			"Deployment.extensions system/memcached-operator-controller-manager from extensions/v1beta1 to apps/v1",
+			"DaemonSet.apps node-agent from apps/v1beta2 to apps/v1",
+		}))
+
+		Expect(c.ClusterRoles[0].APIVersion).To(Equal("rbac.authorization.k8s.io/v1"))
+		Expect(c.ClusterRoles[0].Rules).To(HaveLen(1))
+
+		Expect(c.Deployments).To(HaveLen(1))
+		dep := c.Deployments[0]
+		Expect(dep.APIVersion).To(Equal("apps/v1"))
+		Expect(dep.GetName()).To(Equal("memcached-operator-controller-manager"))
+		Expect(dep.Spec.Selector).To(Equal(&metav1.LabelSelector{
+			MatchLabels: map[string]string{"control-plane": "controller-manager"},
+		}))
+		Expect(dep.Spec.Template.Spec.Containers[0].Image).To(Equal("quay.io/example/memcached-operator:v0.0.1"))
+		// extensions/v1beta1 defaults that apps/v1 defaults differently are kept.
+		one := intstr.FromInt(1)
+		Expect(dep.Spec.RevisionHistoryLimit).To(Equal(pointer.Int32Ptr(math.MaxInt32)))
+		Expect(dep.Spec.ProgressDeadlineSeconds).To(Equal(pointer.Int32Ptr(math.MaxInt32)))
+		Expect(dep.Spec.Strategy.RollingUpdate).To(Equal(&appsv1.RollingUpdateDeployment{MaxUnavailable: &one, MaxSurge: &one}))
+
+		Expect(c.Others).To(HaveLen(2))
+		Expect(c.Others[0].GetAPIVersion()).To(Equal("apps/v1"))
+		Expect(c.Others[0].Object["spec"]).To(HaveKeyWithValue("selector",
+			map[string]interface{}{"matchLabels": map[string]interface{}{"app": "node-agent"}}))
+		// apps/v1beta2 DaemonSets default to the RollingUpdate strategy, like apps/v1.
+		Expect(c.Others[0].Object["spec"]).NotTo(HaveKey("updateStrategy"))
+		// Ingress's stable version has another schema, so it is not converted.
+		Expect(c.Others[1].GetAPIVersion()).To(Equal("networking.k8s.io/v1beta1"))
+	})
+	It("keeps the OnDelete update strategy deprecated workloads defaulted to", func() {
+		c = &Manifests{}
+		Expect(c.UpdateFromReader(strings.NewReader(`apiVersion: extensions/v1beta1
+kind: DaemonSet
+metadata:
+  name: node-agent
+---
+apiVersion: apps/v1beta1
+kind: StatefulSet
+metadata:
+  name: store
+spec:
+  updateStrategy:
+    type: RollingUpdate
+---
+apiVersion: apps/v1beta1
+kind: Deployment
+metadata:
+  name: memcached-operator-controller-manager
+`))).To(Succeed())
+		_, err := c.UpgradeAPIVersions()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Others).To(HaveLen(2))
+		Expect(c.Others[0].Object["spec"]).To(HaveKeyWithValue("updateStrategy", map[string]interface{}{"type": "OnDelete"}))
+		Expect(c.Others[1].Object["spec"]).To(HaveKeyWithValue("updateStrategy", map[string]interface{}{"type": "RollingUpdate"}))
+		Expect(c.Deployments).To(HaveLen(1))
+		Expect(c.Deployments[0].Spec.RevisionHistoryLimit).To(Equal(pointer.Int32Ptr(2)))
+		Expect(c.Deployments[0].Spec.ProgressDeadlineSeconds).To(BeNil())
+	})
+	It("returns nothing if no object has a deprecated API version", func() {
+		_, err := c.UpgradeAPIVersions()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.UpgradeAPIVersions()).To(BeEmpty())
+	})
+})
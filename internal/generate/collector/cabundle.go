@@ -0,0 +1,43 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+// StripConversionCABundles removes the CA bundle from the conversion webhook client config of all
+// CustomResourceDefinitions in c, since OLM injects the CA of the certificates it generates, and a
+// bundle collected from a cluster or cert-manager is stale once packaged. CRDs that had a CA bundle
+// are returned as "<kind>.<group> <name>".
+func (c *Manifests) StripConversionCABundles() (stripped []string) {
+	for i := range c.V1CustomResourceDefinitions {
+		conversion := c.V1CustomResourceDefinitions[i].Spec.Conversion
+		if conversion == nil || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+			continue
+		}
+		if len(conversion.Webhook.ClientConfig.CABundle) != 0 {
+			conversion.Webhook.ClientConfig.CABundle = nil
+			stripped = append(stripped, objectKey(crdGK, "", c.V1CustomResourceDefinitions[i].GetName()))
+		}
+	}
+	for i := range c.V1beta1CustomResourceDefinitions {
+		conversion := c.V1beta1CustomResourceDefinitions[i].Spec.Conversion
+		if conversion == nil || conversion.WebhookClientConfig == nil {
+			continue
+		}
+		if len(conversion.WebhookClientConfig.CABundle) != 0 {
+			conversion.WebhookClientConfig.CABundle = nil
+			stripped = append(stripped, objectKey(crdGK, "", c.V1beta1CustomResourceDefinitions[i].GetName()))
+		}
+	}
+	return stripped
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StripConversionCABundles", func() {
+	const manifests = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    plural: memcacheds
+  scope: Namespaced
+  conversion:
+    strategy: Webhook
+    webhook:
+      clientConfig:
+        caBundle: Y2EtYnVuZGxl
+        service:
+          name: webhook-service
+          namespace: system
+          path: /convert
+      conversionReviewVersions:
+      - v1
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+---
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: caches.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Cache
+    plural: caches
+  scope: Namespaced
+  conversion:
+    strategy: Webhook
+    webhookClientConfig:
+      caBundle: Y2EtYnVuZGxl
+      service:
+        name: webhook-service
+        namespace: system
+  version: v1alpha1
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: nodes.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Node
+    plural: nodes
+  scope: Cluster
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+`
+	var c *Manifests
+	BeforeEach(func() {
+		c = &Manifests{}
+		Expect(c.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+	})
+
+	It("removes CA bundles from conversion webhook client configs", func() {
+		Expect(c.StripConversionCABundles()).To(Equal([]string{
+			"CustomResourceDefinition.apiextensions.k8s.io memcacheds.cache.example.com",
+			"CustomResourceDefinition.apiextensions.k8s.io caches.cache.example.com",
+		}))
+		webhook := c.V1CustomResourceDefinitions[0].Spec.Conversion.Webhook
+		Expect(webhook.ClientConfig.CABundle).To(BeEmpty())
+		// The rest of the client config is kept.
+		Expect(webhook.ClientConfig.Service.Name).To(Equal("webhook-service"))
+		Expect(c.V1beta1CustomResourceDefinitions[0].Spec.Conversion.WebhookClientConfig.CABundle).To(BeEmpty())
+	})
+	It("returns nothing if no CA bundle is set", func() {
+		c.StripConversionCABundles()
+		Expect(c.StripConversionCABundles()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,297 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
+)
+
+// fileCacheFormat is the version of the format of cached manifests, incremented whenever it or the way
+// files are decoded changes, so manifests decoded by an older decoder are never collected.
+const fileCacheFormat = "2"
+
+// FileCache caches the manifests decoded from each file read by UpdateFromDirsWithCache, keyed on the
+// file's path and a hash of its contents, so files unchanged since they were cached are not decoded
+// again. A cache opened from a file saved with another format version is discarded.
+type FileCache struct {
+	path    string
+	entries map[string]fileCacheEntry
+	// used are the paths looked up since the cache was opened, which are the only entries saved.
+	used         map[string]struct{}
+	hits, misses int
+}
+
+// fileCache is the serialized form of a FileCache.
+type fileCache struct {
+	Format  string                    `json:"format"`
+	Entries map[string]fileCacheEntry `json:"entries"`
+}
+
+// fileCacheEntry holds the manifests decoded from a file whose contents have the SHA-256 hash Hash.
+type fileCacheEntry struct {
+	Hash      string          `json:"hash"`
+	Manifests json.RawMessage `json:"manifests"`
+}
+
+// OpenFileCache reads a cache previously saved to path. A missing, unreadable, or mismatched cache
+// file results in an empty cache, which Save overwrites. A file another user could have written,
+// ex. one not owned by the current user, is ignored, so its manifests are never collected.
+func OpenFileCache(path string) *FileCache {
+	fc := &FileCache{path: path, entries: map[string]fileCacheEntry{}, used: map[string]struct{}{}}
+	saved, err := readFileCache(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debugf("Ignoring manifests cache %s: %v", path, err)
+		}
+		return fc
+	}
+	if saved.Format != fileCacheFormat {
+		log.Debugf("Ignoring manifests cache %s saved with format %q", path, saved.Format)
+		return fc
+	}
+	if saved.Entries != nil {
+		fc.entries = saved.Entries
+	}
+	return fc
+}
+
+// readFileCache reads the cache file at path, returning an error if it is not a regular file only
+// the current user can write.
+func readFileCache(path string) (*fileCache, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCacheOwner(info); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	saved := &fileCache{}
+	if err := json.Unmarshal(b, saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// checkCacheOwner returns an error if info is not of a regular file or directory owned by the current
+// user, or if others can write to it.
+func checkCacheOwner(info os.FileInfo) error {
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return fmt.Errorf("%s is not a regular file", info.Name())
+	}
+	return checkPrivate(info)
+}
+
+// Save writes entries of files looked up since the cache was opened to the cache's path, with the
+// entries other runs saved since for files that still exist, so entries of deleted files are dropped
+// but those of, ex. another --batch operator's, are kept. The file is replaced atomically so concurrent
+// runs never read a partially written cache. The cache's directory and file are only accessible by the
+// current user.
+func (fc *FileCache) Save() error {
+	log.Debugf("Decoded %d manifest files from cache %s, and %d from disk", fc.hits, fc.path, fc.misses)
+	saved := fileCache{Format: fileCacheFormat, Entries: make(map[string]fileCacheEntry, len(fc.used))}
+	if current, err := readFileCache(fc.path); err == nil && current.Format == fileCacheFormat {
+		for path, entry := range current.Entries {
+			if _, err := os.Stat(path); err == nil {
+				saved.Entries[path] = entry
+			}
+		}
+	}
+	for path := range fc.used {
+		if entry, ok := fc.entries[path]; ok {
+			saved.Entries[path] = entry
+		}
+	}
+	b, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("error marshaling manifests cache: %v", err)
+	}
+	dir := filepath.Dir(fc.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if err := checkCacheOwner(info); err != nil {
+		return fmt.Errorf("not saving manifests cache: %v", err)
+	}
+	// TempFile creates files only the current user can read and write.
+	tmp, err := ioutil.TempFile(dir, filepath.Base(fc.path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fc.path)
+}
+
+// read returns the manifests in the file at path, decoding them from the cache if the file's contents
+// are unchanged since it was cached. The returned Manifests are not filtered or deduplicated.
+func (fc *FileCache) read(path string) (*Manifests, error) {
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+	fc.used[key] = struct{}{}
+
+	log.Tracef("Reading manifests from %s", path)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(b)
+	hash := hex.EncodeToString(h[:])
+	if entry, ok := fc.entries[key]; ok && entry.Hash == hash {
+		col := &Manifests{}
+		if err := json.Unmarshal(entry.Manifests, col); err == nil {
+			log.Tracef("Decoded manifests in %s from cache", path)
+			fc.hits++
+			return col, nil
+		}
+		log.Debugf("Ignoring corrupt manifests cache entry for %s", path)
+	}
+
+	col := &Manifests{}
+	if err := col.updateFromReader(bytes.NewBuffer(b)); err != nil {
+		return nil, err
+	}
+	fc.misses++
+	manifests, err := json.Marshal(col)
+	if err != nil {
+		return nil, fmt.Errorf("error caching manifests in %s: %v", path, err)
+	}
+	fc.entries[key] = fileCacheEntry{Hash: hash, Manifests: manifests}
+	return col, nil
+}
+
+// UpdateFromDirsWithCache is like UpdateFromDirs, but decodes files unchanged since they were added to
+// cache from cache. If cache is nil, it is equivalent to UpdateFromDirs.
+func (c *Manifests) UpdateFromDirsWithCache(deployDir, crdsDir string, cache *FileCache) error {
+	if cache == nil {
+		return c.UpdateFromDirs(deployDir, crdsDir)
+	}
+
+	err := filepath.Walk(deployDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return skipVCSDir(info, err)
+		}
+		col, err := cache.read(path)
+		if err != nil {
+			return err
+		}
+		return c.add(col)
+	})
+	if err != nil {
+		return fmt.Errorf("error collecting manifests from directory %s: %w", deployDir, err)
+	}
+
+	if isDirExist(crdsDir) {
+		if err := c.setCustomResourceDefinitionsWithCache(crdsDir, cache); err != nil {
+			return fmt.Errorf("error adding CustomResourceDefinitions to manifest collector: %w", err)
+		}
+	}
+
+	c.filter()
+
+	if err := c.deduplicate(); err != nil {
+		return fmt.Errorf("error removing duplicate manifests: %v", err)
+	}
+
+	return nil
+}
+
+// setCustomResourceDefinitionsWithCache replaces c's CustomResourceDefinitions with those in the files
+// directly in crdsDir, like k8sutil.GetCustomResourceDefinitions, decoding unchanged files from cache.
+func (c *Manifests) setCustomResourceDefinitionsWithCache(crdsDir string, cache *FileCache) error {
+	infos, err := ioutil.ReadDir(crdsDir)
+	if err != nil {
+		return err
+	}
+	crds := &Manifests{}
+	crGVKSet := map[schema.GroupVersionKind]struct{}{}
+	for _, info := range infos {
+		path := filepath.Join(crdsDir, info.Name())
+		if info.IsDir() {
+			log.Debugf("Skipping dir: %s", path)
+			continue
+		}
+		col, err := cache.read(path)
+		if err != nil {
+			return fmt.Errorf("error reading manifest %s: %w", path, err)
+		}
+		crGVKs := append(k8sutil.GVKsForV1CustomResourceDefinitions(col.V1CustomResourceDefinitions...),
+			k8sutil.GVKsForV1beta1CustomResourceDefinitions(col.V1beta1CustomResourceDefinitions...)...)
+		for _, gvk := range crGVKs {
+			if _, hasGVK := crGVKSet[gvk]; hasGVK {
+				return fmt.Errorf("duplicate custom resource GVK %s in %s", gvk, path)
+			}
+			crGVKSet[gvk] = struct{}{}
+		}
+		crds.V1CustomResourceDefinitions = append(crds.V1CustomResourceDefinitions, col.V1CustomResourceDefinitions...)
+		crds.V1beta1CustomResourceDefinitions = append(crds.V1beta1CustomResourceDefinitions,
+			col.V1beta1CustomResourceDefinitions...)
+	}
+	c.V1CustomResourceDefinitions = crds.V1CustomResourceDefinitions
+	c.V1beta1CustomResourceDefinitions = crds.V1beta1CustomResourceDefinitions
+	return nil
+}
+
+// add appends all objects in o to c without filtering or deduplicating them.
+func (c *Manifests) add(o *Manifests) error {
+	c.ClusterServiceVersions = append(c.ClusterServiceVersions, o.ClusterServiceVersions...)
+	c.Roles = append(c.Roles, o.Roles...)
+	c.ClusterRoles = append(c.ClusterRoles, o.ClusterRoles...)
+	c.RoleBindings = append(c.RoleBindings, o.RoleBindings...)
+	c.ClusterRoleBindings = append(c.ClusterRoleBindings, o.ClusterRoleBindings...)
+	c.Deployments = append(c.Deployments, o.Deployments...)
+	c.ServiceAccounts = append(c.ServiceAccounts, o.ServiceAccounts...)
+	c.Services = append(c.Services, o.Services...)
+	c.V1CustomResourceDefinitions = append(c.V1CustomResourceDefinitions, o.V1CustomResourceDefinitions...)
+	c.V1beta1CustomResourceDefinitions = append(c.V1beta1CustomResourceDefinitions, o.V1beta1CustomResourceDefinitions...)
+	c.ValidatingWebhooks = append(c.ValidatingWebhooks, o.ValidatingWebhooks...)
+	c.MutatingWebhooks = append(c.MutatingWebhooks, o.MutatingWebhooks...)
+	c.Others = append(c.Others, o.Others...)
+	if o.ScorecardConfig.Metadata.Name != "" {
+		if c.ScorecardConfig.Metadata.Name != "" {
+			return errors.New("duplicate scorecard configurations in collector input")
+		}
+		c.ScorecardConfig = o.ScorecardConfig
+	}
+	return nil
+}
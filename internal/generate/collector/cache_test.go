@@ -0,0 +1,176 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileCache", func() {
+	const (
+		deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: memcached-operator
+`
+		crd = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    plural: memcacheds
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+---
+apiVersion: cache.example.com/v1alpha1
+kind: Memcached
+metadata:
+  name: memcached-sample
+`
+	)
+
+	var tmp, deployDir, crdsDir, cachePath string
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = ioutil.TempDir("", "collector-cache-")
+		Expect(err).NotTo(HaveOccurred())
+		deployDir = filepath.Join(tmp, "deploy")
+		crdsDir = filepath.Join(deployDir, "crds")
+		cachePath = filepath.Join(tmp, "cache", "manifests.json")
+		Expect(os.MkdirAll(crdsDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(deployDir, "deployment.yaml"), []byte(deployment), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(crdsDir, "crd.yaml"), []byte(crd), 0644)).To(Succeed())
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	collect := func(cache *FileCache) *Manifests {
+		col := &Manifests{}
+		Expect(col.UpdateFromDirsWithCache(deployDir, crdsDir, cache)).To(Succeed())
+		return col
+	}
+
+	It("collects the same manifests as UpdateFromDirs", func() {
+		uncached := &Manifests{}
+		Expect(uncached.UpdateFromDirs(deployDir, crdsDir)).To(Succeed())
+
+		cache := OpenFileCache(cachePath)
+		Expect(collect(cache)).To(Equal(uncached))
+		Expect(cache.Save()).To(Succeed())
+
+		cache = OpenFileCache(cachePath)
+		Expect(collect(cache)).To(Equal(uncached))
+		Expect(cache.misses).To(BeZero())
+		Expect(cache.hits).To(BeNumerically(">", 0))
+	})
+
+	It("decodes files changed since they were cached", func() {
+		cache := OpenFileCache(cachePath)
+		collect(cache)
+		Expect(cache.Save()).To(Succeed())
+
+		// An edit that keeps the file's size and modification time is still detected.
+		path := filepath.Join(deployDir, "deployment.yaml")
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		changed := strings.Replace(deployment, "memcached-operator", "memcached-instance", 1)
+		Expect(changed).NotTo(Equal(deployment))
+		Expect(ioutil.WriteFile(path, []byte(changed), 0644)).To(Succeed())
+		Expect(os.Chtimes(path, info.ModTime(), info.ModTime())).To(Succeed())
+
+		cache = OpenFileCache(cachePath)
+		col := collect(cache)
+		Expect(col.Deployments).To(HaveLen(1))
+		Expect(col.Deployments[0].GetName()).To(Equal("memcached-instance"))
+		Expect(cache.misses).To(Equal(1))
+	})
+
+	It("discards a cache saved with a different format", func() {
+		cache := OpenFileCache(cachePath)
+		collect(cache)
+		Expect(cache.Save()).To(Succeed())
+		b, err := ioutil.ReadFile(cachePath)
+		Expect(err).NotTo(HaveOccurred())
+		b = bytes.Replace(b, []byte(`"format":"`+fileCacheFormat+`"`), []byte(`"format":"0"`), 1)
+		Expect(ioutil.WriteFile(cachePath, b, 0600)).To(Succeed())
+
+		// The CRDs file is read while walking deployDir and crdsDir, so is only decoded once.
+		cache = OpenFileCache(cachePath)
+		collect(cache)
+		Expect(cache.misses).To(Equal(2))
+	})
+
+	It("ignores an unreadable cache file", func() {
+		Expect(os.MkdirAll(filepath.Dir(cachePath), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(cachePath, []byte("not json"), 0644)).To(Succeed())
+
+		cache := OpenFileCache(cachePath)
+		Expect(collect(cache).V1CustomResourceDefinitions).To(HaveLen(1))
+		Expect(cache.Save()).To(Succeed())
+		Expect(OpenFileCache(cachePath).entries).To(HaveLen(2))
+	})
+
+	It("is only accessible by the current user, and ignores a file others can write", func() {
+		cache := OpenFileCache(cachePath)
+		collect(cache)
+		Expect(cache.Save()).To(Succeed())
+		dirInfo, err := os.Stat(filepath.Dir(cachePath))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dirInfo.Mode().Perm()).To(Equal(os.FileMode(0700)))
+		info, err := os.Stat(cachePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		Expect(OpenFileCache(cachePath).entries).To(HaveLen(2))
+
+		Expect(os.Chmod(cachePath, 0666)).To(Succeed())
+		Expect(OpenFileCache(cachePath).entries).To(BeEmpty())
+	})
+
+	It("keeps entries saved by other runs for files that still exist", func() {
+		otherDir := filepath.Join(tmp, "other")
+		Expect(os.Mkdir(otherDir, 0755)).To(Succeed())
+		otherPath := filepath.Join(otherDir, "deployment.yaml")
+		Expect(ioutil.WriteFile(otherPath, []byte(deployment), 0644)).To(Succeed())
+
+		other := OpenFileCache(cachePath)
+		Expect((&Manifests{}).UpdateFromDirsWithCache(otherDir, "", other)).To(Succeed())
+		cache := OpenFileCache(cachePath)
+		collect(cache)
+		Expect(other.Save()).To(Succeed())
+		Expect(cache.Save()).To(Succeed())
+		Expect(OpenFileCache(cachePath).entries).To(HaveLen(3))
+
+		Expect(os.Remove(otherPath)).To(Succeed())
+		cache = OpenFileCache(cachePath)
+		collect(cache)
+		Expect(cache.Save()).To(Succeed())
+		Expect(OpenFileCache(cachePath).entries).To(HaveLen(2))
+	})
+})
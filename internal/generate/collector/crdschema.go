@@ -0,0 +1,181 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// NormalizeCRDSchemas canonicalizes the OpenAPI v3 validation schemas of all CRDs in c, so equivalent
+// schemas serialized differently, ex. by different controller-gen versions, are written the same.
+// Required property lists are sorted and deduplicated, and default, example, and enum values are
+// re-encoded with sorted object keys and numbers without trailing fractional zeros. Schema semantics
+// are unchanged.
+func (c *Manifests) NormalizeCRDSchemas() error {
+	for i := range c.V1CustomResourceDefinitions {
+		crd := &c.V1CustomResourceDefinitions[i]
+		for j := range crd.Spec.Versions {
+			if schema := crd.Spec.Versions[j].Schema; schema != nil && schema.OpenAPIV3Schema != nil {
+				if err := normalizeSchemaObject(schema.OpenAPIV3Schema); err != nil {
+					return fmt.Errorf("error normalizing CRD %s version %s schema: %v",
+						crd.GetName(), crd.Spec.Versions[j].Name, err)
+				}
+			}
+		}
+	}
+	for i := range c.V1beta1CustomResourceDefinitions {
+		crd := &c.V1beta1CustomResourceDefinitions[i]
+		if v := crd.Spec.Validation; v != nil && v.OpenAPIV3Schema != nil {
+			if err := normalizeSchemaObject(v.OpenAPIV3Schema); err != nil {
+				return fmt.Errorf("error normalizing CRD %s schema: %v", crd.GetName(), err)
+			}
+		}
+		for j := range crd.Spec.Versions {
+			if v := crd.Spec.Versions[j].Schema; v != nil && v.OpenAPIV3Schema != nil {
+				if err := normalizeSchemaObject(v.OpenAPIV3Schema); err != nil {
+					return fmt.Errorf("error normalizing CRD %s version %s schema: %v",
+						crd.GetName(), crd.Spec.Versions[j].Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeSchemaObject normalizes schema, a *JSONSchemaProps of either CRD API version, in place.
+// The schema is normalized as JSON so both API versions share one implementation.
+func normalizeSchemaObject(schema interface{}) error {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	// Numbers are decoded as written so large integers keep their precision.
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return err
+	}
+	normalizeSchema(obj)
+	if b, err = json.Marshal(obj); err != nil {
+		return err
+	}
+	// Decoding into existing maps would merge with their keys, so the schema is reset first.
+	v := reflect.ValueOf(schema).Elem()
+	v.Set(reflect.Zero(v.Type()))
+	return json.Unmarshal(b, schema)
+}
+
+// normalizeSchema normalizes the JSON schema obj and its subschemas. Only keys at schema positions are
+// interpreted, so a property or value named like a schema keyword, ex. "required", is not changed.
+func normalizeSchema(obj map[string]interface{}) {
+	for key, value := range obj {
+		switch key {
+		case "required":
+			if list, ok := value.([]interface{}); ok {
+				obj[key] = sortedStrings(list)
+			}
+		case "default", "example":
+			obj[key] = normalizeValue(value)
+		case "enum":
+			if list, ok := value.([]interface{}); ok {
+				for i := range list {
+					list[i] = normalizeValue(list[i])
+				}
+			}
+		case "properties", "patternProperties", "definitions", "dependencies":
+			if schemas, ok := value.(map[string]interface{}); ok {
+				for _, s := range schemas {
+					if s, ok := s.(map[string]interface{}); ok {
+						normalizeSchema(s)
+					}
+				}
+			}
+		case "allOf", "anyOf", "oneOf", "items":
+			if list, ok := value.([]interface{}); ok {
+				for _, s := range list {
+					if s, ok := s.(map[string]interface{}); ok {
+						normalizeSchema(s)
+					}
+				}
+			} else if s, ok := value.(map[string]interface{}); ok {
+				normalizeSchema(s)
+			}
+		case "not", "additionalProperties", "additionalItems":
+			if s, ok := value.(map[string]interface{}); ok {
+				normalizeSchema(s)
+			}
+		}
+	}
+}
+
+// sortedStrings returns the unique strings in list sorted, or list if it contains other values.
+func sortedStrings(list []interface{}) []interface{} {
+	seen := make(map[string]struct{}, len(list))
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return list
+		}
+		if _, isDup := seen[s]; !isDup {
+			seen[s] = struct{}{}
+			strs = append(strs, s)
+		}
+	}
+	sort.Strings(strs)
+	sorted := make([]interface{}, len(strs))
+	for i, s := range strs {
+		sorted[i] = s
+	}
+	return sorted
+}
+
+// normalizeValue returns the JSON value v with numbers normalized. Object keys are sorted when v is
+// encoded, so need no normalization.
+func normalizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		return normalizeNumber(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeValue(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = normalizeValue(v[k])
+		}
+	}
+	return v
+}
+
+// normalizeNumber removes trailing fractional zeros from n, ex. 1.50 becomes 1.5 and 1.0 becomes 1.
+// Numbers with an exponent are not changed.
+func normalizeNumber(n json.Number) json.Number {
+	s := string(n)
+	if !strings.Contains(s, ".") || strings.ContainsAny(s, "eE") {
+		return n
+	}
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return json.Number(s)
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("NormalizeCRDSchemas", func() {
+	const crd = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    plural: memcacheds
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: [size, image, size]
+            properties:
+              size:
+                type: integer
+                default: 3.0
+                maximum: 10
+              ratio:
+                type: number
+                default: 0.50
+              image:
+                type: string
+                enum: [memcached, redis]
+              options:
+                type: object
+                default: {"verbose": true, "required": ["b", "a"], "level": 1.0}
+                x-kubernetes-preserve-unknown-fields: true
+              required:
+                type: array
+                items:
+                  type: object
+                  required: [z, a]
+                  properties:
+                    z: {type: string}
+                    a: {type: string}
+`
+
+	var c *Manifests
+	BeforeEach(func() {
+		c = &Manifests{}
+		Expect(c.UpdateFromReader(strings.NewReader(crd))).To(Succeed())
+		Expect(c.V1CustomResourceDefinitions).To(HaveLen(1))
+	})
+
+	specSchema := func() apiextv1.JSONSchemaProps {
+		return c.V1CustomResourceDefinitions[0].Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	}
+
+	It("sorts required properties and normalizes defaults", func() {
+		Expect(c.NormalizeCRDSchemas()).To(Succeed())
+		spec := specSchema()
+		Expect(spec.Required).To(Equal([]string{"image", "size"}))
+		Expect(string(spec.Properties["size"].Default.Raw)).To(Equal("3"))
+		Expect(string(spec.Properties["ratio"].Default.Raw)).To(Equal("0.5"))
+		// Values named like schema keywords are not schemas, so are not sorted.
+		Expect(string(spec.Properties["options"].Default.Raw)).To(Equal(`{"level":1,"required":["b","a"],"verbose":true}`))
+		Expect(spec.Properties["required"].Items.Schema.Required).To(Equal([]string{"a", "z"}))
+		Expect(spec.Properties["image"].Enum).To(HaveLen(2))
+	})
+
+	It("is idempotent", func() {
+		Expect(c.NormalizeCRDSchemas()).To(Succeed())
+		once := c.V1CustomResourceDefinitions[0].DeepCopy()
+		Expect(c.NormalizeCRDSchemas()).To(Succeed())
+		Expect(c.V1CustomResourceDefinitions[0]).To(Equal(*once))
+	})
+
+	It("does not change which objects the schema accepts", func() {
+		validate := func(objs []string) (errs []string) {
+			internal := &apiextensions.CustomResourceValidation{}
+			Expect(apiextv1.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(
+				c.V1CustomResourceDefinitions[0].Spec.Versions[0].Schema, internal, nil)).To(Succeed())
+			v, _, err := validation.NewSchemaValidator(internal)
+			Expect(err).NotTo(HaveOccurred())
+			for _, obj := range objs {
+				var u interface{}
+				Expect(yaml.Unmarshal([]byte(obj), &u)).To(Succeed())
+				errs = append(errs, fmt.Sprint(validation.ValidateCustomResource(nil, u, v).ToAggregate()))
+			}
+			return errs
+		}
+		objs := []string{
+			"spec: {size: 3, image: memcached}",
+			"spec: {size: 3}",
+			"spec: {size: 11, image: memcached}",
+			"spec: {size: 3, image: nginx}",
+			"spec: {size: 3, image: redis, required: [{a: x, z: y}]}",
+			"spec: {size: 3, image: redis, required: [{a: x}]}",
+		}
+		before := validate(objs)
+		Expect(before[0]).To(Equal("<nil>"))
+		Expect(before[1]).To(ContainSubstring("spec.image: Required value"))
+
+		Expect(c.NormalizeCRDSchemas()).To(Succeed())
+		Expect(validate(objs)).To(Equal(before))
+	})
+})
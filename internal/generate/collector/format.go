@@ -0,0 +1,119 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// InputFormat is the format of manifests read by UpdateFromReaderWithFormat.
+type InputFormat string
+
+const (
+	// InputFormatYAML is a stream of YAML documents.
+	InputFormatYAML InputFormat = "yaml"
+	// InputFormatJSON is a stream of JSON objects, or of JSON arrays of objects, as some tools emit.
+	InputFormatJSON InputFormat = "json"
+	// InputFormatAuto is InputFormatJSON if the first non-whitespace byte of a stream starts
+	// a JSON object or array, and InputFormatYAML otherwise.
+	InputFormatAuto InputFormat = "auto"
+)
+
+// InputFormats are all input formats.
+var InputFormats = []InputFormat{InputFormatYAML, InputFormatJSON, InputFormatAuto}
+
+// UpdateFromReaderWithFormat adds Kubernetes manifests read from r, in format, to c.
+func (c *Manifests) UpdateFromReaderWithFormat(r io.Reader, format InputFormat) error {
+	switch format {
+	case InputFormatYAML:
+		return c.UpdateFromReader(r)
+	case InputFormatAuto:
+		br := bufio.NewReader(r)
+		isJSON, err := startsWithJSON(br)
+		if err != nil {
+			return err
+		}
+		if !isJSON {
+			return c.UpdateFromReader(br)
+		}
+		r = br
+	case InputFormatJSON:
+	default:
+		return fmt.Errorf("unknown input format %q", format)
+	}
+
+	// JSON is YAML, so each JSON object is collected as a YAML document.
+	docs, err := splitJSON(r)
+	if err != nil {
+		return err
+	}
+	return c.UpdateFromReader(bytes.NewReader(bytes.Join(docs, []byte("\n---\n"))))
+}
+
+// startsWithJSON returns true if the first non-whitespace byte in br starts a JSON object or array.
+func startsWithJSON(br *bufio.Reader) (bool, error) {
+	for {
+		b, err := br.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		if err := br.UnreadByte(); err != nil {
+			return false, err
+		}
+		return b == '{' || b == '[', nil
+	}
+}
+
+// splitJSON returns each object in r, a stream of JSON objects and arrays of objects.
+func splitJSON(r io.Reader) (docs [][]byte, err error) {
+	dec := json.NewDecoder(r)
+	for i := 0; ; i++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			return docs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("error decoding JSON value %d: %v", i, err)
+		}
+		switch raw = bytes.TrimSpace(raw); {
+		case bytes.HasPrefix(raw, []byte("[")):
+			var objs []json.RawMessage
+			if err := json.Unmarshal(raw, &objs); err != nil {
+				return nil, fmt.Errorf("error decoding JSON value %d: %v", i, err)
+			}
+			for j, obj := range objs {
+				if !bytes.HasPrefix(bytes.TrimSpace(obj), []byte("{")) {
+					return nil, fmt.Errorf("JSON value %d element %d is not an object", i, j)
+				}
+				docs = append(docs, obj)
+			}
+		case bytes.HasPrefix(raw, []byte("{")):
+			docs = append(docs, raw)
+		default:
+			return nil, fmt.Errorf("JSON value %d is not an object or an array of objects", i)
+		}
+	}
+}
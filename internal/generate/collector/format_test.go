@@ -0,0 +1,68 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateFromReaderWithFormat", func() {
+	const (
+		deployment = `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "controller-manager"}}`
+		sa         = `{"apiVersion": "v1", "kind": "ServiceAccount", "metadata": {"name": "controller-manager"}}`
+		configMap  = `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "config"}}`
+	)
+	var c *Manifests
+	BeforeEach(func() {
+		c = &Manifests{}
+	})
+
+	It("collects a JSON array of objects followed by an object", func() {
+		stream := "\n  [" + deployment + ",\n" + sa + "]\n" + configMap + "\n"
+		for _, format := range []InputFormat{InputFormatJSON, InputFormatAuto} {
+			c = &Manifests{}
+			Expect(c.UpdateFromReaderWithFormat(strings.NewReader(stream), format)).To(Succeed())
+			Expect(c.Deployments).To(HaveLen(1))
+			Expect(c.Deployments[0].GetName()).To(Equal("controller-manager"))
+			Expect(c.ServiceAccounts).To(HaveLen(1))
+			Expect(c.Others).To(HaveLen(1))
+			Expect(c.Others[0].GetName()).To(Equal("config"))
+		}
+	})
+	It("collects YAML when auto-detecting a stream that does not start with JSON", func() {
+		stream := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n---\n" + sa + "\n"
+		Expect(c.UpdateFromReaderWithFormat(strings.NewReader(stream), InputFormatAuto)).To(Succeed())
+		Expect(c.ServiceAccounts).To(HaveLen(1))
+		Expect(c.Others).To(HaveLen(1))
+	})
+	It("collects nothing from an empty stream", func() {
+		Expect(c.UpdateFromReaderWithFormat(strings.NewReader(" \n"), InputFormatAuto)).To(Succeed())
+		Expect(c.Others).To(BeEmpty())
+	})
+	It("fails for invalid JSON or values that are not objects", func() {
+		err := c.UpdateFromReaderWithFormat(strings.NewReader("["+configMap+", 1]"), InputFormatJSON)
+		Expect(err).To(MatchError("JSON value 0 element 1 is not an object"))
+		err = c.UpdateFromReaderWithFormat(strings.NewReader(configMap+"\n\"foo\""), InputFormatJSON)
+		Expect(err).To(MatchError("JSON value 1 is not an object or an array of objects"))
+		err = c.UpdateFromReaderWithFormat(strings.NewReader("{"), InputFormatJSON)
+		Expect(err).To(MatchError(ContainSubstring("error decoding JSON value 0")))
+	})
+	It("fails for an unknown format", func() {
+		Expect(c.UpdateFromReaderWithFormat(strings.NewReader(""), "toml")).To(MatchError(`unknown input format "toml"`))
+	})
+})
@@ -0,0 +1,95 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// UpdateFromHelmChart renders the Helm chart in dir in-process, like 'helm template <dir>', with values
+// read from valuesFiles, later files taking precedence, then adds the chart's CRDs and rendered manifests
+// to c like UpdateFromReader. The release is named after the chart and has no namespace. Dependencies
+// must be in the chart's charts/ directory. Hooks, including tests, are not collected since OLM does
+// not run them.
+func (c *Manifests) UpdateFromHelmChart(dir string, valuesFiles []string) error {
+	chrt, err := loader.Load(dir)
+	if err != nil {
+		return fmt.Errorf("error loading Helm chart %s: %w", dir, err)
+	}
+	if err := action.CheckDependencies(chrt, chrt.Metadata.Dependencies); err != nil {
+		return fmt.Errorf("error loading Helm chart %s: dependencies %v; run 'helm dependency build %s' first",
+			dir, err, dir)
+	}
+
+	vals := map[string]interface{}{}
+	for _, path := range valuesFiles {
+		fileVals, err := chartutil.ReadValuesFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading Helm values file %s: %w", path, err)
+		}
+		vals = chartutil.CoalesceTables(fileVals, vals)
+	}
+	if err := chartutil.ProcessDependencies(chrt, vals); err != nil {
+		return fmt.Errorf("error processing Helm chart %s dependencies: %v", dir, err)
+	}
+	opts := chartutil.ReleaseOptions{Name: chrt.Name(), IsInstall: true}
+	renderVals, err := chartutil.ToRenderValues(chrt, vals, opts, nil)
+	if err != nil {
+		return helmError(dir, err)
+	}
+	files, err := engine.Render(chrt, renderVals)
+	if err != nil {
+		return helmError(dir, err)
+	}
+	for name := range files {
+		if strings.HasSuffix(name, "NOTES.txt") {
+			delete(files, name)
+		}
+	}
+	_, manifests, err := releaseutil.SortManifests(files, chartutil.DefaultCapabilities.APIVersions, releaseutil.InstallOrder)
+	if err != nil {
+		return fmt.Errorf("error reading manifests rendered from Helm chart %s: %v", dir, err)
+	}
+
+	buf := &bytes.Buffer{}
+	for _, crd := range chrt.CRDObjects() {
+		fmt.Fprintf(buf, "---\n%s\n", crd.File.Data)
+	}
+	for _, manifest := range manifests {
+		fmt.Fprintf(buf, "---\n%s\n", manifest.Content)
+	}
+	if err := c.UpdateFromReader(buf); err != nil {
+		return fmt.Errorf("error reading manifests rendered from Helm chart %s: %v", dir, err)
+	}
+	return nil
+}
+
+// helmError wraps err, returned by rendering the Helm chart in dir, with a hint on how to fix
+// missing values.
+func helmError(dir string, err error) error {
+	msg := err.Error()
+	if strings.Contains(msg, "is required") || strings.Contains(msg, "don't meet the specifications of the schema") {
+		return fmt.Errorf("error rendering Helm chart %s: %v; set the missing values in a values file", dir, err)
+	}
+	return fmt.Errorf("error rendering Helm chart %s: %v", dir, err)
+}
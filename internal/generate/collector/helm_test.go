@@ -0,0 +1,145 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateFromHelmChart", func() {
+	var (
+		c        *Manifests
+		tmp      string
+		chartDir string
+	)
+
+	writeFile := func(name, data string) {
+		path := filepath.Join(chartDir, name)
+		ExpectWithOffset(1, os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		ExpectWithOffset(1, ioutil.WriteFile(path, []byte(data), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		c = &Manifests{}
+		var err error
+		tmp, err = ioutil.TempDir("", "collector-helm-")
+		Expect(err).NotTo(HaveOccurred())
+		chartDir = filepath.Join(tmp, "memcached-operator")
+
+		writeFile("Chart.yaml", `apiVersion: v2
+name: memcached-operator
+version: 0.1.0
+dependencies:
+- name: config
+  version: 0.1.0
+`)
+		writeFile("values.yaml", `image:
+  repository: ""
+  tag: v0.0.1
+`)
+		writeFile("templates/manager.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}-controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - name: manager
+        image: {{ required "image.repository is required" .Values.image.repository }}:{{ .Values.image.tag }}
+`)
+		writeFile("templates/hook.yaml", `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-install
+`)
+		writeFile("templates/NOTES.txt", "Installed {{ .Release.Name }}.\n")
+		writeFile("crds/crd.yaml", `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    plural: memcacheds
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+`)
+		writeFile("charts/config/Chart.yaml", "apiVersion: v2\nname: config\nversion: 0.1.0\n")
+		writeFile("charts/config/templates/config.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-manager-config
+`)
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	writeValues := func(name, data string) string {
+		path := filepath.Join(tmp, name)
+		ExpectWithOffset(1, ioutil.WriteFile(path, []byte(data), 0644)).To(Succeed())
+		return path
+	}
+
+	It("collects CRDs and rendered manifests of the chart and its dependencies", func() {
+		values := writeValues("values.yaml", "image:\n  repository: quay.io/example/memcached-operator\n")
+		override := writeValues("override.yaml", "image:\n  tag: v0.0.2\n")
+		Expect(c.UpdateFromHelmChart(chartDir, []string{values, override})).To(Succeed())
+		Expect(c.V1CustomResourceDefinitions).To(HaveLen(1))
+		Expect(c.Deployments).To(HaveLen(1))
+		Expect(c.Deployments[0].GetName()).To(Equal("memcached-operator-controller-manager"))
+		Expect(c.Deployments[0].Spec.Template.Spec.Containers[0].Image).To(
+			Equal("quay.io/example/memcached-operator:v0.0.2"))
+		// The hook Job is not collected.
+		Expect(c.Others).To(HaveLen(1))
+		Expect(c.Others[0].GetName()).To(Equal("memcached-operator-manager-config"))
+	})
+	It("fails with a hint if a required value is missing", func() {
+		err := c.UpdateFromHelmChart(chartDir, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("image.repository is required"))
+		Expect(err.Error()).To(ContainSubstring("set the missing values in a values file"))
+	})
+	It("fails if a dependency is missing", func() {
+		Expect(os.RemoveAll(filepath.Join(chartDir, "charts"))).To(Succeed())
+		err := c.UpdateFromHelmChart(chartDir, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("missing in charts/ directory: config"))
+		Expect(err.Error()).To(ContainSubstring("helm dependency build"))
+	})
+	It("fails if a values file does not exist", func() {
+		err := c.UpdateFromHelmChart(chartDir, []string{filepath.Join(tmp, "missing.yaml")})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("error reading Helm values file"))
+	})
+})
@@ -0,0 +1,67 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/filesys"
+	"sigs.k8s.io/kustomize/api/krusty"
+)
+
+// UpdateFromKustomize renders the kustomization in dir in-process, like 'kustomize build <dir>',
+// then adds the rendered manifests to c like UpdateFromReader. Rendering with the SDK's kustomize
+// avoids differences between the SDK and the version of a user's kustomize binary. Kustomizations
+// are rendered like kustomize v4 renders them.
+func (c *Manifests) UpdateFromKustomize(dir string) error {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resMap, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return kustomizeError(dir, err)
+	}
+	b, err := resMap.AsYaml()
+	if err != nil {
+		return fmt.Errorf("error encoding manifests rendered from kustomization %s: %v", dir, err)
+	}
+	if err := c.UpdateFromReader(bytes.NewReader(b)); err != nil {
+		return fmt.Errorf("error reading manifests rendered from kustomization %s: %v", dir, err)
+	}
+	return nil
+}
+
+// kustomizeError wraps err, returned by building the kustomization in dir,
+// with a hint on how to fix common errors.
+func kustomizeError(dir string, err error) error {
+	msg := err.Error()
+	var hint string
+	switch {
+	case strings.Contains(msg, "unable to find one of"):
+		hint = "the directory must contain a kustomization.yaml, ex. config/manifests in a kubebuilder project"
+	case strings.Contains(msg, "no such file or directory"), strings.Contains(msg, "must resolve to a file"):
+		hint = "check that every resource, base, and patch the kustomization references exists, " +
+			"ex. by running 'make manifests' first"
+	case strings.Contains(msg, "doc is missing path"):
+		hint = "a JSON patch path does not exist; list indices, ex. of containers, may differ from " +
+			"those kustomize v3 renders, so update the indices or pipe 'kustomize build " + dir + "' to this command instead"
+	case strings.Contains(msg, "plugin"):
+		hint = "kustomize plugins are not supported; pipe 'kustomize build " + dir + "' to this command instead"
+	}
+	if hint == "" {
+		return fmt.Errorf("error building kustomization %s: %v", dir, err)
+	}
+	return fmt.Errorf("error building kustomization %s: %v; %s", dir, err, hint)
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateFromKustomize", func() {
+	var (
+		c   *Manifests
+		tmp string
+	)
+
+	BeforeEach(func() {
+		c = &Manifests{}
+		var err error
+		tmp, err = ioutil.TempDir("", "collector-kustomize-")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	writeFile := func(name, data string) {
+		ExpectWithOffset(1, ioutil.WriteFile(filepath.Join(tmp, name), []byte(data), 0644)).To(Succeed())
+	}
+
+	It("collects rendered manifests", func() {
+		writeFile("kustomization.yaml", `namePrefix: memcached-operator-
+namespace: memcached-operator-system
+resources:
+- manager.yaml
+- config.yaml
+`)
+		writeFile("manager.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - name: manager
+        image: controller:latest
+`)
+		writeFile("config.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: manager-config
+`)
+		Expect(c.UpdateFromKustomize(tmp)).To(Succeed())
+		Expect(c.Deployments).To(HaveLen(1))
+		Expect(c.Deployments[0].GetName()).To(Equal("memcached-operator-controller-manager"))
+		Expect(c.Deployments[0].GetNamespace()).To(Equal("memcached-operator-system"))
+		Expect(c.Others).To(HaveLen(1))
+		Expect(c.Others[0].GetName()).To(Equal("memcached-operator-manager-config"))
+	})
+	It("fails with a hint if the directory has no kustomization", func() {
+		err := c.UpdateFromKustomize(tmp)
+		Expect(err).To(MatchError(ContainSubstring("the directory must contain a kustomization.yaml")))
+	})
+	It("fails with a hint if a resource does not exist", func() {
+		writeFile("kustomization.yaml", "resources:\n- manager.yaml\n")
+		err := c.UpdateFromKustomize(tmp)
+		Expect(err).To(MatchError(ContainSubstring("check that every resource, base, and patch the kustomization references exists")))
+	})
+})
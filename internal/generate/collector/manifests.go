@@ -38,6 +38,14 @@ import (
 	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
 )
 
+// Collector is a source of manifests, ex. files, a stream, or a live cluster.
+type Collector interface {
+	// Collect returns all manifests relevant to CSV updates from the source.
+	Collect() (*Manifests, error)
+}
+
+var _ Collector = &Manifests{}
+
 // Manifests holds a collector of all manifests relevant to CSV updates.
 type Manifests struct {
 	ClusterServiceVersions           []operatorsv1alpha1.ClusterServiceVersion
@@ -73,6 +81,20 @@ var (
 	v1alpha3ScorecardCfgGK = scorecardv1alpha3.GroupVersion.WithKind("Configuration").GroupKind()
 )
 
+// Collect returns c, which is the default Collector implementation.
+func (c *Manifests) Collect() (*Manifests, error) {
+	return c, nil
+}
+
+// skipVCSDir returns filepath.SkipDir for a directory of version control metadata, ex. ".git" in a
+// fetched git input, which holds no manifests, otherwise err.
+func skipVCSDir(info os.FileInfo, err error) error {
+	if err == nil && info.IsDir() && info.Name() == ".git" {
+		return filepath.SkipDir
+	}
+	return err
+}
+
 // UpdateFromDirs adds CustomResourceDefinitions found in crdsDir, and all other CSV-relevant manifests
 // from deployDir, to their respective fields in a Manifests, then filters and deduplicates them.
 // All other objects are added to Manifests.Others.
@@ -80,9 +102,10 @@ func (c *Manifests) UpdateFromDirs(deployDir, crdsDir string) error {
 	// Collect all manifests in paths.
 	err := filepath.Walk(deployDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil || info.IsDir() {
-			return err
+			return skipVCSDir(info, err)
 		}
 
+		log.Tracef("Reading manifests from %s", path)
 		b, err := ioutil.ReadFile(path)
 		if err != nil {
 			return err
@@ -90,14 +113,14 @@ func (c *Manifests) UpdateFromDirs(deployDir, crdsDir string) error {
 		return c.updateFromReader(bytes.NewBuffer(b))
 	})
 	if err != nil {
-		return fmt.Errorf("error collecting manifests from directory %s: %v", deployDir, err)
+		return fmt.Errorf("error collecting manifests from directory %s: %w", deployDir, err)
 	}
 
 	// Add CRDs from input.
 	if isDirExist(crdsDir) {
 		c.V1CustomResourceDefinitions, c.V1beta1CustomResourceDefinitions, err = k8sutil.GetCustomResourceDefinitions(crdsDir)
 		if err != nil {
-			return fmt.Errorf("error adding CustomResourceDefinitions to manifest collector: %v", err)
+			return fmt.Errorf("error adding CustomResourceDefinitions to manifest collector: %w", err)
 		}
 	}
 
@@ -122,9 +145,21 @@ func (c *Manifests) UpdateFromDir(dir string) error {
 // and Custom Resources found in r to their respective fields in a Manifests, then
 // filters and deduplicates them. All other objects are added to Manifests.Others.
 func (c *Manifests) UpdateFromReader(r io.Reader) error {
+	return c.UpdateFromReaders(r)
+}
+
+// UpdateFromReaders is like UpdateFromReader, but reads manifests from each of rs in order before
+// filtering and deduplicating them, like the files of a directory read by UpdateFromDirs. Objects
+// identical to one already read are removed, while objects that differ are all kept.
+func (c *Manifests) UpdateFromReaders(rs ...io.Reader) error {
 	// Bundle contents.
-	if err := c.updateFromReader(r); err != nil {
-		return err
+	for i, r := range rs {
+		if err := c.updateFromReader(r); err != nil {
+			if len(rs) > 1 {
+				return fmt.Errorf("error reading manifests from reader %d: %v", i, err)
+			}
+			return err
+		}
 	}
 
 	// Filter manifests based on data collected.
@@ -149,6 +184,7 @@ func (c *Manifests) updateFromReader(r io.Reader) error {
 		}
 
 		gvk := typeMeta.GroupVersionKind()
+		log.Tracef("Parsed %s manifest", gvk)
 		switch gvk.GroupKind() {
 		case csvGK:
 			err = c.addClusterServiceVersions(manifest)
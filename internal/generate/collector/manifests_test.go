@@ -0,0 +1,72 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateFromReaders", func() {
+	const (
+		manager = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: controller-manager\n"
+		role    = "apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: manager-role\n" +
+			"rules:\n- apiGroups: [\"\"]\n  resources: [configmaps]\n  verbs: [get]\n"
+		editedRole = "apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: manager-role\n" +
+			"rules:\n- apiGroups: [\"\"]\n  resources: [secrets]\n  verbs: [get]\n"
+	)
+	var c *Manifests
+	BeforeEach(func() {
+		c = &Manifests{}
+	})
+
+	It("collects the manifests of each reader in order, removing identical objects", func() {
+		Expect(c.UpdateFromReaders(
+			strings.NewReader(manager+"---\n"+role),
+			strings.NewReader(role+"---\n"+editedRole),
+		)).To(Succeed())
+		Expect(c.Deployments).To(HaveLen(1))
+		Expect(c.ClusterRoles).To(HaveLen(2))
+		Expect(c.ClusterRoles[0].Rules[0].Resources).To(Equal([]string{"configmaps"}))
+		Expect(c.ClusterRoles[1].Rules[0].Resources).To(Equal([]string{"secrets"}))
+	})
+	It("collects the same manifests as the files of a directory", func() {
+		dir, err := ioutil.TempDir("", "collector-readers-")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		Expect(ioutil.WriteFile(filepath.Join(dir, "1.yaml"), []byte(manager+"---\n"+role), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "2.yaml"), []byte(role+"---\n"+editedRole), 0644)).To(Succeed())
+		dirCol := &Manifests{}
+		Expect(dirCol.UpdateFromDir(dir)).To(Succeed())
+
+		Expect(c.UpdateFromReaders(
+			strings.NewReader(manager+"---\n"+role),
+			strings.NewReader(role+"---\n"+editedRole),
+		)).To(Succeed())
+		Expect(c).To(Equal(dirCol))
+	})
+	It("reports which reader a manifest could not be read from", func() {
+		err := c.UpdateFromReaders(
+			strings.NewReader(manager),
+			strings.NewReader("apiVersion: apps/v1\nkind: Deployment\nspec: []\n"),
+		)
+		Expect(err).To(MatchError(HavePrefix("error reading manifests from reader 1: ")))
+	})
+})
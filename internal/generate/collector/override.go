@@ -0,0 +1,193 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// Webhooks are collected from their configurations, and are keyed by their own name.
+	validatingWebhookGK = schema.GroupKind{Kind: "ValidatingWebhook"}
+	mutatingWebhookGK   = schema.GroupKind{Kind: "MutatingWebhook"}
+)
+
+// Override adds all objects in o to c, replacing objects in c that have the same group, kind,
+// namespace, and name as an object in o. Objects in o therefore take precedence over objects in c.
+// The replaced objects are returned as "<kind>.<group> <namespace>/<name>".
+func (c *Manifests) Override(o *Manifests) (overridden []string, err error) {
+	keys := make(map[string]struct{})
+	o.filterObjects(func(gk schema.GroupKind, namespace, name string, _ map[string]string) bool {
+		keys[objectKey(gk, namespace, name)] = struct{}{}
+		return true
+	})
+	c.filterObjects(func(gk schema.GroupKind, namespace, name string, _ map[string]string) bool {
+		key := objectKey(gk, namespace, name)
+		if _, isOverridden := keys[key]; isOverridden {
+			overridden = append(overridden, key)
+			return false
+		}
+		return true
+	})
+
+	c.ClusterServiceVersions = append(c.ClusterServiceVersions, o.ClusterServiceVersions...)
+	c.Roles = append(c.Roles, o.Roles...)
+	c.ClusterRoles = append(c.ClusterRoles, o.ClusterRoles...)
+	c.RoleBindings = append(c.RoleBindings, o.RoleBindings...)
+	c.ClusterRoleBindings = append(c.ClusterRoleBindings, o.ClusterRoleBindings...)
+	c.Deployments = append(c.Deployments, o.Deployments...)
+	c.ServiceAccounts = append(c.ServiceAccounts, o.ServiceAccounts...)
+	c.Services = append(c.Services, o.Services...)
+	c.V1CustomResourceDefinitions = append(c.V1CustomResourceDefinitions, o.V1CustomResourceDefinitions...)
+	c.V1beta1CustomResourceDefinitions = append(c.V1beta1CustomResourceDefinitions, o.V1beta1CustomResourceDefinitions...)
+	c.ValidatingWebhooks = append(c.ValidatingWebhooks, o.ValidatingWebhooks...)
+	c.MutatingWebhooks = append(c.MutatingWebhooks, o.MutatingWebhooks...)
+	c.Others = append(c.Others, o.Others...)
+	if name := o.ScorecardConfig.Metadata.Name; name != "" {
+		if c.ScorecardConfig.Metadata.Name != "" {
+			overridden = append(overridden, objectKey(v1alpha3ScorecardCfgGK, "", c.ScorecardConfig.Metadata.Name))
+		}
+		c.ScorecardConfig = o.ScorecardConfig
+	}
+
+	// Custom Resources are a subset of Others, so must be found again.
+	c.filter()
+
+	if err := c.deduplicate(); err != nil {
+		return nil, fmt.Errorf("error removing duplicate manifests: %v", err)
+	}
+
+	return overridden, nil
+}
+
+// objectKey returns a key unique to an object with gk, namespace, and name.
+func objectKey(gk schema.GroupKind, namespace, name string) string {
+	if namespace == "" {
+		return gk.String() + " " + name
+	}
+	return gk.String() + " " + namespace + "/" + name
+}
+
+// filterObjects removes all objects, except Custom Resources and the scorecard config,
+// for which keep returns false from c. Webhooks are collected without their configuration,
+// so have no namespace or labels.
+func (c *Manifests) filterObjects(keep func(gk schema.GroupKind, namespace, name string, labels map[string]string) bool) {
+	csvs := c.ClusterServiceVersions[:0]
+	for _, csv := range c.ClusterServiceVersions {
+		if keep(csvGK, csv.GetNamespace(), csv.GetName(), csv.GetLabels()) {
+			csvs = append(csvs, csv)
+		}
+	}
+	c.ClusterServiceVersions = csvs
+
+	roles := c.Roles[:0]
+	for _, role := range c.Roles {
+		if keep(roleGK, role.GetNamespace(), role.GetName(), role.GetLabels()) {
+			roles = append(roles, role)
+		}
+	}
+	c.Roles = roles
+
+	clusterRoles := c.ClusterRoles[:0]
+	for _, clusterRole := range c.ClusterRoles {
+		if keep(clusterRoleGK, "", clusterRole.GetName(), clusterRole.GetLabels()) {
+			clusterRoles = append(clusterRoles, clusterRole)
+		}
+	}
+	c.ClusterRoles = clusterRoles
+
+	roleBindings := c.RoleBindings[:0]
+	for _, roleBinding := range c.RoleBindings {
+		if keep(roleBindingGK, roleBinding.GetNamespace(), roleBinding.GetName(), roleBinding.GetLabels()) {
+			roleBindings = append(roleBindings, roleBinding)
+		}
+	}
+	c.RoleBindings = roleBindings
+
+	clusterRoleBindings := c.ClusterRoleBindings[:0]
+	for _, clusterRoleBinding := range c.ClusterRoleBindings {
+		if keep(clusterRoleBindingGK, "", clusterRoleBinding.GetName(), clusterRoleBinding.GetLabels()) {
+			clusterRoleBindings = append(clusterRoleBindings, clusterRoleBinding)
+		}
+	}
+	c.ClusterRoleBindings = clusterRoleBindings
+
+	deps := c.Deployments[:0]
+	for _, dep := range c.Deployments {
+		if keep(deploymentGK, dep.GetNamespace(), dep.GetName(), dep.GetLabels()) {
+			deps = append(deps, dep)
+		}
+	}
+	c.Deployments = deps
+
+	serviceAccounts := c.ServiceAccounts[:0]
+	for _, serviceAccount := range c.ServiceAccounts {
+		if keep(serviceAccountGK, serviceAccount.GetNamespace(), serviceAccount.GetName(), serviceAccount.GetLabels()) {
+			serviceAccounts = append(serviceAccounts, serviceAccount)
+		}
+	}
+	c.ServiceAccounts = serviceAccounts
+
+	services := c.Services[:0]
+	for _, service := range c.Services {
+		if keep(serviceGK, service.GetNamespace(), service.GetName(), service.GetLabels()) {
+			services = append(services, service)
+		}
+	}
+	c.Services = services
+
+	// v1 and v1beta1 CRDs share a group and kind, so either version can replace the other.
+	v1crds := c.V1CustomResourceDefinitions[:0]
+	for _, crd := range c.V1CustomResourceDefinitions {
+		if keep(crdGK, "", crd.GetName(), crd.GetLabels()) {
+			v1crds = append(v1crds, crd)
+		}
+	}
+	c.V1CustomResourceDefinitions = v1crds
+
+	v1beta1crds := c.V1beta1CustomResourceDefinitions[:0]
+	for _, crd := range c.V1beta1CustomResourceDefinitions {
+		if keep(crdGK, "", crd.GetName(), crd.GetLabels()) {
+			v1beta1crds = append(v1beta1crds, crd)
+		}
+	}
+	c.V1beta1CustomResourceDefinitions = v1beta1crds
+
+	validatingWebhooks := c.ValidatingWebhooks[:0]
+	for _, webhook := range c.ValidatingWebhooks {
+		if keep(validatingWebhookGK, "", webhook.Name, nil) {
+			validatingWebhooks = append(validatingWebhooks, webhook)
+		}
+	}
+	c.ValidatingWebhooks = validatingWebhooks
+
+	mutatingWebhooks := c.MutatingWebhooks[:0]
+	for _, webhook := range c.MutatingWebhooks {
+		if keep(mutatingWebhookGK, "", webhook.Name, nil) {
+			mutatingWebhooks = append(mutatingWebhooks, webhook)
+		}
+	}
+	c.MutatingWebhooks = mutatingWebhooks
+
+	others := c.Others[:0]
+	for _, other := range c.Others {
+		if keep(other.GroupVersionKind().GroupKind(), other.GetNamespace(), other.GetName(), other.GetLabels()) {
+			others = append(others, other)
+		}
+	}
+	c.Others = others
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Override", func() {
+	var c, o *Manifests
+
+	BeforeEach(func() {
+		c, o = &Manifests{}, &Manifests{}
+	})
+
+	It("adds all objects if none have the same kind and name", func() {
+		c.Roles = []rbacv1.Role{newRole("role-a")}
+		o.Roles = []rbacv1.Role{newRole("role-b")}
+		o.ClusterRoles = []rbacv1.ClusterRole{newClusterRole("role-c")}
+		overridden, err := c.Override(o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(overridden).To(BeEmpty())
+		Expect(c.Roles).To(Equal([]rbacv1.Role{newRole("role-a"), newRole("role-b")}))
+		Expect(c.ClusterRoles).To(Equal([]rbacv1.ClusterRole{newClusterRole("role-c")}))
+	})
+
+	It("replaces objects with the same kind, namespace, and name", func() {
+		depA, depB := newDeployment("ns", "dep"), newDeployment("ns", "dep")
+		depA.Spec.Template.Spec.ServiceAccountName = "a"
+		depB.Spec.Template.Spec.ServiceAccountName = "b"
+		c.Deployments = []appsv1.Deployment{depA, newDeployment("other-ns", "dep")}
+		o.Deployments = []appsv1.Deployment{depB}
+		overridden, err := c.Override(o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(overridden).To(Equal([]string{"Deployment.apps ns/dep"}))
+		Expect(c.Deployments).To(Equal([]appsv1.Deployment{newDeployment("other-ns", "dep"), depB}))
+	})
+
+	It("replaces other objects by group and kind", func() {
+		c.Others = []unstructured.Unstructured{newOther("v1", "ConfigMap", "cm", "a"), newOther("v1", "Secret", "cm", "a")}
+		o.Others = []unstructured.Unstructured{newOther("v1", "ConfigMap", "cm", "b")}
+		overridden, err := c.Override(o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(overridden).To(Equal([]string{"ConfigMap cm"}))
+		Expect(c.Others).To(Equal([]unstructured.Unstructured{
+			newOther("v1", "Secret", "cm", "a"),
+			newOther("v1", "ConfigMap", "cm", "b"),
+		}))
+	})
+})
+
+func newDeployment(namespace, name string) (d appsv1.Deployment) {
+	d.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	d.SetNamespace(namespace)
+	d.SetName(name)
+	return d
+}
+
+func newOther(apiVersion, kind, name, value string) (u unstructured.Unstructured) {
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetName(name)
+	u.Object["data"] = map[string]interface{}{"key": value}
+	return u
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// lastAppliedConfigAnnotation is the annotation 'kubectl apply' records an object's applied manifest in.
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// StripRuntimeMetadata removes metadata set by the API server or kubectl from all objects in c, ex. of
+// manifests exported from a live cluster: managedFields, resourceVersion, uid, generation,
+// creationTimestamp, and the last-applied-configuration annotation. Objects that had any of this
+// metadata are returned as "<kind>.<group> <namespace>/<name>".
+func (c *Manifests) StripRuntimeMetadata() (stripped []string) {
+	strip := func(gk schema.GroupKind, obj metav1.Object) {
+		if stripRuntimeMetadata(obj) {
+			stripped = append(stripped, objectKey(gk, obj.GetNamespace(), obj.GetName()))
+		}
+	}
+	for i := range c.ClusterServiceVersions {
+		strip(csvGK, &c.ClusterServiceVersions[i])
+	}
+	for i := range c.Roles {
+		strip(roleGK, &c.Roles[i])
+	}
+	for i := range c.ClusterRoles {
+		strip(clusterRoleGK, &c.ClusterRoles[i])
+	}
+	for i := range c.RoleBindings {
+		strip(roleBindingGK, &c.RoleBindings[i])
+	}
+	for i := range c.ClusterRoleBindings {
+		strip(clusterRoleBindingGK, &c.ClusterRoleBindings[i])
+	}
+	for i := range c.Deployments {
+		strip(deploymentGK, &c.Deployments[i])
+	}
+	for i := range c.ServiceAccounts {
+		strip(serviceAccountGK, &c.ServiceAccounts[i])
+	}
+	for i := range c.Services {
+		strip(serviceGK, &c.Services[i])
+	}
+	for i := range c.V1CustomResourceDefinitions {
+		strip(crdGK, &c.V1CustomResourceDefinitions[i])
+	}
+	for i := range c.V1beta1CustomResourceDefinitions {
+		strip(crdGK, &c.V1beta1CustomResourceDefinitions[i])
+	}
+	for i := range c.Others {
+		other := &c.Others[i]
+		strip(other.GroupVersionKind().GroupKind(), other)
+	}
+
+	// Custom Resources are a subset of Others, so must be found again.
+	c.filter()
+
+	return stripped
+}
+
+// stripRuntimeMetadata removes runtime metadata from obj, and returns true if obj had any.
+func stripRuntimeMetadata(obj metav1.Object) (hadMetadata bool) {
+	if len(obj.GetManagedFields()) != 0 {
+		obj.SetManagedFields(nil)
+		hadMetadata = true
+	}
+	if obj.GetResourceVersion() != "" {
+		obj.SetResourceVersion("")
+		hadMetadata = true
+	}
+	if obj.GetUID() != "" {
+		obj.SetUID("")
+		hadMetadata = true
+	}
+	if obj.GetGeneration() != 0 {
+		obj.SetGeneration(0)
+		hadMetadata = true
+	}
+	if ts := obj.GetCreationTimestamp(); !ts.IsZero() {
+		obj.SetCreationTimestamp(metav1.Time{})
+		hadMetadata = true
+	}
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		if _, hasLastApplied := annotations[lastAppliedConfigAnnotation]; hasLastApplied {
+			delete(annotations, lastAppliedConfigAnnotation)
+			if len(annotations) == 0 {
+				annotations = nil
+			}
+			obj.SetAnnotations(annotations)
+			hadMetadata = true
+		}
+	}
+	return hadMetadata
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("StripRuntimeMetadata", func() {
+	// Exported with 'kubectl get crd memcacheds.cache.example.com -o yaml'.
+	const manifests = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  annotations:
+    controller-gen.kubebuilder.io/version: v0.4.1
+    kubectl.kubernetes.io/last-applied-configuration: |
+      {"apiVersion":"apiextensions.k8s.io/v1","kind":"CustomResourceDefinition","metadata":{"name":"memcacheds.cache.example.com"}}
+  creationTimestamp: "2021-06-02T10:15:30Z"
+  generation: 2
+  labels:
+    app.kubernetes.io/name: memcached-operator
+  managedFields:
+  - apiVersion: apiextensions.k8s.io/v1
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:spec:
+        f:group: {}
+    manager: kubectl-client-side-apply
+    operation: Update
+    time: "2021-06-02T10:15:30Z"
+  name: memcacheds.cache.example.com
+  resourceVersion: "48213"
+  uid: 4b9d4a43-2d07-4a61-8f5b-7d4b4f0c2d3e
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    plural: memcacheds
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+---
+apiVersion: cache.example.com/v1alpha1
+kind: Memcached
+metadata:
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: |
+      {"apiVersion":"cache.example.com/v1alpha1","kind":"Memcached","metadata":{"name":"memcached-sample"}}
+  creationTimestamp: "2021-06-02T10:16:02Z"
+  generation: 1
+  name: memcached-sample
+  namespace: default
+  resourceVersion: "48290"
+  uid: 0c1f7e0e-5d2a-4c57-9f43-0b8f1b4a5d11
+spec:
+  size: 3
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: controller-manager
+`
+
+	var c *Manifests
+
+	BeforeEach(func() {
+		c = &Manifests{}
+		Expect(c.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+		Expect(c.CustomResources).To(HaveLen(1))
+	})
+
+	It("removes runtime metadata and keeps all other metadata", func() {
+		Expect(c.StripRuntimeMetadata()).To(Equal([]string{
+			"CustomResourceDefinition.apiextensions.k8s.io memcacheds.cache.example.com",
+			"Memcached.cache.example.com default/memcached-sample",
+		}))
+		Expect(c.V1CustomResourceDefinitions[0].ObjectMeta).To(Equal(metav1.ObjectMeta{
+			Name:        "memcacheds.cache.example.com",
+			Labels:      map[string]string{"app.kubernetes.io/name": "memcached-operator"},
+			Annotations: map[string]string{"controller-gen.kubebuilder.io/version": "v0.4.1"},
+		}))
+		Expect(c.V1CustomResourceDefinitions[0].Spec.Group).To(Equal("cache.example.com"))
+
+		Expect(c.CustomResources).To(HaveLen(1))
+		Expect(c.CustomResources[0].Object["metadata"]).To(Equal(map[string]interface{}{
+			"name":      "memcached-sample",
+			"namespace": "default",
+		}))
+		Expect(c.CustomResources[0].Object).To(HaveKey("spec"))
+	})
+	It("returns nothing if no runtime metadata is set", func() {
+		c.StripRuntimeMetadata()
+		Expect(c.StripRuntimeMetadata()).To(BeEmpty())
+	})
+})
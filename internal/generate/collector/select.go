@@ -0,0 +1,45 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Select removes all objects whose labels do not match selector from c, like 'kubectl get -l'.
+// ClusterServiceVersions, which are bases, and webhooks, which are collected without their
+// configuration's labels, are always kept. If includeCRDs is true, CustomResourceDefinitions
+// are kept regardless of their labels. The removed objects are returned as
+// "<kind>.<group> <namespace>/<name>".
+func (c *Manifests) Select(selector labels.Selector, includeCRDs bool) (removed []string) {
+	c.filterObjects(func(gk schema.GroupKind, namespace, name string, objLabels map[string]string) bool {
+		switch {
+		case gk == csvGK, gk == validatingWebhookGK, gk == mutatingWebhookGK:
+			return true
+		case gk == crdGK && includeCRDs:
+			return true
+		case selector.Matches(labels.Set(objLabels)):
+			return true
+		}
+		removed = append(removed, objectKey(gk, namespace, name))
+		return false
+	})
+
+	// Custom Resources are a subset of Others, so must be found again.
+	c.filter()
+
+	return removed
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var _ = Describe("Select", func() {
+	var (
+		c                  *Manifests
+		selector           labels.Selector
+		labeledDep         appsv1.Deployment
+		labeledRole        rbacv1.Role
+		labeledConfigMap   unstructured.Unstructured
+		labeledCRD         apiextv1.CustomResourceDefinition
+		unlabeledCRD       apiextv1.CustomResourceDefinition
+		unlabeledConfigMap unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		selector = labels.SelectorFromSet(labels.Set{"operator": "memcached"})
+		memcached := map[string]string{"operator": "memcached"}
+
+		labeledDep = newDeployment("ns", "memcached-manager")
+		labeledDep.SetLabels(memcached)
+		labeledRole = newRole("memcached-role")
+		labeledRole.SetLabels(map[string]string{"operator": "memcached", "tier": "rbac"})
+		labeledConfigMap = newOther("v1", "ConfigMap", "memcached-config", "a")
+		labeledConfigMap.SetLabels(memcached)
+		unlabeledConfigMap = newOther("v1", "ConfigMap", "shared-config", "b")
+		labeledCRD.SetName("memcacheds.cache.example.com")
+		labeledCRD.SetLabels(memcached)
+		unlabeledCRD.SetName("shareds.cache.example.com")
+
+		redisDep := newDeployment("ns", "redis-manager")
+		redisDep.SetLabels(map[string]string{"operator": "redis"})
+		c = &Manifests{
+			ClusterServiceVersions:      []operatorsv1alpha1.ClusterServiceVersion{{}},
+			Deployments:                 []appsv1.Deployment{labeledDep, redisDep},
+			Roles:                       []rbacv1.Role{labeledRole, newRole("shared-role")},
+			V1CustomResourceDefinitions: []apiextv1.CustomResourceDefinition{labeledCRD, unlabeledCRD},
+			ValidatingWebhooks:          []admissionregv1.ValidatingWebhook{{Name: "vmemcached.kb.io"}},
+			Others:                      []unstructured.Unstructured{labeledConfigMap, unlabeledConfigMap},
+		}
+	})
+
+	It("keeps only objects with matching labels", func() {
+		removed := c.Select(selector, false)
+		Expect(removed).To(Equal([]string{
+			"Role.rbac.authorization.k8s.io shared-role",
+			"Deployment.apps ns/redis-manager",
+			"CustomResourceDefinition.apiextensions.k8s.io shareds.cache.example.com",
+			"ConfigMap shared-config",
+		}))
+		Expect(c.Deployments).To(Equal([]appsv1.Deployment{labeledDep}))
+		Expect(c.Roles).To(Equal([]rbacv1.Role{labeledRole}))
+		Expect(c.V1CustomResourceDefinitions).To(Equal([]apiextv1.CustomResourceDefinition{labeledCRD}))
+		Expect(c.Others).To(Equal([]unstructured.Unstructured{labeledConfigMap}))
+	})
+	It("keeps CustomResourceDefinitions without matching labels if configured", func() {
+		removed := c.Select(selector, true)
+		Expect(removed).NotTo(ContainElement(ContainSubstring("CustomResourceDefinition")))
+		Expect(c.V1CustomResourceDefinitions).To(Equal([]apiextv1.CustomResourceDefinition{labeledCRD, unlabeledCRD}))
+	})
+	It("keeps ClusterServiceVersions and webhooks regardless of labels", func() {
+		c.Select(selector, false)
+		Expect(c.ClusterServiceVersions).To(HaveLen(1))
+		Expect(c.ValidatingWebhooks).To(HaveLen(1))
+	})
+})
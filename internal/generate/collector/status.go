@@ -0,0 +1,88 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"reflect"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// StripStatus removes the status of all objects in c, ex. of manifests exported from a live cluster,
+// since runtime status should not be packaged. Objects that had a non-empty status are returned as
+// "<kind>.<group> <namespace>/<name>".
+func (c *Manifests) StripStatus() (stripped []string) {
+	for i := range c.ClusterServiceVersions {
+		csv := &c.ClusterServiceVersions[i]
+		if !reflect.DeepEqual(csv.Status, operatorsv1alpha1.ClusterServiceVersionStatus{}) {
+			csv.Status = operatorsv1alpha1.ClusterServiceVersionStatus{}
+			stripped = append(stripped, objectKey(csvGK, csv.GetNamespace(), csv.GetName()))
+		}
+	}
+	for i := range c.Deployments {
+		dep := &c.Deployments[i]
+		if !reflect.DeepEqual(dep.Status, appsv1.DeploymentStatus{}) {
+			dep.Status = appsv1.DeploymentStatus{}
+			stripped = append(stripped, objectKey(deploymentGK, dep.GetNamespace(), dep.GetName()))
+		}
+	}
+	for i := range c.Services {
+		service := &c.Services[i]
+		if !reflect.DeepEqual(service.Status, corev1.ServiceStatus{}) {
+			service.Status = corev1.ServiceStatus{}
+			stripped = append(stripped, objectKey(serviceGK, service.GetNamespace(), service.GetName()))
+		}
+	}
+	for i := range c.V1CustomResourceDefinitions {
+		crd := &c.V1CustomResourceDefinitions[i]
+		if status := crd.Status; len(status.Conditions) != 0 || len(status.StoredVersions) != 0 ||
+			status.AcceptedNames.Kind != "" || status.AcceptedNames.Plural != "" {
+			// Empty lists match the status controller-gen writes.
+			crd.Status = apiextv1.CustomResourceDefinitionStatus{
+				Conditions:     []apiextv1.CustomResourceDefinitionCondition{},
+				StoredVersions: []string{},
+			}
+			stripped = append(stripped, objectKey(crdGK, "", crd.GetName()))
+		}
+	}
+	for i := range c.V1beta1CustomResourceDefinitions {
+		crd := &c.V1beta1CustomResourceDefinitions[i]
+		if status := crd.Status; len(status.Conditions) != 0 || len(status.StoredVersions) != 0 ||
+			status.AcceptedNames.Kind != "" || status.AcceptedNames.Plural != "" {
+			crd.Status = apiextv1beta1.CustomResourceDefinitionStatus{
+				Conditions:     []apiextv1beta1.CustomResourceDefinitionCondition{},
+				StoredVersions: []string{},
+			}
+			stripped = append(stripped, objectKey(crdGK, "", crd.GetName()))
+		}
+	}
+	for i := range c.Others {
+		other := &c.Others[i]
+		if status, hasStatus := other.Object["status"].(map[string]interface{}); hasStatus && len(status) != 0 {
+			unstructured.RemoveNestedField(other.Object, "status")
+			stripped = append(stripped, objectKey(other.GroupVersionKind().GroupKind(), other.GetNamespace(), other.GetName()))
+		}
+	}
+
+	// Custom Resources are a subset of Others, so must be found again.
+	c.filter()
+
+	return stripped
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+var _ = Describe("StripStatus", func() {
+	const manifests = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    plural: memcacheds
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+status:
+  acceptedNames:
+    kind: Memcached
+    plural: memcacheds
+  conditions:
+  - type: Established
+    status: "True"
+  storedVersions:
+  - v1alpha1
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: caches.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Cache
+    plural: caches
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+status:
+  acceptedNames:
+    kind: ""
+    plural: ""
+  conditions: []
+  storedVersions: []
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller-manager
+  namespace: memcached-operator-system
+status:
+  replicas: 1
+  readyReplicas: 1
+---
+apiVersion: cache.example.com/v1alpha1
+kind: Memcached
+metadata:
+  name: memcached-sample
+spec:
+  size: 3
+status:
+  nodes:
+  - memcached-sample-0
+`
+
+	var c *Manifests
+
+	BeforeEach(func() {
+		c = &Manifests{}
+		Expect(c.UpdateFromReader(strings.NewReader(manifests))).To(Succeed())
+		Expect(c.CustomResources).To(HaveLen(1))
+	})
+
+	It("removes non-empty statuses", func() {
+		Expect(c.StripStatus()).To(Equal([]string{
+			"Deployment.apps memcached-operator-system/controller-manager",
+			"CustomResourceDefinition.apiextensions.k8s.io memcacheds.cache.example.com",
+			"Memcached.cache.example.com memcached-sample",
+		}))
+		Expect(c.Deployments[0].Status).To(Equal(appsv1.DeploymentStatus{}))
+		for _, crd := range c.V1CustomResourceDefinitions {
+			Expect(crd.Status).To(Equal(apiextv1.CustomResourceDefinitionStatus{
+				Conditions:     []apiextv1.CustomResourceDefinitionCondition{},
+				StoredVersions: []string{},
+			}))
+		}
+		Expect(c.Others[0].Object).NotTo(HaveKey("status"))
+		Expect(c.CustomResources).To(HaveLen(1))
+		Expect(c.CustomResources[0].Object).NotTo(HaveKey("status"))
+		Expect(c.CustomResources[0].Object).To(HaveKey("spec"))
+	})
+	It("returns nothing if no status is set", func() {
+		c.StripStatus()
+		Expect(c.StripStatus()).To(BeEmpty())
+	})
+})
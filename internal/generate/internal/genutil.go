@@ -24,7 +24,9 @@ import (
 
 	"sigs.k8s.io/yaml"
 
+	"github.com/operator-framework/operator-sdk/internal/util/fileutil"
 	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
 )
 
 // InternalError wraps errors that are development issues and unrelated to user
@@ -47,17 +49,26 @@ type File struct {
 }
 
 // Open first creates dir then opens <dir>/<fileName> for reading and writing,
-// creating the file if it does not exist.
-func Open(dir, fileName string) (*File, error) {
-	if err := os.MkdirAll(dir, 0755); err != nil {
+// creating the file if it does not exist. Non-zero dirMode and fileMode are set as
+// the modes of dir and the file regardless of umask.
+func Open(dir, fileName string, dirMode, fileMode os.FileMode) (*File, error) {
+	if err := fileutil.MkdirAll(dir, dirMode); err != nil {
 		return nil, err
 	}
-	f, err := os.OpenFile(filepath.Join(dir, fileName), os.O_RDWR|os.O_CREATE, 0666)
-	return &File{f}, err
+	f, err := fileutil.OpenFile(filepath.Join(dir, fileName), os.O_RDWR|os.O_CREATE, fileMode)
+	if err != nil {
+		return nil, err
+	}
+	return &File{f}, nil
 }
 
 // WriteObject writes a k8s object to w.
 func WriteObject(w io.Writer, obj interface{}) error {
+	return WriteObjectFormatted(w, obj, yamlutil.Options{})
+}
+
+// WriteObjectFormatted writes a k8s object to w, formatted with opts.
+func WriteObjectFormatted(w io.Writer, obj interface{}, opts yamlutil.Options) error {
 	b, err := k8sutil.GetObjectBytes(obj, yaml.Marshal)
 	if err != nil {
 		return err
@@ -67,15 +78,32 @@ func WriteObject(w io.Writer, obj interface{}) error {
 	const cleanup = "cleanup:\n    enabled: false\n  "
 	b = bytes.ReplaceAll(b, []byte(cleanup), []byte(""))
 
+	if b, err = yamlutil.Format(b, opts); err != nil {
+		return err
+	}
 	return write(w, b)
 }
 
 // WriteObject writes any object to w.
 func WriteYAML(w io.Writer, obj interface{}) error {
+	return WriteYAMLFormatted(w, obj, yamlutil.Options{})
+}
+
+// WriteYAMLFormatted writes any object to w, formatted with opts.
+func WriteYAMLFormatted(w io.Writer, obj interface{}, opts yamlutil.Options) error {
 	b, err := yaml.Marshal(obj)
 	if err != nil {
 		return err
 	}
+	if b, err = yamlutil.Format(b, opts); err != nil {
+		return err
+	}
+	return write(w, b)
+}
+
+// WriteBytes writes b to w. If w is a File, its contents will be cleared and w
+// will be closed following the write.
+func WriteBytes(w io.Writer, b []byte) error {
 	return write(w, b)
 }
 
@@ -15,18 +15,24 @@
 package packagemanifest
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"text/template"
 
+	"github.com/blang/semver/v4"
 	apimanifests "github.com/operator-framework/api/pkg/manifests"
 	"github.com/operator-framework/api/pkg/validation"
 	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/yaml"
 
 	genutil "github.com/operator-framework/operator-sdk/internal/generate/internal"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
 )
 
 const (
@@ -81,6 +87,112 @@ type Options struct {
 	// generated PackageManifest. If true, ChannelName will be the PackageManifest's default channel.
 	// Setting this field is only necessary when more than one channel exists.
 	IsDefaultChannel bool
+	// TemplatePath is the path to a Go template used to render the PackageManifest file.
+	// The template is executed with a TemplateData. If not set, the PackageManifest
+	// is marshalled to YAML as-is.
+	TemplatePath string
+	// DirMode and FileMode are the modes of the output directory and PackageManifest file.
+	// Zero modes use the defaults.
+	DirMode, FileMode os.FileMode
+	// Format configures how the PackageManifest's YAML is formatted. It is not applied
+	// to files rendered from TemplatePath.
+	Format yamlutil.Options
+	// ChannelConfig, if set, declares all of the PackageManifest's channels and its default channel,
+	// replacing those of the base. ChannelName and IsDefaultChannel must not be set with it.
+	ChannelConfig *ChannelConfig
+	// NewVersions are versions, other than the generated version, whose directories will be written
+	// to the output directory after the PackageManifest, so ChannelConfig heads may reference them.
+	NewVersions []string
+}
+
+// ChannelConfig declares a package's channels, ex. in a channels.yaml file:
+//
+//	defaultChannel: stable
+//	channels:
+//	- name: alpha
+//	  head: 0.2.0
+//	- name: stable
+//	  head: 0.1.0
+type ChannelConfig struct {
+	// DefaultChannel is the package's default channel. It can be omitted if only one channel is declared.
+	DefaultChannel string `json:"defaultChannel,omitempty"`
+	// Channels are the package's channels.
+	Channels []ChannelHead `json:"channels"`
+}
+
+// ChannelHead is a channel and the version of its head, i.e. current, CSV.
+type ChannelHead struct {
+	Name string `json:"name"`
+	Head string `json:"head"`
+}
+
+// ReadChannelConfig returns the ChannelConfig at path. Unknown fields are an error, since
+// a misspelled field would otherwise silently leave a channel without a head.
+func ReadChannelConfig(path string) (*ChannelConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading channel config: %v", err)
+	}
+	cfg := &ChannelConfig{}
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshalling channel config %s: %v", path, err)
+	}
+	if len(cfg.Channels) == 0 {
+		return nil, fmt.Errorf("channel config %s declares no channels", path)
+	}
+	return cfg, nil
+}
+
+// validate returns an error if a channel does not have exactly one head, the default channel is
+// not declared, or a head's version directory is not in outputDir and is not in versions.
+func (cfg ChannelConfig) validate(outputDir string, versions ...string) error {
+	generated := make(map[string]struct{}, len(versions))
+	for _, version := range versions {
+		generated[version] = struct{}{}
+	}
+	heads := make(map[string]string, len(cfg.Channels))
+	for _, channel := range cfg.Channels {
+		if channel.Name == "" {
+			return errors.New("channel config declares a channel with no name")
+		}
+		if head, isDup := heads[channel.Name]; isDup {
+			return fmt.Errorf("channel %q has more than one head: %s and %s", channel.Name, head, channel.Head)
+		}
+		if channel.Head == "" {
+			return fmt.Errorf("channel %q has no head", channel.Name)
+		}
+		heads[channel.Name] = channel.Head
+		if _, isGenerated := generated[channel.Head]; isGenerated {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(outputDir, channel.Head)); err != nil || !info.IsDir() {
+			return fmt.Errorf("head %s of channel %q has no version directory in %s", channel.Head, channel.Name, outputDir)
+		}
+	}
+	if cfg.DefaultChannel == "" {
+		if len(cfg.Channels) != 1 {
+			return errors.New("channel config must set defaultChannel if more than one channel is declared")
+		}
+	} else if _, hasDefault := heads[cfg.DefaultChannel]; !hasDefault {
+		return fmt.Errorf("default channel %q is not declared in channel config", cfg.DefaultChannel)
+	}
+	return nil
+}
+
+// apply replaces pkg's channels and default channel with those of cfg.
+func (cfg ChannelConfig) apply(pkg *apimanifests.PackageManifest, operatorName string) {
+	pkg.Channels = make([]apimanifests.PackageChannel, 0, len(cfg.Channels))
+	for _, channel := range cfg.Channels {
+		pkg.Channels = append(pkg.Channels, apimanifests.PackageChannel{
+			Name:           channel.Name,
+			CurrentCSVName: genutil.MakeCSVName(operatorName, channel.Head),
+		})
+	}
+	sortChannelsByName(pkg)
+	pkg.DefaultChannelName = cfg.DefaultChannel
+	if pkg.DefaultChannelName == "" {
+		pkg.DefaultChannelName = cfg.Channels[0].Name
+	}
 }
 
 // Generate configures the Generator with opts then runs it.
@@ -94,18 +206,44 @@ func (g generator) Generate(operatorName, version, outputDir string, opts Option
 	if outputDir == "" {
 		return ErrNoOutputDir
 	}
+	if opts.ChannelConfig != nil {
+		if opts.ChannelName != "" || opts.IsDefaultChannel {
+			return genutil.InternalError("channel name and default channel cannot be set with a channel config")
+		}
+		if err := opts.ChannelConfig.validate(outputDir, append([]string{version}, opts.NewVersions...)...); err != nil {
+			return err
+		}
+	}
+
+	var tmpl *template.Template
+	if opts.TemplatePath != "" {
+		var err error
+		if tmpl, err = ParseTemplate(opts.TemplatePath); err != nil {
+			return err
+		}
+	}
 
 	pkg, err := g.generate(operatorName, version, opts)
 	if err != nil {
 		return err
 	}
 
-	outputWriter, err := genutil.Open(outputDir, makePkgManFileName(operatorName))
+	var b []byte
+	if tmpl != nil {
+		if b, err = renderTemplate(tmpl, pkg); err != nil {
+			return err
+		}
+	}
+
+	outputWriter, err := genutil.Open(outputDir, makePkgManFileName(operatorName), opts.DirMode, opts.FileMode)
 	if err != nil {
 		return err
 	}
 
-	return genutil.WriteYAML(outputWriter, pkg)
+	if tmpl != nil {
+		return genutil.WriteBytes(outputWriter, b)
+	}
+	return genutil.WriteYAMLFormatted(outputWriter, pkg, opts.Format)
 }
 
 // generate takes the input and generates the populated package manifest object
@@ -126,15 +264,23 @@ func (g generator) generate(operatorName, version string, opts Options) (*apiman
 	}
 
 	csvName := genutil.MakeCSVName(operatorName, version)
-	if opts.ChannelName != "" {
+	if opts.ChannelConfig != nil {
+		log.Debugf("Setting channels from channel config")
+		opts.ChannelConfig.apply(base, operatorName)
+	} else if opts.ChannelName != "" {
+		log.Debugf("Setting channel %q current CSV to %q", opts.ChannelName, csvName)
 		setChannels(base, opts.ChannelName, csvName)
 		sortChannelsByName(base)
 		if opts.IsDefaultChannel || len(base.Channels) == 1 {
+			log.Debugf("Setting default channel to %q", opts.ChannelName)
 			base.DefaultChannelName = opts.ChannelName
 		}
 	} else if len(base.Channels) == 0 {
+		log.Debugf("No channel set and base has no channels, adding default channel %q", "alpha")
 		setChannels(base, "alpha", csvName)
 		base.DefaultChannelName = "alpha"
+	} else {
+		log.Debugf("No channel set, leaving base channels unchanged")
 	}
 
 	if err = validatePackageManifest(base); err != nil {
@@ -144,6 +290,56 @@ func (g generator) generate(operatorName, version string, opts Options) (*apiman
 	return base, nil
 }
 
+// TemplateData is the data a PackageManifest template is executed with.
+type TemplateData struct {
+	// PackageName is the package's name.
+	PackageName string
+	// Channels are the package's channels sorted by name. Each channel has a Name and CurrentCSVName.
+	Channels []apimanifests.PackageChannel
+	// DefaultChannel is the name of the package's default channel.
+	DefaultChannel string
+}
+
+// ParseTemplate parses the PackageManifest template at path.
+func ParseTemplate(path string) (*template.Template, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading PackageManifest template: %v", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing PackageManifest template %s: %v", path, err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate executes tmpl with pkg's data, and returns an error if the result
+// is not a valid PackageManifest with the same package name and default channel as pkg.
+func renderTemplate(tmpl *template.Template, pkg *apimanifests.PackageManifest) ([]byte, error) {
+	data := TemplateData{
+		PackageName:    pkg.PackageName,
+		Channels:       pkg.Channels,
+		DefaultChannel: pkg.DefaultChannelName,
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("error executing PackageManifest template: %v", err)
+	}
+
+	rendered := &apimanifests.PackageManifest{}
+	if err := yaml.Unmarshal(buf.Bytes(), rendered); err != nil {
+		return nil, fmt.Errorf("PackageManifest template rendered invalid YAML: %v", err)
+	}
+	if rendered.PackageName != pkg.PackageName || rendered.DefaultChannelName != pkg.DefaultChannelName {
+		return nil, fmt.Errorf("PackageManifest template rendered package %q with default channel %q, expected %q and %q",
+			rendered.PackageName, rendered.DefaultChannelName, pkg.PackageName, pkg.DefaultChannelName)
+	}
+	if err := validatePackageManifest(rendered); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // makePkgManFileName will return the file name of a PackageManifest.
 func makePkgManFileName(operatorName string) string {
 	return operatorName + packageManifestFileExt
@@ -184,12 +380,20 @@ func validatePackageManifest(pkg *apimanifests.PackageManifest) error {
 	return nil
 }
 
-// setChannels checks for duplicate channels in pkg and sets the default channel if possible.
+// setChannels sets channelName's current CSV to csvName, adding the channel to pkg if it does not exist.
+// A channel's head only moves forward: if the current CSV's version has a higher semver precedence
+// than csvName's, it is kept. Precedence follows semver, so a prerelease such as 0.3.0-rc.1 sorts
+// after 0.2.0 and before 0.3.0, and a 0.3.0-rc.1 head is replaced by 0.3.0.
 func setChannels(pkg *apimanifests.PackageManifest, channelName, csvName string) {
 	channelIdx := -1
 	for i, channel := range pkg.Channels {
 		if channel.Name == channelName {
-			pkg.Channels[i].CurrentCSVName = csvName
+			if isHigherCSV(pkg.PackageName, channel.CurrentCSVName, csvName) {
+				log.Infof("Channel %q current CSV %q has a higher version than %q, not replacing it",
+					channelName, channel.CurrentCSVName, csvName)
+			} else {
+				pkg.Channels[i].CurrentCSVName = csvName
+			}
 			channelIdx = i
 			break
 		}
@@ -202,6 +406,24 @@ func setChannels(pkg *apimanifests.PackageManifest, channelName, csvName string)
 	}
 }
 
+// isHigherCSV returns true if CSV name a's version has a higher semver precedence than b's.
+// Names that are not of the form "<packageName>.v<version>" are never higher.
+func isHigherCSV(packageName, a, b string) bool {
+	prefix := packageName + ".v"
+	if !strings.HasPrefix(a, prefix) || !strings.HasPrefix(b, prefix) {
+		return false
+	}
+	va, err := semver.Parse(strings.TrimPrefix(a, prefix))
+	if err != nil {
+		return false
+	}
+	vb, err := semver.Parse(strings.TrimPrefix(b, prefix))
+	if err != nil {
+		return false
+	}
+	return va.GT(vb)
+}
+
 // GetBase returns a base PackageManifest, populated either with default
 // values or, if b.BasePath is set, bytes from disk.
 func (b PackageManifest) GetBase() (base *apimanifests.PackageManifest, err error) {
@@ -22,6 +22,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/operator-framework/operator-sdk/internal/generate/packagemanifest"
+	"github.com/operator-framework/operator-sdk/internal/util/yamlutil"
 )
 
 var _ = Describe("A package manifest generator", func() {
@@ -105,6 +106,25 @@ packageName: memcached-operator
 				Expect(string(file)).To(Equal(pkgManOneChannel))
 			})
 		})
+		Context("when writing a formatted package manifest", func() {
+			It("writes a package manifest with the given indentation", func() {
+				opts := Options{
+					ChannelName: "stable",
+					Format:      yamlutil.Options{Indent: 4},
+				}
+
+				err := g.Generate(operatorName, "0.0.1", outputDir, opts)
+				Expect(err).NotTo(HaveOccurred())
+				file, err := ioutil.ReadFile(outputDir + string(os.PathSeparator) + pkgManFilename)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(file)).To(Equal(`channels:
+    - currentCSV: memcached-operator.v0.0.1
+      name: stable
+defaultChannel: stable
+packageName: memcached-operator
+`))
+			})
+		})
 		Context("when updating an existing package manifest", func() {
 			It("creates a new package manifest if provided an existing packagemanifest that doesn't exist", func() {
 				opts := Options{
@@ -156,6 +176,207 @@ packageName: memcached-operator
 				Expect(string(file)).To(Equal(pkgManUpdatedSecondChannelNewDefault))
 			})
 		})
+		Context("when ordering a channel's head by version", func() {
+			var pkgDir string
+			BeforeEach(func() {
+				var err error
+				pkgDir, err = ioutil.TempDir("", "pkgman-heads-")
+				Expect(err).NotTo(HaveOccurred())
+			})
+			AfterEach(func() {
+				Expect(os.RemoveAll(pkgDir)).To(Succeed())
+			})
+			// generateHead generates version into the alpha channel, updating the previously generated
+			// package manifest, and returns the channel's head.
+			generateHead := func(version string) string {
+				opts := Options{BaseDir: pkgDir, ChannelName: "alpha"}
+				Expect(g.Generate(operatorName, version, pkgDir, opts)).To(Succeed())
+				pkg, err := (PackageManifest{BasePath: filepath.Join(pkgDir, pkgManFilename)}).GetBase()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pkg.Channels).To(HaveLen(1))
+				return pkg.Channels[0].CurrentCSVName
+			}
+
+			It("replaces a release head with a higher prerelease", func() {
+				Expect(generateHead("0.2.0")).To(Equal("memcached-operator.v0.2.0"))
+				Expect(generateHead("0.3.0-rc.1")).To(Equal("memcached-operator.v0.3.0-rc.1"))
+				Expect(generateHead("0.3.0-rc.2")).To(Equal("memcached-operator.v0.3.0-rc.2"))
+			})
+			It("replaces a prerelease head with its release", func() {
+				Expect(generateHead("0.3.0-rc.1")).To(Equal("memcached-operator.v0.3.0-rc.1"))
+				Expect(generateHead("0.3.0")).To(Equal("memcached-operator.v0.3.0"))
+			})
+			It("keeps a head with a higher version", func() {
+				Expect(generateHead("0.3.0")).To(Equal("memcached-operator.v0.3.0"))
+				Expect(generateHead("0.3.0-rc.1")).To(Equal("memcached-operator.v0.3.0"))
+				Expect(generateHead("0.2.1")).To(Equal("memcached-operator.v0.3.0"))
+				Expect(generateHead("0.3.0-rc.2")).To(Equal("memcached-operator.v0.3.0"))
+			})
+			It("orders prerelease identifiers numerically", func() {
+				Expect(generateHead("0.3.0-rc.10")).To(Equal("memcached-operator.v0.3.0-rc.10"))
+				Expect(generateHead("0.3.0-rc.9")).To(Equal("memcached-operator.v0.3.0-rc.10"))
+			})
+		})
+		Context("when rendering a package manifest template", func() {
+			var tmplDir string
+			BeforeEach(func() {
+				var err error
+				tmplDir, err = ioutil.TempDir("", "pkgman-template-")
+				Expect(err).NotTo(HaveOccurred())
+			})
+			AfterEach(func() {
+				Expect(os.RemoveAll(tmplDir)).To(Succeed())
+			})
+			writeTemplate := func(tmpl string) string {
+				path := filepath.Join(tmplDir, "package.yaml.tmpl")
+				Expect(ioutil.WriteFile(path, []byte(tmpl), 0644)).To(Succeed())
+				return path
+			}
+
+			It("writes a package manifest rendered by the template", func() {
+				opts := Options{
+					ChannelName: "stable",
+					TemplatePath: writeTemplate(`# Generated for {{ .PackageName }}
+packageName: {{ .PackageName }}
+defaultChannel: {{ .DefaultChannel }}
+channels:
+{{- range .Channels }}
+- name: {{ .Name }}
+  currentCSV: {{ .CurrentCSVName }}
+{{- end }}
+`),
+				}
+
+				err := g.Generate(operatorName, "0.0.1", outputDir, opts)
+				Expect(err).NotTo(HaveOccurred())
+				file, err := ioutil.ReadFile(filepath.Join(outputDir, pkgManFilename))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(file)).To(Equal(`# Generated for memcached-operator
+packageName: memcached-operator
+defaultChannel: stable
+channels:
+- name: stable
+  currentCSV: memcached-operator.v0.0.1
+`))
+			})
+			It("fails if the template cannot be parsed", func() {
+				opts := Options{TemplatePath: writeTemplate("packageName: {{ .PackageName ")}
+				err := g.Generate(operatorName, "0.0.1", outputDir, opts)
+				Expect(err).To(MatchError(ContainSubstring("error parsing PackageManifest template")))
+			})
+			It("fails if the template renders invalid YAML", func() {
+				opts := Options{TemplatePath: writeTemplate("packageName: [{{ .PackageName }}\n")}
+				err := g.Generate(operatorName, "0.0.1", outputDir, opts)
+				Expect(err).To(MatchError(ContainSubstring("rendered invalid YAML")))
+			})
+			It("fails if the template renders a different package", func() {
+				opts := Options{TemplatePath: writeTemplate("packageName: foo\ndefaultChannel: {{ .DefaultChannel }}\n")}
+				err := g.Generate(operatorName, "0.0.1", outputDir, opts)
+				Expect(err).To(MatchError(ContainSubstring(`rendered package "foo"`)))
+			})
+		})
+		Context("when setting channels from a channel config", func() {
+			var pkgDir string
+			BeforeEach(func() {
+				var err error
+				pkgDir, err = ioutil.TempDir("", "pkgman-channels-")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.Mkdir(filepath.Join(pkgDir, "0.0.1"), 0755)).To(Succeed())
+			})
+			AfterEach(func() {
+				Expect(os.RemoveAll(pkgDir)).To(Succeed())
+			})
+			writeConfig := func(cfg string) *ChannelConfig {
+				path := filepath.Join(pkgDir, "channels.yaml")
+				Expect(ioutil.WriteFile(path, []byte(cfg), 0644)).To(Succeed())
+				c, err := ReadChannelConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				return c
+			}
+
+			It("replaces the base's channels with the configured channels", func() {
+				opts := Options{
+					BaseDir: testDataDir,
+					ChannelConfig: writeConfig(`defaultChannel: stable
+channels:
+- name: stable
+  head: 0.0.1
+- name: alpha
+  head: 0.0.2
+`),
+				}
+
+				err := g.Generate(operatorName, "0.0.2", pkgDir, opts)
+				Expect(err).NotTo(HaveOccurred())
+				file, err := ioutil.ReadFile(filepath.Join(pkgDir, pkgManFilename))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(file)).To(Equal(`channels:
+- currentCSV: memcached-operator.v0.0.2
+  name: alpha
+- currentCSV: memcached-operator.v0.0.1
+  name: stable
+defaultChannel: stable
+packageName: memcached-operator
+`))
+			})
+			It("defaults to the only configured channel", func() {
+				opts := Options{ChannelConfig: writeConfig("channels:\n- name: stable\n  head: 0.0.1\n")}
+				err := g.Generate(operatorName, "0.0.1", pkgDir, opts)
+				Expect(err).NotTo(HaveOccurred())
+				file, err := ioutil.ReadFile(filepath.Join(pkgDir, pkgManFilename))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(file)).To(Equal(pkgManOneChannel))
+			})
+			It("allows heads of new versions that are not yet written", func() {
+				opts := Options{
+					ChannelConfig: writeConfig("channels:\n- name: alpha\n  head: 0.0.3\n"),
+					NewVersions:   []string{"0.0.3"},
+				}
+				Expect(g.Generate(operatorName, "0.0.2", pkgDir, opts)).To(Succeed())
+			})
+			It("fails if a head has no version directory", func() {
+				opts := Options{ChannelConfig: writeConfig("channels:\n- name: alpha\n  head: 0.0.3\n")}
+				err := g.Generate(operatorName, "0.0.2", pkgDir, opts)
+				Expect(err).To(MatchError(ContainSubstring(`head 0.0.3 of channel "alpha" has no version directory`)))
+			})
+			It("fails if a channel has more than one head", func() {
+				opts := Options{ChannelConfig: writeConfig(`defaultChannel: alpha
+channels:
+- name: alpha
+  head: 0.0.1
+- name: alpha
+  head: 0.0.2
+`)}
+				err := g.Generate(operatorName, "0.0.2", pkgDir, opts)
+				Expect(err).To(MatchError(`channel "alpha" has more than one head: 0.0.1 and 0.0.2`))
+			})
+			It("fails if a channel has no head", func() {
+				opts := Options{ChannelConfig: writeConfig("channels:\n- name: alpha\n")}
+				err := g.Generate(operatorName, "0.0.1", pkgDir, opts)
+				Expect(err).To(MatchError(`channel "alpha" has no head`))
+			})
+			It("fails if the default channel is not declared", func() {
+				opts := Options{ChannelConfig: writeConfig("defaultChannel: stable\nchannels:\n- name: alpha\n  head: 0.0.1\n")}
+				err := g.Generate(operatorName, "0.0.1", pkgDir, opts)
+				Expect(err).To(MatchError(`default channel "stable" is not declared in channel config`))
+			})
+			It("fails if more than one channel is declared without a default channel", func() {
+				opts := Options{ChannelConfig: writeConfig(`channels:
+- name: alpha
+  head: 0.0.1
+- name: stable
+  head: 0.0.1
+`)}
+				err := g.Generate(operatorName, "0.0.1", pkgDir, opts)
+				Expect(err).To(MatchError(ContainSubstring("must set defaultChannel")))
+			})
+			It("fails to read a config with unknown fields", func() {
+				path := filepath.Join(pkgDir, "channels.yaml")
+				Expect(ioutil.WriteFile(path, []byte("channels:\n- name: alpha\n  currentCSV: 0.0.1\n"), 0644)).To(Succeed())
+				_, err := ReadChannelConfig(path)
+				Expect(err).To(MatchError(ContainSubstring("error unmarshalling channel config")))
+			})
+		})
 		Context("when incorrect params are provided", func() {
 			It("fails if no operator name is specified", func() {
 				err := g.Generate("", "", "", blankOpts)
@@ -0,0 +1,363 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	apimanifests "github.com/operator-framework/api/pkg/manifests"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// CheckVersionDirs returns a description of each CSV in dir's version directories whose name is also
+// used by a CSV in another version directory, or whose version, less build metadata, does not match
+// the name of its directory. Problems are described by CSV path relative to dir.
+func CheckVersionDirs(dir string) (problems []string, err error) {
+	versionDirs, err := VersionDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// CSV name to the paths of all CSVs with that name.
+	pathsByName := make(map[string][]string)
+	for _, versionDir := range versionDirs {
+		csvs, err := ReadDirCSVs(filepath.Join(dir, versionDir))
+		if err != nil {
+			return nil, err
+		}
+		for _, fileName := range SortedFileNames(csvs) {
+			csv := csvs[fileName]
+			rel := filepath.Join(versionDir, fileName)
+			pathsByName[csv.GetName()] = append(pathsByName[csv.GetName()], rel)
+
+			version := csv.Spec.Version.Version
+			version.Build = nil
+			if version.String() != filepath.Base(versionDir) {
+				problems = append(problems, fmt.Sprintf("%s: CSV version %s does not match its directory %s",
+					rel, version, filepath.Base(versionDir)))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(pathsByName))
+	for name, paths := range pathsByName {
+		if len(paths) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		problems = append(problems, fmt.Sprintf("CSV name %s is used by more than one version directory: %s",
+			name, strings.Join(pathsByName[name], ", ")))
+	}
+	return problems, nil
+}
+
+// VersionDirs returns the paths, relative to dir, of the version directories of the package in dir:
+// each directory in dir, or in a channel directory in dir if the package has the <channel>/<version> layout.
+func VersionDirs(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading package manifests from %s: %w", dir, err)
+	}
+	_, channels, err := ReadPackageLayout(dir)
+	if err != nil {
+		return nil, err
+	}
+	isChannel := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		isChannel[channel] = true
+	}
+	var versionDirs []string
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		if !isChannel[info.Name()] {
+			versionDirs = append(versionDirs, info.Name())
+			continue
+		}
+		subInfos, err := ioutil.ReadDir(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading package manifests from %s: %w", dir, err)
+		}
+		for _, subInfo := range subInfos {
+			if subInfo.IsDir() {
+				versionDirs = append(versionDirs, filepath.Join(info.Name(), subInfo.Name()))
+			}
+		}
+	}
+	return versionDirs, nil
+}
+
+// ReadPackageLayout returns the sorted names of the version directories directly in dir, and of
+// the channel directories in dir, which are those containing version directories. dir not existing
+// is not an error, since it has neither layout yet.
+func ReadPackageLayout(dir string) (versions, channels []string, err error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("error reading package manifests from %s: %v", dir, err)
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		if isVersionDirName(info.Name()) {
+			versions = append(versions, info.Name())
+			continue
+		}
+		subInfos, err := ioutil.ReadDir(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading package manifests from %s: %v", dir, err)
+		}
+		for _, subInfo := range subInfos {
+			if subInfo.IsDir() && isVersionDirName(subInfo.Name()) {
+				channels = append(channels, info.Name())
+				break
+			}
+		}
+	}
+	sort.Strings(versions)
+	sort.Strings(channels)
+	return versions, channels, nil
+}
+
+// isVersionDirName returns true if name is a semantic version, as version directories are named.
+func isVersionDirName(name string) bool {
+	_, err := semver.Parse(name)
+	return err == nil
+}
+
+// ReadPackage returns the package manifest in dir, or nil if there is none, and the CSVs in the
+// package's version directories.
+func ReadPackage(dir string) (*apimanifests.PackageManifest, []*operatorsv1alpha1.ClusterServiceVersion, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading package manifests from %s: %w", dir, err)
+	}
+	var pkg *apimanifests.PackageManifest
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".package.yaml") {
+			continue
+		}
+		path := filepath.Join(dir, info.Name())
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		pkg = &apimanifests.PackageManifest{}
+		if err := yaml.Unmarshal(b, pkg); err != nil {
+			return nil, nil, fmt.Errorf("error parsing package manifest %s: %v", path, err)
+		}
+		break
+	}
+	if pkg == nil || pkg.IsEmpty() {
+		return nil, nil, nil
+	}
+
+	versionDirs, err := VersionDirs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var csvs []*operatorsv1alpha1.ClusterServiceVersion
+	for _, versionDir := range versionDirs {
+		dirCSVs, err := ReadDirCSVs(filepath.Join(dir, versionDir))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, fileName := range SortedFileNames(dirCSVs) {
+			csvs = append(csvs, dirCSVs[fileName])
+		}
+	}
+	return pkg, csvs, nil
+}
+
+// ReadDirCSVs returns the CSVs in the YAML or JSON files directly in dir, keyed by file name.
+func ReadDirCSVs(dir string) (map[string]*operatorsv1alpha1.ClusterServiceVersion, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading version directory %s: %w", dir, err)
+	}
+	csvs := make(map[string]*operatorsv1alpha1.ClusterServiceVersion)
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		switch filepath.Ext(info.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		path := filepath.Join(dir, info.Name())
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		// Other files, ex. CRDs, are skipped.
+		typeMeta := metav1.TypeMeta{}
+		if err := yaml.Unmarshal(b, &typeMeta); err != nil || typeMeta.Kind != operatorsv1alpha1.ClusterServiceVersionKind {
+			continue
+		}
+		csv := &operatorsv1alpha1.ClusterServiceVersion{}
+		if err := yaml.Unmarshal(b, csv); err != nil {
+			return nil, fmt.Errorf("error parsing CSV %s: %v", path, err)
+		}
+		csvs[info.Name()] = csv
+	}
+	return csvs, nil
+}
+
+// SortedFileNames returns the sorted file names of csvs, as returned by ReadDirCSVs.
+func SortedFileNames(csvs map[string]*operatorsv1alpha1.ClusterServiceVersion) []string {
+	fileNames := make([]string, 0, len(csvs))
+	for fileName := range csvs {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	return fileNames
+}
+
+// CheckGraph returns a description of each inconsistency in the graph formed by csvs'
+// replaces and skips fields: replaces targets that do not exist, replaces cycles,
+// channels whose current CSV does not exist, and heads (CSVs not replaced or skipped
+// by any other CSV) that are not the current CSV of a channel.
+func CheckGraph(pkg *apimanifests.PackageManifest, csvs []*operatorsv1alpha1.ClusterServiceVersion) (problems []string) {
+	byName := make(map[string]*operatorsv1alpha1.ClusterServiceVersion, len(csvs))
+	names := make([]string, 0, len(csvs))
+	for _, csv := range csvs {
+		if _, seen := byName[csv.GetName()]; seen {
+			problems = append(problems, fmt.Sprintf("CSV %s exists in more than one version directory", csv.GetName()))
+			continue
+		}
+		byName[csv.GetName()] = csv
+		names = append(names, csv.GetName())
+	}
+	sort.Strings(names)
+
+	// Dangling replaces, and which CSVs are replaced or skipped by another.
+	replaced := make(map[string]bool, len(names))
+	for _, name := range names {
+		csv := byName[name]
+		if csv.Spec.Replaces != "" {
+			if _, exists := byName[csv.Spec.Replaces]; !exists {
+				problems = append(problems, fmt.Sprintf("CSV %s replaces %s, which does not exist", name, csv.Spec.Replaces))
+			}
+			replaced[csv.Spec.Replaces] = true
+		}
+		for _, skip := range csv.Spec.Skips {
+			replaced[skip] = true
+		}
+	}
+
+	// Cycles. Each CSV replaces at most one other, so walking replaces from each CSV finds every cycle.
+	reported := make(map[string]bool)
+	for _, name := range names {
+		chain, cycle := replacesChain(byName, name)
+		if !cycle {
+			continue
+		}
+		// The cycle is the tail of the chain starting at the first repeated CSV.
+		start := byName[chain[len(chain)-1]].Spec.Replaces
+		var members []string
+		for i := len(chain) - 1; i >= 0; i-- {
+			members = append([]string{chain[i]}, members...)
+			if chain[i] == start {
+				break
+			}
+		}
+		key := strings.Join(sortedCopy(members), ",")
+		if !reported[key] {
+			reported[key] = true
+			problems = append(problems, fmt.Sprintf("replaces cycle: %s -> %s", strings.Join(members, " -> "), members[0]))
+		}
+	}
+
+	// Channel membership via replaces from each channel's current CSV.
+	channelMembers := make(map[string]map[string]bool, len(pkg.Channels))
+	currentCSVs := make(map[string]bool, len(pkg.Channels))
+	for _, channel := range pkg.Channels {
+		currentCSVs[channel.CurrentCSVName] = true
+		if _, exists := byName[channel.CurrentCSVName]; !exists {
+			problems = append(problems, fmt.Sprintf("channel %q current CSV %s does not exist", channel.Name, channel.CurrentCSVName))
+			continue
+		}
+		chain, _ := replacesChain(byName, channel.CurrentCSVName)
+		members := make(map[string]bool, len(chain))
+		for _, member := range chain {
+			members[member] = true
+		}
+		channelMembers[channel.Name] = members
+	}
+
+	// Heads that are not a channel's current CSV.
+	for _, name := range names {
+		if replaced[name] || currentCSVs[name] {
+			continue
+		}
+		chain, _ := replacesChain(byName, name)
+		inChannel := false
+		for _, channel := range pkg.Channels {
+			for _, member := range chain {
+				if channelMembers[channel.Name][member] {
+					problems = append(problems, fmt.Sprintf("channel %q has multiple heads: %s and %s",
+						channel.Name, channel.CurrentCSVName, name))
+					inChannel = true
+					break
+				}
+			}
+		}
+		if !inChannel {
+			problems = append(problems, fmt.Sprintf("CSV %s is not replaced by any CSV and is not in any channel", name))
+		}
+	}
+
+	return problems
+}
+
+// replacesChain returns the names of CSVs in byName reached by following replaces from name,
+// including name. The walk stops at the first CSV not in byName or already in the chain,
+// in which case cycle is true.
+func replacesChain(byName map[string]*operatorsv1alpha1.ClusterServiceVersion, name string) (chain []string, cycle bool) {
+	seen := make(map[string]bool)
+	for {
+		csv, exists := byName[name]
+		if !exists {
+			return chain, false
+		}
+		if seen[name] {
+			return chain, true
+		}
+		seen[name] = true
+		chain = append(chain, name)
+		name = csv.Spec.Replaces
+	}
+}
+
+// sortedCopy returns a sorted copy of s.
+func sortedCopy(s []string) []string {
+	c := append([]string(nil), s...)
+	sort.Strings(c)
+	return c
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apimanifests "github.com/operator-framework/api/pkg/manifests"
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Checking the replaces graph", func() {
+	newCSV := func(name, replaces string, skips ...string) *operatorsv1alpha1.ClusterServiceVersion {
+		return &operatorsv1alpha1.ClusterServiceVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: operatorsv1alpha1.ClusterServiceVersionSpec{
+				Replaces: replaces,
+				Skips:    skips,
+			},
+		}
+	}
+	newPkg := func(channels ...apimanifests.PackageChannel) *apimanifests.PackageManifest {
+		return &apimanifests.PackageManifest{PackageName: "memcached-operator", Channels: channels}
+	}
+	alpha := func(current string) apimanifests.PackageChannel {
+		return apimanifests.PackageChannel{Name: "alpha", CurrentCSVName: current}
+	}
+
+	Describe("CheckGraph", func() {
+		It("accepts a linear chain", func() {
+			csvs := []*operatorsv1alpha1.ClusterServiceVersion{
+				newCSV("op.v0.0.1", ""),
+				newCSV("op.v0.0.2", "op.v0.0.1"),
+				newCSV("op.v0.0.3", "op.v0.0.2"),
+			}
+			Expect(CheckGraph(newPkg(alpha("op.v0.0.3")), csvs)).To(BeEmpty())
+		})
+		It("accepts heads replaced only by skips", func() {
+			csvs := []*operatorsv1alpha1.ClusterServiceVersion{
+				newCSV("op.v0.0.1", ""),
+				newCSV("op.v0.0.2", "op.v0.0.1"),
+				newCSV("op.v0.0.3", "op.v0.0.1", "op.v0.0.2"),
+			}
+			Expect(CheckGraph(newPkg(alpha("op.v0.0.3")), csvs)).To(BeEmpty())
+		})
+		It("reports dangling replaces", func() {
+			csvs := []*operatorsv1alpha1.ClusterServiceVersion{
+				newCSV("op.v0.0.2", "op.v0.0.1"),
+			}
+			Expect(CheckGraph(newPkg(alpha("op.v0.0.2")), csvs)).To(ConsistOf(
+				"CSV op.v0.0.2 replaces op.v0.0.1, which does not exist",
+			))
+		})
+		It("reports a cycle once", func() {
+			csvs := []*operatorsv1alpha1.ClusterServiceVersion{
+				newCSV("op.v0.0.1", "op.v0.0.2"),
+				newCSV("op.v0.0.2", "op.v0.0.1"),
+			}
+			Expect(CheckGraph(newPkg(alpha("op.v0.0.2")), csvs)).To(ConsistOf(
+				"replaces cycle: op.v0.0.1 -> op.v0.0.2 -> op.v0.0.1",
+			))
+		})
+		It("reports multiple heads in a channel", func() {
+			csvs := []*operatorsv1alpha1.ClusterServiceVersion{
+				newCSV("op.v0.0.1", ""),
+				newCSV("op.v0.0.2", "op.v0.0.1"),
+				newCSV("op.v0.0.3", "op.v0.0.1"),
+			}
+			Expect(CheckGraph(newPkg(alpha("op.v0.0.3")), csvs)).To(ConsistOf(
+				`channel "alpha" has multiple heads: op.v0.0.3 and op.v0.0.2`,
+			))
+		})
+		It("reports missing channel heads and CSVs outside any channel", func() {
+			csvs := []*operatorsv1alpha1.ClusterServiceVersion{
+				newCSV("op.v0.0.1", ""),
+			}
+			Expect(CheckGraph(newPkg(alpha("op.v0.0.2")), csvs)).To(ConsistOf(
+				`channel "alpha" current CSV op.v0.0.2 does not exist`,
+				"CSV op.v0.0.1 is not replaced by any CSV and is not in any channel",
+			))
+		})
+	})
+})
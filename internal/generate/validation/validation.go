@@ -0,0 +1,188 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validation checks the inputs and output of package manifests generation, returning every
+// problem found instead of failing at the first.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
+)
+
+// Severity is the severity of a validation Result.
+type Severity int
+
+const (
+	// SeverityWarning is a problem generation tolerates, but that likely makes the package incorrect.
+	SeverityWarning Severity = iota
+	// SeverityError is a problem that fails generation or makes the package invalid.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ObjectRef identifies the collected object a Result is about.
+type ObjectRef struct {
+	Kind string
+	Name string
+}
+
+func (r ObjectRef) String() string {
+	if r.Kind == "" {
+		return ""
+	}
+	return r.Kind + "/" + r.Name
+}
+
+// Result is a problem found by Validate. Object is empty if the problem is not about one object.
+type Result struct {
+	Severity Severity
+	Message  string
+	Object   ObjectRef
+}
+
+// Config configures Validate.
+type Config struct {
+	// Collector contains the manifests a version is generated from, as generation changes them.
+	Collector *collector.Manifests
+	// Generator generates the version's ClusterServiceVersion from Collector.
+	Generator gencsv.Generator
+	// Options transform the ClusterServiceVersion as Generator would when writing it.
+	Options []gencsv.Option
+	// RequireQualifiedImages makes images without a registry host errors instead of warnings.
+	RequireQualifiedImages bool
+	// PackageDir, if set, is a package manifests directory with the version generated into it,
+	// whose version directories and replaces graph are checked.
+	PackageDir string
+}
+
+// Validate runs all checks of the manifests in cfg.Collector, the ClusterServiceVersion generated from
+// them, and cfg.PackageDir, and returns the problems found. Nothing is written. An error is returned only
+// if the checks could not be run.
+func Validate(cfg Config) ([]Result, error) {
+	results := CheckCollected(cfg.Collector, cfg.RequireQualifiedImages)
+
+	csvResults, err := checkClusterServiceVersion(cfg.Generator, cfg.Collector, cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, csvResults...)
+
+	if cfg.PackageDir != "" {
+		pkgResults, err := CheckPackage(cfg.PackageDir)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, pkgResults...)
+	}
+	return results, nil
+}
+
+// CheckCollected returns the problems found in col that are about one collected object.
+func CheckCollected(col *collector.Manifests, requireQualifiedImages bool) (results []Result) {
+	imageSeverity := SeverityWarning
+	if requireQualifiedImages {
+		imageSeverity = SeverityError
+	}
+	for _, dep := range col.Deployments {
+		ref := ObjectRef{Kind: "Deployment", Name: dep.GetName()}
+		for _, msg := range gencsv.CheckQualifiedPodImages("deployment "+dep.GetName(), dep.Spec.Template.Spec) {
+			results = append(results, Result{Severity: imageSeverity, Message: msg, Object: ref})
+		}
+	}
+
+	// Objects of a collected CRD's group are likely custom resources of a version or kind whose CRD is missing,
+	// since they are otherwise added to the CSV's alm-examples annotation.
+	groups := make(map[string]struct{})
+	for _, gvk := range k8sutil.GVKsForV1CustomResourceDefinitions(col.V1CustomResourceDefinitions...) {
+		groups[gvk.Group] = struct{}{}
+	}
+	for _, gvk := range k8sutil.GVKsForV1beta1CustomResourceDefinitions(col.V1beta1CustomResourceDefinitions...) {
+		groups[gvk.Group] = struct{}{}
+	}
+	for _, obj := range col.Others {
+		if _, inCRDGroup := groups[obj.GroupVersionKind().Group]; inCRDGroup {
+			results = append(results, Result{
+				Severity: SeverityWarning,
+				Message: fmt.Sprintf("no CustomResourceDefinition was collected for %s %s, so it is not added "+
+					"to the CSV's alm-examples annotation", obj.GetAPIVersion(), obj.GetKind()),
+				Object: ObjectRef{Kind: obj.GetKind(), Name: obj.GetName()},
+			})
+		}
+	}
+	return results
+}
+
+// checkClusterServiceVersion generates a ClusterServiceVersion from col with g and opts, and returns
+// the warnings and failed checks of generation.
+func checkClusterServiceVersion(g gencsv.Generator, col *collector.Manifests, opts []gencsv.Option) ([]Result, error) {
+	var results []Result
+	g.Collector = col
+	// Images are checked in the collected manifests, so are reported once per container with their Deployment.
+	g.RequireQualifiedImages = false
+	opts = append(append([]gencsv.Option{}, opts...),
+		gencsv.WithWriter(ioutil.Discard),
+		gencsv.WithWarningHandler(func(msg string) {
+			results = append(results, Result{Severity: SeverityWarning, Message: msg})
+		}))
+	if err := g.Generate(opts...); err != nil {
+		checkErr := gencsv.CheckError{}
+		if !errors.As(err, &checkErr) {
+			return nil, err
+		}
+		for _, problem := range checkErr.Problems() {
+			results = append(results, Result{Severity: SeverityError, Message: problem})
+		}
+	}
+	return results, nil
+}
+
+// CheckPackage returns the problems found in the version directories and replaces graph of the package
+// in dir. The graph is only checked if the version directories have no problems, since a duplicate CSV
+// name makes it ambiguous.
+func CheckPackage(dir string) ([]Result, error) {
+	problems, err := CheckVersionDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) == 0 {
+		pkg, csvs, err := ReadPackage(dir)
+		if err != nil {
+			return nil, err
+		}
+		if pkg == nil {
+			return nil, fmt.Errorf("no package manifest found in %s", dir)
+		}
+		problems = CheckGraph(pkg, csvs)
+	}
+	results := make([]Result, 0, len(problems))
+	for _, problem := range problems {
+		results = append(results, Result{Severity: SeverityError, Message: problem})
+	}
+	return results, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	gencsv "github.com/operator-framework/operator-sdk/internal/generate/clusterserviceversion"
+	"github.com/operator-framework/operator-sdk/internal/generate/collector"
+)
+
+var _ = Describe("Validating manifests", func() {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller-manager
+spec:
+  selector:
+    matchLabels:
+      control-plane: controller-manager
+  template:
+    metadata:
+      labels:
+        control-plane: controller-manager
+    spec:
+      containers:
+      - image: memcached-operator:v0.0.1
+        name: manager
+`
+	const crd = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: memcacheds.cache.example.com
+spec:
+  group: cache.example.com
+  names:
+    kind: Memcached
+    listKind: MemcachedList
+    plural: memcacheds
+    singular: memcached
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+`
+	const cr = `apiVersion: cache.example.com/v1beta1
+kind: Memcached
+metadata:
+  name: memcached-sample
+`
+
+	newCollector := func(docs ...string) *collector.Manifests {
+		col := &collector.Manifests{}
+		Expect(col.UpdateFromReader(strings.NewReader(strings.Join(docs, "---\n")))).To(Succeed())
+		return col
+	}
+	cfg := func(col *collector.Manifests) Config {
+		return Config{
+			Collector: col,
+			Generator: gencsv.Generator{OperatorName: "memcached-operator", Version: "0.1.0"},
+		}
+	}
+
+	errorResults := func(results []Result) (errs []Result) {
+		for _, result := range results {
+			if result.Severity == SeverityError {
+				errs = append(errs, result)
+			}
+		}
+		return errs
+	}
+
+	Describe("CheckCollected", func() {
+		It("reports unqualified images as warnings, or errors if required", func() {
+			col := newCollector(deployment)
+			ref := ObjectRef{Kind: "Deployment", Name: "controller-manager"}
+			results := CheckCollected(col, false)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Severity).To(Equal(SeverityWarning))
+			Expect(results[0].Object).To(Equal(ref))
+			Expect(results[0].Message).To(ContainSubstring(`"memcached-operator:v0.0.1" has no registry host`))
+
+			results = CheckCollected(col, true)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Severity).To(Equal(SeverityError))
+		})
+		It("reports objects of a CRD's group whose CRD was not collected", func() {
+			Expect(CheckCollected(newCollector(crd, cr), false)).To(Equal([]Result{{
+				Severity: SeverityWarning,
+				Message: "no CustomResourceDefinition was collected for cache.example.com/v1beta1 Memcached, " +
+					"so it is not added to the CSV's alm-examples annotation",
+				Object: ObjectRef{Kind: "Memcached", Name: "memcached-sample"},
+			}}))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("returns failed generation checks as errors", func() {
+			results, err := Validate(cfg(newCollector(crd)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errorResults(results)).To(HaveLen(1))
+			Expect(errorResults(results)[0].Message).To(HavePrefix(
+				"ClusterServiceVersion memcached-operator.v0.1.0 install strategy has no deployments"))
+		})
+		It("returns generation warnings, and images once with their Deployment", func() {
+			c := cfg(newCollector(deployment, crd))
+			c.Generator.RequireQualifiedImages = true
+			results, err := Validate(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errorResults(results)).To(BeEmpty())
+			Expect(results).To(ContainElement(Result{
+				Severity: SeverityWarning,
+				Message:  "ClusterServiceVersion validation: [OperationFailed] provided API should have an example annotation",
+			}))
+		})
+		It("returns an error if generation fails for other reasons", func() {
+			c := cfg(newCollector(deployment))
+			c.Generator.Version = "not-a-version"
+			_, err := Validate(c)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CheckPackage", func() {
+		var dir string
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "validation-package-")
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("reports a channel head that does not exist", func() {
+			Expect(os.Mkdir(filepath.Join(dir, "0.1.0"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "0.1.0", "memcached-operator.clusterserviceversion.yaml"), []byte(`apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  name: memcached-operator.v0.1.0
+spec:
+  version: 0.1.0
+`), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "memcached-operator.package.yaml"), []byte(`channels:
+- currentCSV: memcached-operator.v0.2.0
+  name: alpha
+defaultChannel: alpha
+packageName: memcached-operator
+`), 0644)).To(Succeed())
+			results, err := CheckPackage(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(ContainElement(Result{
+				Severity: SeverityError,
+				Message:  `channel "alpha" current CSV memcached-operator.v0.2.0 does not exist`,
+			}))
+		})
+		It("reads versions from channel directories", func() {
+			Expect(os.MkdirAll(filepath.Join(dir, "stable", "0.1.0"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "stable", "0.1.0", "memcached-operator.clusterserviceversion.yaml"), []byte(`apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  name: memcached-operator.v0.1.0
+spec:
+  version: 0.1.0
+`), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "memcached-operator.package.yaml"), []byte(`channels:
+- currentCSV: memcached-operator.v0.1.0
+  name: stable
+defaultChannel: stable
+packageName: memcached-operator
+`), 0644)).To(Succeed())
+			Expect(VersionDirs(dir)).To(Equal([]string{filepath.Join("stable", "0.1.0")}))
+			results, err := CheckPackage(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(BeEmpty())
+		})
+		It("returns an error if dir has no package manifest", func() {
+			_, err := CheckPackage(dir)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -20,7 +20,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/docker/distribution/reference"
 	registryimage "github.com/operator-framework/operator-registry/pkg/image"
 	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
 	log "github.com/sirupsen/logrus"
@@ -108,3 +110,42 @@ func GetImageLabels(ctx context.Context, logger *log.Entry, image string, local
 
 	return labels, err
 }
+
+// ResolveTimeout bounds the registry query of ResolveImageDigest if its context has no deadline.
+const ResolveTimeout = time.Minute
+
+// ResolveImageDigest returns image pinned to the digest its registry resolves it to,
+// ex. "quay.io/example/operator@sha256:...". Images already pinned to a digest are returned
+// without querying their registry. Registries are authenticated with docker config credentials,
+// and queried for at most ResolveTimeout unless ctx has a deadline.
+func ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("error parsing image %s: %v", image, err)
+	}
+	if _, isCanonical := named.(reference.Canonical); isCanonical {
+		return named.String(), nil
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ResolveTimeout)
+		defer cancel()
+	}
+	resolver, err := containerdregistry.NewResolver("", false, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating image resolver: %v", err)
+	}
+	_, desc, err := resolver.Resolve(ctx, reference.TagNameOnly(named).String())
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("error resolving image %s: timed out querying its registry", image)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error resolving image %s: %v", image, err)
+	}
+	pinned, err := reference.WithDigest(reference.TrimNamed(named), desc.Digest)
+	if err != nil {
+		return "", fmt.Errorf("error pinning image %s to digest %s: %v", image, desc.Digest, err)
+	}
+	return pinned.String(), nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fileutil creates directories and files with modes that are applied regardless of umask.
+// A zero mode means the default mode, 0755 for directories and 0666 before umask for files.
+package fileutil
+
+import (
+	"os"
+)
+
+// MkdirAll creates dir and its parents, then sets dir's mode to mode if non-zero.
+// Unlike os.MkdirAll, mode is applied regardless of umask and whether dir exists.
+func MkdirAll(dir string, mode os.FileMode) error {
+	if mode == 0 {
+		return os.MkdirAll(dir, 0755)
+	}
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return err
+	}
+	return os.Chmod(dir, mode)
+}
+
+// OpenFile opens the file at path with flag like os.OpenFile, then sets its mode to mode if non-zero.
+// Unlike os.OpenFile, mode is applied regardless of umask and whether the file exists.
+func OpenFile(path string, flag int, mode os.FileMode) (*os.File, error) {
+	if mode == 0 {
+		return os.OpenFile(path, flag, 0666)
+	}
+	f, err := os.OpenFile(path, flag, mode)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Create creates or truncates the file at path, then sets its mode to mode if non-zero.
+func Create(path string, mode os.FileMode) (*os.File, error) {
+	return OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
+}
+
+// WriteFile writes b to the file at path, creating or truncating it, then sets its mode to mode if non-zero.
+func WriteFile(path string, b []byte, mode os.FileMode) error {
+	f, err := Create(path, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModesIgnoreUmask(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(0077))
+	tmp, err := ioutil.TempDir("", "fileutil-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, "a", "b")
+	require.NoError(t, MkdirAll(dir, 0750))
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+
+	path := filepath.Join(dir, "file")
+	require.NoError(t, WriteFile(path, []byte("a"), 0644))
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestModesAppliedToExistingFiles(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "fileutil-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	require.NoError(t, MkdirAll(tmp, 0700))
+	info, err := os.Stat(tmp)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	path := filepath.Join(tmp, "file")
+	require.NoError(t, ioutil.WriteFile(path, []byte("old contents"), 0600))
+	require.NoError(t, WriteFile(path, []byte("new"), 0640))
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(b))
+
+	f, err := OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	b, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(b))
+}
@@ -15,7 +15,14 @@
 package k8sutil
 
 import (
+	"encoding/json"
+	"fmt"
+
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 type MarshalFunc func(interface{}) ([]byte, error)
@@ -53,3 +60,35 @@ func deleteKeyFromUnstructured(u map[string]interface{}, key string) {
 		}
 	}
 }
+
+// ValidateObjectMetadata returns an error listing each label and annotation of obj whose key or value
+// an API server would reject, ex. a key with an invalid prefix.
+func ValidateObjectMetadata(obj metav1.Object) error {
+	metaPath := field.NewPath("metadata")
+	errs := metav1validation.ValidateLabels(obj.GetLabels(), metaPath.Child("labels"))
+	errs = append(errs, apivalidation.ValidateAnnotations(obj.GetAnnotations(), metaPath.Child("annotations"))...)
+	if len(errs) != 0 {
+		return fmt.Errorf("%q has invalid metadata: %v", obj.GetName(), errs.ToAggregate())
+	}
+	return nil
+}
+
+// DefaultMaxObjectSize is etcd's default maximum request size, 1.5 MiB, which limits the size
+// of an object an API server can store.
+const DefaultMaxObjectSize = 3 << 19
+
+// CheckObjectSize returns an error if obj, serialized as JSON as an API server stores it,
+// is larger than maxSize bytes. A maxSize of zero disables the check.
+func CheckObjectSize(obj metav1.Object, maxSize int) error {
+	if maxSize <= 0 {
+		return nil
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if len(b) > maxSize {
+		return fmt.Errorf("%q is %d bytes serialized, which exceeds the maximum of %d bytes", obj.GetName(), len(b), maxSize)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateObjectMetadata(t *testing.T) {
+	cases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		wantErr     string
+	}{
+		{"valid", map[string]string{"app.kubernetes.io/name": "memcached"},
+			map[string]string{"operators.operatorframework.io/builder": "operator-sdk"}, ""},
+		{"invalid annotation prefix", nil, map[string]string{"-example.com/foo": "bar"},
+			`metadata.annotations: Invalid value: "-example.com/foo"`},
+		{"invalid label key", map[string]string{"example.com/foo bar": "baz"}, nil,
+			`metadata.labels: Invalid value: "example.com/foo bar"`},
+		{"invalid label value", map[string]string{"app": "memcached operator"}, nil,
+			`metadata.labels: Invalid value: "memcached operator"`},
+	}
+
+	for _, c := range cases {
+		obj := &metav1.ObjectMeta{Name: "memcached-operator", Labels: c.labels, Annotations: c.annotations}
+		err := ValidateObjectMetadata(obj)
+		switch {
+		case c.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		case c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr)):
+			t.Errorf("%s: wanted error containing %q, got %v", c.name, c.wantErr, err)
+		}
+	}
+}
+
+func TestCheckObjectSize(t *testing.T) {
+	obj := &metav1.ObjectMeta{Name: "memcached-operator", Annotations: map[string]string{"description": strings.Repeat("a", 100)}}
+	if err := CheckObjectSize(obj, 0); err != nil {
+		t.Errorf("unexpected error with the check disabled: %v", err)
+	}
+	if err := CheckObjectSize(obj, DefaultMaxObjectSize); err != nil {
+		t.Errorf("unexpected error for a small object: %v", err)
+	}
+	err := CheckObjectSize(obj, 100)
+	if wantErr := `"memcached-operator" is 187 bytes serialized, which exceeds the maximum of 100 bytes`; err == nil || err.Error() != wantErr {
+		t.Errorf("wanted error %q, got %v", wantErr, err)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yamlutil
+
+import (
+	"errors"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mappingEntry is the entry at index of a mapping node.
+type mappingEntry struct {
+	mapping *yaml.Node
+	index   int
+}
+
+// SetAnnotation sets the metadata.annotations key of the object in the YAML document b to value.
+// Only the lines of that annotation, or of metadata.annotations if it is not a non-empty block mapping,
+// are changed, so the rest of b keeps its formatting.
+func SetAnnotation(b []byte, key, value string) ([]byte, error) {
+	if len(b) != 0 && b[len(b)-1] != '\n' {
+		b = append(b, '\n')
+	}
+	docs, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) != 1 || len(docs[0].Content) != 1 || !isBlockMapping(docs[0].Content[0]) {
+		return nil, errors.New("expected one YAML document with a mapping")
+	}
+	root := docs[0].Content[0]
+	metaIndex := mappingIndex(root, "metadata")
+	if metaIndex < 0 || !isBlockMapping(root.Content[2*metaIndex+1]) {
+		return nil, errors.New("expected metadata to be a non-empty mapping")
+	}
+	meta := root.Content[2*metaIndex+1]
+	lines := strings.SplitAfter(string(b), "\n")
+	lines = lines[:len(lines)-1]
+	path := []mappingEntry{{root, metaIndex}}
+
+	metaColumn := meta.Content[0].Column - 1
+	indent := metaColumn - (root.Content[2*metaIndex].Column - 1)
+	annIndex := mappingIndex(meta, "annotations")
+	if annIndex < 0 {
+		// Keys are inserted in sorted order, as objects are marshaled.
+		entry, err := encodeEntry(annotationsNode(nil, key, value), indent, metaColumn)
+		if err != nil {
+			return nil, err
+		}
+		line := insertLine(meta, "annotations", append(path, mappingEntry{meta, len(meta.Content)/2 - 1}), len(lines))
+		return spliceLines(lines, line, line, entry), nil
+	}
+
+	path = append(path, mappingEntry{meta, annIndex})
+	ann := meta.Content[2*annIndex+1]
+	if !isBlockMapping(ann) {
+		entry, err := encodeEntry(annotationsNode(ann, key, value), indent, metaColumn)
+		if err != nil {
+			return nil, err
+		}
+		start := meta.Content[2*annIndex].Line
+		return spliceLines(lines, start, nextLine(path, len(lines)), entry), nil
+	}
+
+	annColumn := ann.Content[0].Column - 1
+	entry, err := encodeEntry(stringMapping(key, value), indent, annColumn)
+	if err != nil {
+		return nil, err
+	}
+	if i := mappingIndex(ann, key); i >= 0 {
+		start := ann.Content[2*i].Line
+		return spliceLines(lines, start, nextLine(append(path, mappingEntry{ann, i}), len(lines)), entry), nil
+	}
+	line := insertLine(ann, key, append(path, mappingEntry{ann, len(ann.Content)/2 - 1}), len(lines))
+	return spliceLines(lines, line, line, entry), nil
+}
+
+// isBlockMapping returns true if node is a non-empty mapping in block style, whose entries each start a line.
+func isBlockMapping(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0 && len(node.Content) != 0
+}
+
+// mappingIndex returns the index of the entry of mapping with key, or -1 if there is none.
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i / 2
+		}
+	}
+	return -1
+}
+
+// stringMapping returns a mapping node of key to the string value.
+func stringMapping(key, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	}}
+}
+
+// annotationsNode returns a mapping node of "annotations" to a block mapping of the entries of ann,
+// if not nil, and key set to value.
+func annotationsNode(ann *yaml.Node, key, value string) *yaml.Node {
+	annotations := stringMapping(key, value)
+	if ann != nil && ann.Kind == yaml.MappingNode {
+		annotations.Content = nil
+		for i := 0; i+1 < len(ann.Content); i += 2 {
+			if ann.Content[i].Value != key {
+				annotations.Content = append(annotations.Content, ann.Content[i], ann.Content[i+1])
+			}
+		}
+		annotations.Content = append(annotations.Content, stringMapping(key, value).Content...)
+		sortKeys(annotations)
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "annotations"},
+		annotations,
+	}}
+}
+
+// encodeEntry encodes the entries of mapping with nested collections indented by indent spaces,
+// then indents each line by column spaces.
+func encodeEntry(mapping *yaml.Node, indent, column int) ([]string, error) {
+	if indent < minIndent || indent > maxIndent {
+		indent = DefaultIndent
+	}
+	b, err := encode([]*yaml.Node{mapping}, indent)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	lines = lines[:len(lines)-1]
+	prefix := strings.Repeat(" ", column)
+	for i, line := range lines {
+		if line != "\n" {
+			lines[i] = prefix + line
+		}
+	}
+	return lines, nil
+}
+
+// insertLine returns the line an entry with key is inserted at in mapping to keep it sorted:
+// that of the first key sorting after key, or the line after the last entry, at the end of path.
+func insertLine(mapping *yaml.Node, key string, path []mappingEntry, numLines int) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value > key {
+			return mapping.Content[i].Line
+		}
+	}
+	return nextLine(path, numLines)
+}
+
+// nextLine returns the line after the entry at the end of path, which is the line of the next key of
+// the innermost mapping in path that has one, or the line after the last of numLines.
+func nextLine(path []mappingEntry, numLines int) int {
+	for i := len(path) - 1; i >= 0; i-- {
+		if next := 2 * (path[i].index + 1); next < len(path[i].mapping.Content) {
+			return path[i].mapping.Content[next].Line
+		}
+	}
+	return numLines + 1
+}
+
+// spliceLines returns lines with lines start through end, exclusive and numbered from 1, replaced by with.
+func spliceLines(lines []string, start, end int, with []string) []byte {
+	spliced := append(append(append([]string{}, lines[:start-1]...), with...), lines[end-1:]...)
+	return []byte(strings.Join(spliced, ""))
+}
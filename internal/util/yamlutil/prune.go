@@ -0,0 +1,193 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yamlutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// podTemplatePaths are the paths, relative to a pod template, of fields whose empty value means the same
+// as no value, since their types omit them when empty.
+var podTemplatePaths = []string{
+	"metadata.creationTimestamp",
+	"metadata.labels",
+	"metadata.annotations",
+	"metadata",
+	"spec.containers[].args",
+	"spec.containers[].command",
+	"spec.containers[].env",
+	"spec.containers[].ports",
+	"spec.containers[].resources",
+	"spec.containers[].securityContext",
+	"spec.containers[].volumeMounts",
+	"spec.initContainers[].args",
+	"spec.initContainers[].command",
+	"spec.initContainers[].env",
+	"spec.initContainers[].resources",
+	"spec.initContainers[].volumeMounts",
+	"spec.securityContext",
+	"spec.volumes",
+}
+
+// prunablePaths are the paths of fields whose empty value means the same as no value, by the kind of the
+// document they are in, or "" for every kind. Paths are dot-separated keys, where "[]" is any element of a
+// list. Only these fields are pruned, since the empty value of another may be meaningful, ex. a
+// PodDisruptionBudget's "selector: {}" selects all pods, and a CRD's "subresources.status: {}" enables its
+// status subresource.
+var prunablePaths = func() map[string]map[string]struct{} {
+	paths := map[string][]string{
+		"": {
+			"metadata.creationTimestamp",
+			"metadata.labels",
+			"metadata.annotations",
+			"metadata",
+			"status",
+		},
+		"ClusterServiceVersion": {
+			"spec.apiservicedefinitions",
+			"spec.customresourcedefinitions",
+			"spec.icon",
+			"spec.install.spec.clusterPermissions",
+			"spec.install.spec.permissions",
+			"spec.install.spec.deployments[].spec.strategy",
+			"spec.keywords",
+			"spec.links",
+			"spec.maintainers",
+			"spec.nativeAPIs",
+			"spec.provider",
+			"spec.relatedImages",
+			"spec.webhookdefinitions",
+		},
+		"Deployment": {
+			"spec.strategy",
+		},
+	}
+	for _, path := range podTemplatePaths {
+		paths["ClusterServiceVersion"] = append(paths["ClusterServiceVersion"],
+			"spec.install.spec.deployments[].spec.template."+path)
+		paths["Deployment"] = append(paths["Deployment"], "spec.template."+path)
+	}
+	sets := make(map[string]map[string]struct{}, len(paths))
+	for kind, kindPaths := range paths {
+		sets[kind] = make(map[string]struct{}, len(kindPaths))
+		for _, path := range kindPaths {
+			sets[kind][path] = struct{}{}
+		}
+	}
+	return sets
+}()
+
+// isPrunable returns true if the field at path in a document of kind may be pruned when empty.
+func isPrunable(kind, path string) bool {
+	if _, ok := prunablePaths[""][path]; ok {
+		return true
+	}
+	_, ok := prunablePaths[kind][path]
+	return ok
+}
+
+// pruneEmpty removes null values and empty mappings and lists of prunablePaths from each YAML document
+// in b, ex. "creationTimestamp: null". Documents are written as sigs.k8s.io/yaml writes them, so pruning
+// does not change their style.
+func pruneEmpty(b []byte) ([]byte, error) {
+	out := &bytes.Buffer{}
+	r := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(b)))
+	for {
+		doc, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding YAML: %v", err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		j, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding YAML: %v", err)
+		}
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(j))
+		// Keep numbers as written.
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("error decoding YAML: %v", err)
+		}
+		if m, isMap := v.(map[string]interface{}); isMap {
+			kind, _ := m["kind"].(string)
+			pruneMap(m, kind, "")
+		}
+		pruned, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding YAML: %v", err)
+		}
+		if out.Len() != 0 {
+			out.WriteString("---\n")
+		}
+		out.Write(pruned)
+	}
+}
+
+// pruneMap removes prunable null and empty values from m, the mapping at path in a document of kind,
+// after pruning them.
+func pruneMap(m map[string]interface{}, kind, path string) {
+	for key, value := range m {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		pruneValue(value, kind, keyPath)
+		if isEmpty(value) && isPrunable(kind, keyPath) {
+			delete(m, key)
+		}
+	}
+}
+
+// pruneValue prunes the mappings in value, the value at path. List elements are kept even if empty,
+// since removing them would change the meaning of the others' indices.
+func pruneValue(value interface{}, kind, path string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		pruneMap(v, kind, path)
+	case []interface{}:
+		for _, elem := range v {
+			if m, isMap := elem.(map[string]interface{}); isMap {
+				pruneMap(m, kind, path+"[]")
+			}
+		}
+	}
+}
+
+// isEmpty returns true if value is null, an empty mapping, or an empty list.
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yamlutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultIndent is the number of spaces nested collections are indented by if not set,
+	// which matches the indentation of sigs.k8s.io/yaml.
+	DefaultIndent = 2
+	// minIndent and maxIndent bound the indentation supported by the encoder.
+	minIndent, maxIndent = 2, 9
+)
+
+// Options configure how Format re-encodes YAML. The zero value leaves YAML unchanged,
+// while any other value besides PruneEmpty re-encodes YAML in a canonical style: strings
+// are only quoted when necessary and not wrapped, and lists are indented under their mapping keys.
+type Options struct {
+	// Indent is the number of spaces nested collections are indented by. Defaults to DefaultIndent.
+	Indent int
+	// SortKeys sorts mapping keys.
+	SortKeys bool
+	// MaxLineWidth, if non-zero, is the preferred maximum width of a line. String values
+	// that would be written past MaxLineWidth are written as folded block scalars wrapped
+	// at spaces so that the decoded value is unchanged.
+	MaxLineWidth int
+	// PruneEmpty removes null values and empty mappings and lists, ex. "labels: {}", unless they
+	// have a meaning when empty, ex. "emptyDir: {}". It does not otherwise change the style of YAML.
+	PruneEmpty bool
+}
+
+// IsZero returns true if opts leave YAML unchanged.
+func (opts Options) IsZero() bool {
+	return opts == Options{}
+}
+
+// Validate returns an error if opts are invalid.
+func (opts Options) Validate() error {
+	if opts.Indent != 0 && (opts.Indent < minIndent || opts.Indent > maxIndent) {
+		return fmt.Errorf("indent %d must be between %d and %d", opts.Indent, minIndent, maxIndent)
+	}
+	if opts.MaxLineWidth < 0 {
+		return fmt.Errorf("max line width %d must not be negative", opts.MaxLineWidth)
+	}
+	return nil
+}
+
+// Format re-encodes the YAML documents in b according to opts. Decoding the result
+// yields the same values as decoding b.
+func Format(b []byte, opts Options) ([]byte, error) {
+	if opts.IsZero() {
+		return b, nil
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.PruneEmpty {
+		var err error
+		if b, err = pruneEmpty(b); err != nil {
+			return nil, err
+		}
+		// Pruned YAML is only re-encoded if other options are set.
+		if opts.PruneEmpty = false; opts.IsZero() {
+			return b, nil
+		}
+	}
+
+	indent := opts.Indent
+	if indent == 0 {
+		indent = DefaultIndent
+	}
+
+	docs, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SortKeys {
+		for _, doc := range docs {
+			sortKeys(doc)
+		}
+	}
+	if opts.MaxLineWidth == 0 {
+		return encode(docs, indent)
+	}
+
+	// Columns of values are only known once encoded, so encode then decode again to find
+	// values that overflow when written in this package's layout.
+	if b, err = encode(docs, indent); err != nil {
+		return nil, err
+	}
+	if docs, err = decode(b); err != nil {
+		return nil, err
+	}
+	folded := make(map[string]struct{})
+	for _, doc := range docs {
+		foldLongStrings(doc, opts.MaxLineWidth, folded)
+	}
+	if b, err = encode(docs, indent); err != nil {
+		return nil, err
+	}
+	return wrapFoldedLines(b, opts.MaxLineWidth, folded), nil
+}
+
+// decode decodes all YAML documents in b.
+func decode(b []byte) (docs []*yaml.Node, err error) {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		doc := &yaml.Node{}
+		if err := dec.Decode(doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			return nil, fmt.Errorf("error decoding YAML: %v", err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// encode encodes docs, separated by "---", indenting nested collections by indent spaces.
+func encode(docs []*yaml.Node, indent int) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(indent)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("error encoding YAML: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding YAML: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sortKeys recursively sorts the keys of mappings under node.
+func sortKeys(node *yaml.Node) {
+	for _, child := range node.Content {
+		sortKeys(child)
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i][0].Value < pairs[j][0].Value
+	})
+	for i, pair := range pairs {
+		node.Content[2*i], node.Content[2*i+1] = pair[0], pair[1]
+	}
+}
+
+// foldLongStrings sets the style of foldable string values under node that end past width
+// to folded, and adds them to folded. Mapping keys are never folded.
+func foldLongStrings(node *yaml.Node, width int, folded map[string]struct{}) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			foldLongStrings(child, width, folded)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			foldLongStrings(node.Content[i], width, folded)
+		}
+	case yaml.ScalarNode:
+		end := node.Column - 1 + utf8.RuneCountInString(node.Value)
+		if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0 {
+			end += 2
+		}
+		if end > width && isFoldable(node) {
+			node.Style = yaml.FoldedStyle
+			folded[node.Value] = struct{}{}
+		}
+	}
+}
+
+// isFoldable returns true if node is a string that can be split at its spaces into lines of a
+// folded block scalar without changing its value: a line break in a folded scalar is read as a
+// single space, so the string must be on one line and separate words by exactly one space.
+func isFoldable(node *yaml.Node) bool {
+	v := node.Value
+	return node.Tag == "!!str" && strings.Contains(v, " ") &&
+		!strings.ContainsAny(v, "\t\r\n") && !strings.Contains(v, "  ") &&
+		strings.TrimSpace(v) == v
+}
+
+// wrapFoldedLines wraps the content line of each folded block scalar in b whose value is in folded
+// at spaces, such that lines end at or before width unless a single word is longer.
+func wrapFoldedLines(b []byte, width int, folded map[string]struct{}) []byte {
+	lines := strings.Split(string(b), "\n")
+	wrapped := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		wrapped = append(wrapped, lines[i])
+		if !strings.HasSuffix(lines[i], ">-") || i+1 == len(lines) {
+			continue
+		}
+		content := strings.TrimLeft(lines[i+1], " ")
+		if _, isFolded := folded[content]; !isFolded {
+			continue
+		}
+		prefix := lines[i+1][:len(lines[i+1])-len(content)]
+		line := prefix
+		for _, word := range strings.Split(content, " ") {
+			switch {
+			case line == prefix:
+				line += word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width:
+				wrapped = append(wrapped, line)
+				line = prefix + word
+			default:
+				line += " " + word
+			}
+		}
+		wrapped = append(wrapped, line)
+		i++
+	}
+	return []byte(strings.Join(wrapped, "\n"))
+}
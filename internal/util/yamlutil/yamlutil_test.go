@@ -0,0 +1,276 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yamlutil
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestFormatZeroOptions(t *testing.T) {
+	in := []byte("a:\n- b\n")
+	out, err := Format(in, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+}
+
+func TestFormatInvalidOptions(t *testing.T) {
+	_, err := Format([]byte("a: b\n"), Options{MaxLineWidth: -1})
+	assert.EqualError(t, err, "max line width -1 must not be negative")
+}
+
+func TestFormatInvalidIndent(t *testing.T) {
+	_, err := Format([]byte("a: b\n"), Options{Indent: 10})
+	assert.EqualError(t, err, "indent 10 must be between 2 and 9")
+}
+
+func TestFormatIndent(t *testing.T) {
+	out, err := Format([]byte("a:\n  b:\n  - c: 'true'\n    d: e\n"), Options{Indent: 4})
+	require.NoError(t, err)
+	assert.Equal(t, "a:\n    b:\n        - c: 'true'\n          d: e\n", string(out))
+}
+
+func TestFormatSortKeys(t *testing.T) {
+	out, err := Format([]byte("b: 1\na:\n- d: 2\n  c: 3\n"), Options{SortKeys: true})
+	require.NoError(t, err)
+	assert.Equal(t, "a:\n  - c: 3\n    d: 2\nb: 1\n", string(out))
+}
+
+func TestFormatMaxLineWidth(t *testing.T) {
+	long := strings.TrimSpace(strings.Repeat("a fairly long description ", 10))
+	obj := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"description": long,
+			"short":       "fits on one line",
+			"version":     "1.0",
+			"image":       "quay.io/example/" + strings.Repeat("x", 100),
+			"owned": []interface{}{
+				map[string]interface{}{"description": "has  double spaces " + long},
+				map[string]interface{}{"description": long},
+			},
+		},
+	}
+	in, err := yaml.Marshal(obj)
+	require.NoError(t, err)
+
+	for _, width := range []int{40, 80, 120} {
+		out, err := Format(in, Options{MaxLineWidth: width})
+		require.NoError(t, err)
+
+		// The decoded values must not change.
+		var got interface{}
+		require.NoError(t, yaml.Unmarshal(out, &got))
+		assert.Equal(t, obj, got)
+
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.Contains(line, "quay.io") || strings.Contains(line, "double spaces") {
+				continue
+			}
+			assert.LessOrEqual(t, utf8.RuneCountInString(line), width, "line %q is longer than %d", line, width)
+		}
+		assert.Contains(t, string(out), "description: >-\n")
+		assert.Contains(t, string(out), "short: fits on one line\n")
+		assert.Contains(t, string(out), "version: \"1.0\"\n")
+	}
+}
+
+func TestFormatMultipleDocuments(t *testing.T) {
+	out, err := Format([]byte("a: b\n---\nc: d\n"), Options{MaxLineWidth: 80})
+	require.NoError(t, err)
+	assert.Equal(t, "a: b\n---\nc: d\n", string(out))
+}
+
+func TestFormatPruneEmpty(t *testing.T) {
+	in := []byte(`apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  annotations:
+    alm-examples: '[]'
+  creationTimestamp: null
+  labels: {}
+  name: memcached-operator.v0.0.1
+spec:
+  apiservicedefinitions: {}
+  install:
+    spec:
+      deployments:
+      - name: memcached-operator
+        spec:
+          replicas: 1
+          strategy: {}
+          template:
+            metadata:
+              creationTimestamp: null
+            spec:
+              containers:
+              - args: []
+                name: manager
+                resources: {}
+              volumes:
+              - emptyDir: {}
+                name: cache
+  relatedImages: []
+  replaces: ""
+`)
+	pruned := `apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  annotations:
+    alm-examples: '[]'
+  name: memcached-operator.v0.0.1
+spec:
+  install:
+    spec:
+      deployments:
+      - name: memcached-operator
+        spec:
+          replicas: 1
+          template:
+            spec:
+              containers:
+              - name: manager
+              volumes:
+              - emptyDir: {}
+                name: cache
+  replaces: ""
+`
+	out, err := Format(in, Options{PruneEmpty: true})
+	require.NoError(t, err)
+	assert.Equal(t, pruned, string(out))
+
+	// Empty values are kept unless pruned.
+	out, err = Format(in, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, string(in), string(out))
+
+	// Pruned YAML is re-encoded if other options are set.
+	out, err = Format(in, Options{PruneEmpty: true, Indent: 4})
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "\n    install:\n        spec:\n            deployments:\n                - name: memcached-operator\n")
+}
+
+func TestFormatPruneEmptyKeepsMeaningfulEmpties(t *testing.T) {
+	in := []byte(`kind: CustomResourceDefinition
+spec:
+  versions:
+  - name: v1
+    schema:
+      openAPIV3Schema:
+        properties:
+          spec: {}
+        type: object
+    subresources:
+      status: {}
+---
+kind: NetworkPolicy
+spec:
+  podSelector: {}
+status: {}
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  creationTimestamp: null
+spec:
+  maxUnavailable: 1
+  selector: {}
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+spec:
+  endpoints: []
+  selector: {}
+`)
+	out, err := Format(in, Options{PruneEmpty: true})
+	require.NoError(t, err)
+	assert.Equal(t, `kind: CustomResourceDefinition
+spec:
+  versions:
+  - name: v1
+    schema:
+      openAPIV3Schema:
+        properties:
+          spec: {}
+        type: object
+    subresources:
+      status: {}
+---
+kind: NetworkPolicy
+spec:
+  podSelector: {}
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+spec:
+  maxUnavailable: 1
+  selector: {}
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+spec:
+  endpoints: []
+  selector: {}
+`, string(out))
+}
+
+func TestSetAnnotation(t *testing.T) {
+	for _, c := range []struct {
+		name, in, out string
+	}{
+		{
+			name: "replaces an existing annotation",
+			in:   "metadata:\n  annotations:\n    a: \"1\"\n    b: >-\n      long\n      value\n    c: \"3\"\n  name: x\nspec:\n- a\n",
+			out:  "metadata:\n  annotations:\n    a: \"1\"\n    b: new value\n    c: \"3\"\n  name: x\nspec:\n- a\n",
+		},
+		{
+			name: "inserts an annotation in sorted order",
+			in:   "metadata:\n    annotations:\n        a: \"1\"\n        c: \"3\"\n    name: x\n",
+			out:  "metadata:\n    annotations:\n        a: \"1\"\n        b: new value\n        c: \"3\"\n    name: x\n",
+		},
+		{
+			name: "appends an annotation to the last entry of the document",
+			in:   "kind: X\nmetadata:\n  name: x\n  annotations:\n    a: \"1\"",
+			out:  "kind: X\nmetadata:\n  name: x\n  annotations:\n    a: \"1\"\n    b: new value\n",
+		},
+		{
+			name: "adds annotations",
+			in:   "metadata:\n  creationTimestamp: null\n  name: x\nspec:\n  a:\n  - b\n",
+			out:  "metadata:\n  annotations:\n    b: new value\n  creationTimestamp: null\n  name: x\nspec:\n  a:\n  - b\n",
+		},
+		{
+			name: "rewrites flow-style annotations",
+			in:   "metadata:\n  annotations: {c: \"3\", a: '1'}\n  name: x\n",
+			out:  "metadata:\n  annotations:\n    a: '1'\n    b: new value\n    c: \"3\"\n  name: x\n",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := SetAnnotation([]byte(c.in), "b", "new value")
+			require.NoError(t, err)
+			assert.Equal(t, c.out, string(out))
+		})
+	}
+}
+
+func TestSetAnnotationInvalid(t *testing.T) {
+	for _, in := range []string{"a: 1\n---\nb: 2\n", "- a\n", "metadata: {}\n", "kind: X\n"} {
+		_, err := SetAnnotation([]byte(in), "b", "new value")
+		assert.Error(t, err, in)
+	}
+}